@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/QuUteO/video-communication/internal/config"
+	importerrepository "github.com/QuUteO/video-communication/internal/importer/repository"
+	importerservice "github.com/QuUteO/video-communication/internal/importer/service"
+	"github.com/QuUteO/video-communication/internal/logger"
+	"github.com/QuUteO/video-communication/internal/model"
+	postgres "github.com/QuUteO/video-communication/pkg/db"
+)
+
+// runImport подкоманда: import [-dry-run] [-source name] [-batch N] archive.jsonl
+// Архив "-" читается из stdin; отчет печатается в stdout в JSON
+func runImport(args []string) int {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "validate the archive and roll everything back")
+	source := fs.String("source", "", "name of the source system, used to map foreign user ids")
+	batch := fs.Int("batch", 0, "records per transaction")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: import [-dry-run] [-source name] [-batch N] archive.jsonl")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	cfg, err := config.New()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to init config:", err)
+		return 1
+	}
+	log := logger.New(cfg.Env)
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	client, err := postgres.NewClient(ctx, &cfg.Postgres)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to connect to database:", err)
+		return 1
+	}
+	defer client.Close()
+
+	var in io.Reader = os.Stdin
+	if path := fs.Arg(0); path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer f.Close()
+		in = f
+	}
+
+	srv := importerservice.NewImporterService(importerrepository.New(client, log), log)
+	report, err := srv.Import(ctx, in, model.ImportOptions{
+		Source:    *source,
+		DryRun:    *dryRun,
+		BatchSize: *batch,
+	})
+
+	if report != nil {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(report)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "import failed:", err)
+		return 1
+	}
+
+	return 0
+}
@@ -12,6 +12,16 @@ import (
 )
 
 func main() {
+	// Подкоманды
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "import":
+			os.Exit(runImport(os.Args[2:]))
+		case "role":
+			os.Exit(runRole(os.Args[2:]))
+		}
+	}
+
 	// Создание приложения
 	application, err := app.New()
 	if err != nil {
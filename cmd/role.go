@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	authpolicy "github.com/QuUteO/video-communication/internal/auth/policy"
+	authrepository "github.com/QuUteO/video-communication/internal/auth/repository"
+	"github.com/QuUteO/video-communication/internal/config"
+	"github.com/QuUteO/video-communication/internal/logger"
+	postgres "github.com/QuUteO/video-communication/pkg/db"
+	"github.com/gofrs/uuid"
+)
+
+// runRole подкоманда: role <email|user-id> <user|admin>
+// Нужна, чтобы назначить первого администратора: через API роли меняет только администратор
+func runRole(args []string) int {
+	fs := flag.NewFlagSet("role", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: role <email|user-id> <user|admin>")
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 2 || !authpolicy.ValidRole(fs.Arg(1)) {
+		fs.Usage()
+		return 2
+	}
+
+	cfg, err := config.New()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to init config:", err)
+		return 1
+	}
+	log := logger.New(cfg.Env)
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	client, err := postgres.NewClient(ctx, &cfg.Postgres)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to connect to database:", err)
+		return 1
+	}
+	defer client.Close()
+
+	repo := authrepository.New(client, log)
+
+	userID, err := uuid.FromString(fs.Arg(0))
+	if err != nil {
+		user, err := repo.FindByEmail(ctx, fs.Arg(0))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "user not found:", fs.Arg(0))
+			return 1
+		}
+		userID = user.Id
+	}
+
+	ok, err := repo.SetRole(ctx, userID, fs.Arg(1))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to set role:", err)
+		return 1
+	}
+	if !ok {
+		fmt.Fprintln(os.Stderr, "user not found or is the last admin:", userID)
+		return 1
+	}
+
+	fmt.Printf("%s is now %s\n", userID, fs.Arg(1))
+	return 0
+}
@@ -13,6 +13,7 @@ require (
 	github.com/jackc/pgconn v1.14.3
 	github.com/jackc/pgx/v4 v4.18.3
 	golang.org/x/crypto v0.47.0
+	golang.org/x/image v0.33.0
 )
 
 require (
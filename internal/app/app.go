@@ -8,19 +8,60 @@ import (
 	"net/http"
 	"time"
 
+	attachmenthandler "github.com/QuUteO/video-communication/internal/attachment/handler"
+	attachmentrepository "github.com/QuUteO/video-communication/internal/attachment/repository"
+	attachmentservice "github.com/QuUteO/video-communication/internal/attachment/service"
 	authhandler "github.com/QuUteO/video-communication/internal/auth/handler"
 	authjwt "github.com/QuUteO/video-communication/internal/auth/jwt"
+	authmiddleware "github.com/QuUteO/video-communication/internal/auth/middleware"
+	authpolicy "github.com/QuUteO/video-communication/internal/auth/policy"
 	authrepository "github.com/QuUteO/video-communication/internal/auth/repository"
 	authservice "github.com/QuUteO/video-communication/internal/auth/service"
+	bothandler "github.com/QuUteO/video-communication/internal/bot/handler"
+	botrepository "github.com/QuUteO/video-communication/internal/bot/repository"
+	botservice "github.com/QuUteO/video-communication/internal/bot/service"
+	"github.com/QuUteO/video-communication/internal/command"
 	"github.com/QuUteO/video-communication/internal/config"
+	exporthandler "github.com/QuUteO/video-communication/internal/export/handler"
+	exportrepository "github.com/QuUteO/video-communication/internal/export/repository"
+	exportservice "github.com/QuUteO/video-communication/internal/export/service"
+	importerhandler "github.com/QuUteO/video-communication/internal/importer/handler"
+	importerrepository "github.com/QuUteO/video-communication/internal/importer/repository"
+	importerservice "github.com/QuUteO/video-communication/internal/importer/service"
 	"github.com/QuUteO/video-communication/internal/logger"
+	mentionhandler "github.com/QuUteO/video-communication/internal/mention/handler"
+	mentionrepository "github.com/QuUteO/video-communication/internal/mention/repository"
+	mentionservice "github.com/QuUteO/video-communication/internal/mention/service"
+	"github.com/QuUteO/video-communication/internal/model"
+	pollhandler "github.com/QuUteO/video-communication/internal/poll/handler"
+	pollrepository "github.com/QuUteO/video-communication/internal/poll/repository"
+	pollservice "github.com/QuUteO/video-communication/internal/poll/service"
+	profilehandler "github.com/QuUteO/video-communication/internal/profile/handler"
+	profilerepository "github.com/QuUteO/video-communication/internal/profile/repository"
+	profileservice "github.com/QuUteO/video-communication/internal/profile/service"
+	retentionhandler "github.com/QuUteO/video-communication/internal/retention/handler"
+	retentionrepository "github.com/QuUteO/video-communication/internal/retention/repository"
+	retentionservice "github.com/QuUteO/video-communication/internal/retention/service"
 	"github.com/QuUteO/video-communication/internal/routes"
+	schedulehandler "github.com/QuUteO/video-communication/internal/schedule/handler"
+	schedulerepository "github.com/QuUteO/video-communication/internal/schedule/repository"
+	scheduleservice "github.com/QuUteO/video-communication/internal/schedule/service"
+	searchhandler "github.com/QuUteO/video-communication/internal/search/handler"
+	searchrepository "github.com/QuUteO/video-communication/internal/search/repository"
+	searchservice "github.com/QuUteO/video-communication/internal/search/service"
 	"github.com/QuUteO/video-communication/internal/user/handler"
 	"github.com/QuUteO/video-communication/internal/user/repository"
 	"github.com/QuUteO/video-communication/internal/user/service"
+	webhookhandler "github.com/QuUteO/video-communication/internal/webhook/handler"
+	webhookrepository "github.com/QuUteO/video-communication/internal/webhook/repository"
+	webhookservice "github.com/QuUteO/video-communication/internal/webhook/service"
 	"github.com/QuUteO/video-communication/internal/websocket"
 	"github.com/QuUteO/video-communication/pkg/db"
+	"github.com/QuUteO/video-communication/pkg/mail"
+	"github.com/QuUteO/video-communication/pkg/safehttp"
+	"github.com/QuUteO/video-communication/pkg/storage"
 	"github.com/go-chi/chi/v5"
+	"github.com/gofrs/uuid"
 )
 
 type Application struct {
@@ -28,10 +69,15 @@ type Application struct {
 	cfg    *config.Config
 	logger *slog.Logger
 	server *http.Server
+
+	// контекст фоновых воркеров, отменяется при Shutdown
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
 func New() (*Application, error) {
 	app := &Application{}
+	app.ctx, app.cancel = context.WithCancel(context.Background())
 
 	if err := app.initConfig(); err != nil {
 		return nil, fmt.Errorf("failed to init config: %w", err)
@@ -78,26 +124,150 @@ func (a *Application) initRouter() error {
 		return fmt.Errorf("failed to connect to database: %w", err)
 	}
 
-	// Пользовательский сервис
+	// AuthJWT
+	// старый ключ проверяет подписи, пока живут выданные им токены
+	jwtKeys, err := authjwt.NewKeySet(authjwt.KeySetConfig{
+		Dir:         a.cfg.JWT.Keys.Dir,
+		Algorithm:   a.cfg.JWT.Keys.Algorithm,
+		RotateEvery: a.cfg.JWT.Keys.RotateEvery,
+		Grace:       a.cfg.JWT.Ttl + a.cfg.JWT.Keys.Interval,
+		Interval:    a.cfg.JWT.Keys.Interval,
+	}, a.logger)
+	if err != nil {
+		return fmt.Errorf("failed to load jwt keys: %w", err)
+	}
+	go jwtKeys.Run(a.ctx)
+	AuthJWT := authjwt.NewJWT(jwtKeys, a.cfg.JWT.Issuer, a.cfg.JWT.Audience, a.cfg.JWT.Ttl)
+
+	// Поиск по сообщениям
+	searchRepo := searchrepository.New(client, a.logger)
+	searchSrv := searchservice.NewSearchService(searchRepo, a.logger)
+	searchHandler := searchhandler.NewHandler(searchSrv, a.logger)
+
+	// Исходящие вебхуки и боты ходят по URL пользователей: внутренние адреса запрещены
+	outbound, err := safehttp.NewClient(a.cfg.Webhooks.Timeout, a.cfg.Webhooks.AllowedNetworks)
+	if err != nil {
+		return fmt.Errorf("failed to init outbound http client: %w", err)
+	}
+
+	webhookRepo := webhookrepository.New(client, a.logger)
+	dispatcher := webhookservice.NewDispatcher(webhookRepo, outbound, &a.cfg.Webhooks, a.logger)
+	go dispatcher.Run(a.ctx)
+
+	// Хранилище файлов
+	store, err := storage.New(&a.cfg.Storage)
+	if err != nil {
+		return fmt.Errorf("failed to init storage: %w", err)
+	}
+
+	// WebSocket
+	hub := websocket.NewHub(dispatcher, a.logger)
+
+	// profile: хаб подписывает сообщения актуальными именем и аватаром, в том числе
+	// сообщения авторов без соединений; подключается до запуска фоновых рассыльщиков
+	profileSrv := profileservice.NewProfileService(profilerepository.New(client, a.logger), hub, a.logger)
+	profileHandler := profilehandler.NewHandler(profileSrv, a.logger)
+	hub.UseProfiles(profileSrv)
+
+	// Почта
+	mailer, err := mail.New(&a.cfg.Mail, a.logger)
+	if err != nil {
+		return fmt.Errorf("failed to init mail: %w", err)
+	}
+
+	// AuthService: отзыв сессии закрывает ее websocket-соединения
+	repositor := authrepository.New(client, a.logger)
+	servic := authservice.NewAuthService(repositor, AuthJWT, a.cfg.JWT.RefreshTtl, hub, mailer, &a.cfg.Mail, &a.cfg.TwoFactor, &a.cfg.Login, &a.cfg.OIDC, a.logger)
+	authHandler := authhandler.NewHandler(servic, AuthJWT, &a.cfg.OIDC, a.logger)
+
+	// Пользовательский сервис: смена адреса требует нового подтверждения
 	repo := repository.NewRepository(client, a.logger)
-	srv := service.NewService(repo, a.logger)
+	srv := service.NewService(repo, servic, a.logger)
 	userHandler := handler.NewUserHandler(srv, a.logger)
 
-	// AuthJWT
-	AuthJWT := authjwt.NewJWT(a.cfg.JWT.Secret, time.Minute*time.Duration(a.cfg.JWT.Ttl))
+	webhookSrv := webhookservice.NewWebhookService(webhookRepo, srv, a.logger)
+	webhookHandler := webhookhandler.NewHandler(webhookSrv, a.logger)
 
-	// AuthService
-	repositor := authrepository.New(client, a.logger)
-	servic := authservice.NewAuthService(repositor, AuthJWT, a.logger)
-	authHandler := authhandler.NewHandler(servic, a.logger)
+	// Вложения и миниатюры
+	attachmentRepo := attachmentrepository.New(client, a.logger)
+	thumbnailer := attachmentservice.NewThumbnailer(attachmentRepo, store, func(att model.Attachment) {
+		// миниатюры готовы после отправки сообщения — сообщаем клиентам канала
+		if att.MessageID == uuid.Nil {
+			return
+		}
+		hub.Broadcast(model.Message{
+			ID:          att.MessageID,
+			Type:        "attachment_updated",
+			Channel:     att.Channel,
+			Time:        time.Now(),
+			Attachments: []model.Attachment{att},
+		})
+	}, a.logger)
+	go thumbnailer.Run(a.ctx)
 
-	// WebSocket
-	hub := websocket.NewHub(a.logger)
-	wsHandler := websocket.NewHandlerWS(hub, srv, a.logger)
+	attachmentSrv := attachmentservice.NewAttachmentService(attachmentRepo, store, srv, thumbnailer, &a.cfg.Storage, a.logger)
+	attachmentHandler := attachmenthandler.NewHandler(attachmentSrv, a.cfg.Storage.MaxUploadSize, a.logger)
+
+	// Упоминания
+	mentionRepo := mentionrepository.New(client, a.logger)
+	mentionSrv := mentionservice.NewMentionService(mentionRepo, a.logger)
+	mentionHandler := mentionhandler.NewHandler(mentionSrv, a.logger)
+
+	incomingSrv := webhookservice.NewIncomingWebhookService(webhookRepo, srv, hub, a.logger)
+	incomingHandler := webhookhandler.NewIncomingHandler(incomingSrv, a.logger)
+
+	// Slash-команды и боты
+	commands := command.NewRegistry()
+	command.RegisterBuiltins(commands)
+	botRepo := botrepository.New(client, a.logger)
+	botSrv := botservice.NewBotService(botRepo, commands, outbound, a.logger)
+	if err := botSrv.Load(a.ctx); err != nil {
+		return fmt.Errorf("failed to load bots: %w", err)
+	}
+	botHandler := bothandler.NewHandler(botSrv, a.logger)
+
+	// сообщения из сокета и отложенные проходят после сохранения одни и те же шаги
+	publisher := websocket.NewPublisher(hub, attachmentSrv, mentionSrv, a.logger)
+
+	// Отложенные сообщения
+	scheduleRepo := schedulerepository.New(client, a.logger)
+	scheduleSrv := scheduleservice.NewScheduleService(scheduleRepo, srv, attachmentSrv, a.logger)
+	scheduleHandler := schedulehandler.NewHandler(scheduleSrv, a.logger)
+	scheduler := scheduleservice.NewScheduler(scheduleRepo, srv, attachmentSrv, publisher, &a.cfg.Scheduler, a.logger)
+	go scheduler.Run(a.ctx)
+
+	// Срок хранения сообщений
+	retentionRepo := retentionrepository.New(client, a.logger)
+	retentionSrv := retentionservice.NewRetentionService(retentionRepo, srv, a.logger)
+	retentionHandler := retentionhandler.NewHandler(retentionSrv, a.logger)
+	janitor := retentionservice.NewJanitor(retentionRepo, attachmentSrv, hub, &a.cfg.Retention, a.logger)
+	go janitor.Run(a.ctx)
+
+	// Опросы
+	pollRepo := pollrepository.New(client, a.logger)
+	pollSrv := pollservice.NewPollService(pollRepo, srv, hub, a.logger)
+	pollHandler := pollhandler.NewHandler(pollSrv, a.logger)
+
+	// Выгрузка истории каналов
+	exportRepo := exportrepository.New(client, a.logger)
+	exportWorker := exportservice.NewWorker(exportRepo, store, a.logger)
+	go exportWorker.Run(a.ctx)
+	exportSrv := exportservice.NewExportService(exportRepo, store, srv, exportWorker, a.logger)
+	exportHandler := exporthandler.NewHandler(exportSrv, a.logger)
+
+	// import
+	importRepo := importerrepository.New(client, a.logger)
+	importSrv := importerservice.NewImporterService(importRepo, a.logger)
+	importHandler := importerhandler.NewHandler(importSrv, a.logger)
+
+	wsHandler := websocket.NewHandlerWS(hub, srv, attachmentSrv, publisher, commands, scheduleSrv, pollSrv, profileSrv, a.logger)
 	go hub.Run()
 
+	// права на маршрутах: роль пользователя читается из users.role
+	authz := authmiddleware.NewAuthorizer(authpolicy.New(a.cfg.Admin.RequireTwoFactor), servic)
+
 	// Регистрация маршрутов
-	route := routes.NewRoute(userHandler, wsHandler, authHandler, AuthJWT)
+	route := routes.NewRoute(userHandler, wsHandler, authHandler, searchHandler, attachmentHandler, mentionHandler, webhookHandler, incomingHandler, botHandler, scheduleHandler, retentionHandler, pollHandler, exportHandler, importHandler, profileHandler, AuthJWT, servic, servic, authz)
 	route.RegisterRoutes(a.router)
 
 	// Настройка HTTP сервера
@@ -121,5 +291,6 @@ func (a *Application) Run() error {
 
 func (a *Application) Shutdown(ctx context.Context) error {
 	a.logger.Info("Shutting down server")
+	a.cancel()
 	return a.server.Shutdown(ctx)
 }
@@ -0,0 +1,198 @@
+package attachmenthandler
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/QuUteO/video-communication/internal/attachment/service"
+	authmiddleware "github.com/QuUteO/video-communication/internal/auth/middleware"
+	"github.com/QuUteO/video-communication/internal/model"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// запас на заголовки multipart поверх максимального размера файла
+const multipartOverhead = 1 << 20
+
+type Handler struct {
+	service attachmentservice.Service
+	maxSize int64
+	logger  *slog.Logger
+}
+
+func NewHandler(service attachmentservice.Service, maxSize int64, logger *slog.Logger) *Handler {
+	return &Handler{
+		service: service,
+		maxSize: maxSize,
+		logger:  logger,
+	}
+}
+
+// Upload POST /attachments (multipart/form-data: channel, file)
+func (h *Handler) Upload(w http.ResponseWriter, r *http.Request) {
+	const op = "internal/attachment/handler/Upload"
+	log := h.logger.With("op: ", op)
+
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxSize+multipartOverhead)
+	if err := r.ParseMultipartForm(8 << 20); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			h.error(w, r, http.StatusRequestEntityTooLarge, attachmentservice.ErrTooLarge.Error())
+			return
+		}
+		h.error(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	channel := r.FormValue("channel")
+	if channel == "" {
+		h.error(w, r, http.StatusBadRequest, "channel is required")
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		h.error(w, r, http.StatusBadRequest, "file is required")
+		return
+	}
+	defer file.Close()
+
+	userID, _ := r.Context().Value(authmiddleware.UserIDKey).(string)
+
+	attachment, err := h.service.Upload(r.Context(), userID, channel, header.Filename, header.Size, file)
+	if err != nil {
+		status := statusFor(err)
+		if status == http.StatusInternalServerError {
+			log.Error("Failed to upload attachment", slog.Any("error", err))
+		}
+		h.error(w, r, status, err.Error())
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, model.Response{
+		StatusCode: http.StatusCreated,
+		Message:    "Attachment uploaded",
+		Data:       attachment,
+		Error:      "nil",
+	})
+}
+
+// Download GET /attachments/{id}
+func (h *Handler) Download(w http.ResponseWriter, r *http.Request) {
+	const op = "internal/attachment/handler/Download"
+	log := h.logger.With("op: ", op)
+
+	userID, _ := r.Context().Value(authmiddleware.UserIDKey).(string)
+
+	attachment, body, err := h.service.Open(r.Context(), userID, chi.URLParam(r, "id"))
+	if err != nil {
+		status := statusFor(err)
+		if status == http.StatusInternalServerError {
+			log.Error("Failed to open attachment", slog.Any("error", err))
+		}
+		h.error(w, r, status, err.Error())
+		return
+	}
+	defer body.Close()
+
+	disposition := "attachment"
+	if strings.HasPrefix(attachment.ContentType, "image/") {
+		disposition = "inline"
+	}
+
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(attachment.Size, 10))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": attachment.FileName}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "private, max-age=3600")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := io.Copy(w, body); err != nil {
+		log.Error("Failed to stream attachment", slog.Any("error", err))
+	}
+}
+
+// Thumbnail GET /attachments/{id}/thumbnails/{size}
+func (h *Handler) Thumbnail(w http.ResponseWriter, r *http.Request) {
+	const op = "internal/attachment/handler/Thumbnail"
+	log := h.logger.With("op: ", op)
+
+	userID, _ := r.Context().Value(authmiddleware.UserIDKey).(string)
+
+	thumb, body, err := h.service.OpenThumbnail(r.Context(), userID, chi.URLParam(r, "id"), chi.URLParam(r, "size"))
+	if err != nil {
+		status := statusFor(err)
+		if status == http.StatusInternalServerError {
+			log.Error("Failed to open thumbnail", slog.Any("error", err))
+		}
+		h.error(w, r, status, err.Error())
+		return
+	}
+	defer body.Close()
+
+	w.Header().Set("Content-Type", thumb.ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := io.Copy(w, body); err != nil {
+		log.Error("Failed to stream thumbnail", slog.Any("error", err))
+	}
+}
+
+// Avatar GET /users/{id}/avatar?size=small|medium
+func (h *Handler) Avatar(w http.ResponseWriter, r *http.Request) {
+	const op = "internal/attachment/handler/Avatar"
+	log := h.logger.With("op: ", op)
+
+	contentType, body, err := h.service.OpenAvatar(r.Context(), chi.URLParam(r, "id"), r.URL.Query().Get("size"))
+	if err != nil {
+		status := statusFor(err)
+		if status == http.StatusInternalServerError {
+			log.Error("Failed to open avatar", slog.Any("error", err))
+		}
+		h.error(w, r, status, err.Error())
+		return
+	}
+	defer body.Close()
+
+	// адрес меняется вместе с аватаром (?v=), поэтому кеш может быть долгим
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := io.Copy(w, body); err != nil {
+		log.Error("Failed to stream avatar", slog.Any("error", err))
+	}
+}
+
+func (h *Handler) error(w http.ResponseWriter, r *http.Request, status int, msg string) {
+	render.Status(r, status)
+	render.JSON(w, r, model.Response{
+		StatusCode: status,
+		Error:      msg,
+	})
+}
+
+func statusFor(err error) int {
+	switch {
+	case errors.Is(err, attachmentservice.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, attachmentservice.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, attachmentservice.ErrTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, attachmentservice.ErrTypeNotAllowed):
+		return http.StatusUnsupportedMediaType
+	default:
+		return http.StatusInternalServerError
+	}
+}
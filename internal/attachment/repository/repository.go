@@ -0,0 +1,292 @@
+package attachmentrepository
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+
+	"github.com/QuUteO/video-communication/internal/model"
+	postgres "github.com/QuUteO/video-communication/pkg/db"
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+type Repository interface {
+	Create(ctx context.Context, a *model.Attachment) error
+	FindByID(ctx context.Context, id string) (*model.Attachment, error)
+	FindAvatar(ctx context.Context, userID string) (*model.Attachment, error)
+	FindPending(ctx context.Context, ids []string, userID string, channel string) ([]model.Attachment, error)
+	BindToMessage(ctx context.Context, ids []string, messageID uuid.UUID) error
+	FindByMessageIDs(ctx context.Context, messageIDs []string) ([]model.Attachment, error)
+	FindPendingImages(ctx context.Context, limit int) ([]model.Attachment, error)
+	SaveImageInfo(ctx context.Context, id uuid.UUID, width, height int, thumbnails []model.Thumbnail) error
+	DeleteByMessageIDs(ctx context.Context, messageIDs []string) ([]model.Attachment, error)
+}
+
+type repository struct {
+	db     postgres.Client
+	logger *slog.Logger
+}
+
+const selectColumns = `id, message_id, channel, user_id, file_name, content_type, size, storage_key, created_at,
+	width, height, thumbnails`
+
+// thumbnailRow Представление миниатюры в колонке thumbnails (JSONB)
+type thumbnailRow struct {
+	Size        string `json:"size"`
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+	ContentType string `json:"content_type"`
+	Key         string `json:"key"`
+}
+
+func (r *repository) Create(ctx context.Context, a *model.Attachment) error {
+	const op = "./internal/attachment/repository.Create"
+	log := r.logger.With("op: ", op)
+
+	q := `
+		INSERT INTO attachments (id, channel, user_id, file_name, content_type, size, storage_key, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+
+	if _, err := r.db.Exec(ctx, q,
+		a.ID,
+		a.Channel,
+		a.UserID,
+		a.FileName,
+		a.ContentType,
+		a.Size,
+		a.StorageKey,
+		a.CreatedAt,
+	); err != nil {
+		log.Error("Error to insert attachment", slog.Any("err", err))
+		return err
+	}
+
+	return nil
+}
+
+func (r *repository) FindByID(ctx context.Context, id string) (*model.Attachment, error) {
+	const op = "./internal/attachment/repository.FindByID"
+	log := r.logger.With("op: ", op)
+
+	// вложения истекших сообщений недоступны, даже если janitor их еще не удалил
+	q := `
+		SELECT ` + selectColumns + `
+		FROM attachments
+		WHERE id = $1
+		  AND (message_id IS NULL OR EXISTS (
+			SELECT 1 FROM message m
+			WHERE m.id = attachments.message_id AND message_is_live(m.channel, m.created_at, m.expires_at)
+		  ))
+	`
+
+	a, err := scanAttachment(r.db.QueryRow(ctx, q, id))
+	if err != nil {
+		log.Error("Error to find attachment", slog.Any("err", err))
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// FindAvatar изображение, выбранное пользователем аватаром
+func (r *repository) FindAvatar(ctx context.Context, userID string) (*model.Attachment, error) {
+	q := `
+		SELECT ` + selectColumns + `
+		FROM attachments
+		WHERE id = (SELECT avatar_id FROM users WHERE id = $1)
+	`
+
+	return scanAttachment(r.db.QueryRow(ctx, q, userID))
+}
+
+// FindPending Вложения пользователя в канале, еще не привязанные к сообщению
+func (r *repository) FindPending(ctx context.Context, ids []string, userID string, channel string) ([]model.Attachment, error) {
+	const op = "./internal/attachment/repository.FindPending"
+	log := r.logger.With("op: ", op)
+
+	q := `
+		SELECT ` + selectColumns + `
+		FROM attachments
+		WHERE id = ANY($1::uuid[]) AND user_id = $2 AND channel = $3 AND message_id IS NULL
+		ORDER BY created_at
+	`
+
+	rows, err := r.db.Query(ctx, q, ids, userID, channel)
+	if err != nil {
+		log.Error("Error to find pending attachments", slog.Any("err", err))
+		return nil, err
+	}
+
+	return collect(rows)
+}
+
+func (r *repository) BindToMessage(ctx context.Context, ids []string, messageID uuid.UUID) error {
+	const op = "./internal/attachment/repository.BindToMessage"
+	log := r.logger.With("op: ", op)
+
+	q := `UPDATE attachments SET message_id = $1 WHERE id = ANY($2::uuid[]) AND message_id IS NULL`
+
+	if _, err := r.db.Exec(ctx, q, messageID, ids); err != nil {
+		log.Error("Error to bind attachments", slog.Any("err", err))
+		return err
+	}
+
+	return nil
+}
+
+func (r *repository) FindByMessageIDs(ctx context.Context, messageIDs []string) ([]model.Attachment, error) {
+	const op = "./internal/attachment/repository.FindByMessageIDs"
+	log := r.logger.With("op: ", op)
+
+	q := `
+		SELECT ` + selectColumns + `
+		FROM attachments
+		WHERE message_id = ANY($1::uuid[])
+		ORDER BY created_at
+	`
+
+	rows, err := r.db.Query(ctx, q, messageIDs)
+	if err != nil {
+		log.Error("Error to find message attachments", slog.Any("err", err))
+		return nil, err
+	}
+
+	return collect(rows)
+}
+
+// FindPendingImages Изображения, для которых еще не построены миниатюры
+func (r *repository) FindPendingImages(ctx context.Context, limit int) ([]model.Attachment, error) {
+	const op = "./internal/attachment/repository.FindPendingImages"
+	log := r.logger.With("op: ", op)
+
+	q := `
+		SELECT ` + selectColumns + `
+		FROM attachments
+		WHERE width IS NULL AND content_type LIKE 'image/%'
+		ORDER BY created_at
+		LIMIT $1
+	`
+
+	rows, err := r.db.Query(ctx, q, limit)
+	if err != nil {
+		log.Error("Error to find pending images", slog.Any("err", err))
+		return nil, err
+	}
+
+	return collect(rows)
+}
+
+func (r *repository) SaveImageInfo(ctx context.Context, id uuid.UUID, width, height int, thumbnails []model.Thumbnail) error {
+	const op = "./internal/attachment/repository.SaveImageInfo"
+	log := r.logger.With("op: ", op)
+
+	thumbs := make([]thumbnailRow, 0, len(thumbnails))
+	for _, t := range thumbnails {
+		thumbs = append(thumbs, thumbnailRow{
+			Size:        t.Size,
+			Width:       t.Width,
+			Height:      t.Height,
+			ContentType: t.ContentType,
+			Key:         t.StorageKey,
+		})
+	}
+
+	data, err := json.Marshal(thumbs)
+	if err != nil {
+		return err
+	}
+
+	q := `UPDATE attachments SET width = $1, height = $2, thumbnails = $3 WHERE id = $4`
+
+	if _, err := r.db.Exec(ctx, q, width, height, string(data), id); err != nil {
+		log.Error("Error to save image info", slog.Any("err", err))
+		return err
+	}
+
+	return nil
+}
+
+// DeleteByMessageIDs удаляет вложения сообщений и возвращает их, чтобы можно было удалить файлы
+func (r *repository) DeleteByMessageIDs(ctx context.Context, messageIDs []string) ([]model.Attachment, error) {
+	const op = "./internal/attachment/repository.DeleteByMessageIDs"
+	log := r.logger.With("op: ", op)
+
+	q := `DELETE FROM attachments WHERE message_id = ANY($1::uuid[]) RETURNING ` + selectColumns
+
+	rows, err := r.db.Query(ctx, q, messageIDs)
+	if err != nil {
+		log.Error("Error to delete message attachments", slog.Any("err", err))
+		return nil, err
+	}
+
+	return collect(rows)
+}
+
+func collect(rows pgx.Rows) ([]model.Attachment, error) {
+	defer rows.Close()
+
+	attachments := make([]model.Attachment, 0)
+	for rows.Next() {
+		a, err := scanAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, *a)
+	}
+
+	return attachments, rows.Err()
+}
+
+func scanAttachment(row pgx.Row) (*model.Attachment, error) {
+	var a model.Attachment
+	var messageID uuid.NullUUID
+	var width, height *int
+	var thumbs []byte
+
+	if err := row.Scan(
+		&a.ID,
+		&messageID,
+		&a.Channel,
+		&a.UserID,
+		&a.FileName,
+		&a.ContentType,
+		&a.Size,
+		&a.StorageKey,
+		&a.CreatedAt,
+		&width,
+		&height,
+		&thumbs,
+	); err != nil {
+		return nil, err
+	}
+	a.MessageID = messageID.UUID
+
+	if width != nil && height != nil {
+		a.Width, a.Height = *width, *height
+	}
+
+	var rows []thumbnailRow
+	if err := json.Unmarshal(thumbs, &rows); err != nil {
+		return nil, err
+	}
+	for _, t := range rows {
+		a.Thumbnails = append(a.Thumbnails, model.Thumbnail{
+			Size:        t.Size,
+			Width:       t.Width,
+			Height:      t.Height,
+			ContentType: t.ContentType,
+			StorageKey:  t.Key,
+		})
+	}
+
+	return &a, nil
+}
+
+func New(db postgres.Client, logger *slog.Logger) Repository {
+	return &repository{
+		db:     db,
+		logger: logger,
+	}
+}
@@ -0,0 +1,405 @@
+package attachmentservice
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/QuUteO/video-communication/internal/attachment/repository"
+	"github.com/QuUteO/video-communication/internal/config"
+	"github.com/QuUteO/video-communication/internal/model"
+	"github.com/QuUteO/video-communication/internal/user/service"
+	"github.com/QuUteO/video-communication/pkg/storage"
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+const maxAttachmentsPerMessage = 10
+
+var (
+	ErrNotFound          = errors.New("attachment not found")
+	ErrForbidden         = errors.New("no access to channel")
+	ErrTooLarge          = errors.New("file is too large")
+	ErrTypeNotAllowed    = errors.New("file type is not allowed")
+	ErrInvalidAttachment = errors.New("invalid attachments")
+)
+
+type Service interface {
+	Upload(ctx context.Context, userID, channel, fileName string, size int64, r io.ReadSeeker) (*model.Attachment, error)
+	Open(ctx context.Context, userID, id string) (*model.Attachment, io.ReadCloser, error)
+	OpenThumbnail(ctx context.Context, userID, id, size string) (*model.Thumbnail, io.ReadCloser, error)
+	// OpenAvatar аватар пользователя виден всем, без проверки членства в канале; size "" — оригинал
+	OpenAvatar(ctx context.Context, ownerID, size string) (string, io.ReadCloser, error)
+	Resolve(ctx context.Context, userID, channel string, ids []string) ([]model.Attachment, error)
+	Bind(ctx context.Context, msg *model.Message) error
+	FillMessages(ctx context.Context, messages []model.Message) error
+	Purge(ctx context.Context, messageIDs []string) error
+}
+
+type AttachmentService struct {
+	repo    attachmentrepository.Repository
+	store   storage.Storage
+	users   service.Service
+	thumbs  *Thumbnailer
+	maxSize int64
+	allowed []string
+	logger  *slog.Logger
+}
+
+func (s *AttachmentService) Upload(ctx context.Context, userID, channel, fileName string, size int64, r io.ReadSeeker) (*model.Attachment, error) {
+	const op = "internal/attachment/service.Upload"
+	log := s.logger.With("op: ", op)
+
+	if size > s.maxSize {
+		return nil, ErrTooLarge
+	}
+
+	if err := s.checkAccess(ctx, channel, userID); err != nil {
+		return nil, err
+	}
+
+	contentType, err := s.detectType(fileName, r)
+	if err != nil {
+		return nil, err
+	}
+
+	id := uuid.Must(uuid.NewV4())
+	a := &model.Attachment{
+		ID:          id,
+		Channel:     channel,
+		UserID:      uuid.FromStringOrNil(userID),
+		FileName:    sanitizeFileName(fileName),
+		ContentType: contentType,
+		Size:        size,
+		StorageKey:  "attachments/" + id.String(),
+		CreatedAt:   time.Now(),
+	}
+
+	if err := s.store.Put(ctx, a.StorageKey, r, size, contentType); err != nil {
+		log.Error("Error storing attachment", slog.Any("error", err))
+		return nil, err
+	}
+
+	if err := s.repo.Create(ctx, a); err != nil {
+		log.Error("Error saving attachment", slog.Any("error", err))
+		if err := s.store.Delete(ctx, a.StorageKey); err != nil {
+			log.Error("Error removing orphan blob", slog.Any("error", err))
+		}
+		return nil, err
+	}
+
+	s.thumbs.Enqueue(*a)
+
+	withURL(a)
+	return a, nil
+}
+
+func (s *AttachmentService) Open(ctx context.Context, userID, id string) (*model.Attachment, io.ReadCloser, error) {
+	const op = "internal/attachment/service.Open"
+	log := s.logger.With("op: ", op)
+
+	a, err := s.find(ctx, userID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	body, err := s.open(ctx, a.StorageKey)
+	if err != nil {
+		log.Error("Error reading attachment", slog.Any("error", err))
+		return nil, nil, err
+	}
+
+	withURL(a)
+	return a, body, nil
+}
+
+func (s *AttachmentService) OpenThumbnail(ctx context.Context, userID, id, size string) (*model.Thumbnail, io.ReadCloser, error) {
+	const op = "internal/attachment/service.OpenThumbnail"
+	log := s.logger.With("op: ", op)
+
+	a, err := s.find(ctx, userID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	withURL(a)
+	for _, thumb := range a.Thumbnails {
+		if thumb.Size != size {
+			continue
+		}
+
+		body, err := s.open(ctx, thumb.StorageKey)
+		if err != nil {
+			log.Error("Error reading thumbnail", slog.Any("error", err))
+			return nil, nil, err
+		}
+		return &thumb, body, nil
+	}
+
+	return nil, nil, ErrNotFound
+}
+
+func (s *AttachmentService) OpenAvatar(ctx context.Context, ownerID, size string) (string, io.ReadCloser, error) {
+	const op = "internal/attachment/service.OpenAvatar"
+	log := s.logger.With("op: ", op)
+
+	if _, err := uuid.FromString(ownerID); err != nil {
+		return "", nil, ErrNotFound
+	}
+
+	a, err := s.repo.FindAvatar(ctx, ownerID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil, ErrNotFound
+	}
+	if err != nil {
+		log.Error("Error finding avatar", slog.Any("error", err))
+		return "", nil, err
+	}
+
+	key, contentType := a.StorageKey, a.ContentType
+	if size != "" {
+		key = ""
+		for _, thumb := range a.Thumbnails {
+			if thumb.Size == size {
+				key, contentType = thumb.StorageKey, thumb.ContentType
+			}
+		}
+		if key == "" {
+			return "", nil, ErrNotFound
+		}
+	}
+
+	body, err := s.open(ctx, key)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return contentType, body, nil
+}
+
+// find загружает вложение и проверяет доступ пользователя к его каналу
+func (s *AttachmentService) find(ctx context.Context, userID, id string) (*model.Attachment, error) {
+	if _, err := uuid.FromString(id); err != nil {
+		return nil, ErrNotFound
+	}
+
+	a, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	if err := s.checkAccess(ctx, a.Channel, userID); err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+func (s *AttachmentService) open(ctx context.Context, key string) (io.ReadCloser, error) {
+	body, err := s.store.Get(ctx, key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, ErrNotFound
+	}
+	return body, err
+}
+
+// Resolve проверяет, что вложения загружены этим пользователем в этот канал и еще не отправлены
+func (s *AttachmentService) Resolve(ctx context.Context, userID, channel string, ids []string) ([]model.Attachment, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	if len(ids) > maxAttachmentsPerMessage {
+		return nil, ErrInvalidAttachment
+	}
+	for _, id := range ids {
+		if _, err := uuid.FromString(id); err != nil {
+			return nil, ErrInvalidAttachment
+		}
+	}
+
+	attachments, err := s.repo.FindPending(ctx, ids, userID, channel)
+	if err != nil {
+		return nil, err
+	}
+	if len(attachments) != len(ids) {
+		return nil, ErrInvalidAttachment
+	}
+
+	for i := range attachments {
+		withURL(&attachments[i])
+	}
+	return attachments, nil
+}
+
+// Bind привязывает вложения к сохраненному сообщению
+func (s *AttachmentService) Bind(ctx context.Context, msg *model.Message) error {
+	if len(msg.Attachments) == 0 {
+		return nil
+	}
+
+	ids := make([]string, 0, len(msg.Attachments))
+	for i := range msg.Attachments {
+		msg.Attachments[i].MessageID = msg.ID
+		ids = append(ids, msg.Attachments[i].ID.String())
+	}
+
+	if err := s.repo.BindToMessage(ctx, ids, msg.ID); err != nil {
+		return err
+	}
+
+	// миниатюры могли появиться после FindPending; если их еще нет, воркер увидит
+	// message_id и разошлет attachment_updated сам
+	attachments, err := s.repo.FindByMessageIDs(ctx, []string{msg.ID.String()})
+	if err != nil {
+		return err
+	}
+	for i := range attachments {
+		withURL(&attachments[i])
+	}
+	msg.Attachments = attachments
+	return nil
+}
+
+// FillMessages подгружает вложения для истории сообщений одним запросом
+func (s *AttachmentService) FillMessages(ctx context.Context, messages []model.Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	ids := make([]string, 0, len(messages))
+	index := make(map[uuid.UUID]int, len(messages))
+	for i, m := range messages {
+		ids = append(ids, m.ID.String())
+		index[m.ID] = i
+	}
+
+	attachments, err := s.repo.FindByMessageIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	for _, a := range attachments {
+		withURL(&a)
+		if i, ok := index[a.MessageID]; ok {
+			messages[i].Attachments = append(messages[i].Attachments, a)
+		}
+	}
+	return nil
+}
+
+// Purge удаляет вложения сообщений вместе с файлами и миниатюрами в хранилище
+func (s *AttachmentService) Purge(ctx context.Context, messageIDs []string) error {
+	const op = "internal/attachment/service.Purge"
+	log := s.logger.With("op: ", op)
+
+	if len(messageIDs) == 0 {
+		return nil
+	}
+
+	attachments, err := s.repo.DeleteByMessageIDs(ctx, messageIDs)
+	if err != nil {
+		return err
+	}
+
+	// файл, который не удалось удалить, останется сиротой, но запись уже недоступна
+	for _, a := range attachments {
+		keys := []string{a.StorageKey}
+		for _, t := range a.Thumbnails {
+			keys = append(keys, t.StorageKey)
+		}
+
+		for _, key := range keys {
+			if err := s.store.Delete(ctx, key); err != nil && !errors.Is(err, storage.ErrNotFound) {
+				log.Warn("Error deleting attachment file", slog.String("key", key), slog.Any("error", err))
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *AttachmentService) checkAccess(ctx context.Context, channel, userID string) error {
+	member, err := s.users.IsChannelMember(ctx, channel, userID)
+	if err != nil {
+		return err
+	}
+	if !member {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// detectType определяет тип по содержимому и сверяет его со списком разрешенных
+func (s *AttachmentService) detectType(fileName string, r io.ReadSeeker) (string, error) {
+	head := make([]byte, 512)
+	n, err := io.ReadFull(r, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	contentType := http.DetectContentType(head[:n])
+	// DetectContentType не различает текстовые форматы и zip-контейнеры, уточняем по расширению
+	if byExt := mime.TypeByExtension(filepath.Ext(fileName)); byExt != "" &&
+		(contentType == "application/octet-stream" || strings.HasPrefix(contentType, "text/plain")) &&
+		!strings.HasPrefix(byExt, "text/html") && !strings.Contains(byExt, "javascript") && !strings.Contains(byExt, "svg") {
+		contentType = byExt
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	for _, allowed := range s.allowed {
+		if mediaType == allowed || (strings.HasSuffix(allowed, "/") && strings.HasPrefix(mediaType, allowed)) {
+			return contentType, nil
+		}
+	}
+
+	return "", ErrTypeNotAllowed
+}
+
+func sanitizeFileName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || r == '"' {
+			return -1
+		}
+		return r
+	}, name)
+
+	if name == "" || name == "." || name == "/" {
+		return "file"
+	}
+	if runes := []rune(name); len(runes) > 255 {
+		name = string(runes[:255])
+	}
+	return name
+}
+
+func withURL(a *model.Attachment) {
+	a.URL = "/attachments/" + a.ID.String()
+	for i := range a.Thumbnails {
+		a.Thumbnails[i].URL = a.URL + "/thumbnails/" + a.Thumbnails[i].Size
+	}
+}
+
+func NewAttachmentService(repo attachmentrepository.Repository, store storage.Storage, users service.Service, thumbs *Thumbnailer, cfg *config.Storage, logger *slog.Logger) Service {
+	return &AttachmentService{
+		repo:    repo,
+		store:   store,
+		users:   users,
+		thumbs:  thumbs,
+		maxSize: cfg.MaxUploadSize,
+		allowed: cfg.AllowedTypes,
+		logger:  logger,
+	}
+}
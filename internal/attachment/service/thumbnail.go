@@ -0,0 +1,227 @@
+package attachmentservice
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/QuUteO/video-communication/internal/attachment/repository"
+	"github.com/QuUteO/video-communication/internal/model"
+	"github.com/QuUteO/video-communication/pkg/storage"
+	"github.com/jackc/pgx/v4"
+	"golang.org/x/image/draw"
+
+	_ "image/gif"
+
+	_ "golang.org/x/image/bmp"
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	thumbnailWorkers   = 2
+	thumbnailQueueSize = 256
+	// защита от «бомб» — изображений с огромными размерами при маленьком файле
+	maxImagePixels = 50_000_000
+)
+
+var errNotImage = errors.New("unsupported image")
+
+// thumbnailSizes Размеры миниатюр: максимальная сторона в пикселях
+var thumbnailSizes = []struct {
+	name  string
+	limit int
+}{
+	{name: "small", limit: 160},
+	{name: "medium", limit: 480},
+}
+
+// Thumbnailer Фоновый обработчик, строящий миниатюры загруженных изображений
+type Thumbnailer struct {
+	repo    attachmentrepository.Repository
+	store   storage.Storage
+	jobs    chan model.Attachment
+	onReady func(model.Attachment) // вызывается после сохранения миниатюр
+	logger  *slog.Logger
+}
+
+func NewThumbnailer(repo attachmentrepository.Repository, store storage.Storage, onReady func(model.Attachment), logger *slog.Logger) *Thumbnailer {
+	return &Thumbnailer{
+		repo:    repo,
+		store:   store,
+		jobs:    make(chan model.Attachment, thumbnailQueueSize),
+		onReady: onReady,
+		logger:  logger,
+	}
+}
+
+// Enqueue ставит изображение в очередь; при переполнении задача будет подобрана после рестарта
+func (t *Thumbnailer) Enqueue(a model.Attachment) {
+	if !strings.HasPrefix(a.ContentType, "image/") {
+		return
+	}
+
+	select {
+	case t.jobs <- a:
+	default:
+		t.logger.Warn("thumbnail queue is full", slog.String("attachment_id", a.ID.String()))
+	}
+}
+
+// Run запускает воркеры и блокируется до отмены контекста
+func (t *Thumbnailer) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < thumbnailWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			t.work(ctx)
+		}()
+	}
+
+	// изображения, не обработанные до перезапуска
+	pending, err := t.repo.FindPendingImages(ctx, thumbnailQueueSize/2)
+	if err != nil {
+		t.logger.Error("failed to load pending images", slog.String("error", err.Error()))
+	}
+	for _, a := range pending {
+		t.Enqueue(a)
+	}
+
+	wg.Wait()
+}
+
+func (t *Thumbnailer) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case a := <-t.jobs:
+			if err := t.process(ctx, a); err != nil {
+				t.logger.Error("failed to build thumbnails",
+					slog.String("attachment_id", a.ID.String()),
+					slog.String("error", err.Error()))
+			}
+		}
+	}
+}
+
+func (t *Thumbnailer) process(ctx context.Context, a model.Attachment) error {
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	body, err := t.store.Get(ctx, a.StorageKey)
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(body)
+	body.Close()
+	if err != nil {
+		return err
+	}
+
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		// не изображение или неподдерживаемый формат: запоминаем размеры 0x0, чтобы не повторять
+		return t.repo.SaveImageInfo(ctx, a.ID, 0, 0, nil)
+	}
+	if cfg.Width*cfg.Height > maxImagePixels {
+		t.logger.Warn("image is too large for thumbnails", slog.String("attachment_id", a.ID.String()))
+		return t.repo.SaveImageInfo(ctx, a.ID, cfg.Width, cfg.Height, nil)
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		// заголовок читается, а само изображение битое — тоже не повторяем после рестарта
+		t.logger.Warn("failed to decode image",
+			slog.String("attachment_id", a.ID.String()),
+			slog.String("error", fmt.Errorf("%w: %s", errNotImage, err).Error()))
+		return t.repo.SaveImageInfo(ctx, a.ID, 0, 0, nil)
+	}
+
+	thumbnails := make([]model.Thumbnail, 0, len(thumbnailSizes))
+	for _, size := range thumbnailSizes {
+		thumb, err := t.build(ctx, a, src, format, size.name, size.limit)
+		if err != nil {
+			return err
+		}
+		thumbnails = append(thumbnails, thumb)
+	}
+
+	if err := t.repo.SaveImageInfo(ctx, a.ID, cfg.Width, cfg.Height, thumbnails); err != nil {
+		return err
+	}
+
+	// в очередь попадает копия до привязки к сообщению: message_id берем из базы
+	saved, err := t.repo.FindByID(ctx, a.ID.String())
+	if errors.Is(err, pgx.ErrNoRows) {
+		// сообщение уже истекло
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	withURL(saved)
+
+	if t.onReady != nil {
+		t.onReady(*saved)
+	}
+	return nil
+}
+
+func (t *Thumbnailer) build(ctx context.Context, a model.Attachment, src image.Image, format, name string, limit int) (model.Thumbnail, error) {
+	bounds := src.Bounds()
+	w, h := fit(bounds.Dx(), bounds.Dy(), limit)
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, bounds, draw.Over, nil)
+
+	var buf bytes.Buffer
+	contentType := "image/jpeg"
+	ext := ".jpg"
+
+	// форматы с прозрачностью сохраняем в PNG, остальное в JPEG
+	switch format {
+	case "png", "gif", "webp":
+		contentType, ext = "image/png", ".png"
+		if err := png.Encode(&buf, dst); err != nil {
+			return model.Thumbnail{}, err
+		}
+	default:
+		if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 80}); err != nil {
+			return model.Thumbnail{}, err
+		}
+	}
+
+	key := "thumbnails/" + a.ID.String() + "_" + name + ext
+	if err := t.store.Put(ctx, key, &buf, int64(buf.Len()), contentType); err != nil {
+		return model.Thumbnail{}, err
+	}
+
+	return model.Thumbnail{
+		Size:        name,
+		Width:       w,
+		Height:      h,
+		ContentType: contentType,
+		StorageKey:  key,
+	}, nil
+}
+
+// fit вписывает изображение в квадрат limit x limit с сохранением пропорций (без увеличения)
+func fit(w, h, limit int) (int, int) {
+	if w <= limit && h <= limit {
+		return w, h
+	}
+	if w >= h {
+		return limit, max(1, h*limit/w)
+	}
+	return max(1, w*limit/h), limit
+}
@@ -0,0 +1,84 @@
+package authhandler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	authmiddleware "github.com/QuUteO/video-communication/internal/auth/middleware"
+	"github.com/QuUteO/video-communication/internal/model"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// ListAPIKeys GET /me/api-keys
+func (h *Handler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	const op = "internal/auth/handler/ListAPIKeys"
+	log := h.logger.With("op: ", op)
+
+	userID, _ := r.Context().Value(authmiddleware.UserIDKey).(string)
+
+	keys, err := h.service.ListAPIKeys(r.Context(), userID)
+	if err != nil {
+		h.fail(w, r, log, err)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, model.Response{
+		StatusCode: http.StatusOK,
+		Message:    "API keys retrieved successfully",
+		Data:       keys,
+		Error:      "nil",
+	})
+}
+
+// CreateAPIKey POST /me/api-keys — ключ в ответе показывается один раз
+func (h *Handler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	const op = "internal/auth/handler/CreateAPIKey"
+	log := h.logger.With("op: ", op)
+
+	userID, _ := r.Context().Value(authmiddleware.UserIDKey).(string)
+
+	var req model.CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.error(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	key, err := h.service.CreateAPIKey(r.Context(), userID, &req)
+	if err != nil {
+		h.fail(w, r, log, err)
+		return
+	}
+
+	log.Info("API key created", slog.String("user_id", userID), slog.String("key_id", key.ID.String()))
+
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, model.Response{
+		StatusCode: http.StatusCreated,
+		Message:    "API key created, store it now: it will not be shown again",
+		Data:       key,
+		Error:      "nil",
+	})
+}
+
+// RevokeAPIKey DELETE /me/api-keys/{id}
+func (h *Handler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	const op = "internal/auth/handler/RevokeAPIKey"
+	log := h.logger.With("op: ", op)
+
+	userID, _ := r.Context().Value(authmiddleware.UserIDKey).(string)
+
+	if err := h.service.RevokeAPIKey(r.Context(), userID, chi.URLParam(r, "id")); err != nil {
+		h.fail(w, r, log, err)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, model.Response{
+		StatusCode: http.StatusOK,
+		Message:    "API key revoked",
+		Error:      "nil",
+	})
+}
@@ -2,22 +2,35 @@ package authhandler
 
 import (
 	"encoding/json"
+	"errors"
 	"log/slog"
+	"math"
+	"net"
 	"net/http"
+	"strconv"
 
+	authjwt "github.com/QuUteO/video-communication/internal/auth/jwt"
+	authmiddleware "github.com/QuUteO/video-communication/internal/auth/middleware"
+	authoidc "github.com/QuUteO/video-communication/internal/auth/oidc"
 	"github.com/QuUteO/video-communication/internal/auth/service"
+	"github.com/QuUteO/video-communication/internal/config"
 	"github.com/QuUteO/video-communication/internal/model"
+	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
 )
 
 type Handler struct {
 	service authservice.Service
+	jwt     *authjwt.Manager
+	oidc    *config.OIDC
 	logger  *slog.Logger
 }
 
-func NewHandler(service authservice.Service, logger *slog.Logger) *Handler {
+func NewHandler(service authservice.Service, jwt *authjwt.Manager, oidc *config.OIDC, logger *slog.Logger) *Handler {
 	return &Handler{
 		service: service,
+		jwt:     jwt,
+		oidc:    oidc,
 		logger:  logger,
 	}
 }
@@ -33,7 +46,7 @@ func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, err := h.service.Register(r.Context(), req)
+	token, err := h.service.Register(r.Context(), req, deviceInfo(r))
 	if err != nil {
 		log.Error("error registering user", slog.String("error", err.Error()))
 		render.Status(r, http.StatusUnprocessableEntity)
@@ -41,27 +54,472 @@ func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
 	}
 
 	render.Status(r, http.StatusCreated)
-	render.JSON(w, r, model.AuthResponse{Token: token})
+	render.JSON(w, r, token)
 }
 
 func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 	const op = "internal/auth/handler/Login"
 	log := h.logger.With("op: ", op)
 
-	var req *model.LoginRequest
+	var req model.LoginRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		log.Error("error decoding request", slog.String("error", err.Error()))
-		render.Status(r, http.StatusUnprocessableEntity)
+		h.error(w, r, http.StatusUnprocessableEntity, "invalid request body")
 		return
 	}
 
-	token, err := h.service.Login(r.Context(), req)
+	token, err := h.service.Login(r.Context(), &req, deviceInfo(r))
 	if err != nil {
-		log.Error("error login", slog.String("error", err.Error()))
-		render.Status(r, http.StatusUnprocessableEntity)
+		h.fail(w, r, log, err)
 		return
 	}
 
 	render.Status(r, http.StatusCreated)
-	render.JSON(w, r, model.LoginResponse{Token: token})
+	render.JSON(w, r, model.LoginResponse(*token))
+}
+
+// Refresh POST /auth/refresh
+func (h *Handler) Refresh(w http.ResponseWriter, r *http.Request) {
+	const op = "internal/auth/handler/Refresh"
+	log := h.logger.With("op: ", op)
+
+	var req model.RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.error(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	token, err := h.service.Refresh(r.Context(), &req, deviceInfo(r))
+	if err != nil {
+		if errors.Is(err, authservice.ErrInvalidRefreshToken) {
+			h.error(w, r, http.StatusUnauthorized, err.Error())
+			return
+		}
+		log.Error("error refreshing token", slog.String("error", err.Error()))
+		h.error(w, r, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, model.LoginResponse(*token))
+}
+
+// Logout POST /auth/logout — отзывает сессию текущего access-токена
+func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
+	const op = "internal/auth/handler/Logout"
+	log := h.logger.With("op: ", op)
+
+	sessionID, _ := r.Context().Value(authmiddleware.SessionIDKey).(string)
+
+	if err := h.service.Logout(r.Context(), sessionID); err != nil {
+		log.Error("error logout", slog.String("error", err.Error()))
+		h.error(w, r, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, model.Response{
+		StatusCode: http.StatusOK,
+		Message:    "Logged out",
+		Error:      "nil",
+	})
+}
+
+// ListSessions GET /me/sessions
+func (h *Handler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	const op = "internal/auth/handler/ListSessions"
+	log := h.logger.With("op: ", op)
+
+	userID, _ := r.Context().Value(authmiddleware.UserIDKey).(string)
+	sessionID, _ := r.Context().Value(authmiddleware.SessionIDKey).(string)
+
+	sessions, err := h.service.ListSessions(r.Context(), userID, sessionID)
+	if err != nil {
+		log.Error("error listing sessions", slog.String("error", err.Error()))
+		h.error(w, r, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, model.Response{
+		StatusCode: http.StatusOK,
+		Message:    "Sessions retrieved successfully",
+		Data:       sessions,
+		Error:      "nil",
+	})
+}
+
+// RevokeSession DELETE /me/sessions/{id}
+func (h *Handler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	const op = "internal/auth/handler/RevokeSession"
+	log := h.logger.With("op: ", op)
+
+	userID, _ := r.Context().Value(authmiddleware.UserIDKey).(string)
+
+	if err := h.service.RevokeSession(r.Context(), userID, chi.URLParam(r, "id")); err != nil {
+		if errors.Is(err, authservice.ErrSessionNotFound) {
+			h.error(w, r, http.StatusNotFound, err.Error())
+			return
+		}
+		log.Error("error revoking session", slog.String("error", err.Error()))
+		h.error(w, r, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, model.Response{
+		StatusCode: http.StatusOK,
+		Message:    "Session revoked",
+		Error:      "nil",
+	})
+}
+
+// RequestEmailVerification POST /auth/verify-email/request — повторная отправка письма
+func (h *Handler) RequestEmailVerification(w http.ResponseWriter, r *http.Request) {
+	const op = "internal/auth/handler/RequestEmailVerification"
+	log := h.logger.With("op: ", op)
+
+	userID, _ := r.Context().Value(authmiddleware.UserIDKey).(string)
+
+	if err := h.service.RequestEmailVerification(r.Context(), userID); err != nil {
+		h.fail(w, r, log, err)
+		return
+	}
+
+	render.Status(r, http.StatusAccepted)
+	render.JSON(w, r, model.Response{
+		StatusCode: http.StatusAccepted,
+		Message:    "Verification email sent",
+		Error:      "nil",
+	})
+}
+
+// VerifyEmail POST /auth/verify-email
+func (h *Handler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	const op = "internal/auth/handler/VerifyEmail"
+	log := h.logger.With("op: ", op)
+
+	var req model.VerifyEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.error(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.service.VerifyEmail(r.Context(), &req); err != nil {
+		h.fail(w, r, log, err)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, model.Response{
+		StatusCode: http.StatusOK,
+		Message:    "Email verified",
+		Error:      "nil",
+	})
+}
+
+// RequestPasswordReset POST /auth/password-reset — ответ одинаковый, есть такой адрес или нет
+func (h *Handler) RequestPasswordReset(w http.ResponseWriter, r *http.Request) {
+	const op = "internal/auth/handler/RequestPasswordReset"
+	log := h.logger.With("op: ", op)
+
+	var req model.PasswordResetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.error(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.service.RequestPasswordReset(r.Context(), &req); err != nil {
+		h.fail(w, r, log, err)
+		return
+	}
+
+	render.Status(r, http.StatusAccepted)
+	render.JSON(w, r, model.Response{
+		StatusCode: http.StatusAccepted,
+		Message:    "If the address is registered, a reset link has been sent",
+		Error:      "nil",
+	})
+}
+
+// ResetPassword POST /auth/password-reset/confirm
+func (h *Handler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	const op = "internal/auth/handler/ResetPassword"
+	log := h.logger.With("op: ", op)
+
+	var req model.PasswordResetConfirmRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.error(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.service.ResetPassword(r.Context(), &req); err != nil {
+		h.fail(w, r, log, err)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, model.Response{
+		StatusCode: http.StatusOK,
+		Message:    "Password changed, please log in again",
+		Error:      "nil",
+	})
+}
+
+// LoginTwoFactor POST /auth/login/2fa
+func (h *Handler) LoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	const op = "internal/auth/handler/LoginTwoFactor"
+	log := h.logger.With("op: ", op)
+
+	var req model.TwoFactorLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.error(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	token, err := h.service.LoginTwoFactor(r.Context(), &req, deviceInfo(r))
+	if err != nil {
+		h.fail(w, r, log, err)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, model.LoginResponse(*token))
+}
+
+// EnrollTOTP POST /me/2fa/totp
+func (h *Handler) EnrollTOTP(w http.ResponseWriter, r *http.Request) {
+	const op = "internal/auth/handler/EnrollTOTP"
+	log := h.logger.With("op: ", op)
+
+	userID, _ := r.Context().Value(authmiddleware.UserIDKey).(string)
+
+	enrollment, err := h.service.EnrollTOTP(r.Context(), userID)
+	if err != nil {
+		h.fail(w, r, log, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, model.Response{
+		StatusCode: http.StatusCreated,
+		Message:    "Scan the QR code and confirm with a code from the app",
+		Data:       enrollment,
+		Error:      "nil",
+	})
+}
+
+// ConfirmTOTP POST /me/2fa/totp/confirm
+func (h *Handler) ConfirmTOTP(w http.ResponseWriter, r *http.Request) {
+	const op = "internal/auth/handler/ConfirmTOTP"
+	log := h.logger.With("op: ", op)
+
+	var req model.TOTPCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.error(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	userID, _ := r.Context().Value(authmiddleware.UserIDKey).(string)
+
+	codes, err := h.service.ConfirmTOTP(r.Context(), userID, &req)
+	if err != nil {
+		h.fail(w, r, log, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, model.Response{
+		StatusCode: http.StatusOK,
+		Message:    "Two-factor authentication enabled",
+		Data:       codes,
+		Error:      "nil",
+	})
+}
+
+// DisableTOTP DELETE /me/2fa/totp
+func (h *Handler) DisableTOTP(w http.ResponseWriter, r *http.Request) {
+	const op = "internal/auth/handler/DisableTOTP"
+	log := h.logger.With("op: ", op)
+
+	var req model.TOTPCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.error(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	userID, _ := r.Context().Value(authmiddleware.UserIDKey).(string)
+
+	if err := h.service.DisableTOTP(r.Context(), userID, &req); err != nil {
+		h.fail(w, r, log, err)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, model.Response{
+		StatusCode: http.StatusOK,
+		Message:    "Two-factor authentication disabled",
+		Error:      "nil",
+	})
+}
+
+// RegenerateRecoveryCodes POST /me/2fa/recovery-codes
+func (h *Handler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	const op = "internal/auth/handler/RegenerateRecoveryCodes"
+	log := h.logger.With("op: ", op)
+
+	var req model.TOTPCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.error(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	userID, _ := r.Context().Value(authmiddleware.UserIDKey).(string)
+
+	codes, err := h.service.RegenerateRecoveryCodes(r.Context(), userID, &req)
+	if err != nil {
+		h.fail(w, r, log, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, model.Response{
+		StatusCode: http.StatusOK,
+		Message:    "Recovery codes regenerated",
+		Data:       codes,
+		Error:      "nil",
+	})
+}
+
+// Unlock POST /admin/users/{id}/unlock — снимает блокировку входа
+func (h *Handler) Unlock(w http.ResponseWriter, r *http.Request) {
+	const op = "internal/auth/handler/Unlock"
+	log := h.logger.With("op: ", op)
+
+	if err := h.service.Unlock(r.Context(), chi.URLParam(r, "id")); err != nil {
+		h.fail(w, r, log, err)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, model.Response{
+		StatusCode: http.StatusOK,
+		Message:    "Login unlocked",
+		Error:      "nil",
+	})
+}
+
+// SetRole PUT /admin/users/{id}/role
+func (h *Handler) SetRole(w http.ResponseWriter, r *http.Request) {
+	const op = "internal/auth/handler/SetRole"
+	log := h.logger.With("op: ", op)
+
+	var req model.SetRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.error(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.service.SetRole(r.Context(), chi.URLParam(r, "id"), req.Role); err != nil {
+		h.fail(w, r, log, err)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, model.Response{
+		StatusCode: http.StatusOK,
+		Message:    "Role updated",
+		Error:      "nil",
+	})
+}
+
+// JWKS GET /.well-known/jwks.json — открытые ключи для проверки токенов другими сервисами
+func (h *Handler) JWKS(w http.ResponseWriter, r *http.Request) {
+	// кеш короче интервала ротации, чтобы новый kid появлялся у потребителей вовремя
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, h.jwt.JWKS())
+}
+
+// deviceInfo клиент и адрес запроса; прокси перед сервером не предполагается
+func deviceInfo(r *http.Request) model.DeviceInfo {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+
+	return model.DeviceInfo{
+		UserAgent: r.UserAgent(),
+		IP:        ip,
+	}
+}
+
+func (h *Handler) fail(w http.ResponseWriter, r *http.Request, log *slog.Logger, err error) {
+	status := http.StatusInternalServerError
+	msg := err.Error()
+
+	var throttled *authservice.ThrottledError
+	switch {
+	case errors.As(err, &throttled):
+		status = http.StatusTooManyRequests
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+	case errors.Is(err, authservice.ErrInvalidCredentials):
+		status = http.StatusUnauthorized
+	case errors.Is(err, authservice.ErrUserNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, authservice.ErrInvalidToken):
+		status = http.StatusBadRequest
+	case errors.Is(err, authservice.ErrWeakPassword):
+		status = http.StatusBadRequest
+	case errors.Is(err, authservice.ErrEmailAlreadyVerified),
+		errors.Is(err, authservice.ErrTwoFactorEnabled),
+		errors.Is(err, authservice.ErrTwoFactorNotEnabled):
+		status = http.StatusConflict
+	case errors.Is(err, authservice.ErrInvalidCode),
+		errors.Is(err, authservice.ErrInvalidChallenge):
+		status = http.StatusUnauthorized
+	case errors.Is(err, authservice.ErrTooManyCodes):
+		status = http.StatusTooManyRequests
+	case errors.Is(err, authservice.ErrInvalidRole):
+		status = http.StatusBadRequest
+	case errors.Is(err, authservice.ErrLastAdmin):
+		status = http.StatusConflict
+	case errors.Is(err, authservice.ErrInvalidAPIKey):
+		status = http.StatusBadRequest
+	case errors.Is(err, authservice.ErrAPIKeyNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, authservice.ErrTooManyAPIKeys):
+		status = http.StatusConflict
+	case errors.Is(err, authservice.ErrOIDCDisabled):
+		status = http.StatusNotFound
+	case errors.Is(err, authservice.ErrInvalidOIDCState):
+		status = http.StatusBadRequest
+	case errors.Is(err, authservice.ErrOIDCDenied),
+		errors.Is(err, authoidc.ErrInvalidIDToken):
+		status = http.StatusUnauthorized
+	case errors.Is(err, authservice.ErrOIDCEmailNotVerified):
+		status = http.StatusForbidden
+	case errors.Is(err, authservice.ErrOIDCAccountConflict):
+		status = http.StatusConflict
+	case errors.Is(err, authoidc.ErrExchange):
+		log.Warn("Sso code exchange failed", slog.Any("error", err))
+		status = http.StatusBadGateway
+		msg = authoidc.ErrExchange.Error()
+	default:
+		log.Error("Auth request failed", slog.Any("error", err))
+		msg = "internal error"
+	}
+
+	h.error(w, r, status, msg)
+}
+
+func (h *Handler) error(w http.ResponseWriter, r *http.Request, status int, msg string) {
+	render.Status(r, status)
+	render.JSON(w, r, model.Response{
+		StatusCode: status,
+		Error:      msg,
+	})
 }
@@ -0,0 +1,119 @@
+package authhandler
+
+import (
+	"crypto/subtle"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	authoidc "github.com/QuUteO/video-communication/internal/auth/oidc"
+	authservice "github.com/QuUteO/video-communication/internal/auth/service"
+	"github.com/QuUteO/video-communication/internal/model"
+	"github.com/go-chi/render"
+)
+
+// oidcStateCookie привязывает state к браузеру, начавшему вход: чужая ссылка возврата не залогинит жертву
+const oidcStateCookie = "oidc_state"
+
+// OIDCLogin GET /auth/oidc/login — перенаправляет на страницу входа провайдера
+func (h *Handler) OIDCLogin(w http.ResponseWriter, r *http.Request) {
+	const op = "internal/auth/handler/OIDCLogin"
+	log := h.logger.With("op: ", op)
+
+	authURL, state, err := h.service.OIDCLogin(r.Context())
+	if err != nil {
+		h.fail(w, r, log, err)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     "/auth/oidc",
+		MaxAge:   int(h.oidc.StateTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil || strings.HasPrefix(h.oidc.RedirectURL, "https://"),
+		SameSite: http.SameSiteLaxMode, // cookie должна прийти при переходе с провайдера
+	})
+
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// OIDCCallback GET /auth/oidc/callback — возврат с провайдера.
+// С post_login_redirect токены уходят во фрагменте адреса, иначе отдаются JSON
+func (h *Handler) OIDCCallback(w http.ResponseWriter, r *http.Request) {
+	const op = "internal/auth/handler/OIDCCallback"
+	log := h.logger.With("op: ", op)
+
+	q := r.URL.Query()
+	req := model.OIDCCallback{
+		Code:             q.Get("code"),
+		State:            q.Get("state"),
+		Error:            q.Get("error"),
+		ErrorDescription: q.Get("error_description"),
+	}
+
+	http.SetCookie(w, &http.Cookie{Name: oidcStateCookie, Path: "/auth/oidc", MaxAge: -1, HttpOnly: true})
+
+	var token *model.AuthResponse
+	cookie, err := r.Cookie(oidcStateCookie)
+	if err != nil || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(req.State)) != 1 {
+		err = authservice.ErrInvalidOIDCState
+	} else {
+		token, err = h.service.OIDCCallback(r.Context(), &req, deviceInfo(r))
+	}
+
+	if h.oidc.PostLoginRedirect == "" {
+		if err != nil {
+			h.fail(w, r, log, err)
+			return
+		}
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, model.LoginResponse(*token))
+		return
+	}
+
+	fragment := url.Values{}
+	switch {
+	case err != nil:
+		if errors.Is(err, authservice.ErrOIDCDisabled) {
+			h.fail(w, r, log, err)
+			return
+		}
+		fragment.Set("error", oidcError(log, err))
+	case token.TwoFactorRequired:
+		fragment.Set("two_factor_required", "true")
+		fragment.Set("challenge_token", token.ChallengeToken)
+	default:
+		fragment.Set("token", token.Token)
+		fragment.Set("refresh_token", token.RefreshToken)
+		fragment.Set("expires_in", strconv.FormatInt(token.ExpiresIn, 10))
+	}
+
+	// фрагмент не уходит на сервер и не попадает в Referer
+	w.Header().Set("Cache-Control", "no-store")
+	http.Redirect(w, r, h.oidc.PostLoginRedirect+"#"+fragment.Encode(), http.StatusFound)
+}
+
+// oidcError текст ошибки для клиента; подробности внутренних ошибок остаются в журнале
+func oidcError(log *slog.Logger, err error) string {
+	for _, known := range []error{
+		authservice.ErrInvalidOIDCState,
+		authservice.ErrOIDCDenied,
+		authservice.ErrOIDCEmailNotVerified,
+		authservice.ErrOIDCAccountConflict,
+		authoidc.ErrInvalidIDToken,
+		authoidc.ErrExchange,
+	} {
+		if errors.Is(err, known) {
+			log.Warn("Sso login failed", slog.Any("error", err))
+			return known.Error()
+		}
+	}
+
+	log.Error("Sso login failed", slog.Any("error", err))
+	return "internal error"
+}
@@ -1,43 +1,128 @@
 package authjwt
 
 import (
+	"errors"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// leeway допуск на расхождение часов между сервисами
+const leeway = 30 * time.Second
+
+var ErrInvalidToken = errors.New("invalid token")
+
 type Manager struct {
-	secret []byte
-	ttl    time.Duration
+	keys     *KeySet
+	issuer   string
+	audience []string
+	ttl      time.Duration
+}
+
+// Claims Данные access-токена
+type Claims struct {
+	UserID    string
+	SessionID string
+	MFA       bool // вход подтвержден вторым фактором (amr содержит "otp")
 }
 
-func NewJWT(secret string, ttl time.Duration) *Manager {
+func NewJWT(keys *KeySet, issuer string, audience []string, ttl time.Duration) *Manager {
 	return &Manager{
-		secret: []byte(secret),
-		ttl:    ttl,
+		keys:     keys,
+		issuer:   issuer,
+		audience: audience,
+		ttl:      ttl,
 	}
 }
 
-func (m *Manager) Generate(userID string) (string, error) {
+// TTL время жизни access-токена
+func (m *Manager) TTL() time.Duration {
+	return m.ttl
+}
+
+// JWKS открытые ключи для /.well-known/jwks.json
+func (m *Manager) JWKS() JWKS {
+	return m.keys.JWKS()
+}
+
+func (m *Manager) Generate(userID, sessionID string, mfa bool) (string, error) {
+	key := m.keys.signer()
+	now := time.Now()
+
+	// amr по RFC 8176: способы, которыми подтвержден вход
+	amr := []string{"pwd"}
+	if mfa {
+		amr = append(amr, "otp", "mfa")
+	}
+
 	claims := jwt.MapClaims{
+		"iss": m.issuer,
 		"sub": userID,
-		"exp": time.Now().Add(m.ttl).Unix(),
-		"iat": time.Now().Unix(),
+		"sid": sessionID,
+		"amr": amr,
+		"exp": now.Add(m.ttl).Unix(),
+		"iat": now.Unix(),
+	}
+	if len(m.audience) > 0 {
+		claims["aud"] = m.audience
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(m.secret)
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.id
+	return token.SignedString(key.private)
 }
 
-func (m *Manager) Parse(tokenStr string) (string, error) {
+func (m *Manager) Parse(tokenStr string) (*Claims, error) {
+	opts := []jwt.ParserOption{
+		// алгоритм из заголовка не выбирает ключ: он обязан совпасть с алгоритмом ключа kid
+		jwt.WithValidMethods([]string{AlgRS256, AlgEdDSA}),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithIssuer(m.issuer),
+		jwt.WithLeeway(leeway),
+	}
+	if len(m.audience) > 0 {
+		opts = append(opts, jwt.WithAudience(m.audience[0]))
+	}
+
 	token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (any, error) {
-		return m.secret, nil
-	})
+		kid, _ := t.Header["kid"].(string)
+		key, ok := m.keys.verifier(kid)
+		if !ok {
+			return nil, ErrInvalidToken
+		}
+		if t.Method.Alg() != key.method.Alg() {
+			return nil, ErrInvalidToken
+		}
+		return key.private.Public(), nil
+	}, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, ErrInvalidToken
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	// токены без сессии (выданные до введения refresh) не принимаются
+	sub, _ := claims["sub"].(string)
+	sid, _ := claims["sid"].(string)
+	if sub == "" || sid == "" {
+		return nil, ErrInvalidToken
+	}
 
-	if err != nil || !token.Valid {
-		return "", err
+	result := &Claims{UserID: sub, SessionID: sid}
+	if amr, ok := claims["amr"].([]any); ok {
+		for _, method := range amr {
+			if method == "mfa" {
+				result.MFA = true
+			}
+		}
 	}
 
-	claims := token.Claims.(jwt.MapClaims)
-	return claims["sub"].(string), nil
+	return result, nil
 }
@@ -0,0 +1,162 @@
+package authjwt
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testIssuer   = "video-communication"
+	testAudience = "video-communication"
+)
+
+func newManager(t *testing.T, algorithm string) *Manager {
+	t.Helper()
+
+	keys, err := NewKeySet(KeySetConfig{
+		Dir:         t.TempDir(),
+		Algorithm:   algorithm,
+		RotateEvery: time.Hour,
+		Grace:       time.Hour,
+		Interval:    time.Minute,
+	}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("NewKeySet: %v", err)
+	}
+
+	return NewJWT(keys, testIssuer, []string{testAudience}, time.Minute)
+}
+
+func validClaims() jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss": testIssuer,
+		"aud": []string{testAudience},
+		"sub": "user",
+		"sid": "session",
+		"amr": []string{"pwd"},
+		"exp": now.Add(time.Minute).Unix(),
+		"iat": now.Unix(),
+	}
+}
+
+// sign подписывает claims заданным методом и ключом, указывая kid
+func sign(t *testing.T, method jwt.SigningMethod, kid string, key any, claims jwt.MapClaims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+	s, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("SignedString: %v", err)
+	}
+	return s
+}
+
+func TestParseRoundTrip(t *testing.T) {
+	for _, alg := range []string{AlgRS256, AlgEdDSA} {
+		m := newManager(t, alg)
+
+		token, err := m.Generate("user", "session", true)
+		if err != nil {
+			t.Fatalf("%s: Generate: %v", alg, err)
+		}
+
+		claims, err := m.Parse(token)
+		if err != nil {
+			t.Fatalf("%s: Parse: %v", alg, err)
+		}
+		if claims.UserID != "user" || claims.SessionID != "session" || !claims.MFA {
+			t.Errorf("%s: Parse = %+v", alg, claims)
+		}
+	}
+}
+
+// HS256 с открытым ключом в роли секрета — классическая подмена алгоритма
+func TestParseRejectsHS256(t *testing.T) {
+	m := newManager(t, AlgRS256)
+	key := m.keys.signer()
+
+	public, err := x509.MarshalPKIXPublicKey(key.private.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, secret := range [][]byte{public, []byte("secret")} {
+		token := sign(t, jwt.SigningMethodHS256, key.id, secret, validClaims())
+		if _, err := m.Parse(token); err == nil {
+			t.Error("Parse accepted HS256 token")
+		}
+	}
+}
+
+func TestParseRejectsNone(t *testing.T) {
+	m := newManager(t, AlgEdDSA)
+
+	token := sign(t, jwt.SigningMethodNone, m.keys.signer().id, jwt.UnsafeAllowNoneSignatureType, validClaims())
+	if _, err := m.Parse(token); err == nil {
+		t.Error("Parse accepted unsigned token")
+	}
+}
+
+// алгоритм токена должен совпадать с алгоритмом ключа kid, даже если оба разрешены
+func TestParseRejectsAlgorithmMismatch(t *testing.T) {
+	m := newManager(t, AlgEdDSA)
+
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token := sign(t, jwt.SigningMethodRS256, m.keys.signer().id, other, validClaims())
+	_, err = m.Parse(token)
+	if !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Parse error = %v, want %v", err, ErrInvalidToken)
+	}
+}
+
+func TestParseRejectsUnknownKid(t *testing.T) {
+	m := newManager(t, AlgEdDSA)
+	key := m.keys.signer()
+
+	for _, kid := range []string{"", "unknown"} {
+		token := sign(t, key.method, kid, key.private, validClaims())
+		if _, err := m.Parse(token); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("kid %q: Parse error = %v, want %v", kid, err, ErrInvalidToken)
+		}
+	}
+}
+
+// подпись правильным ключом не спасает токен с чужими или просроченными claims
+func TestParseRejectsInvalidClaims(t *testing.T) {
+	m := newManager(t, AlgEdDSA)
+	key := m.keys.signer()
+
+	tests := map[string]func(jwt.MapClaims){
+		"expired":       func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() },
+		"no exp":        func(c jwt.MapClaims) { delete(c, "exp") },
+		"issuer":        func(c jwt.MapClaims) { c["iss"] = "someone-else" },
+		"audience":      func(c jwt.MapClaims) { c["aud"] = []string{"someone-else"} },
+		"no session":    func(c jwt.MapClaims) { delete(c, "sid") },
+		"future iat":    func(c jwt.MapClaims) { c["iat"] = time.Now().Add(time.Hour).Unix() },
+		"empty subject": func(c jwt.MapClaims) { c["sub"] = "" },
+	}
+
+	for name, mutate := range tests {
+		claims := validClaims()
+		mutate(claims)
+
+		token := sign(t, key.method, key.id, key.private, claims)
+		if _, err := m.Parse(token); err == nil {
+			t.Errorf("%s: Parse accepted token", name)
+		}
+	}
+}
@@ -0,0 +1,343 @@
+package authjwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+
+	rsaBits   = 2048
+	kidLayout = "20060102T150405Z"
+)
+
+var ErrUnsupportedKey = errors.New("unsupported key type, expected RSA or Ed25519")
+
+// KeySetConfig Параметры набора ключей
+type KeySetConfig struct {
+	Dir         string        // каталог с <kid>.pem (PKCS#8)
+	Algorithm   string        // RS256 | EdDSA — алгоритм новых ключей
+	RotateEvery time.Duration // возраст активного ключа, после которого создается новый
+	Grace       time.Duration // сколько старый ключ еще проверяет подписи после ротации (не меньше TTL токена)
+	Interval    time.Duration // как часто перечитывать каталог и проверять ротацию
+}
+
+type signingKey struct {
+	id      string
+	method  jwt.SigningMethod
+	private crypto.Signer
+	created time.Time
+}
+
+// KeySet Ключи подписи с kid; активный — самый новый, старые остаются для проверки до конца Grace.
+// Каталог может быть общим для нескольких экземпляров: ключи соседей подхватываются при перечитывании
+type KeySet struct {
+	cfg    KeySetConfig
+	mu     sync.RWMutex
+	keys   map[string]*signingKey
+	active *signingKey
+	logger *slog.Logger
+}
+
+func NewKeySet(cfg KeySetConfig, logger *slog.Logger) (*KeySet, error) {
+	if cfg.Algorithm != AlgRS256 && cfg.Algorithm != AlgEdDSA {
+		return nil, fmt.Errorf("jwt algorithm must be %s or %s, got %q", AlgRS256, AlgEdDSA, cfg.Algorithm)
+	}
+	if err := os.MkdirAll(cfg.Dir, 0o700); err != nil {
+		return nil, err
+	}
+
+	ks := &KeySet{
+		cfg:    cfg,
+		keys:   make(map[string]*signingKey),
+		logger: logger,
+	}
+	if err := ks.refresh(); err != nil {
+		return nil, err
+	}
+
+	return ks, nil
+}
+
+// Run периодически перечитывает каталог, ротирует и выводит из оборота ключи
+func (ks *KeySet) Run(ctx context.Context) {
+	const op = "internal/auth/jwt.KeySet.Run"
+	log := ks.logger.With("op: ", op)
+
+	ticker := time.NewTicker(ks.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := ks.refresh(); err != nil {
+				log.Error("Error refreshing jwt keys", slog.Any("error", err))
+			}
+		}
+	}
+}
+
+func (ks *KeySet) refresh() error {
+	keys, err := ks.load()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	newest := newestKey(keys)
+	if newest == nil || newest.method.Alg() != ks.cfg.Algorithm || now.Sub(newest.created) >= ks.cfg.RotateEvery {
+		key, err := ks.generate(now)
+		if err != nil {
+			return err
+		}
+		keys[key.id] = key
+		newest = key
+		ks.logger.Info("JWT signing key rotated", slog.String("kid", key.id), slog.String("alg", key.method.Alg()))
+	}
+
+	ks.retire(keys, now)
+
+	ks.mu.Lock()
+	ks.keys = keys
+	ks.active = newest
+	ks.mu.Unlock()
+
+	return nil
+}
+
+func (ks *KeySet) load() (map[string]*signingKey, error) {
+	files, err := filepath.Glob(filepath.Join(ks.cfg.Dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]*signingKey, len(files))
+	for _, file := range files {
+		key, err := loadKey(file)
+		if err != nil {
+			// битый файл не должен ронять проверку остальных токенов
+			ks.logger.Error("Error loading jwt key", slog.String("file", file), slog.Any("error", err))
+			continue
+		}
+		keys[key.id] = key
+	}
+
+	return keys, nil
+}
+
+// retire удаляет ключи, которые не подписывают уже дольше Grace
+func (ks *KeySet) retire(keys map[string]*signingKey, now time.Time) {
+	ordered := sortedKeys(keys)
+	for i := 0; i < len(ordered)-1; i++ {
+		if now.Sub(ordered[i+1].created) < ks.cfg.Grace {
+			continue
+		}
+
+		old := ordered[i]
+		delete(keys, old.id)
+		if err := os.Remove(filepath.Join(ks.cfg.Dir, old.id+".pem")); err != nil && !errors.Is(err, os.ErrNotExist) {
+			ks.logger.Error("Error removing retired jwt key", slog.String("kid", old.id), slog.Any("error", err))
+			continue
+		}
+		ks.logger.Info("JWT signing key retired", slog.String("kid", old.id))
+	}
+}
+
+func (ks *KeySet) generate(now time.Time) (*signingKey, error) {
+	var private crypto.Signer
+	var method jwt.SigningMethod
+
+	switch ks.cfg.Algorithm {
+	case AlgRS256:
+		key, err := rsa.GenerateKey(rand.Reader, rsaBits)
+		if err != nil {
+			return nil, err
+		}
+		private, method = key, jwt.SigningMethodRS256
+	default:
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		private, method = key, jwt.SigningMethodEdDSA
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, err
+	}
+
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, err
+	}
+	id := now.UTC().Format(kidLayout) + "-" + hex.EncodeToString(suffix)
+
+	// запись через временный файл, чтобы соседние экземпляры не прочитали половину ключа
+	path := filepath.Join(ks.cfg.Dir, id+".pem")
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return nil, err
+	}
+
+	return &signingKey{id: id, method: method, private: private, created: now}, nil
+}
+
+// signer активный ключ подписи
+func (ks *KeySet) signer() *signingKey {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	return ks.active
+}
+
+// verifier ключ проверки по kid
+func (ks *KeySet) verifier(kid string) (*signingKey, bool) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	key, ok := ks.keys[kid]
+	return key, ok
+}
+
+// JWK Открытый ключ в формате RFC 7517
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// OKP (Ed25519)
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS открытые части всех ключей, которыми могут быть подписаны действующие токены
+func (ks *KeySet) JWKS() JWKS {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	set := JWKS{Keys: make([]JWK, 0, len(ks.keys))}
+	for _, key := range sortedKeys(ks.keys) {
+		jwk := JWK{Kid: key.id, Use: "sig", Alg: key.method.Alg()}
+
+		switch pub := key.private.Public().(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		default:
+			continue
+		}
+
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	return set
+}
+
+func loadKey(file string) (*signingKey, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	var parsed any
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key := &signingKey{id: strings.TrimSuffix(filepath.Base(file), ".pem")}
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		if k.N.BitLen() < rsaBits {
+			return nil, fmt.Errorf("rsa key must be at least %d bits", rsaBits)
+		}
+		key.private, key.method = k, jwt.SigningMethodRS256
+	case ed25519.PrivateKey:
+		key.private, key.method = k, jwt.SigningMethodEdDSA
+	default:
+		return nil, ErrUnsupportedKey
+	}
+
+	// время создания берется из kid, чтобы все экземпляры видели одинаковый возраст ключа;
+	// для ключей, положенных вручную, — из времени изменения файла
+	prefix, _, _ := strings.Cut(key.id, "-")
+	if created, err := time.Parse(kidLayout, prefix); err == nil {
+		key.created = created
+	} else if info, err := os.Stat(file); err == nil {
+		key.created = info.ModTime()
+	}
+
+	return key, nil
+}
+
+func sortedKeys(keys map[string]*signingKey) []*signingKey {
+	ordered := make([]*signingKey, 0, len(keys))
+	for _, key := range keys {
+		ordered = append(ordered, key)
+	}
+	sort.Slice(ordered, func(i, j int) bool {
+		if ordered[i].created.Equal(ordered[j].created) {
+			return ordered[i].id < ordered[j].id
+		}
+		return ordered[i].created.Before(ordered[j].created)
+	})
+	return ordered
+}
+
+func newestKey(keys map[string]*signingKey) *signingKey {
+	ordered := sortedKeys(keys)
+	if len(ordered) == 0 {
+		return nil
+	}
+	return ordered[len(ordered)-1]
+}
@@ -3,38 +3,175 @@ package authmiddleware
 import (
 	"context"
 	"net/http"
+	"slices"
 	"strings"
 
 	"github.com/QuUteO/video-communication/internal/auth/jwt"
+	authpolicy "github.com/QuUteO/video-communication/internal/auth/policy"
+	"github.com/QuUteO/video-communication/internal/model"
+	"github.com/go-chi/chi/v5"
 )
 
 type ctxKey string
 
-const UserIDKey ctxKey = "user_id"
+const (
+	UserIDKey    ctxKey = "user_id"
+	SessionIDKey ctxKey = "session_id"
+	MFAKey       ctxKey = "mfa"
+	APIKeyIDKey  ctxKey = "api_key_id" // запрос авторизован ключом API, а не сессией
+	RoleKey      ctxKey = "role"       // ставит Authorizer
 
-func JWT(jwt *authjwt.Manager) func(http.Handler) http.Handler {
+	scopeKey ctxKey = "api_scope"
+)
+
+// Sessions проверяет, что сессия токена не отозвана (logout, повторное использование refresh-токена)
+type Sessions interface {
+	SessionActive(ctx context.Context, sessionID string) (bool, error)
+}
+
+// APIKeys проверяет личные ключи API; nil-ключ без ошибки — ключ неизвестен, истек или отозван
+type APIKeys interface {
+	AuthenticateAPIKey(ctx context.Context, secret string) (*model.APIKey, error)
+}
+
+// JWT пускает по Bearer JWT или по ключу API (Bearer vck_... либо X-API-Key).
+// Права ключа проверяются по методу запроса (read — чтение, write — изменения) или заданы маршрутом через Scope
+func JWT(jwt *authjwt.Manager, sessions Sessions, keys APIKeys) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			h := r.Header.Get("Authorization")
-			if h == "" {
+			token := r.Header.Get("X-API-Key")
+			if h := r.Header.Get("Authorization"); h != "" && token == "" {
+				parts := strings.Split(h, " ")
+				if len(parts) != 2 || parts[0] != "Bearer" {
+					http.Error(w, "invalid token", http.StatusUnauthorized)
+					return
+				}
+				token = parts[1]
+			}
+			if token == "" {
 				http.Error(w, "missing token", http.StatusUnauthorized)
 				return
 			}
 
-			parts := strings.Split(h, " ")
-			if len(parts) != 2 || parts[0] != "Bearer" {
-				http.Error(w, "invalid token", http.StatusUnauthorized)
+			if strings.HasPrefix(token, model.APIKeyPrefix) && keys != nil {
+				key, err := keys.AuthenticateAPIKey(r.Context(), token)
+				if err != nil {
+					http.Error(w, "internal error", http.StatusInternalServerError)
+					return
+				}
+				if key == nil {
+					http.Error(w, "invalid api key", http.StatusUnauthorized)
+					return
+				}
+				if scope := requiredScope(r); !slices.Contains(key.Scopes, scope) {
+					http.Error(w, "api key lacks scope "+scope, http.StatusForbidden)
+					return
+				}
+
+				ctx := context.WithValue(r.Context(), UserIDKey, key.UserID.String())
+				ctx = context.WithValue(ctx, APIKeyIDKey, key.ID.String())
+				ctx = context.WithValue(ctx, MFAKey, false)
+				next.ServeHTTP(w, r.WithContext(ctx))
 				return
 			}
 
-			userID, err := jwt.Parse(parts[1])
+			claims, err := jwt.Parse(token)
 			if err != nil {
 				http.Error(w, "invalid token", http.StatusUnauthorized)
 				return
 			}
 
-			ctx := context.WithValue(r.Context(), UserIDKey, userID)
+			active, err := sessions.SessionActive(r.Context(), claims.SessionID)
+			if err != nil {
+				http.Error(w, "internal error", http.StatusInternalServerError)
+				return
+			}
+			if !active {
+				http.Error(w, "session revoked", http.StatusUnauthorized)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), UserIDKey, claims.UserID)
+			ctx = context.WithValue(ctx, SessionIDKey, claims.SessionID)
+			ctx = context.WithValue(ctx, MFAKey, claims.MFA)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
+
+// Interactive не пускает ключи API: управление ключами, сессиями, 2FA и администрирование
+// доступны только после входа пользователя. Ставится после JWT
+func Interactive(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if keyID, _ := r.Context().Value(APIKeyIDKey).(string); keyID != "" {
+			http.Error(w, "this action requires an interactive login", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Scope задает право ключа API для маршрута вместо выводимого из метода; ставится до JWT
+func Scope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), scopeKey, scope)))
+		})
+	}
+}
+
+func requiredScope(r *http.Request) string {
+	if scope, _ := r.Context().Value(scopeKey).(string); scope != "" {
+		return scope
+	}
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return model.APIScopeRead
+	default:
+		return model.APIScopeWrite
+	}
+}
+
+// Roles глобальная роль пользователя; читается на каждый запрос, чтобы смена роли действовала сразу
+type Roles interface {
+	UserRole(ctx context.Context, userID string) (string, error)
+}
+
+// Authorizer проверяет объявленные маршрутами права по политике; ставится после JWT
+type Authorizer struct {
+	policy *authpolicy.Policy
+	roles  Roles
+}
+
+func NewAuthorizer(policy *authpolicy.Policy, roles Roles) *Authorizer {
+	return &Authorizer{
+		policy: policy,
+		roles:  roles,
+	}
+}
+
+// Require пропускает запрос, если действие разрешено; владелец ресурса — параметр маршрута {id}
+func (a *Authorizer) Require(perm authpolicy.Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, _ := r.Context().Value(UserIDKey).(string)
+			mfa, _ := r.Context().Value(MFAKey).(bool)
+
+			role, err := a.roles.UserRole(r.Context(), userID)
+			if err != nil {
+				http.Error(w, "internal error", http.StatusInternalServerError)
+				return
+			}
+
+			subject := authpolicy.Subject{UserID: userID, Role: role, MFA: mfa}
+			if err := a.policy.Can(subject, perm, chi.URLParam(r, "id")); err != nil {
+				http.Error(w, err.Error(), http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), RoleKey, role)))
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package authmiddleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	authpolicy "github.com/QuUteO/video-communication/internal/auth/policy"
+	"github.com/QuUteO/video-communication/internal/model"
+	"github.com/go-chi/chi/v5"
+	"github.com/gofrs/uuid"
+)
+
+// staticKeys один известный ключ API
+type staticKeys struct {
+	secret string
+	key    *model.APIKey
+}
+
+func (k staticKeys) AuthenticateAPIKey(_ context.Context, secret string) (*model.APIKey, error) {
+	if secret == k.secret {
+		return k.key, nil
+	}
+	return nil, nil
+}
+
+func newKeys(scopes ...string) staticKeys {
+	return staticKeys{
+		secret: model.APIKeyPrefix + "secret",
+		key:    &model.APIKey{ID: uuid.Must(uuid.NewV4()), UserID: uuid.Must(uuid.NewV4()), Scopes: scopes},
+	}
+}
+
+// serve прогоняет запрос через middleware до обработчика, который отвечает 200
+func serve(h func(http.Handler) http.Handler, r *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})).ServeHTTP(w, r)
+	return w
+}
+
+func TestAPIKeyScopes(t *testing.T) {
+	tests := []struct {
+		name   string
+		scopes []string
+		method string
+		scope  string // маршрут с явным правом
+		want   int
+	}{
+		{"read key reads", []string{model.APIScopeRead}, http.MethodGet, "", http.StatusOK},
+		{"read key cannot write", []string{model.APIScopeRead}, http.MethodPost, "", http.StatusForbidden},
+		{"read key cannot delete", []string{model.APIScopeRead}, http.MethodDelete, "", http.StatusForbidden},
+		{"write key writes", []string{model.APIScopeWrite}, http.MethodPut, "", http.StatusOK},
+		{"write key cannot read", []string{model.APIScopeWrite}, http.MethodGet, "", http.StatusForbidden},
+		{"ws route needs ws", []string{model.APIScopeRead}, http.MethodGet, model.APIScopeWebSocket, http.StatusForbidden},
+		{"ws key connects", []string{model.APIScopeWebSocket}, http.MethodGet, model.APIScopeWebSocket, http.StatusOK},
+		{"ws key cannot read", []string{model.APIScopeWebSocket}, http.MethodGet, "", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys := newKeys(tt.scopes...)
+			h := JWT(nil, nil, keys)
+			if tt.scope != "" {
+				jwt := h
+				h = func(next http.Handler) http.Handler { return Scope(tt.scope)(jwt(next)) }
+			}
+
+			r := httptest.NewRequest(tt.method, "/", nil)
+			r.Header.Set("Authorization", "Bearer "+keys.secret)
+			if w := serve(h, r); w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestAPIKeyHeaderAndContext(t *testing.T) {
+	keys := newKeys(model.APIScopeRead)
+
+	var userID, keyID string
+	var mfa bool
+	h := JWT(nil, nil, keys)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, _ = r.Context().Value(UserIDKey).(string)
+		keyID, _ = r.Context().Value(APIKeyIDKey).(string)
+		mfa, _ = r.Context().Value(MFAKey).(bool)
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-API-Key", keys.secret)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d", w.Code)
+	}
+	if userID != keys.key.UserID.String() || keyID != keys.key.ID.String() {
+		t.Errorf("user = %q, key = %q", userID, keyID)
+	}
+	if mfa {
+		t.Error("api key must not carry mfa")
+	}
+}
+
+func TestUnknownAPIKey(t *testing.T) {
+	keys := newKeys(model.APIScopeRead, model.APIScopeWrite)
+
+	for _, secret := range []string{model.APIKeyPrefix + "other", ""} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if secret != "" {
+			r.Header.Set("X-API-Key", secret)
+		}
+		if w := serve(JWT(nil, nil, keys), r); w.Code != http.StatusUnauthorized {
+			t.Errorf("key %q: status = %d, want %d", secret, w.Code, http.StatusUnauthorized)
+		}
+	}
+}
+
+func TestInteractiveRejectsAPIKeys(t *testing.T) {
+	keys := newKeys(model.APIScopeRead, model.APIScopeWrite)
+	h := func(next http.Handler) http.Handler { return JWT(nil, nil, keys)(Interactive(next)) }
+
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	r.Header.Set("Authorization", "Bearer "+keys.secret)
+	if w := serve(h, r); w.Code != http.StatusForbidden {
+		t.Errorf("api key: status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+
+	// сессия пользователя проходит
+	r = httptest.NewRequest(http.MethodPost, "/", nil)
+	r = r.WithContext(context.WithValue(r.Context(), UserIDKey, "user"))
+	if w := serve(Interactive, r); w.Code != http.StatusOK {
+		t.Errorf("session: status = %d, want %d", w.Code, http.StatusOK)
+	}
+}
+
+// roleMap роли по id пользователя
+type roleMap map[string]string
+
+func (m roleMap) UserRole(_ context.Context, userID string) (string, error) {
+	return m[userID], nil
+}
+
+func TestAuthorizerRequire(t *testing.T) {
+	roles := roleMap{"alice": authpolicy.RoleUser, "root": authpolicy.RoleAdmin}
+
+	tests := []struct {
+		name   string
+		userID string
+		mfa    bool
+		perm   authpolicy.Permission
+		path   string
+		want   int
+	}{
+		{"owner updates self", "alice", false, authpolicy.UsersUpdate, "/users/alice", http.StatusOK},
+		{"user updates other", "alice", false, authpolicy.UsersUpdate, "/users/bob", http.StatusForbidden},
+		{"user changes roles", "alice", false, authpolicy.AdminRoles, "/users/bob", http.StatusForbidden},
+		{"admin without mfa", "root", false, authpolicy.AdminRoles, "/users/bob", http.StatusForbidden},
+		{"admin with mfa", "root", true, authpolicy.AdminRoles, "/users/bob", http.StatusOK},
+		{"unknown user", "mallory", true, authpolicy.UsersRead, "/users/bob", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var role string
+			r := chi.NewRouter()
+			r.With(NewAuthorizer(authpolicy.New(true), roles).Require(tt.perm)).
+				Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+					role, _ = r.Context().Value(RoleKey).(string)
+				})
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			ctx := context.WithValue(req.Context(), UserIDKey, tt.userID)
+			ctx = context.WithValue(ctx, MFAKey, tt.mfa)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req.WithContext(ctx))
+
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d", w.Code, tt.want)
+			}
+			if w.Code == http.StatusOK && role != roles[tt.userID] {
+				t.Errorf("role in context = %q, want %q", role, roles[tt.userID])
+			}
+		})
+	}
+}
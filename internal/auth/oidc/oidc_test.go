@@ -0,0 +1,266 @@
+package authoidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/QuUteO/video-communication/internal/config"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// fakeIdP провайдер с discovery, токен-эндпоинтом и JWKS; claims — что положить в следующий ID-токен
+type fakeIdP struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	claims   jwt.MapClaims
+	verifier string // code_verifier из последнего обмена
+}
+
+func newFakeIdP(t *testing.T) *fakeIdP {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp := &fakeIdP{}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(discovery{
+			Issuer:                idp.URL,
+			AuthorizationEndpoint: idp.URL + "/authorize",
+			TokenEndpoint:         idp.URL + "/token",
+			JWKSURI:               idp.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": []jwk{{
+			Kty: "RSA",
+			Kid: "k1",
+			Use: "sig",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		idp.mu.Lock()
+		idp.verifier = r.PostForm.Get("code_verifier")
+		claims := idp.claims
+		idp.mu.Unlock()
+
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = "k1"
+		raw, err := token.SignedString(key)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"id_token": raw})
+	})
+	idp.Server = httptest.NewServer(mux)
+	t.Cleanup(idp.Close)
+
+	return idp
+}
+
+func (idp *fakeIdP) provider() *Provider {
+	return NewProvider(&config.OIDC{
+		Issuer:      idp.URL,
+		ClientID:    "client",
+		RedirectURL: "http://localhost/callback",
+		Scopes:      []string{"openid", "email"},
+		Timeout:     5 * time.Second,
+	})
+}
+
+// issue задает claims следующего ID-токена поверх валидных по умолчанию
+func (idp *fakeIdP) issue(nonce string, override jwt.MapClaims) {
+	claims := jwt.MapClaims{
+		"iss":            idp.URL,
+		"aud":            "client",
+		"sub":            "user-1",
+		"email":          "user@example.com",
+		"email_verified": true,
+		"nonce":          nonce,
+		"exp":            time.Now().Add(time.Minute).Unix(),
+		"iat":            time.Now().Unix(),
+	}
+	for k, v := range override {
+		if v == nil {
+			delete(claims, k)
+			continue
+		}
+		claims[k] = v
+	}
+
+	idp.mu.Lock()
+	idp.claims = claims
+	idp.mu.Unlock()
+}
+
+func TestExchangeReturnsIdentity(t *testing.T) {
+	idp := newFakeIdP(t)
+	p := idp.provider()
+
+	idp.issue("nonce-1", nil)
+	id, err := p.Exchange(context.Background(), "code", "verifier", "nonce-1")
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if id.Issuer != idp.URL || id.Subject != "user-1" || id.Email != "user@example.com" || !id.EmailVerified {
+		t.Errorf("identity = %+v", id)
+	}
+}
+
+func TestExchangeRejectsNonceMismatch(t *testing.T) {
+	idp := newFakeIdP(t)
+	p := idp.provider()
+
+	tests := []struct {
+		name  string
+		nonce any // nil — без nonce
+	}{
+		{"other nonce", "nonce-2"},
+		{"missing nonce", nil},
+		{"empty nonce", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idp.issue("", jwt.MapClaims{"nonce": tt.nonce})
+			if _, err := p.Exchange(context.Background(), "code", "verifier", "nonce-1"); !errors.Is(err, ErrInvalidIDToken) {
+				t.Errorf("error = %v, want %v", err, ErrInvalidIDToken)
+			}
+		})
+	}
+}
+
+func TestExchangeRejectsInvalidClaims(t *testing.T) {
+	idp := newFakeIdP(t)
+	p := idp.provider()
+
+	tests := []struct {
+		name   string
+		claims jwt.MapClaims
+	}{
+		{"other issuer", jwt.MapClaims{"iss": "https://evil.example.com"}},
+		{"other audience", jwt.MapClaims{"aud": "someone-else"}},
+		{"foreign azp", jwt.MapClaims{"aud": []string{"client", "other"}, "azp": "other"}},
+		{"expired", jwt.MapClaims{"exp": time.Now().Add(-time.Hour).Unix()}},
+		{"missing exp", jwt.MapClaims{"exp": nil}},
+		{"missing sub", jwt.MapClaims{"sub": nil}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idp.issue("nonce-1", tt.claims)
+			if _, err := p.Exchange(context.Background(), "code", "verifier", "nonce-1"); !errors.Is(err, ErrInvalidIDToken) {
+				t.Errorf("error = %v, want %v", err, ErrInvalidIDToken)
+			}
+		})
+	}
+}
+
+func TestVerifyRejectsForeignSignature(t *testing.T) {
+	idp := newFakeIdP(t)
+	p := idp.provider()
+
+	// подписано чужим ключом с тем же kid
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp.issue("nonce-1", nil)
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, idp.claims)
+	token.Header["kid"] = "k1"
+	raw, err := token.SignedString(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	meta, err := p.discover(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.verify(context.Background(), meta, raw, "nonce-1"); !errors.Is(err, ErrInvalidIDToken) {
+		t.Errorf("error = %v, want %v", err, ErrInvalidIDToken)
+	}
+}
+
+func TestAuthCodeURLUsesPKCE(t *testing.T) {
+	idp := newFakeIdP(t)
+	p := idp.provider()
+
+	verifier, err := NewVerifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := p.AuthCodeURL(context.Background(), "state-1", "nonce-1", verifier)
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	sum := sha256.Sum256([]byte(verifier))
+	if got, want := q.Get("code_challenge"), base64.RawURLEncoding.EncodeToString(sum[:]); got != want {
+		t.Errorf("code_challenge = %q, want %q", got, want)
+	}
+	if q.Get("code_challenge_method") != "S256" || q.Get("state") != "state-1" || q.Get("nonce") != "nonce-1" {
+		t.Errorf("query = %v", q)
+	}
+	if q.Has("code_verifier") {
+		t.Error("code_verifier leaked into authorization url")
+	}
+
+	idp.issue("nonce-1", nil)
+	if _, err := p.Exchange(context.Background(), "code", verifier, "nonce-1"); err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	idp.mu.Lock()
+	got := idp.verifier
+	idp.mu.Unlock()
+	if got != verifier {
+		t.Errorf("token request code_verifier = %q, want %q", got, verifier)
+	}
+}
+
+func TestDiscoveryRejectsIssuerMismatch(t *testing.T) {
+	idp := newFakeIdP(t)
+	p := NewProvider(&config.OIDC{Issuer: idp.URL + "/tenant", ClientID: "client", Timeout: 5 * time.Second})
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(discovery{
+			Issuer:                "https://evil.example.com",
+			AuthorizationEndpoint: idp.URL + "/authorize",
+			TokenEndpoint:         idp.URL + "/token",
+			JWKSURI:               idp.URL + "/jwks",
+		})
+	}))
+	defer srv.Close()
+	p.cfg.Issuer = srv.URL
+
+	if _, err := p.AuthCodeURL(context.Background(), "s", "n", "v"); err == nil {
+		t.Error("discovery with foreign issuer accepted")
+	}
+}
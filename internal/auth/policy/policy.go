@@ -0,0 +1,104 @@
+// Package authpolicy — глобальные роли и правила доступа к действиям REST API.
+package authpolicy
+
+import (
+	"errors"
+	"slices"
+)
+
+// глобальные роли пользователя (users.role)
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+var Roles = []string{RoleUser, RoleAdmin}
+
+// Permission действие, которое маршрут объявляет требуемым
+type Permission string
+
+const (
+	UsersRead   Permission = "users.read"
+	UsersUpdate Permission = "users.update"
+	UsersDelete Permission = "users.delete"
+
+	AdminImport Permission = "admin.import"
+	AdminUnlock Permission = "admin.unlock"
+	AdminRoles  Permission = "admin.roles"
+
+	// боты получают все вызовы своих команд во всех каналах
+	BotsManage Permission = "bots.manage"
+
+	// участником канала становится любой, кто в него зашел, поэтому настройки,
+	// влияющие на весь канал и его историю, доступны только администраторам
+	ChannelsManage Permission = "channels.manage"
+)
+
+var (
+	ErrForbidden   = errors.New("forbidden")
+	ErrMFARequired = errors.New("two-factor authentication required")
+)
+
+// rule кому разрешено действие; администратору разрешено все, но сверх roles — только с учетом adminMFA
+type rule struct {
+	roles []string // роли, которым действие разрешено над любым ресурсом
+	owner bool     // владельцу ресурса действие разрешено независимо от роли
+}
+
+var rules = map[Permission]rule{
+	UsersRead:   {roles: []string{RoleUser, RoleAdmin}},
+	UsersUpdate: {owner: true},
+	UsersDelete: {owner: true},
+	AdminImport: {},
+	AdminUnlock: {},
+	AdminRoles:  {},
+	BotsManage:  {},
+
+	ChannelsManage: {},
+}
+
+// Subject кто выполняет действие
+type Subject struct {
+	UserID string
+	Role   string
+	MFA    bool // вход подтвержден вторым фактором
+}
+
+type Policy struct {
+	adminMFA bool
+}
+
+// New adminMFA — полномочия администратора (доступ, который дает только роль admin)
+// действуют лишь при входе со вторым фактором
+func New(adminMFA bool) *Policy {
+	return &Policy{adminMFA: adminMFA}
+}
+
+// Can проверяет, может ли subject выполнить действие над ресурсом владельца ownerID ("" — ресурс без владельца)
+func (p *Policy) Can(subject Subject, perm Permission, ownerID string) error {
+	r, ok := rules[perm]
+	if !ok || subject.UserID == "" {
+		return ErrForbidden
+	}
+
+	if slices.Contains(r.roles, subject.Role) {
+		return nil
+	}
+	if r.owner && ownerID != "" && ownerID == subject.UserID {
+		return nil
+	}
+
+	if subject.Role != RoleAdmin {
+		return ErrForbidden
+	}
+	if p.adminMFA && !subject.MFA {
+		return ErrMFARequired
+	}
+
+	return nil
+}
+
+// ValidRole известна ли роль
+func ValidRole(role string) bool {
+	return slices.Contains(Roles, role)
+}
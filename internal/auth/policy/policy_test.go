@@ -0,0 +1,61 @@
+package authpolicy
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCan(t *testing.T) {
+	const (
+		alice = "alice"
+		bob   = "bob"
+	)
+
+	tests := []struct {
+		name     string
+		adminMFA bool
+		subject  Subject
+		perm     Permission
+		owner    string
+		want     error
+	}{
+		{"user reads users", false, Subject{UserID: alice, Role: RoleUser}, UsersRead, "", nil},
+		{"user updates self", false, Subject{UserID: alice, Role: RoleUser}, UsersUpdate, alice, nil},
+		{"user updates other", false, Subject{UserID: alice, Role: RoleUser}, UsersUpdate, bob, ErrForbidden},
+		{"user deletes other", false, Subject{UserID: alice, Role: RoleUser}, UsersDelete, bob, ErrForbidden},
+		{"owner without resource", false, Subject{UserID: alice, Role: RoleUser}, UsersUpdate, "", ErrForbidden},
+		{"user imports", false, Subject{UserID: alice, Role: RoleUser}, AdminImport, "", ErrForbidden},
+		{"user changes roles", false, Subject{UserID: alice, Role: RoleUser}, AdminRoles, "", ErrForbidden},
+		{"user manages bots", false, Subject{UserID: alice, Role: RoleUser}, BotsManage, "", ErrForbidden},
+		{"member manages channel", false, Subject{UserID: alice, Role: RoleUser}, ChannelsManage, "", ErrForbidden},
+		{"unknown role", false, Subject{UserID: alice, Role: "root"}, AdminUnlock, "", ErrForbidden},
+		{"anonymous", false, Subject{Role: RoleAdmin}, UsersRead, "", ErrForbidden},
+		{"unknown permission", false, Subject{UserID: alice, Role: RoleAdmin}, Permission("nope"), "", ErrForbidden},
+
+		{"admin changes roles", false, Subject{UserID: alice, Role: RoleAdmin}, AdminRoles, "", nil},
+		{"admin manages channel", false, Subject{UserID: alice, Role: RoleAdmin}, ChannelsManage, "", nil},
+		{"admin updates other", false, Subject{UserID: alice, Role: RoleAdmin}, UsersDelete, bob, nil},
+		{"admin without mfa", true, Subject{UserID: alice, Role: RoleAdmin}, AdminRoles, "", ErrMFARequired},
+		{"admin with mfa", true, Subject{UserID: alice, Role: RoleAdmin, MFA: true}, AdminRoles, "", nil},
+		// без второго фактора администратору остается то, что доступно ему как обычному пользователю
+		{"admin without mfa reads", true, Subject{UserID: alice, Role: RoleAdmin}, UsersRead, "", nil},
+		{"admin without mfa updates self", true, Subject{UserID: alice, Role: RoleAdmin}, UsersUpdate, alice, nil},
+		{"admin without mfa updates other", true, Subject{UserID: alice, Role: RoleAdmin}, UsersUpdate, bob, ErrMFARequired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := New(tt.adminMFA).Can(tt.subject, tt.perm, tt.owner); !errors.Is(err, tt.want) {
+				t.Errorf("Can = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestEveryPermissionHasRule(t *testing.T) {
+	perms := []Permission{UsersRead, UsersUpdate, UsersDelete, AdminImport, AdminUnlock, AdminRoles, BotsManage, ChannelsManage}
+	for _, perm := range perms {
+		if _, ok := rules[perm]; !ok {
+			t.Errorf("no rule for %s", perm)
+		}
+	}
+}
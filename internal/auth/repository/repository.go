@@ -2,15 +2,76 @@ package authrepository
 
 import (
 	"context"
+	"errors"
 	"log/slog"
+	"time"
 
 	"github.com/QuUteO/video-communication/internal/model"
 	postgres "github.com/QuUteO/video-communication/pkg/db"
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4"
 )
 
 type Repository interface {
 	Register(ctx context.Context, user *model.User) error
 	FindByEmail(ctx context.Context, email string) (*model.User, error)
+	FindByID(ctx context.Context, userID string) (*model.User, error)
+	MarkEmailVerified(ctx context.Context, userID uuid.UUID, email string) (bool, error)
+	SetPassword(ctx context.Context, userID uuid.UUID, hash string) error
+
+	CreateSession(ctx context.Context, session *model.AuthSession, ttl time.Duration) error
+	SessionActive(ctx context.Context, sessionID string) (bool, error)
+	TouchSession(ctx context.Context, sessionID string, device model.DeviceInfo) error
+	ListSessions(ctx context.Context, userID string) ([]model.AuthSession, error)
+	RevokeSession(ctx context.Context, sessionID, reason string) (bool, error)
+	RevokeUserSession(ctx context.Context, userID, sessionID, reason string) (bool, error)
+	RevokeAllSessions(ctx context.Context, userID, keep uuid.UUID, reason string) ([]string, error)
+
+	CreateRefreshToken(ctx context.Context, token *model.RefreshToken, ttl time.Duration) error
+	FindRefreshToken(ctx context.Context, tokenHash string) (*model.RefreshToken, error)
+	UseRefreshToken(ctx context.Context, id uuid.UUID) (bool, error)
+
+	CreateAuthToken(ctx context.Context, token *model.AuthToken, ttl time.Duration) error
+	RecentAuthToken(ctx context.Context, userID uuid.UUID, purpose string, within time.Duration) (bool, error)
+	ConsumeAuthToken(ctx context.Context, purpose, tokenHash string) (*model.AuthToken, error)
+	ExpireAuthTokens(ctx context.Context, userID uuid.UUID, purpose string) error
+
+	GetTOTP(ctx context.Context, userID uuid.UUID) (*model.TOTP, error)
+	SaveTOTPSecret(ctx context.Context, userID uuid.UUID, secret string) (bool, error)
+	EnableTOTP(ctx context.Context, userID uuid.UUID) error
+	UseTOTPStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error)
+	TOTPAttempt(ctx context.Context, userID uuid.UUID) (int, time.Duration, error)
+	LockTOTP(ctx context.Context, userID uuid.UUID, lockout time.Duration) error
+	ClearTOTPAttempts(ctx context.Context, userID uuid.UUID) error
+	DeleteTOTP(ctx context.Context, userID uuid.UUID) error
+	ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, hashes []string) error
+	UseRecoveryCode(ctx context.Context, userID uuid.UUID, hash string) (bool, error)
+
+	CreateChallenge(ctx context.Context, challenge *model.LoginChallenge, ttl time.Duration) error
+	FindChallenge(ctx context.Context, tokenHash string) (*model.LoginChallenge, error)
+	ChallengeAttempt(ctx context.Context, id uuid.UUID) (int, error)
+	ConsumeChallenge(ctx context.Context, id uuid.UUID) (bool, error)
+
+	GetLoginAttempts(ctx context.Context, emailKey string) (*model.LoginAttempts, error)
+	RecordLoginFailure(ctx context.Context, emailKey string, window time.Duration) (int, error)
+	LockLogin(ctx context.Context, emailKey string, lockout time.Duration) error
+	ClearLoginAttempts(ctx context.Context, emailKey string) error
+
+	CreateOIDCState(ctx context.Context, state *model.OIDCState, ttl time.Duration) error
+	ConsumeOIDCState(ctx context.Context, stateHash string) (*model.OIDCState, error)
+	FindOIDCIdentity(ctx context.Context, issuer, subject string) (uuid.UUID, error)
+	FindUserForLink(ctx context.Context, email string) (*model.User, error)
+	CreateOIDCUser(ctx context.Context, email, username string) (uuid.UUID, error)
+	LinkOIDCIdentity(ctx context.Context, issuer, subject string, userID uuid.UUID, email string) error
+
+	CreateAPIKey(ctx context.Context, key *model.APIKey, ttl time.Duration, maxActive int) (bool, error)
+	ListAPIKeys(ctx context.Context, userID string) ([]model.APIKey, error)
+	FindAPIKey(ctx context.Context, keyHash string) (*model.APIKey, error)
+	TouchAPIKey(ctx context.Context, id uuid.UUID) error
+	RevokeAPIKey(ctx context.Context, userID, keyID string) (bool, error)
+
+	UserRole(ctx context.Context, userID string) (string, error)
+	SetRole(ctx context.Context, userID uuid.UUID, role string) (bool, error)
 }
 
 type repository struct {
@@ -22,9 +83,9 @@ func (r *repository) Register(ctx context.Context, user *model.User) error {
 	const op = "./internal/auth/repository.Register"
 	log := r.logger.With("op: ", op)
 
-	q := `INSERT INTO users (id, email, password, created_at) VALUES ($1, $2, $3, $4)`
+	q := `INSERT INTO users (id, email, password, created_at, username) VALUES ($1, $2, $3, $4, NULLIF($5, '')) RETURNING id`
 
-	if err := r.db.QueryRow(ctx, q, user.Id, user.Email, user.Password, user.CreatedAt).Scan(&user.Id); err != nil {
+	if err := r.db.QueryRow(ctx, q, user.Id, user.Email, user.Password, user.CreatedAt, user.Username).Scan(&user.Id); err != nil {
 		log.Error("Error to insert user", slog.Any("err", err))
 		return err
 	}
@@ -37,13 +98,13 @@ func (r *repository) FindByEmail(ctx context.Context, email string) (*model.User
 	log := r.logger.With("op: ", op)
 
 	q := `
-		SELECT id, email, password, created_at 
-		FROM users 
+		SELECT id, email, password, created_at
+		FROM users
 		WHERE email = $1
 			`
 
 	var user model.User
-	if err := r.db.QueryRow(ctx, q, email).Scan(&user.Id, &user.Email, &user.Password); err != nil {
+	if err := r.db.QueryRow(ctx, q, email).Scan(&user.Id, &user.Email, &user.Password, &user.CreatedAt); err != nil {
 		log.Error("Error to find user by email", slog.Any("err", err))
 		return nil, err
 	}
@@ -51,6 +112,879 @@ func (r *repository) FindByEmail(ctx context.Context, email string) (*model.User
 	return &user, nil
 }
 
+func (r *repository) FindByID(ctx context.Context, userID string) (*model.User, error) {
+	const op = "./internal/auth/repository.FindByID"
+	log := r.logger.With("op: ", op)
+
+	q := `
+		SELECT id, email, COALESCE(username, ''), password, created_at, email_verified_at
+		FROM users
+		WHERE id = $1
+	`
+
+	var user model.User
+	if err := r.db.QueryRow(ctx, q, userID).Scan(&user.Id, &user.Email, &user.Username, &user.Password,
+		&user.CreatedAt, &user.EmailVerifiedAt); err != nil {
+		log.Error("Error to find user by id", slog.Any("err", err))
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// MarkEmailVerified подтверждает адрес, только если он не менялся после отправки письма
+func (r *repository) MarkEmailVerified(ctx context.Context, userID uuid.UUID, email string) (bool, error) {
+	const op = "./internal/auth/repository.MarkEmailVerified"
+	log := r.logger.With("op: ", op)
+
+	q := `
+		UPDATE users
+		SET email_verified_at = COALESCE(email_verified_at, now())
+		WHERE id = $1 AND lower(email) = lower($2)
+	`
+
+	tag, err := r.db.Exec(ctx, q, userID, email)
+	if err != nil {
+		log.Error("Error to mark email verified", slog.Any("err", err))
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *repository) SetPassword(ctx context.Context, userID uuid.UUID, hash string) error {
+	const op = "./internal/auth/repository.SetPassword"
+	log := r.logger.With("op: ", op)
+
+	q := `UPDATE users SET password = $2 WHERE id = $1`
+
+	if _, err := r.db.Exec(ctx, q, userID, hash); err != nil {
+		log.Error("Error to set password", slog.Any("err", err))
+		return err
+	}
+
+	return nil
+}
+
+// CreateSession создает сессию; срок отсчитывается по часам базы
+func (r *repository) CreateSession(ctx context.Context, session *model.AuthSession, ttl time.Duration) error {
+	const op = "./internal/auth/repository.CreateSession"
+	log := r.logger.With("op: ", op)
+
+	q := `
+		INSERT INTO auth_sessions (id, user_id, user_agent, device, ip, mfa, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, now() + make_interval(secs => $7))
+		RETURNING created_at, last_seen_at, expires_at
+	`
+
+	if err := r.db.QueryRow(ctx, q, session.ID, session.UserID, session.UserAgent, session.Device, session.IP, session.MFA, ttl.Seconds()).
+		Scan(&session.CreatedAt, &session.LastSeenAt, &session.ExpiresAt); err != nil {
+		log.Error("Error to insert session", slog.Any("err", err))
+		return err
+	}
+
+	return nil
+}
+
+func (r *repository) SessionActive(ctx context.Context, sessionID string) (bool, error) {
+	const op = "./internal/auth/repository.SessionActive"
+	log := r.logger.With("op: ", op)
+
+	// last_seen_at обновляется не чаще раза в минуту, чтобы не писать на каждый запрос
+	q := `
+		WITH touched AS (
+			UPDATE auth_sessions
+			SET last_seen_at = now()
+			WHERE id = $1 AND revoked_at IS NULL AND last_seen_at < now() - interval '1 minute'
+			RETURNING id
+		)
+		SELECT EXISTS (SELECT 1 FROM auth_sessions WHERE id = $1 AND revoked_at IS NULL)
+	`
+
+	var active bool
+	if err := r.db.QueryRow(ctx, q, sessionID).Scan(&active); err != nil {
+		log.Error("Error to check session", slog.Any("err", err))
+		return false, err
+	}
+
+	return active, nil
+}
+
+// TouchSession запоминает последний адрес и клиент сессии (при обновлении токена)
+func (r *repository) TouchSession(ctx context.Context, sessionID string, device model.DeviceInfo) error {
+	const op = "./internal/auth/repository.TouchSession"
+	log := r.logger.With("op: ", op)
+
+	q := `
+		UPDATE auth_sessions
+		SET last_seen_at = now(), ip = $2, user_agent = COALESCE(NULLIF($3, ''), user_agent)
+		WHERE id = $1
+	`
+
+	if _, err := r.db.Exec(ctx, q, sessionID, device.IP, device.UserAgent); err != nil {
+		log.Error("Error to touch session", slog.Any("err", err))
+		return err
+	}
+
+	return nil
+}
+
+// ListSessions активные сессии пользователя, последние использованные — первыми
+func (r *repository) ListSessions(ctx context.Context, userID string) ([]model.AuthSession, error) {
+	const op = "./internal/auth/repository.ListSessions"
+	log := r.logger.With("op: ", op)
+
+	q := `
+		SELECT id, user_id, device, user_agent, ip, mfa, created_at, last_seen_at, expires_at
+		FROM auth_sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > now()
+		ORDER BY last_seen_at DESC
+	`
+
+	rows, err := r.db.Query(ctx, q, userID)
+	if err != nil {
+		log.Error("Error to list sessions", slog.Any("err", err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := make([]model.AuthSession, 0)
+	for rows.Next() {
+		var s model.AuthSession
+		if err := rows.Scan(&s.ID, &s.UserID, &s.Device, &s.UserAgent, &s.IP, &s.MFA, &s.CreatedAt, &s.LastSeenAt, &s.ExpiresAt); err != nil {
+			log.Error("Error to scan session", slog.Any("err", err))
+			return nil, err
+		}
+		sessions = append(sessions, s)
+	}
+
+	return sessions, rows.Err()
+}
+
+// RevokeSession отзывает сессию; false — сессия не найдена или уже отозвана
+func (r *repository) RevokeSession(ctx context.Context, sessionID, reason string) (bool, error) {
+	const op = "./internal/auth/repository.RevokeSession"
+	log := r.logger.With("op: ", op)
+
+	q := `
+		UPDATE auth_sessions
+		SET revoked_at = now(), revoked_reason = $2
+		WHERE id = $1 AND revoked_at IS NULL
+	`
+
+	tag, err := r.db.Exec(ctx, q, sessionID, reason)
+	if err != nil {
+		log.Error("Error to revoke session", slog.Any("err", err))
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// RevokeUserSession отзывает сессию, только если она принадлежит пользователю
+func (r *repository) RevokeUserSession(ctx context.Context, userID, sessionID, reason string) (bool, error) {
+	const op = "./internal/auth/repository.RevokeUserSession"
+	log := r.logger.With("op: ", op)
+
+	q := `
+		UPDATE auth_sessions
+		SET revoked_at = now(), revoked_reason = $3
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+	`
+
+	tag, err := r.db.Exec(ctx, q, sessionID, userID, reason)
+	if err != nil {
+		log.Error("Error to revoke session", slog.Any("err", err))
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// RevokeAllSessions отзывает все сессии пользователя, кроме keep (uuid.Nil — все), и возвращает их id
+func (r *repository) RevokeAllSessions(ctx context.Context, userID, keep uuid.UUID, reason string) ([]string, error) {
+	const op = "./internal/auth/repository.RevokeAllSessions"
+	log := r.logger.With("op: ", op)
+
+	q := `
+		UPDATE auth_sessions
+		SET revoked_at = now(), revoked_reason = $2
+		WHERE user_id = $1 AND revoked_at IS NULL AND id <> $3
+		RETURNING id
+	`
+
+	rows, err := r.db.Query(ctx, q, userID, reason, keep)
+	if err != nil {
+		log.Error("Error to revoke sessions", slog.Any("err", err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id.String())
+	}
+
+	return ids, rows.Err()
+}
+
+// CreateRefreshToken сохраняет токен и продлевает сессию до его срока
+func (r *repository) CreateRefreshToken(ctx context.Context, token *model.RefreshToken, ttl time.Duration) error {
+	const op = "./internal/auth/repository.CreateRefreshToken"
+	log := r.logger.With("op: ", op)
+
+	q := `
+		WITH t AS (
+			INSERT INTO refresh_tokens (id, session_id, token_hash, expires_at)
+			VALUES ($1, $2, $3, now() + make_interval(secs => $4))
+			RETURNING session_id, expires_at
+		)
+		UPDATE auth_sessions s
+		SET expires_at = t.expires_at
+		FROM t
+		WHERE s.id = t.session_id
+		RETURNING t.expires_at
+	`
+
+	if err := r.db.QueryRow(ctx, q, token.ID, token.SessionID, token.TokenHash, ttl.Seconds()).Scan(&token.ExpiresAt); err != nil {
+		log.Error("Error to insert refresh token", slog.Any("err", err))
+		return err
+	}
+
+	return nil
+}
+
+// FindRefreshToken ищет токен по хешу; истекший токен считается ненайденным
+func (r *repository) FindRefreshToken(ctx context.Context, tokenHash string) (*model.RefreshToken, error) {
+	q := `
+		SELECT t.id, t.session_id, s.user_id, t.token_hash, t.expires_at, t.used_at, s.revoked_at IS NOT NULL, s.mfa
+		FROM refresh_tokens t
+		JOIN auth_sessions s ON s.id = t.session_id
+		WHERE t.token_hash = $1 AND t.expires_at > now()
+	`
+
+	var token model.RefreshToken
+	if err := r.db.QueryRow(ctx, q, tokenHash).Scan(&token.ID, &token.SessionID, &token.UserID, &token.TokenHash,
+		&token.ExpiresAt, &token.UsedAt, &token.SessionRevoked, &token.SessionMFA); err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// UseRefreshToken помечает токен использованным; false — его уже кто-то предъявил
+func (r *repository) UseRefreshToken(ctx context.Context, id uuid.UUID) (bool, error) {
+	const op = "./internal/auth/repository.UseRefreshToken"
+	log := r.logger.With("op: ", op)
+
+	q := `UPDATE refresh_tokens SET used_at = now() WHERE id = $1 AND used_at IS NULL`
+
+	tag, err := r.db.Exec(ctx, q, id)
+	if err != nil {
+		log.Error("Error to mark refresh token used", slog.Any("err", err))
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *repository) CreateAuthToken(ctx context.Context, token *model.AuthToken, ttl time.Duration) error {
+	const op = "./internal/auth/repository.CreateAuthToken"
+	log := r.logger.With("op: ", op)
+
+	q := `
+		INSERT INTO auth_tokens (id, user_id, purpose, email, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4, $5, now() + make_interval(secs => $6))
+		RETURNING expires_at
+	`
+
+	if err := r.db.QueryRow(ctx, q, token.ID, token.UserID, token.Purpose, token.Email, token.TokenHash, ttl.Seconds()).
+		Scan(&token.ExpiresAt); err != nil {
+		log.Error("Error to insert auth token", slog.Any("err", err))
+		return err
+	}
+
+	return nil
+}
+
+// RecentAuthToken был ли токен с этим назначением выдан недавно (защита от рассылки писем)
+func (r *repository) RecentAuthToken(ctx context.Context, userID uuid.UUID, purpose string, within time.Duration) (bool, error) {
+	q := `
+		SELECT EXISTS (
+			SELECT 1 FROM auth_tokens
+			WHERE user_id = $1 AND purpose = $2 AND created_at > now() - make_interval(secs => $3)
+		)
+	`
+
+	var recent bool
+	err := r.db.QueryRow(ctx, q, userID, purpose, within.Seconds()).Scan(&recent)
+	return recent, err
+}
+
+// ConsumeAuthToken гасит действующий токен; pgx.ErrNoRows — токена нет, он истек или уже использован
+func (r *repository) ConsumeAuthToken(ctx context.Context, purpose, tokenHash string) (*model.AuthToken, error) {
+	q := `
+		UPDATE auth_tokens
+		SET used_at = now()
+		WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > now()
+		RETURNING id, user_id, purpose, email, token_hash, expires_at
+	`
+
+	var token model.AuthToken
+	if err := r.db.QueryRow(ctx, q, tokenHash, purpose).Scan(&token.ID, &token.UserID, &token.Purpose, &token.Email,
+		&token.TokenHash, &token.ExpiresAt); err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// ExpireAuthTokens гасит все оставшиеся токены пользователя с этим назначением
+func (r *repository) ExpireAuthTokens(ctx context.Context, userID uuid.UUID, purpose string) error {
+	q := `UPDATE auth_tokens SET used_at = now() WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL`
+
+	_, err := r.db.Exec(ctx, q, userID, purpose)
+	return err
+}
+
+func (r *repository) GetTOTP(ctx context.Context, userID uuid.UUID) (*model.TOTP, error) {
+	q := `SELECT user_id, secret, enabled_at, last_step FROM user_totp WHERE user_id = $1`
+
+	var t model.TOTP
+	if err := r.db.QueryRow(ctx, q, userID).Scan(&t.UserID, &t.Secret, &t.EnabledAt, &t.LastStep); err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+// SaveTOTPSecret начинает (или перезапускает) подключение; false — 2FA уже включена
+func (r *repository) SaveTOTPSecret(ctx context.Context, userID uuid.UUID, secret string) (bool, error) {
+	const op = "./internal/auth/repository.SaveTOTPSecret"
+	log := r.logger.With("op: ", op)
+
+	q := `
+		INSERT INTO user_totp (user_id, secret)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+			SET secret = EXCLUDED.secret, last_step = 0, created_at = now()
+			WHERE user_totp.enabled_at IS NULL
+	`
+
+	tag, err := r.db.Exec(ctx, q, userID, secret)
+	if err != nil {
+		log.Error("Error to save totp secret", slog.Any("err", err))
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *repository) EnableTOTP(ctx context.Context, userID uuid.UUID) error {
+	q := `UPDATE user_totp SET enabled_at = now() WHERE user_id = $1 AND enabled_at IS NULL`
+
+	_, err := r.db.Exec(ctx, q, userID)
+	return err
+}
+
+// UseTOTPStep запоминает принятый шаг; false — код этого или более позднего шага уже использован
+func (r *repository) UseTOTPStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error) {
+	q := `UPDATE user_totp SET last_step = $2 WHERE user_id = $1 AND last_step < $2`
+
+	tag, err := r.db.Exec(ctx, q, userID, step)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// TOTPAttempt учитывает попытку ввода кода до проверки и возвращает число попыток подряд
+// и оставшийся срок блокировки; во время блокировки счетчик не растет
+func (r *repository) TOTPAttempt(ctx context.Context, userID uuid.UUID) (int, time.Duration, error) {
+	q := `
+		UPDATE user_totp
+		SET failed_attempts = CASE WHEN locked_until > now() THEN failed_attempts ELSE failed_attempts + 1 END
+		WHERE user_id = $1
+		RETURNING failed_attempts,
+		          GREATEST(EXTRACT(EPOCH FROM COALESCE(locked_until, now()) - now()), 0)::float8
+	`
+
+	var attempts int
+	var locked float64
+	if err := r.db.QueryRow(ctx, q, userID).Scan(&attempts, &locked); err != nil {
+		return 0, 0, err
+	}
+
+	return attempts, time.Duration(locked * float64(time.Second)), nil
+}
+
+func (r *repository) LockTOTP(ctx context.Context, userID uuid.UUID, lockout time.Duration) error {
+	const op = "./internal/auth/repository.LockTOTP"
+	log := r.logger.With("op: ", op)
+
+	q := `
+		UPDATE user_totp
+		SET failed_attempts = 0, locked_until = now() + make_interval(secs => $2::float8)
+		WHERE user_id = $1
+	`
+
+	if _, err := r.db.Exec(ctx, q, userID, lockout.Seconds()); err != nil {
+		log.Error("Error to lock totp", slog.Any("err", err))
+		return err
+	}
+
+	return nil
+}
+
+func (r *repository) ClearTOTPAttempts(ctx context.Context, userID uuid.UUID) error {
+	q := `UPDATE user_totp SET failed_attempts = 0 WHERE user_id = $1`
+
+	_, err := r.db.Exec(ctx, q, userID)
+	return err
+}
+
+func (r *repository) DeleteTOTP(ctx context.Context, userID uuid.UUID) error {
+	const op = "./internal/auth/repository.DeleteTOTP"
+	log := r.logger.With("op: ", op)
+
+	q := `
+		WITH codes AS (DELETE FROM recovery_codes WHERE user_id = $1)
+		DELETE FROM user_totp WHERE user_id = $1
+	`
+
+	if _, err := r.db.Exec(ctx, q, userID); err != nil {
+		log.Error("Error to delete totp", slog.Any("err", err))
+		return err
+	}
+
+	return nil
+}
+
+// ReplaceRecoveryCodes заменяет все коды восстановления одним запросом
+func (r *repository) ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, hashes []string) error {
+	const op = "./internal/auth/repository.ReplaceRecoveryCodes"
+	log := r.logger.With("op: ", op)
+
+	q := `
+		WITH old AS (DELETE FROM recovery_codes WHERE user_id = $1)
+		INSERT INTO recovery_codes (user_id, code_hash)
+		SELECT $1, unnest($2::text[])
+	`
+
+	if _, err := r.db.Exec(ctx, q, userID, hashes); err != nil {
+		log.Error("Error to replace recovery codes", slog.Any("err", err))
+		return err
+	}
+
+	return nil
+}
+
+func (r *repository) UseRecoveryCode(ctx context.Context, userID uuid.UUID, hash string) (bool, error) {
+	q := `
+		UPDATE recovery_codes
+		SET used_at = now()
+		WHERE id = (
+			SELECT id FROM recovery_codes
+			WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+			LIMIT 1
+		)
+	`
+
+	tag, err := r.db.Exec(ctx, q, userID, hash)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *repository) CreateChallenge(ctx context.Context, challenge *model.LoginChallenge, ttl time.Duration) error {
+	const op = "./internal/auth/repository.CreateChallenge"
+	log := r.logger.With("op: ", op)
+
+	q := `
+		INSERT INTO login_challenges (id, user_id, token_hash, expires_at)
+		VALUES ($1, $2, $3, now() + make_interval(secs => $4))
+		RETURNING expires_at
+	`
+
+	if err := r.db.QueryRow(ctx, q, challenge.ID, challenge.UserID, challenge.TokenHash, ttl.Seconds()).
+		Scan(&challenge.ExpiresAt); err != nil {
+		log.Error("Error to insert login challenge", slog.Any("err", err))
+		return err
+	}
+
+	return nil
+}
+
+// FindChallenge действующий challenge; pgx.ErrNoRows — нет, истек или уже использован
+func (r *repository) FindChallenge(ctx context.Context, tokenHash string) (*model.LoginChallenge, error) {
+	q := `
+		SELECT id, user_id, token_hash, attempts, expires_at
+		FROM login_challenges
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > now()
+	`
+
+	var c model.LoginChallenge
+	if err := r.db.QueryRow(ctx, q, tokenHash).Scan(&c.ID, &c.UserID, &c.TokenHash, &c.Attempts, &c.ExpiresAt); err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+// ChallengeAttempt учитывает попытку ввода кода и возвращает их число
+func (r *repository) ChallengeAttempt(ctx context.Context, id uuid.UUID) (int, error) {
+	q := `UPDATE login_challenges SET attempts = attempts + 1 WHERE id = $1 RETURNING attempts`
+
+	var attempts int
+	err := r.db.QueryRow(ctx, q, id).Scan(&attempts)
+	return attempts, err
+}
+
+func (r *repository) ConsumeChallenge(ctx context.Context, id uuid.UUID) (bool, error) {
+	q := `UPDATE login_challenges SET used_at = now() WHERE id = $1 AND used_at IS NULL`
+
+	tag, err := r.db.Exec(ctx, q, id)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// GetLoginAttempts состояние адреса; без записей — нулевое
+func (r *repository) GetLoginAttempts(ctx context.Context, emailKey string) (*model.LoginAttempts, error) {
+	q := `
+		SELECT failures,
+		       EXTRACT(EPOCH FROM now() - last_failed_at)::float8,
+		       GREATEST(EXTRACT(EPOCH FROM COALESCE(locked_until, now()) - now()), 0)::float8
+		FROM login_attempts
+		WHERE email_key = $1
+	`
+
+	var attempts model.LoginAttempts
+	var since, locked float64
+	err := r.db.QueryRow(ctx, q, emailKey).Scan(&attempts.Failures, &since, &locked)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return &attempts, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	attempts.SinceLastFailure = time.Duration(since * float64(time.Second))
+	attempts.LockedFor = time.Duration(locked * float64(time.Second))
+	return &attempts, nil
+}
+
+// RecordLoginFailure учитывает неудачу и возвращает число неудач подряд в пределах окна
+func (r *repository) RecordLoginFailure(ctx context.Context, emailKey string, window time.Duration) (int, error) {
+	const op = "./internal/auth/repository.RecordLoginFailure"
+	log := r.logger.With("op: ", op)
+
+	q := `
+		INSERT INTO login_attempts (email_key, failures, last_failed_at)
+		VALUES ($1, 1, now())
+		ON CONFLICT (email_key) DO UPDATE SET
+			failures = CASE
+				WHEN login_attempts.last_failed_at < now() - make_interval(secs => $2) THEN 1
+				ELSE login_attempts.failures + 1
+			END,
+			last_failed_at = now()
+		RETURNING failures
+	`
+
+	var failures int
+	if err := r.db.QueryRow(ctx, q, emailKey, window.Seconds()).Scan(&failures); err != nil {
+		log.Error("Error to record login failure", slog.Any("err", err))
+		return 0, err
+	}
+
+	return failures, nil
+}
+
+// LockLogin блокирует вход; счетчик обнуляется, чтобы после блокировки отсчет шел заново
+func (r *repository) LockLogin(ctx context.Context, emailKey string, lockout time.Duration) error {
+	q := `
+		UPDATE login_attempts
+		SET locked_until = now() + make_interval(secs => $2), failures = 0
+		WHERE email_key = $1
+	`
+
+	_, err := r.db.Exec(ctx, q, emailKey, lockout.Seconds())
+	return err
+}
+
+func (r *repository) ClearLoginAttempts(ctx context.Context, emailKey string) error {
+	q := `DELETE FROM login_attempts WHERE email_key = $1`
+
+	_, err := r.db.Exec(ctx, q, emailKey)
+	return err
+}
+
+func (r *repository) CreateOIDCState(ctx context.Context, state *model.OIDCState, ttl time.Duration) error {
+	const op = "./internal/auth/repository.CreateOIDCState"
+	log := r.logger.With("op: ", op)
+
+	// брошенные входы убираются здесь же, отдельная очистка не нужна
+	if _, err := r.db.Exec(ctx, `DELETE FROM oidc_states WHERE expires_at < now()`); err != nil {
+		log.Warn("Error to delete expired oidc states", slog.Any("err", err))
+	}
+
+	q := `
+		INSERT INTO oidc_states (state_hash, nonce, code_verifier, expires_at)
+		VALUES ($1, $2, $3, now() + make_interval(secs => $4))
+	`
+
+	if _, err := r.db.Exec(ctx, q, state.StateHash, state.Nonce, state.CodeVerifier, ttl.Seconds()); err != nil {
+		log.Error("Error to insert oidc state", slog.Any("err", err))
+		return err
+	}
+
+	return nil
+}
+
+// ConsumeOIDCState одноразово забирает state; pgx.ErrNoRows — нет, истек или уже использован
+func (r *repository) ConsumeOIDCState(ctx context.Context, stateHash string) (*model.OIDCState, error) {
+	q := `
+		DELETE FROM oidc_states
+		WHERE state_hash = $1 AND expires_at > now()
+		RETURNING state_hash, nonce, code_verifier
+	`
+
+	var s model.OIDCState
+	if err := r.db.QueryRow(ctx, q, stateHash).Scan(&s.StateHash, &s.Nonce, &s.CodeVerifier); err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+// FindOIDCIdentity пользователь, привязанный к учетной записи провайдера; uuid.Nil — не привязан
+func (r *repository) FindOIDCIdentity(ctx context.Context, issuer, subject string) (uuid.UUID, error) {
+	q := `
+		UPDATE oidc_identities SET last_login = now()
+		WHERE issuer = $1 AND subject = $2
+		RETURNING user_id
+	`
+
+	var userID uuid.UUID
+	err := r.db.QueryRow(ctx, q, issuer, subject).Scan(&userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return uuid.Nil, nil
+	}
+
+	return userID, err
+}
+
+// FindUserForLink пользователь по адресу без учета регистра; точное совпадение в приоритете
+func (r *repository) FindUserForLink(ctx context.Context, email string) (*model.User, error) {
+	q := `
+		SELECT id, email, COALESCE(username, ''), created_at, email_verified_at
+		FROM users
+		WHERE lower(email) = lower($1)
+		ORDER BY email = $1 DESC
+		LIMIT 1
+	`
+
+	var user model.User
+	if err := r.db.QueryRow(ctx, q, email).Scan(&user.Id, &user.Email, &user.Username, &user.CreatedAt,
+		&user.EmailVerifiedAt); err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// CreateOIDCUser создает пользователя без пароля с подтвержденным адресом; занятое имя не берется.
+// uuid.Nil — адрес уже занят (параллельный вход)
+func (r *repository) CreateOIDCUser(ctx context.Context, email, username string) (uuid.UUID, error) {
+	const op = "./internal/auth/repository.CreateOIDCUser"
+	log := r.logger.With("op: ", op)
+
+	q := `
+		INSERT INTO users (email, password, username, email_verified_at)
+		VALUES ($1, '!oidc', CASE
+			WHEN $2 <> '' AND NOT EXISTS (SELECT 1 FROM users WHERE lower(username) = lower($2)) THEN $2
+		END, now())
+		ON CONFLICT (email) DO NOTHING
+		RETURNING id
+	`
+
+	var userID uuid.UUID
+	err := r.db.QueryRow(ctx, q, email, username).Scan(&userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return uuid.Nil, nil
+	}
+	if err != nil {
+		log.Error("Error to create oidc user", slog.Any("err", err))
+		return uuid.Nil, err
+	}
+
+	return userID, nil
+}
+
+func (r *repository) LinkOIDCIdentity(ctx context.Context, issuer, subject string, userID uuid.UUID, email string) error {
+	const op = "./internal/auth/repository.LinkOIDCIdentity"
+	log := r.logger.With("op: ", op)
+
+	q := `
+		INSERT INTO oidc_identities (issuer, subject, user_id, email)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (issuer, subject) DO NOTHING
+	`
+
+	if _, err := r.db.Exec(ctx, q, issuer, subject, userID, email); err != nil {
+		log.Error("Error to link oidc identity", slog.Any("err", err))
+		return err
+	}
+
+	return nil
+}
+
+// CreateAPIKey сохраняет ключ, если у пользователя меньше maxActive действующих ключей; ttl 0 — бессрочный
+func (r *repository) CreateAPIKey(ctx context.Context, key *model.APIKey, ttl time.Duration, maxActive int) (bool, error) {
+	const op = "./internal/auth/repository.CreateAPIKey"
+	log := r.logger.With("op: ", op)
+
+	q := `
+		INSERT INTO api_keys (id, user_id, name, prefix, key_hash, scopes, expires_at)
+		SELECT $1, $2, $3, $4, $5, $6, CASE WHEN $7::float8 > 0 THEN now() + make_interval(secs => $7::float8) END
+		WHERE (
+			SELECT count(*) FROM api_keys
+			WHERE user_id = $2 AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > now())
+		) < $8
+		RETURNING created_at, expires_at
+	`
+
+	err := r.db.QueryRow(ctx, q, key.ID, key.UserID, key.Name, key.Prefix, key.KeyHash, key.Scopes,
+		ttl.Seconds(), maxActive).Scan(&key.CreatedAt, &key.ExpiresAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		log.Error("Error to insert api key", slog.Any("err", err))
+		return false, err
+	}
+
+	return true, nil
+}
+
+// ListAPIKeys действующие ключи пользователя
+func (r *repository) ListAPIKeys(ctx context.Context, userID string) ([]model.APIKey, error) {
+	const op = "./internal/auth/repository.ListAPIKeys"
+	log := r.logger.With("op: ", op)
+
+	q := `
+		SELECT id, user_id, name, prefix, scopes, created_at, expires_at, last_used_at
+		FROM api_keys
+		WHERE user_id = $1 AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > now())
+		ORDER BY created_at DESC
+	`
+
+	rows, err := r.db.Query(ctx, q, userID)
+	if err != nil {
+		log.Error("Error to list api keys", slog.Any("err", err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := make([]model.APIKey, 0)
+	for rows.Next() {
+		var k model.APIKey
+		if err := rows.Scan(&k.ID, &k.UserID, &k.Name, &k.Prefix, &k.Scopes, &k.CreatedAt, &k.ExpiresAt,
+			&k.LastUsedAt); err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+
+	return keys, rows.Err()
+}
+
+// FindAPIKey действующий ключ по хешу; pgx.ErrNoRows — нет, истек или отозван
+func (r *repository) FindAPIKey(ctx context.Context, keyHash string) (*model.APIKey, error) {
+	q := `
+		SELECT id, user_id, name, prefix, scopes, created_at, expires_at, last_used_at
+		FROM api_keys
+		WHERE key_hash = $1 AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > now())
+	`
+
+	var k model.APIKey
+	if err := r.db.QueryRow(ctx, q, keyHash).Scan(&k.ID, &k.UserID, &k.Name, &k.Prefix, &k.Scopes, &k.CreatedAt,
+		&k.ExpiresAt, &k.LastUsedAt); err != nil {
+		return nil, err
+	}
+
+	return &k, nil
+}
+
+// TouchAPIKey отмечает использование не чаще раза в минуту, чтобы не писать на каждый запрос
+func (r *repository) TouchAPIKey(ctx context.Context, id uuid.UUID) error {
+	q := `
+		UPDATE api_keys SET last_used_at = now()
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < now() - interval '1 minute')
+	`
+
+	_, err := r.db.Exec(ctx, q, id)
+	return err
+}
+
+func (r *repository) RevokeAPIKey(ctx context.Context, userID, keyID string) (bool, error) {
+	const op = "./internal/auth/repository.RevokeAPIKey"
+	log := r.logger.With("op: ", op)
+
+	q := `UPDATE api_keys SET revoked_at = now() WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL`
+
+	tag, err := r.db.Exec(ctx, q, keyID, userID)
+	if err != nil {
+		log.Error("Error to revoke api key", slog.Any("err", err))
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// UserRole роль пользователя; pgx.ErrNoRows — пользователя нет
+func (r *repository) UserRole(ctx context.Context, userID string) (string, error) {
+	q := `SELECT role FROM users WHERE id = $1`
+
+	var role string
+	err := r.db.QueryRow(ctx, q, userID).Scan(&role)
+	return role, err
+}
+
+// SetRole меняет роль; последнего администратора разжаловать нельзя (false).
+// Администраторы блокируются на время проверки, чтобы двое не разжаловали друг друга одновременно
+func (r *repository) SetRole(ctx context.Context, userID uuid.UUID, role string) (bool, error) {
+	const op = "./internal/auth/repository.SetRole"
+	log := r.logger.With("op: ", op)
+
+	q := `
+		WITH admins AS (
+			SELECT id FROM users WHERE role = 'admin' FOR UPDATE
+		)
+		UPDATE users
+		SET role = $2
+		WHERE id = $1
+		  AND ($2 = 'admin' OR role <> 'admin' OR (SELECT count(*) FROM admins) > 1)
+	`
+
+	tag, err := r.db.Exec(ctx, q, userID, role)
+	if err != nil {
+		log.Error("Error to set user role", slog.Any("err", err))
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
 func New(db postgres.Client, logger *slog.Logger) Repository {
 	return &repository{
 		db:     db,
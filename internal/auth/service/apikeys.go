@@ -0,0 +1,140 @@
+package authservice
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/QuUteO/video-communication/internal/model"
+	uuid2 "github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+const (
+	maxAPIKeys       = 20
+	maxAPIKeyDays    = 365
+	apiKeyNameMaxLen = 64
+	apiKeyShownLen   = len(model.APIKeyPrefix) + 8
+)
+
+var (
+	ErrInvalidAPIKey  = errors.New("name is required (up to 64 characters), scopes must be read, write or ws, expiry 0-365 days")
+	ErrAPIKeyNotFound = errors.New("api key not found")
+	ErrTooManyAPIKeys = errors.New("too many active api keys")
+)
+
+var apiScopes = []string{model.APIScopeRead, model.APIScopeWrite, model.APIScopeWebSocket}
+
+// CreateAPIKey выпускает ключ; сам ключ возвращается один раз, хранится только хеш
+func (a *AuthService) CreateAPIKey(ctx context.Context, userID string, req *model.CreateAPIKeyRequest) (*model.CreateAPIKeyResponse, error) {
+	const op = "internal/auth/service.CreateAPIKey"
+	log := a.logger.With("op: ", op)
+
+	owner, err := uuid2.FromString(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" || utf8.RuneCountInString(name) > apiKeyNameMaxLen ||
+		req.ExpiresInDays < 0 || req.ExpiresInDays > maxAPIKeyDays || len(req.Scopes) == 0 {
+		return nil, ErrInvalidAPIKey
+	}
+
+	scopes := make([]string, 0, len(req.Scopes))
+	for _, s := range req.Scopes {
+		s = strings.ToLower(strings.TrimSpace(s))
+		if !slices.Contains(apiScopes, s) {
+			return nil, ErrInvalidAPIKey
+		}
+		if !slices.Contains(scopes, s) {
+			scopes = append(scopes, s)
+		}
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, err
+	}
+	secret := model.APIKeyPrefix + hex.EncodeToString(raw)
+
+	key := model.APIKey{
+		ID:      uuid2.Must(uuid2.NewV4()),
+		UserID:  owner,
+		Name:    name,
+		Prefix:  secret[:apiKeyShownLen],
+		KeyHash: hashToken(secret),
+		Scopes:  scopes,
+	}
+	ttl := time.Duration(req.ExpiresInDays) * 24 * time.Hour
+
+	ok, err := a.repo.CreateAPIKey(ctx, &key, ttl, maxAPIKeys)
+	if err != nil {
+		log.Error("Error creating api key", slog.Any("error", err))
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrTooManyAPIKeys
+	}
+
+	return &model.CreateAPIKeyResponse{APIKey: key, Key: secret}, nil
+}
+
+func (a *AuthService) ListAPIKeys(ctx context.Context, userID string) ([]model.APIKey, error) {
+	return a.repo.ListAPIKeys(ctx, userID)
+}
+
+// RevokeAPIKey отзывает ключ и закрывает открытые им websocket-соединения
+func (a *AuthService) RevokeAPIKey(ctx context.Context, userID, keyID string) error {
+	const op = "internal/auth/service.RevokeAPIKey"
+	log := a.logger.With("op: ", op)
+
+	if _, err := uuid2.FromString(keyID); err != nil {
+		return ErrAPIKeyNotFound
+	}
+
+	ok, err := a.repo.RevokeAPIKey(ctx, userID, keyID)
+	if err != nil {
+		log.Error("Error revoking api key", slog.Any("error", err))
+		return err
+	}
+	if !ok {
+		return ErrAPIKeyNotFound
+	}
+
+	if a.closer != nil {
+		a.closer.CloseSession(keyID)
+	}
+
+	return nil
+}
+
+// AuthenticateAPIKey действующий ключ по его значению; nil — ключ неизвестен, истек или отозван
+func (a *AuthService) AuthenticateAPIKey(ctx context.Context, secret string) (*model.APIKey, error) {
+	const op = "internal/auth/service.AuthenticateAPIKey"
+	log := a.logger.With("op: ", op)
+
+	if !strings.HasPrefix(secret, model.APIKeyPrefix) {
+		return nil, nil
+	}
+
+	key, err := a.repo.FindAPIKey(ctx, hashToken(secret))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := a.repo.TouchAPIKey(ctx, key.ID); err != nil {
+		log.Warn("Error updating api key usage", slog.Any("error", err))
+	}
+
+	return key, nil
+}
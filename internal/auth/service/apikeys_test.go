@@ -0,0 +1,162 @@
+package authservice
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/QuUteO/video-communication/internal/model"
+	uuid2 "github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+// keyRepo ключи API в памяти, по хешу
+type keyRepo struct {
+	*sessionRepo
+
+	keysMu sync.Mutex
+	keys   map[string]model.APIKey
+}
+
+func newKeyRepo() *keyRepo {
+	return &keyRepo{sessionRepo: newSessionRepo(), keys: make(map[string]model.APIKey)}
+}
+
+func (r *keyRepo) CreateAPIKey(_ context.Context, key *model.APIKey, _ time.Duration, maxActive int) (bool, error) {
+	r.keysMu.Lock()
+	defer r.keysMu.Unlock()
+	active := 0
+	for _, k := range r.keys {
+		if k.UserID == key.UserID {
+			active++
+		}
+	}
+	if active >= maxActive {
+		return false, nil
+	}
+	r.keys[key.KeyHash] = *key
+	return true, nil
+}
+
+func (r *keyRepo) FindAPIKey(_ context.Context, keyHash string) (*model.APIKey, error) {
+	r.keysMu.Lock()
+	defer r.keysMu.Unlock()
+	k, ok := r.keys[keyHash]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	return &k, nil
+}
+
+func (r *keyRepo) TouchAPIKey(context.Context, uuid2.UUID) error {
+	return nil
+}
+
+func (r *keyRepo) RevokeAPIKey(_ context.Context, userID, keyID string) (bool, error) {
+	r.keysMu.Lock()
+	defer r.keysMu.Unlock()
+	for hash, k := range r.keys {
+		if k.ID.String() == keyID && k.UserID.String() == userID {
+			delete(r.keys, hash)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func TestCreateAPIKeyValidatesRequest(t *testing.T) {
+	svc, _ := newTestService(t, newKeyRepo())
+	userID := uuid2.Must(uuid2.NewV4()).String()
+
+	tests := []struct {
+		name string
+		req  model.CreateAPIKeyRequest
+	}{
+		{"no name", model.CreateAPIKeyRequest{Name: "  ", Scopes: []string{"read"}}},
+		{"long name", model.CreateAPIKeyRequest{Name: strings.Repeat("я", apiKeyNameMaxLen+1), Scopes: []string{"read"}}},
+		{"no scopes", model.CreateAPIKeyRequest{Name: "ci"}},
+		{"unknown scope", model.CreateAPIKeyRequest{Name: "ci", Scopes: []string{"read", "admin"}}},
+		{"negative expiry", model.CreateAPIKeyRequest{Name: "ci", Scopes: []string{"read"}, ExpiresInDays: -1}},
+		{"long expiry", model.CreateAPIKeyRequest{Name: "ci", Scopes: []string{"read"}, ExpiresInDays: maxAPIKeyDays + 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := svc.CreateAPIKey(context.Background(), userID, &tt.req); !errors.Is(err, ErrInvalidAPIKey) {
+				t.Errorf("error = %v, want %v", err, ErrInvalidAPIKey)
+			}
+		})
+	}
+}
+
+func TestAPIKeyRoundTrip(t *testing.T) {
+	repo := newKeyRepo()
+	svc, closed := newTestService(t, repo)
+	userID := uuid2.Must(uuid2.NewV4()).String()
+
+	created, err := svc.CreateAPIKey(context.Background(), userID, &model.CreateAPIKeyRequest{
+		Name:   " ci ",
+		Scopes: []string{" READ ", "ws", "read"},
+	})
+	if err != nil {
+		t.Fatalf("CreateAPIKey: %v", err)
+	}
+	if !strings.HasPrefix(created.Key, model.APIKeyPrefix) || !strings.HasPrefix(created.Key, created.Prefix) {
+		t.Errorf("key = %q, prefix = %q", created.Key, created.Prefix)
+	}
+	if created.Name != "ci" || len(created.Scopes) != 2 {
+		t.Errorf("name = %q, scopes = %v", created.Name, created.Scopes)
+	}
+
+	// в хранилище только хеш
+	for hash := range repo.keys {
+		if hash == created.Key || strings.Contains(hash, created.Key[len(model.APIKeyPrefix):]) {
+			t.Error("raw key was stored")
+		}
+	}
+
+	key, err := svc.AuthenticateAPIKey(context.Background(), created.Key)
+	if err != nil || key == nil {
+		t.Fatalf("AuthenticateAPIKey: %v, %v", key, err)
+	}
+	if key.UserID.String() != userID {
+		t.Errorf("user = %s, want %s", key.UserID, userID)
+	}
+
+	for _, secret := range []string{created.Key + "x", model.APIKeyPrefix, "not-a-key"} {
+		if key, err := svc.AuthenticateAPIKey(context.Background(), secret); key != nil || err != nil {
+			t.Errorf("%q: key = %v, error = %v", secret, key, err)
+		}
+	}
+
+	// ключ отзывает только владелец; отзыв закрывает его соединения
+	if err := svc.RevokeAPIKey(context.Background(), uuid2.Must(uuid2.NewV4()).String(), created.ID.String()); !errors.Is(err, ErrAPIKeyNotFound) {
+		t.Errorf("foreign revoke: error = %v, want %v", err, ErrAPIKeyNotFound)
+	}
+	if err := svc.RevokeAPIKey(context.Background(), userID, created.ID.String()); err != nil {
+		t.Fatalf("RevokeAPIKey: %v", err)
+	}
+	if !closed.closed(created.ID.String()) {
+		t.Error("connections of revoked key were not closed")
+	}
+	if key, _ := svc.AuthenticateAPIKey(context.Background(), created.Key); key != nil {
+		t.Error("revoked key still authenticates")
+	}
+}
+
+func TestCreateAPIKeyLimit(t *testing.T) {
+	svc, _ := newTestService(t, newKeyRepo())
+	userID := uuid2.Must(uuid2.NewV4()).String()
+
+	req := &model.CreateAPIKeyRequest{Name: "ci", Scopes: []string{"read"}}
+	for i := 0; i < maxAPIKeys; i++ {
+		if _, err := svc.CreateAPIKey(context.Background(), userID, req); err != nil {
+			t.Fatalf("key %d: %v", i+1, err)
+		}
+	}
+	if _, err := svc.CreateAPIKey(context.Background(), userID, req); !errors.Is(err, ErrTooManyAPIKeys) {
+		t.Errorf("error = %v, want %v", err, ErrTooManyAPIKeys)
+	}
+}
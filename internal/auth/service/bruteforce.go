@@ -0,0 +1,121 @@
+package authservice
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// первые freeFailures неудач проходят без задержки, дальше пауза удваивается
+const freeFailures = 3
+
+var (
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	ErrTooManyAttempts    = errors.New("too many failed login attempts, try again later")
+	ErrUserNotFound       = errors.New("user not found")
+)
+
+// ThrottledError вход временно запрещен; текст общий для задержки и блокировки
+type ThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *ThrottledError) Error() string {
+	return ErrTooManyAttempts.Error()
+}
+
+func (e *ThrottledError) Is(target error) bool {
+	return target == ErrTooManyAttempts
+}
+
+// dummyHash сравнивается с паролем для несуществующих адресов, чтобы время ответа было одинаковым
+var dummyHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("not-a-real-password"), bcrypt.DefaultCost)
+	return hash
+})
+
+// Unlock снимает блокировку входа и сбрасывает счетчик неудач пользователя (для администраторов)
+func (a *AuthService) Unlock(ctx context.Context, userID string) error {
+	const op = "internal/auth/service.Unlock"
+	log := a.logger.With("op: ", op)
+
+	user, err := a.repo.FindByID(ctx, userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := a.repo.ClearLoginAttempts(ctx, loginKey(user.Email)); err != nil {
+		log.Error("Error clearing login attempts", slog.Any("error", err))
+		return err
+	}
+
+	log.Info("Login unlocked", slog.String("user_id", userID))
+	return nil
+}
+
+// checkThrottle отклоняет попытку до проверки пароля: IP исчерпал лимит неудач,
+// адрес заблокирован или еще не истекла прогрессивная задержка
+func (a *AuthService) checkThrottle(ctx context.Context, key, ip string) error {
+	if ip != "" && a.ipFailures.Exhausted(ip, a.loginCfg.IPFailuresPerMinute) {
+		return &ThrottledError{RetryAfter: time.Minute}
+	}
+
+	attempts, err := a.repo.GetLoginAttempts(ctx, key)
+	if err != nil {
+		return err
+	}
+	if attempts.LockedFor > 0 {
+		return &ThrottledError{RetryAfter: attempts.LockedFor}
+	}
+	if wait := a.delay(attempts.Failures) - attempts.SinceLastFailure; wait > 0 {
+		return &ThrottledError{RetryAfter: wait}
+	}
+
+	return nil
+}
+
+// recordFailure учитывает неудачу по адресу и IP; при достижении порога адрес блокируется
+func (a *AuthService) recordFailure(ctx context.Context, key, ip string) {
+	const op = "internal/auth/service.recordFailure"
+	log := a.logger.With("op: ", op)
+
+	if ip != "" {
+		a.ipFailures.Allow(ip, a.loginCfg.IPFailuresPerMinute)
+	}
+
+	failures, err := a.repo.RecordLoginFailure(ctx, key, a.loginCfg.Window)
+	if err != nil {
+		log.Error("Error recording login failure", slog.Any("error", err))
+		return
+	}
+	if a.loginCfg.MaxFailures > 0 && failures >= a.loginCfg.MaxFailures {
+		if err := a.repo.LockLogin(ctx, key, a.loginCfg.Lockout); err != nil {
+			log.Error("Error locking login", slog.Any("error", err))
+			return
+		}
+		log.Warn("Login locked after repeated failures", slog.String("ip", ip), slog.Int("failures", failures))
+	}
+}
+
+// delay пауза перед следующей попыткой после failures неудач подряд
+func (a *AuthService) delay(failures int) time.Duration {
+	if failures < freeFailures {
+		return 0
+	}
+
+	d := time.Second << min(failures-freeFailures, 16)
+	return min(d, a.loginCfg.MaxDelay)
+}
+
+func loginKey(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,256 @@
+package authservice
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/QuUteO/video-communication/internal/config"
+	"github.com/QuUteO/video-communication/internal/model"
+	"github.com/QuUteO/video-communication/pkg/ratelimit"
+	uuid2 "github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const testPassword = "correct horse battery"
+
+// loginRepo пользователи и счетчики неудачных входов в памяти
+type loginRepo struct {
+	*sessionRepo
+
+	users    map[string]*model.User // по email
+	attempts map[string]*loginState
+}
+
+type loginState struct {
+	failures    int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+func newLoginRepo(t *testing.T, emails ...string) *loginRepo {
+	t.Helper()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(testPassword), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := &loginRepo{
+		sessionRepo: newSessionRepo(),
+		users:       make(map[string]*model.User),
+		attempts:    make(map[string]*loginState),
+	}
+	for _, email := range emails {
+		r.users[email] = &model.User{Id: uuid2.Must(uuid2.NewV4()), Email: email, Password: string(hash)}
+	}
+	return r
+}
+
+func (r *loginRepo) FindByEmail(_ context.Context, email string) (*model.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u, ok := r.users[email]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	found := *u
+	return &found, nil
+}
+
+func (r *loginRepo) FindByID(_ context.Context, userID string) (*model.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, u := range r.users {
+		if u.Id.String() == userID {
+			found := *u
+			return &found, nil
+		}
+	}
+	return nil, pgx.ErrNoRows
+}
+
+func (r *loginRepo) GetTOTP(context.Context, uuid2.UUID) (*model.TOTP, error) {
+	return nil, pgx.ErrNoRows
+}
+
+func (r *loginRepo) GetLoginAttempts(_ context.Context, emailKey string) (*model.LoginAttempts, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, ok := r.attempts[emailKey]
+	if !ok {
+		return &model.LoginAttempts{}, nil
+	}
+	return &model.LoginAttempts{
+		Failures:         s.failures,
+		SinceLastFailure: time.Since(s.lastFailure),
+		LockedFor:        max(time.Until(s.lockedUntil), 0),
+	}, nil
+}
+
+func (r *loginRepo) RecordLoginFailure(_ context.Context, emailKey string, window time.Duration) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, ok := r.attempts[emailKey]
+	if !ok {
+		s = &loginState{}
+		r.attempts[emailKey] = s
+	}
+	if time.Since(s.lastFailure) > window {
+		s.failures = 0
+	}
+	s.failures++
+	s.lastFailure = time.Now()
+	return s.failures, nil
+}
+
+func (r *loginRepo) LockLogin(_ context.Context, emailKey string, lockout time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if s, ok := r.attempts[emailKey]; ok {
+		s.failures, s.lockedUntil = 0, time.Now().Add(lockout)
+	}
+	return nil
+}
+
+func (r *loginRepo) ClearLoginAttempts(_ context.Context, emailKey string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.attempts, emailKey)
+	return nil
+}
+
+func newLoginService(t *testing.T, cfg config.Login, emails ...string) (*AuthService, *loginRepo) {
+	t.Helper()
+
+	repo := newLoginRepo(t, emails...)
+	svc, _ := newTestService(t, repo)
+	svc.loginCfg = &cfg
+	svc.ipFailures = ratelimit.New()
+	return svc, repo
+}
+
+func login(svc *AuthService, email, password, ip string) error {
+	_, err := svc.Login(context.Background(), &model.LoginRequest{Email: email, Password: password}, model.DeviceInfo{IP: ip})
+	return err
+}
+
+func TestLoginLocksOutAfterMaxFailures(t *testing.T) {
+	svc, _ := newLoginService(t, config.Login{MaxFailures: 3, Window: time.Hour, Lockout: time.Hour}, "a@example.com")
+
+	for i := 0; i < 3; i++ {
+		if err := login(svc, "a@example.com", "wrong", ""); !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("attempt %d: error = %v, want %v", i+1, err, ErrInvalidCredentials)
+		}
+	}
+
+	// во время блокировки не проходит и верный пароль, в том числе в другом регистре адреса
+	for _, email := range []string{"a@example.com", " A@Example.com"} {
+		err := login(svc, email, testPassword, "")
+		var throttled *ThrottledError
+		if !errors.As(err, &throttled) || !errors.Is(err, ErrTooManyAttempts) {
+			t.Fatalf("%q: error = %v, want %v", email, err, ErrTooManyAttempts)
+		}
+		if throttled.RetryAfter <= 0 || throttled.RetryAfter > time.Hour {
+			t.Errorf("RetryAfter = %v", throttled.RetryAfter)
+		}
+	}
+}
+
+func TestLoginUnknownEmailLooksLikeWrongPassword(t *testing.T) {
+	svc, repo := newLoginService(t, config.Login{MaxFailures: 3, Window: time.Hour, Lockout: time.Hour}, "a@example.com")
+
+	for i := 0; i < 3; i++ {
+		if err := login(svc, "nobody@example.com", testPassword, ""); !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("attempt %d: error = %v, want %v", i+1, err, ErrInvalidCredentials)
+		}
+	}
+
+	// несуществующий адрес блокируется так же, иначе блокировка выдала бы, что адрес свободен
+	if err := login(svc, "nobody@example.com", testPassword, ""); !errors.Is(err, ErrTooManyAttempts) {
+		t.Errorf("error = %v, want %v", err, ErrTooManyAttempts)
+	}
+	if _, ok := repo.attempts["a@example.com"]; ok {
+		t.Error("failures of another address were counted")
+	}
+}
+
+func TestLoginSuccessClearsFailures(t *testing.T) {
+	svc, repo := newLoginService(t, config.Login{MaxFailures: 3, Window: time.Hour, Lockout: time.Hour}, "a@example.com")
+
+	for i := 0; i < 2; i++ {
+		_ = login(svc, "a@example.com", "wrong", "")
+	}
+	if err := login(svc, "a@example.com", testPassword, ""); err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if _, ok := repo.attempts["a@example.com"]; ok {
+		t.Error("failures were not cleared after successful login")
+	}
+}
+
+func TestLoginProgressiveDelay(t *testing.T) {
+	svc, repo := newLoginService(t, config.Login{MaxFailures: 100, Window: time.Hour, Lockout: time.Hour, MaxDelay: time.Minute}, "a@example.com")
+
+	for i := 0; i < freeFailures; i++ {
+		if err := login(svc, "a@example.com", "wrong", ""); !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("attempt %d: error = %v, want %v", i+1, err, ErrInvalidCredentials)
+		}
+	}
+
+	// сразу после freeFailures неудач нужна пауза, даже с верным паролем
+	if err := login(svc, "a@example.com", testPassword, ""); !errors.Is(err, ErrTooManyAttempts) {
+		t.Fatalf("error = %v, want %v", err, ErrTooManyAttempts)
+	}
+
+	repo.attempts["a@example.com"].lastFailure = time.Now().Add(-svc.delay(freeFailures))
+	if err := login(svc, "a@example.com", testPassword, ""); err != nil {
+		t.Errorf("after delay: %v", err)
+	}
+
+	if d := svc.delay(freeFailures + 20); d != time.Minute {
+		t.Errorf("delay is not capped: %v", d)
+	}
+}
+
+func TestLoginLimitsFailuresPerIP(t *testing.T) {
+	emails := []string{"a@example.com", "b@example.com", "c@example.com"}
+	svc, _ := newLoginService(t, config.Login{MaxFailures: 100, Window: time.Hour, Lockout: time.Hour, IPFailuresPerMinute: 2}, emails...)
+
+	// перебор по разным адресам с одного IP
+	for _, email := range emails[:2] {
+		if err := login(svc, email, "wrong", "203.0.113.1"); !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("%s: error = %v, want %v", email, err, ErrInvalidCredentials)
+		}
+	}
+
+	if err := login(svc, emails[2], testPassword, "203.0.113.1"); !errors.Is(err, ErrTooManyAttempts) {
+		t.Errorf("same ip: error = %v, want %v", err, ErrTooManyAttempts)
+	}
+	if err := login(svc, emails[2], testPassword, "203.0.113.2"); err != nil {
+		t.Errorf("other ip: %v", err)
+	}
+}
+
+func TestUnlockClearsLockout(t *testing.T) {
+	svc, repo := newLoginService(t, config.Login{MaxFailures: 2, Window: time.Hour, Lockout: time.Hour}, "a@example.com")
+
+	for i := 0; i < 2; i++ {
+		_ = login(svc, "a@example.com", "wrong", "")
+	}
+	if err := login(svc, "a@example.com", testPassword, ""); !errors.Is(err, ErrTooManyAttempts) {
+		t.Fatalf("error = %v, want %v", err, ErrTooManyAttempts)
+	}
+
+	if err := svc.Unlock(context.Background(), repo.users["a@example.com"].Id.String()); err != nil {
+		t.Fatal(err)
+	}
+	if err := login(svc, "a@example.com", testPassword, ""); err != nil {
+		t.Errorf("after unlock: %v", err)
+	}
+	if err := svc.Unlock(context.Background(), uuid2.Must(uuid2.NewV4()).String()); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("unknown user: error = %v, want %v", err, ErrUserNotFound)
+	}
+}
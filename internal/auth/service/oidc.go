@@ -0,0 +1,179 @@
+package authservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	authoidc "github.com/QuUteO/video-communication/internal/auth/oidc"
+	"github.com/QuUteO/video-communication/internal/model"
+	uuid2 "github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+var (
+	ErrOIDCDisabled         = errors.New("sso login is not configured")
+	ErrInvalidOIDCState     = errors.New("invalid or expired sso login state")
+	ErrOIDCDenied           = errors.New("sso login was cancelled or denied")
+	ErrOIDCEmailNotVerified = errors.New("identity provider did not return a verified email")
+	// локальная учетная запись с этим адресом не подтверждена: привязка отдала бы ее тому,
+	// кто зарегистрировал чужой адрес с известным ему паролем
+	ErrOIDCAccountConflict = errors.New("an account with this email exists but its email is not verified; log in with password and verify it first")
+)
+
+// OIDCLogin начинает вход через SSO: адрес провайдера и state для привязки к браузеру
+func (a *AuthService) OIDCLogin(ctx context.Context) (string, string, error) {
+	const op = "internal/auth/service.OIDCLogin"
+	log := a.logger.With("op: ", op)
+
+	if a.oidc == nil {
+		return "", "", ErrOIDCDisabled
+	}
+
+	state, err := authoidc.NewState()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := authoidc.NewState()
+	if err != nil {
+		return "", "", err
+	}
+	verifier, err := authoidc.NewVerifier()
+	if err != nil {
+		return "", "", err
+	}
+
+	authURL, err := a.oidc.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		log.Error("Error building authorization url", slog.Any("error", err))
+		return "", "", err
+	}
+
+	if err := a.repo.CreateOIDCState(ctx, &model.OIDCState{
+		StateHash:    hashToken(state),
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+	}, a.oidcCfg.StateTTL); err != nil {
+		return "", "", err
+	}
+
+	return authURL, state, nil
+}
+
+// OIDCCallback завершает вход через SSO: обмен кода, привязка или создание пользователя, выдача наших токенов
+func (a *AuthService) OIDCCallback(ctx context.Context, req *model.OIDCCallback, device model.DeviceInfo) (*model.AuthResponse, error) {
+	const op = "internal/auth/service.OIDCCallback"
+	log := a.logger.With("op: ", op)
+
+	if a.oidc == nil {
+		return nil, ErrOIDCDisabled
+	}
+	if req.State == "" {
+		return nil, ErrInvalidOIDCState
+	}
+
+	// state одноразовый и в случае ошибки провайдера тоже
+	state, err := a.repo.ConsumeOIDCState(ctx, hashToken(req.State))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrInvalidOIDCState
+	}
+	if err != nil {
+		log.Error("Error consuming oidc state", slog.Any("error", err))
+		return nil, err
+	}
+
+	if req.Error != "" {
+		log.Info("Identity provider returned error", slog.String("error", req.Error),
+			slog.String("description", req.ErrorDescription))
+		return nil, ErrOIDCDenied
+	}
+	if req.Code == "" {
+		return nil, ErrInvalidOIDCState
+	}
+
+	identity, err := a.oidc.Exchange(ctx, req.Code, state.CodeVerifier, state.Nonce)
+	if err != nil {
+		log.Warn("Error exchanging authorization code", slog.Any("error", err))
+		return nil, err
+	}
+
+	userID, err := a.oidcUser(ctx, identity)
+	if err != nil {
+		return nil, err
+	}
+
+	// SSO заменяет пароль, но не локальный второй фактор
+	enabled, err := a.twoFactorEnabled(ctx, userID)
+	if err != nil {
+		log.Error("Error checking two-factor", slog.Any("error", err))
+		return nil, err
+	}
+	if enabled {
+		return a.challenge(ctx, userID)
+	}
+
+	return a.startSession(ctx, userID, device, false)
+}
+
+// oidcUser находит привязанного пользователя, привязывает существующего по подтвержденному адресу или создает нового
+func (a *AuthService) oidcUser(ctx context.Context, identity *authoidc.Identity) (uuid2.UUID, error) {
+	const op = "internal/auth/service.oidcUser"
+	log := a.logger.With("op: ", op)
+
+	userID, err := a.repo.FindOIDCIdentity(ctx, identity.Issuer, identity.Subject)
+	if err != nil {
+		log.Error("Error finding oidc identity", slog.Any("error", err))
+		return uuid2.Nil, err
+	}
+	if userID != uuid2.Nil {
+		return userID, nil
+	}
+
+	email := strings.TrimSpace(identity.Email)
+	if email == "" || !identity.EmailVerified {
+		return uuid2.Nil, ErrOIDCEmailNotVerified
+	}
+
+	user, err := a.repo.FindUserForLink(ctx, email)
+	switch {
+	case err == nil:
+		if user.EmailVerifiedAt == nil {
+			return uuid2.Nil, ErrOIDCAccountConflict
+		}
+		userID = user.Id
+	case errors.Is(err, pgx.ErrNoRows):
+		username := identity.Username
+		if !usernameRe.MatchString(username) {
+			username = ""
+		}
+		if userID, err = a.repo.CreateOIDCUser(ctx, email, username); err != nil {
+			return uuid2.Nil, err
+		}
+		if userID == uuid2.Nil {
+			// адрес занял параллельный вход — повторяем поиск
+			return a.oidcUser(ctx, identity)
+		}
+		log.Info("Provisioned user from sso", slog.String("user_id", userID.String()),
+			slog.String("issuer", identity.Issuer))
+	default:
+		log.Error("Error finding user by email", slog.Any("error", err))
+		return uuid2.Nil, err
+	}
+
+	if err := a.repo.LinkOIDCIdentity(ctx, identity.Issuer, identity.Subject, userID, email); err != nil {
+		return uuid2.Nil, err
+	}
+
+	// при гонке привязки побеждает первая запись
+	linked, err := a.repo.FindOIDCIdentity(ctx, identity.Issuer, identity.Subject)
+	if err != nil {
+		return uuid2.Nil, err
+	}
+	if linked == uuid2.Nil {
+		return uuid2.Nil, fmt.Errorf("oidc identity %s was not linked", identity.Subject)
+	}
+
+	return linked, nil
+}
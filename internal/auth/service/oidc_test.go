@@ -0,0 +1,142 @@
+package authservice
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	authoidc "github.com/QuUteO/video-communication/internal/auth/oidc"
+	"github.com/QuUteO/video-communication/internal/config"
+	"github.com/QuUteO/video-communication/internal/model"
+	"github.com/jackc/pgx/v4"
+)
+
+// oidcRepo незавершенные входы через SSO в памяти
+type oidcRepo struct {
+	*sessionRepo
+
+	statesMu sync.Mutex
+	states   map[string]model.OIDCState
+}
+
+func (r *oidcRepo) CreateOIDCState(_ context.Context, state *model.OIDCState, _ time.Duration) error {
+	r.statesMu.Lock()
+	defer r.statesMu.Unlock()
+	r.states[state.StateHash] = *state
+	return nil
+}
+
+func (r *oidcRepo) ConsumeOIDCState(_ context.Context, stateHash string) (*model.OIDCState, error) {
+	r.statesMu.Lock()
+	defer r.statesMu.Unlock()
+	s, ok := r.states[stateHash]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	delete(r.states, stateHash)
+	return &s, nil
+}
+
+// newOIDCService сервис с провайдером, у которого есть только discovery: до обмена кода тесты не доходят
+func newOIDCService(t *testing.T) (*AuthService, *oidcRepo) {
+	t.Helper()
+
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 srv.URL,
+			"authorization_endpoint": srv.URL + "/authorize",
+			"token_endpoint":         srv.URL + "/token",
+			"jwks_uri":               srv.URL + "/jwks",
+		})
+	}))
+	t.Cleanup(srv.Close)
+
+	repo := &oidcRepo{sessionRepo: newSessionRepo(), states: make(map[string]model.OIDCState)}
+	svc, _ := newTestService(t, repo)
+	svc.oidcCfg = &config.OIDC{Issuer: srv.URL, ClientID: "client", StateTTL: time.Minute, Timeout: 5 * time.Second}
+	svc.oidc = authoidc.NewProvider(svc.oidcCfg)
+	return svc, repo
+}
+
+func TestOIDCLoginStoresStateHash(t *testing.T) {
+	svc, repo := newOIDCService(t)
+
+	authURL, state, err := svc.OIDCLogin(context.Background())
+	if err != nil {
+		t.Fatalf("OIDCLogin: %v", err)
+	}
+
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := u.Query().Get("state"); got != state {
+		t.Errorf("state in url = %q, want %q", got, state)
+	}
+
+	stored, ok := repo.states[hashToken(state)]
+	if !ok {
+		t.Fatal("state hash was not stored")
+	}
+	if _, ok := repo.states[state]; ok {
+		t.Error("raw state was stored")
+	}
+	if stored.Nonce == "" || stored.Nonce != u.Query().Get("nonce") {
+		t.Errorf("stored nonce = %q, url nonce = %q", stored.Nonce, u.Query().Get("nonce"))
+	}
+	if stored.CodeVerifier == "" || u.Query().Has("code_verifier") {
+		t.Error("code verifier must be kept server-side only")
+	}
+}
+
+func TestOIDCCallbackRejectsUnknownState(t *testing.T) {
+	svc, _ := newOIDCService(t)
+
+	for _, state := range []string{"", "forged"} {
+		_, err := svc.OIDCCallback(context.Background(), &model.OIDCCallback{Code: "code", State: state}, model.DeviceInfo{})
+		if !errors.Is(err, ErrInvalidOIDCState) {
+			t.Errorf("state %q: error = %v, want %v", state, err, ErrInvalidOIDCState)
+		}
+	}
+}
+
+func TestOIDCCallbackConsumesStateOnProviderError(t *testing.T) {
+	svc, repo := newOIDCService(t)
+
+	_, state, err := svc.OIDCLogin(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = svc.OIDCCallback(context.Background(), &model.OIDCCallback{State: state, Error: "access_denied"}, model.DeviceInfo{})
+	if !errors.Is(err, ErrOIDCDenied) {
+		t.Fatalf("error = %v, want %v", err, ErrOIDCDenied)
+	}
+	if len(repo.states) != 0 {
+		t.Error("state was not consumed")
+	}
+
+	// повтор с тем же state уже не принимается
+	_, err = svc.OIDCCallback(context.Background(), &model.OIDCCallback{Code: "code", State: state}, model.DeviceInfo{})
+	if !errors.Is(err, ErrInvalidOIDCState) {
+		t.Errorf("replay: error = %v, want %v", err, ErrInvalidOIDCState)
+	}
+}
+
+func TestOIDCDisabled(t *testing.T) {
+	svc, _ := newTestService(t, newSessionRepo())
+
+	if _, _, err := svc.OIDCLogin(context.Background()); !errors.Is(err, ErrOIDCDisabled) {
+		t.Errorf("OIDCLogin: error = %v, want %v", err, ErrOIDCDisabled)
+	}
+	if _, err := svc.OIDCCallback(context.Background(), &model.OIDCCallback{State: "s"}, model.DeviceInfo{}); !errors.Is(err, ErrOIDCDisabled) {
+		t.Errorf("OIDCCallback: error = %v, want %v", err, ErrOIDCDisabled)
+	}
+}
@@ -0,0 +1,221 @@
+package authservice
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/QuUteO/video-communication/internal/model"
+	"github.com/QuUteO/video-communication/pkg/mail"
+	uuid2 "github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// повторное письмо того же вида не раньше чем через resendCooldown
+	resendCooldown = time.Minute
+	mailTimeout    = 30 * time.Second
+	minPassword    = 8
+	maxPassword    = 72 // предел bcrypt
+
+	RevokePasswordReset = "password_reset"
+)
+
+var (
+	ErrInvalidToken         = errors.New("invalid or expired token")
+	ErrEmailAlreadyVerified = errors.New("email is already verified")
+	ErrWeakPassword         = errors.New("password must be 8-72 characters")
+)
+
+// RequestEmailVerification отправляет письмо для подтверждения адреса текущего пользователя
+func (a *AuthService) RequestEmailVerification(ctx context.Context, userID string) error {
+	const op = "internal/auth/service.RequestEmailVerification"
+	log := a.logger.With("op: ", op)
+
+	user, err := a.repo.FindByID(ctx, userID)
+	if err != nil {
+		log.Error("Error finding user", slog.Any("error", err))
+		return err
+	}
+	if user.EmailVerifiedAt != nil {
+		return ErrEmailAlreadyVerified
+	}
+
+	return a.sendToken(ctx, user, model.TokenVerifyEmail)
+}
+
+func (a *AuthService) VerifyEmail(ctx context.Context, req *model.VerifyEmailRequest) error {
+	const op = "internal/auth/service.VerifyEmail"
+	log := a.logger.With("op: ", op)
+
+	token, err := a.consume(ctx, model.TokenVerifyEmail, req.Token)
+	if err != nil {
+		return err
+	}
+
+	ok, err := a.repo.MarkEmailVerified(ctx, token.UserID, token.Email)
+	if err != nil {
+		log.Error("Error verifying email", slog.Any("error", err))
+		return err
+	}
+	// адрес сменили после отправки письма — подтверждать нечего
+	if !ok {
+		return ErrInvalidToken
+	}
+
+	return nil
+}
+
+// RequestPasswordReset всегда успешен для клиента, чтобы по ответу нельзя было узнать, есть ли такой адрес
+func (a *AuthService) RequestPasswordReset(ctx context.Context, req *model.PasswordResetRequest) error {
+	const op = "internal/auth/service.RequestPasswordReset"
+	log := a.logger.With("op: ", op)
+
+	user, err := a.repo.FindByEmail(ctx, strings.TrimSpace(req.Email))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		log.Error("Error finding user", slog.Any("error", err))
+		return err
+	}
+
+	return a.sendToken(ctx, user, model.TokenResetPassword)
+}
+
+// ResetPassword меняет пароль по токену из письма и завершает все сессии пользователя
+func (a *AuthService) ResetPassword(ctx context.Context, req *model.PasswordResetConfirmRequest) error {
+	const op = "internal/auth/service.ResetPassword"
+	log := a.logger.With("op: ", op)
+
+	if n := utf8.RuneCountInString(req.Password); n < minPassword || len(req.Password) > maxPassword {
+		return ErrWeakPassword
+	}
+
+	token, err := a.consume(ctx, model.TokenResetPassword, req.Token)
+	if err != nil {
+		return err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		log.Error("Error hashing password", slog.Any("error", err))
+		return err
+	}
+	if err := a.repo.SetPassword(ctx, token.UserID, string(hash)); err != nil {
+		return err
+	}
+
+	if err := a.repo.ExpireAuthTokens(ctx, token.UserID, model.TokenResetPassword); err != nil {
+		log.Error("Error expiring reset tokens", slog.Any("error", err))
+	}
+
+	// письмо пришло на этот адрес — значит, он подтвержден
+	if _, err := a.repo.MarkEmailVerified(ctx, token.UserID, token.Email); err != nil {
+		log.Error("Error verifying email", slog.Any("error", err))
+	}
+
+	sessions, err := a.repo.RevokeAllSessions(ctx, token.UserID, uuid2.Nil, RevokePasswordReset)
+	if err != nil {
+		log.Error("Error revoking sessions", slog.Any("error", err))
+		return err
+	}
+	if a.closer != nil {
+		for _, id := range sessions {
+			a.closer.CloseSession(id)
+		}
+	}
+
+	return nil
+}
+
+func (a *AuthService) consume(ctx context.Context, purpose, raw string) (*model.AuthToken, error) {
+	if raw == "" {
+		return nil, ErrInvalidToken
+	}
+
+	token, err := a.repo.ConsumeAuthToken(ctx, purpose, hashToken(raw))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrInvalidToken
+	}
+	return token, err
+}
+
+// sendToken выдает одноразовый токен и отправляет письмо в фоне:
+// время ответа не должно зависеть от почтового сервера и от того, существует ли адрес
+func (a *AuthService) sendToken(ctx context.Context, user *model.User, purpose string) error {
+	const op = "internal/auth/service.sendToken"
+	log := a.logger.With("op: ", op)
+
+	recent, err := a.repo.RecentAuthToken(ctx, user.Id, purpose, resendCooldown)
+	if err != nil {
+		log.Error("Error checking recent tokens", slog.Any("error", err))
+		return err
+	}
+	if recent {
+		return nil
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return err
+	}
+	secret := hex.EncodeToString(raw)
+
+	ttl, path := a.mailCfg.VerifyTTL, "/verify-email"
+	if purpose == model.TokenResetPassword {
+		ttl, path = a.mailCfg.ResetTTL, "/reset-password"
+	}
+
+	token := &model.AuthToken{
+		ID:        uuid2.Must(uuid2.NewV4()),
+		UserID:    user.Id,
+		Purpose:   purpose,
+		Email:     user.Email,
+		TokenHash: hashToken(secret),
+	}
+	if err := a.repo.CreateAuthToken(ctx, token, ttl); err != nil {
+		return err
+	}
+
+	link := strings.TrimRight(a.mailCfg.LinkBaseURL, "/") + path + "?token=" + url.QueryEscape(secret)
+	msg := mail.Message{To: user.Email}
+	if purpose == model.TokenResetPassword {
+		msg.Subject = "Сброс пароля"
+		msg.Body = "Чтобы задать новый пароль, перейдите по ссылке:\n\n" + link +
+			"\n\nСсылка одноразовая и действует " + ttlText(ttl) + ". " +
+			"Если вы не запрашивали сброс, просто проигнорируйте это письмо — пароль останется прежним.\n"
+	} else {
+		msg.Subject = "Подтвердите адрес электронной почты"
+		msg.Body = "Чтобы подтвердить адрес, перейдите по ссылке:\n\n" + link +
+			"\n\nСсылка действует " + ttlText(ttl) + ". " +
+			"Если вы не регистрировались, просто проигнорируйте это письмо.\n"
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), mailTimeout)
+		defer cancel()
+
+		if err := a.mailer.Send(ctx, msg); err != nil {
+			log.Error("Error sending mail", slog.String("purpose", purpose), slog.Any("error", err))
+		}
+	}()
+
+	return nil
+}
+
+// ttlText срок действия ссылки для текста письма
+func ttlText(d time.Duration) string {
+	if d >= time.Hour && d%time.Hour == 0 {
+		return strconv.Itoa(int(d/time.Hour)) + " ч."
+	}
+	return strconv.Itoa(int(d.Round(time.Minute)/time.Minute)) + " мин."
+}
@@ -0,0 +1,65 @@
+package authservice
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	authpolicy "github.com/QuUteO/video-communication/internal/auth/policy"
+	uuid2 "github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+var (
+	ErrInvalidRole = errors.New("role must be user or admin")
+	ErrLastAdmin   = errors.New("cannot demote the last admin")
+)
+
+// UserRole роль для проверки прав; неизвестный пользователь не получает никакой роли
+func (a *AuthService) UserRole(ctx context.Context, userID string) (string, error) {
+	if _, err := uuid2.FromString(userID); err != nil {
+		return "", nil
+	}
+
+	role, err := a.repo.UserRole(ctx, userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+
+	return role, err
+}
+
+// SetRole назначает глобальную роль пользователю
+func (a *AuthService) SetRole(ctx context.Context, userID, role string) error {
+	const op = "internal/auth/service.SetRole"
+	log := a.logger.With("op: ", op)
+
+	if !authpolicy.ValidRole(role) {
+		return ErrInvalidRole
+	}
+
+	id, err := uuid2.FromString(userID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+
+	current, err := a.UserRole(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if current == "" {
+		return ErrUserNotFound
+	}
+
+	ok, err := a.repo.SetRole(ctx, id, role)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrLastAdmin
+	}
+
+	log.Info("User role changed", slog.String("user_id", userID), slog.String("from", current),
+		slog.String("to", role))
+	return nil
+}
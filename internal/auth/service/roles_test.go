@@ -0,0 +1,111 @@
+package authservice
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	authpolicy "github.com/QuUteO/video-communication/internal/auth/policy"
+	uuid2 "github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+// roleRepo роли пользователей в памяти с той же защитой последнего администратора, что и в SQL
+type roleRepo struct {
+	*sessionRepo
+
+	rolesMu sync.Mutex
+	roles   map[string]string
+}
+
+func (r *roleRepo) UserRole(_ context.Context, userID string) (string, error) {
+	r.rolesMu.Lock()
+	defer r.rolesMu.Unlock()
+	role, ok := r.roles[userID]
+	if !ok {
+		return "", pgx.ErrNoRows
+	}
+	return role, nil
+}
+
+func (r *roleRepo) SetRole(_ context.Context, userID uuid2.UUID, role string) (bool, error) {
+	r.rolesMu.Lock()
+	defer r.rolesMu.Unlock()
+
+	current, ok := r.roles[userID.String()]
+	if !ok {
+		return false, nil
+	}
+	admins := 0
+	for _, rl := range r.roles {
+		if rl == authpolicy.RoleAdmin {
+			admins++
+		}
+	}
+	if role != authpolicy.RoleAdmin && current == authpolicy.RoleAdmin && admins <= 1 {
+		return false, nil
+	}
+	r.roles[userID.String()] = role
+	return true, nil
+}
+
+func newRoleService(t *testing.T, roles ...string) (*AuthService, *roleRepo, []string) {
+	t.Helper()
+
+	repo := &roleRepo{sessionRepo: newSessionRepo(), roles: make(map[string]string)}
+	ids := make([]string, 0, len(roles))
+	for _, role := range roles {
+		id := uuid2.Must(uuid2.NewV4()).String()
+		repo.roles[id] = role
+		ids = append(ids, id)
+	}
+	svc, _ := newTestService(t, repo)
+	return svc, repo, ids
+}
+
+func TestSetRoleKeepsLastAdmin(t *testing.T) {
+	svc, repo, ids := newRoleService(t, authpolicy.RoleAdmin, authpolicy.RoleUser)
+	admin, user := ids[0], ids[1]
+
+	if err := svc.SetRole(context.Background(), admin, authpolicy.RoleUser); !errors.Is(err, ErrLastAdmin) {
+		t.Fatalf("demote last admin: error = %v, want %v", err, ErrLastAdmin)
+	}
+
+	if err := svc.SetRole(context.Background(), user, authpolicy.RoleAdmin); err != nil {
+		t.Fatalf("promote: %v", err)
+	}
+	// второй администратор есть — первого можно разжаловать, но не обоих
+	if err := svc.SetRole(context.Background(), admin, authpolicy.RoleUser); err != nil {
+		t.Fatalf("demote: %v", err)
+	}
+	if err := svc.SetRole(context.Background(), user, authpolicy.RoleUser); !errors.Is(err, ErrLastAdmin) {
+		t.Errorf("demote new last admin: error = %v, want %v", err, ErrLastAdmin)
+	}
+	if repo.roles[user] != authpolicy.RoleAdmin {
+		t.Errorf("last admin role = %q", repo.roles[user])
+	}
+}
+
+func TestSetRoleValidates(t *testing.T) {
+	svc, _, ids := newRoleService(t, authpolicy.RoleAdmin)
+
+	if err := svc.SetRole(context.Background(), ids[0], "root"); !errors.Is(err, ErrInvalidRole) {
+		t.Errorf("invalid role: error = %v, want %v", err, ErrInvalidRole)
+	}
+	for _, id := range []string{"not-a-uuid", uuid2.Must(uuid2.NewV4()).String()} {
+		if err := svc.SetRole(context.Background(), id, authpolicy.RoleAdmin); !errors.Is(err, ErrUserNotFound) {
+			t.Errorf("user %q: error = %v, want %v", id, err, ErrUserNotFound)
+		}
+	}
+}
+
+func TestUserRoleOfUnknownUser(t *testing.T) {
+	svc, _, _ := newRoleService(t)
+
+	for _, id := range []string{"", "not-a-uuid", uuid2.Must(uuid2.NewV4()).String()} {
+		if role, err := svc.UserRole(context.Background(), id); role != "" || err != nil {
+			t.Errorf("%q: role = %q, error = %v", id, role, err)
+		}
+	}
+}
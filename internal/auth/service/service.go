@@ -2,75 +2,375 @@ package authservice
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"log/slog"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/QuUteO/video-communication/internal/auth/jwt"
+	authoidc "github.com/QuUteO/video-communication/internal/auth/oidc"
 	"github.com/QuUteO/video-communication/internal/auth/repository"
+	"github.com/QuUteO/video-communication/internal/config"
 	"github.com/QuUteO/video-communication/internal/model"
+	"github.com/QuUteO/video-communication/pkg/mail"
+	"github.com/QuUteO/video-communication/pkg/ratelimit"
 	uuid2 "github.com/gofrs/uuid"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// причины отзыва сессии
+const (
+	RevokeLogout = "logout"
+	RevokeReuse  = "refresh_reuse"
+	RevokeUser   = "revoked_by_user"
+	// адрес или пароль сменили через PUT /users/{id}
+	RevokeCredentials = "credentials_changed"
+)
+
+var (
+	ErrInvalidUsername     = errors.New("username must be 2-32 characters: letters, digits, '_', '.', '-'")
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrSessionNotFound     = errors.New("session not found")
+)
+
+var usernameRe = regexp.MustCompile(`^[A-Za-z0-9_.-]{2,32}$`)
+
 type Service interface {
-	Register(ctx context.Context, req *model.AuthRequest) (string, error)
-	Login(ctx context.Context, req *model.LoginRequest) (string, error)
+	Register(ctx context.Context, req *model.AuthRequest, device model.DeviceInfo) (*model.AuthResponse, error)
+	Login(ctx context.Context, req *model.LoginRequest, device model.DeviceInfo) (*model.AuthResponse, error)
+	// Refresh обменивает refresh-токен на новую пару; повторное предъявление отзывает всю сессию
+	Refresh(ctx context.Context, req *model.RefreshRequest, device model.DeviceInfo) (*model.AuthResponse, error)
+	Logout(ctx context.Context, sessionID string) error
+	SessionActive(ctx context.Context, sessionID string) (bool, error)
+
+	ListSessions(ctx context.Context, userID, currentSessionID string) ([]model.AuthSession, error)
+	RevokeSession(ctx context.Context, userID, sessionID string) error
+	RevokeOtherSessions(ctx context.Context, userID, keepSessionID string) error
+
+	RequestEmailVerification(ctx context.Context, userID string) error
+	VerifyEmail(ctx context.Context, req *model.VerifyEmailRequest) error
+	RequestPasswordReset(ctx context.Context, req *model.PasswordResetRequest) error
+	ResetPassword(ctx context.Context, req *model.PasswordResetConfirmRequest) error
+
+	// LoginTwoFactor второй шаг входа: challenge из Login + код TOTP или код восстановления
+	LoginTwoFactor(ctx context.Context, req *model.TwoFactorLoginRequest, device model.DeviceInfo) (*model.AuthResponse, error)
+	EnrollTOTP(ctx context.Context, userID string) (*model.TOTPEnrollment, error)
+	ConfirmTOTP(ctx context.Context, userID string, req *model.TOTPCodeRequest) (*model.RecoveryCodesResponse, error)
+	DisableTOTP(ctx context.Context, userID string, req *model.TOTPCodeRequest) error
+	RegenerateRecoveryCodes(ctx context.Context, userID string, req *model.TOTPCodeRequest) (*model.RecoveryCodesResponse, error)
+
+	Unlock(ctx context.Context, userID string) error
+
+	// OIDCLogin адрес входа у провайдера и state, который клиент должен вернуть
+	OIDCLogin(ctx context.Context) (string, string, error)
+	OIDCCallback(ctx context.Context, req *model.OIDCCallback, device model.DeviceInfo) (*model.AuthResponse, error)
+
+	CreateAPIKey(ctx context.Context, userID string, req *model.CreateAPIKeyRequest) (*model.CreateAPIKeyResponse, error)
+	ListAPIKeys(ctx context.Context, userID string) ([]model.APIKey, error)
+	RevokeAPIKey(ctx context.Context, userID, keyID string) error
+	AuthenticateAPIKey(ctx context.Context, secret string) (*model.APIKey, error)
+
+	UserRole(ctx context.Context, userID string) (string, error)
+	SetRole(ctx context.Context, userID, role string) error
+}
+
+// SessionCloser закрывает живые соединения отозванной сессии (websocket.Hub)
+type SessionCloser interface {
+	CloseSession(sessionID string)
 }
 
 type AuthService struct {
-	repo   authrepository.Repository
-	jwt    *authjwt.Manager
-	logger *slog.Logger
+	repo       authrepository.Repository
+	jwt        *authjwt.Manager
+	refreshTTL time.Duration
+	closer     SessionCloser
+	mailer     mail.Sender
+	mailCfg    *config.Mail
+	twoFactor  *config.TwoFactor
+	totpKey    [32]byte // ключ шифрования TOTP-секретов
+	loginCfg   *config.Login
+	ipFailures *ratelimit.Limiter // неудачные входы по IP
+	oidc       *authoidc.Provider // nil — вход через SSO выключен
+	oidcCfg    *config.OIDC
+	logger     *slog.Logger
 }
 
-func (a *AuthService) Register(ctx context.Context, req *model.AuthRequest) (string, error) {
+func (a *AuthService) Register(ctx context.Context, req *model.AuthRequest, device model.DeviceInfo) (*model.AuthResponse, error) {
 	const op = "internal/auth/service.Create"
 	log := a.logger.With("op: ", op)
 
+	if req.Username != "" && !usernameRe.MatchString(req.Username) {
+		return nil, ErrInvalidUsername
+	}
+
 	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
 		log.Error("Error hashing password", slog.Any("error", err))
-		return "", err
+		return nil, err
 	}
 
 	user := &model.User{
 		Id:        uuid2.UUID(uuid.New()),
 		Email:     req.Email,
+		Username:  req.Username,
 		Password:  string(hash),
 		CreatedAt: time.Now(),
 	}
 
 	if err := a.repo.Register(ctx, user); err != nil {
 		log.Error("Error registering user", slog.Any("error", err))
-		return "", err
+		return nil, err
 	}
 
-	return a.jwt.Generate(user.Id.String())
+	if err := a.sendToken(ctx, user, model.TokenVerifyEmail); err != nil {
+		log.Error("Error sending verification email", slog.Any("error", err))
+	}
+
+	return a.startSession(ctx, user.Id, device, false)
 }
 
-func (a *AuthService) Login(ctx context.Context, req *model.LoginRequest) (string, error) {
+func (a *AuthService) Login(ctx context.Context, req *model.LoginRequest, device model.DeviceInfo) (*model.AuthResponse, error) {
 	const op = "internal/auth/service.Create"
 	log := a.logger.With("op: ", op)
 
-	user, err := a.repo.FindByEmail(ctx, req.Email)
-	if err != nil {
+	key := loginKey(req.Email)
+	if err := a.checkThrottle(ctx, key, device.IP); err != nil {
+		return nil, err
+	}
+
+	// неизвестный адрес и неверный пароль неотличимы ни по ответу, ни по времени
+	user, err := a.repo.FindByEmail(ctx, strings.TrimSpace(req.Email))
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
 		log.Error("Error finding user", slog.Any("error", err))
-		return "", err
+		return nil, err
+	}
+
+	hash := dummyHash()
+	if user != nil {
+		hash = []byte(user.Password)
+	}
+	if err := bcrypt.CompareHashAndPassword(hash, []byte(req.Password)); err != nil || user == nil {
+		a.recordFailure(ctx, key, device.IP)
+		return nil, ErrInvalidCredentials
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
-		log.Error("Error comparing password", slog.Any("error", err))
-		return "", err
+	if err := a.repo.ClearLoginAttempts(ctx, key); err != nil {
+		log.Error("Error clearing login attempts", slog.Any("error", err))
 	}
 
-	return a.jwt.Generate(user.Id.String())
+	// при включенной 2FA пароль дает только challenge
+	enabled, err := a.twoFactorEnabled(ctx, user.Id)
+	if err != nil {
+		log.Error("Error checking two-factor", slog.Any("error", err))
+		return nil, err
+	}
+	if enabled {
+		return a.challenge(ctx, user.Id)
+	}
+
+	return a.startSession(ctx, user.Id, device, false)
 }
 
-func NewAuthService(repo authrepository.Repository, jwt *authjwt.Manager, logger *slog.Logger) Service {
+func (a *AuthService) Refresh(ctx context.Context, req *model.RefreshRequest, device model.DeviceInfo) (*model.AuthResponse, error) {
+	const op = "internal/auth/service.Refresh"
+	log := a.logger.With("op: ", op)
+
+	if req.RefreshToken == "" {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	token, err := a.repo.FindRefreshToken(ctx, hashToken(req.RefreshToken))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		log.Error("Error finding refresh token", slog.Any("error", err))
+		return nil, err
+	}
+	if token.SessionRevoked {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	// токен уже обменян: его украли или клиент его переиграл — отзывается все семейство
+	fresh := false
+	if token.UsedAt == nil {
+		if fresh, err = a.repo.UseRefreshToken(ctx, token.ID); err != nil {
+			return nil, err
+		}
+	}
+	if !fresh {
+		log.Warn("Refresh token reuse detected, revoking session",
+			slog.String("session_id", token.SessionID.String()),
+			slog.String("user_id", token.UserID.String()))
+		if err := a.revoke(ctx, token.SessionID.String(), RevokeReuse); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if err := a.repo.TouchSession(ctx, token.SessionID.String(), device); err != nil {
+		log.Warn("Error updating session device", slog.Any("error", err))
+	}
+
+	return a.issue(ctx, token.UserID, token.SessionID, token.SessionMFA)
+}
+
+func (a *AuthService) Logout(ctx context.Context, sessionID string) error {
+	return a.revoke(ctx, sessionID, RevokeLogout)
+}
+
+func (a *AuthService) SessionActive(ctx context.Context, sessionID string) (bool, error) {
+	return a.repo.SessionActive(ctx, sessionID)
+}
+
+func (a *AuthService) ListSessions(ctx context.Context, userID, currentSessionID string) ([]model.AuthSession, error) {
+	sessions, err := a.repo.ListSessions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID.String() == currentSessionID
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession завершает сессию пользователя на другом устройстве (или текущую)
+func (a *AuthService) RevokeSession(ctx context.Context, userID, sessionID string) error {
+	const op = "internal/auth/service.RevokeSession"
+	log := a.logger.With("op: ", op)
+
+	if _, err := uuid2.FromString(sessionID); err != nil {
+		return ErrSessionNotFound
+	}
+
+	ok, err := a.repo.RevokeUserSession(ctx, userID, sessionID, RevokeUser)
+	if err != nil {
+		log.Error("Error revoking session", slog.Any("error", err))
+		return err
+	}
+	if !ok {
+		return ErrSessionNotFound
+	}
+
+	if a.closer != nil {
+		a.closer.CloseSession(sessionID)
+	}
+
+	return nil
+}
+
+// RevokeOtherSessions завершает сессии пользователя, кроме keepSessionID ("" — все)
+func (a *AuthService) RevokeOtherSessions(ctx context.Context, userID, keepSessionID string) error {
+	const op = "internal/auth/service.RevokeOtherSessions"
+	log := a.logger.With("op: ", op)
+
+	sessions, err := a.repo.RevokeAllSessions(ctx, uuid2.FromStringOrNil(userID), uuid2.FromStringOrNil(keepSessionID), RevokeCredentials)
+	if err != nil {
+		log.Error("Error revoking sessions", slog.Any("error", err))
+		return err
+	}
+
+	if a.closer != nil {
+		for _, id := range sessions {
+			a.closer.CloseSession(id)
+		}
+	}
+
+	return nil
+}
+
+// startSession открывает новую сессию и выдает первую пару токенов
+func (a *AuthService) startSession(ctx context.Context, userID uuid2.UUID, device model.DeviceInfo, mfa bool) (*model.AuthResponse, error) {
+	session := &model.AuthSession{
+		ID:        uuid2.Must(uuid2.NewV4()),
+		UserID:    userID,
+		UserAgent: truncate(device.UserAgent, 512),
+		Device:    deviceName(device.UserAgent),
+		IP:        device.IP,
+		MFA:       mfa,
+	}
+	if err := a.repo.CreateSession(ctx, session, a.refreshTTL); err != nil {
+		return nil, err
+	}
+
+	return a.issue(ctx, userID, session.ID, mfa)
+}
+
+func (a *AuthService) issue(ctx context.Context, userID, sessionID uuid2.UUID, mfa bool) (*model.AuthResponse, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, err
+	}
+	refresh := hex.EncodeToString(raw)
+
+	if err := a.repo.CreateRefreshToken(ctx, &model.RefreshToken{
+		ID:        uuid2.Must(uuid2.NewV4()),
+		SessionID: sessionID,
+		TokenHash: hashToken(refresh),
+	}, a.refreshTTL); err != nil {
+		return nil, err
+	}
+
+	access, err := a.jwt.Generate(userID.String(), sessionID.String(), mfa)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.AuthResponse{
+		Token:        access,
+		RefreshToken: refresh,
+		ExpiresIn:    int64(a.jwt.TTL().Seconds()),
+	}, nil
+}
+
+// revoke отзывает сессию и рвет ее websocket-соединения
+func (a *AuthService) revoke(ctx context.Context, sessionID, reason string) error {
+	const op = "internal/auth/service.revoke"
+	log := a.logger.With("op: ", op)
+
+	if _, err := a.repo.RevokeSession(ctx, sessionID, reason); err != nil {
+		log.Error("Error revoking session", slog.Any("error", err))
+		return err
+	}
+	if a.closer != nil {
+		a.closer.CloseSession(sessionID)
+	}
+
+	return nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func NewAuthService(repo authrepository.Repository, jwt *authjwt.Manager, refreshTTL time.Duration, closer SessionCloser, mailer mail.Sender, mailCfg *config.Mail, twoFactor *config.TwoFactor, loginCfg *config.Login, oidcCfg *config.OIDC, logger *slog.Logger) Service {
+	var oidc *authoidc.Provider
+	if oidcCfg.Enabled {
+		oidc = authoidc.NewProvider(oidcCfg)
+	}
+
 	return &AuthService{
-		repo:   repo,
-		jwt:    jwt,
-		logger: logger,
+		repo:       repo,
+		jwt:        jwt,
+		refreshTTL: refreshTTL,
+		closer:     closer,
+		mailer:     mailer,
+		mailCfg:    mailCfg,
+		twoFactor:  twoFactor,
+		totpKey:    sha256.Sum256([]byte(twoFactor.EncryptionKey)),
+		loginCfg:   loginCfg,
+		ipFailures: ratelimit.New(),
+		oidc:       oidc,
+		oidcCfg:    oidcCfg,
+		logger:     logger,
 	}
 }
@@ -0,0 +1,257 @@
+package authservice
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/QuUteO/video-communication/internal/auth/jwt"
+	"github.com/QuUteO/video-communication/internal/auth/repository"
+	"github.com/QuUteO/video-communication/internal/model"
+	uuid2 "github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+// sessionRepo сессии и refresh-токены в памяти; остальные методы не вызываются
+type sessionRepo struct {
+	authrepository.Repository
+
+	mu       sync.Mutex
+	sessions map[string]*model.AuthSession
+	tokens   map[string]*model.RefreshToken // по хешу
+}
+
+func newSessionRepo() *sessionRepo {
+	return &sessionRepo{
+		sessions: make(map[string]*model.AuthSession),
+		tokens:   make(map[string]*model.RefreshToken),
+	}
+}
+
+func (r *sessionRepo) CreateSession(_ context.Context, session *model.AuthSession, _ time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := *session
+	r.sessions[session.ID.String()] = &s
+	return nil
+}
+
+func (r *sessionRepo) SessionActive(_ context.Context, sessionID string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, ok := r.sessions[sessionID]
+	return ok && s.RevokedAt == nil, nil
+}
+
+func (r *sessionRepo) TouchSession(context.Context, string, model.DeviceInfo) error {
+	return nil
+}
+
+func (r *sessionRepo) RevokeSession(_ context.Context, sessionID, reason string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, ok := r.sessions[sessionID]
+	if !ok || s.RevokedAt != nil {
+		return false, nil
+	}
+	now := time.Now()
+	s.RevokedAt, s.RevokedReason = &now, reason
+	return true, nil
+}
+
+func (r *sessionRepo) CreateRefreshToken(_ context.Context, token *model.RefreshToken, _ time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t := *token
+	r.tokens[token.TokenHash] = &t
+	return nil
+}
+
+func (r *sessionRepo) FindRefreshToken(_ context.Context, tokenHash string) (*model.RefreshToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t, ok := r.tokens[tokenHash]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	s := r.sessions[t.SessionID.String()]
+	found := *t
+	found.UserID = s.UserID
+	found.SessionRevoked = s.RevokedAt != nil
+	found.SessionMFA = s.MFA
+	return &found, nil
+}
+
+func (r *sessionRepo) UseRefreshToken(_ context.Context, id uuid2.UUID) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, t := range r.tokens {
+		if t.ID == id {
+			if t.UsedAt != nil {
+				return false, nil
+			}
+			now := time.Now()
+			t.UsedAt = &now
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *sessionRepo) revokedReason(sessionID string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.sessions[sessionID].RevokedReason
+}
+
+// closedSessions запоминает сессии, чьи соединения закрыл сервис
+type closedSessions struct {
+	mu  sync.Mutex
+	ids []string
+}
+
+func (c *closedSessions) CloseSession(sessionID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ids = append(c.ids, sessionID)
+}
+
+func (c *closedSessions) closed(sessionID string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, id := range c.ids {
+		if id == sessionID {
+			return true
+		}
+	}
+	return false
+}
+
+func newTestService(t *testing.T, repo authrepository.Repository) (*AuthService, *closedSessions) {
+	t.Helper()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	keys, err := authjwt.NewKeySet(authjwt.KeySetConfig{
+		Dir:         t.TempDir(),
+		Algorithm:   authjwt.AlgEdDSA,
+		RotateEvery: time.Hour,
+		Grace:       time.Hour,
+		Interval:    time.Minute,
+	}, logger)
+	if err != nil {
+		t.Fatalf("NewKeySet: %v", err)
+	}
+
+	closer := &closedSessions{}
+	return &AuthService{
+		repo:       repo,
+		jwt:        authjwt.NewJWT(keys, "test", []string{"test"}, time.Minute),
+		refreshTTL: time.Hour,
+		closer:     closer,
+		logger:     logger,
+	}, closer
+}
+
+func TestRefreshRotatesToken(t *testing.T) {
+	repo := newSessionRepo()
+	svc, _ := newTestService(t, repo)
+	ctx := context.Background()
+
+	first, err := svc.startSession(ctx, uuid2.Must(uuid2.NewV4()), model.DeviceInfo{}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := svc.Refresh(ctx, &model.RefreshRequest{RefreshToken: first.RefreshToken}, model.DeviceInfo{})
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if second.RefreshToken == first.RefreshToken || second.Token == "" {
+		t.Fatal("Refresh did not issue a new pair")
+	}
+
+	// новый токен тоже обменивается ровно один раз
+	if _, err := svc.Refresh(ctx, &model.RefreshRequest{RefreshToken: second.RefreshToken}, model.DeviceInfo{}); err != nil {
+		t.Fatalf("Refresh with rotated token: %v", err)
+	}
+}
+
+func TestRefreshReuseRevokesSession(t *testing.T) {
+	repo := newSessionRepo()
+	svc, closer := newTestService(t, repo)
+	ctx := context.Background()
+
+	first, err := svc.startSession(ctx, uuid2.Must(uuid2.NewV4()), model.DeviceInfo{}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := svc.jwt.Parse(first.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := svc.Refresh(ctx, &model.RefreshRequest{RefreshToken: first.RefreshToken}, model.DeviceInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// старый токен предъявлен повторно: отзывается вся сессия, включая выданный взамен токен
+	_, err = svc.Refresh(ctx, &model.RefreshRequest{RefreshToken: first.RefreshToken}, model.DeviceInfo{})
+	if !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("reuse: error = %v, want %v", err, ErrInvalidRefreshToken)
+	}
+
+	if active, _ := svc.SessionActive(ctx, claims.SessionID); active {
+		t.Error("session is still active after reuse")
+	}
+	if reason := repo.revokedReason(claims.SessionID); reason != RevokeReuse {
+		t.Errorf("revoked reason = %q, want %q", reason, RevokeReuse)
+	}
+	if !closer.closed(claims.SessionID) {
+		t.Error("websocket connections of the session were not closed")
+	}
+
+	_, err = svc.Refresh(ctx, &model.RefreshRequest{RefreshToken: second.RefreshToken}, model.DeviceInfo{})
+	if !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("rotated token after reuse: error = %v, want %v", err, ErrInvalidRefreshToken)
+	}
+}
+
+func TestLogoutRevokesRefreshToken(t *testing.T) {
+	repo := newSessionRepo()
+	svc, _ := newTestService(t, repo)
+	ctx := context.Background()
+
+	resp, err := svc.startSession(ctx, uuid2.Must(uuid2.NewV4()), model.DeviceInfo{}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := svc.jwt.Parse(resp.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := svc.Logout(ctx, claims.SessionID); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = svc.Refresh(ctx, &model.RefreshRequest{RefreshToken: resp.RefreshToken}, model.DeviceInfo{})
+	if !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("error = %v, want %v", err, ErrInvalidRefreshToken)
+	}
+}
+
+func TestRefreshRejectsUnknownToken(t *testing.T) {
+	svc, _ := newTestService(t, newSessionRepo())
+
+	for _, token := range []string{"", "unknown"} {
+		_, err := svc.Refresh(context.Background(), &model.RefreshRequest{RefreshToken: token}, model.DeviceInfo{})
+		if !errors.Is(err, ErrInvalidRefreshToken) {
+			t.Errorf("Refresh(%q) error = %v, want %v", token, err, ErrInvalidRefreshToken)
+		}
+	}
+}
@@ -0,0 +1,365 @@
+package authservice
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/QuUteO/video-communication/internal/model"
+	"github.com/QuUteO/video-communication/pkg/totp"
+	uuid2 "github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+const (
+	recoveryCodeCount  = 10
+	maxChallengeTries  = 5
+	recoveryCodeLength = 10
+
+	// после maxCodeTries неверных кодов подряд (при входе и управлении 2FA) — пауза;
+	// счетчик общий для всех challenge пользователя и сбрасывается только верным кодом
+	maxCodeTries = 5
+	codeLockout  = 15 * time.Minute
+)
+
+var (
+	ErrTwoFactorEnabled    = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnabled = errors.New("two-factor authentication is not enabled")
+	ErrInvalidCode         = errors.New("invalid two-factor code")
+	ErrInvalidChallenge    = errors.New("invalid or expired login challenge")
+	ErrTooManyCodes        = errors.New("too many invalid two-factor codes, try again later")
+)
+
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+func (a *AuthService) LoginTwoFactor(ctx context.Context, req *model.TwoFactorLoginRequest, device model.DeviceInfo) (*model.AuthResponse, error) {
+	const op = "internal/auth/service.LoginTwoFactor"
+	log := a.logger.With("op: ", op)
+
+	if req.ChallengeToken == "" {
+		return nil, ErrInvalidChallenge
+	}
+
+	challenge, err := a.repo.FindChallenge(ctx, hashToken(req.ChallengeToken))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrInvalidChallenge
+	}
+	if err != nil {
+		log.Error("Error finding login challenge", slog.Any("error", err))
+		return nil, err
+	}
+
+	// перебор кодов ограничен: после maxChallengeTries challenge сгорает и нужен новый вход по паролю
+	attempts, err := a.repo.ChallengeAttempt(ctx, challenge.ID)
+	if err != nil {
+		return nil, err
+	}
+	if attempts > maxChallengeTries {
+		_, _ = a.repo.ConsumeChallenge(ctx, challenge.ID)
+		return nil, ErrInvalidChallenge
+	}
+
+	t, err := a.repo.GetTOTP(ctx, challenge.UserID)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && t.EnabledAt == nil) {
+		// 2FA отключили между шагами входа
+		return nil, ErrInvalidChallenge
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// новый challenge после верного пароля не обнуляет счетчик неверных кодов пользователя
+	if err := a.verifyCode(ctx, t, req.Code, true); err != nil {
+		if attempts == maxChallengeTries {
+			_, _ = a.repo.ConsumeChallenge(ctx, challenge.ID)
+		}
+		return nil, err
+	}
+
+	if ok, err := a.repo.ConsumeChallenge(ctx, challenge.ID); err != nil || !ok {
+		return nil, ErrInvalidChallenge
+	}
+
+	return a.startSession(ctx, challenge.UserID, device, true)
+}
+
+// EnrollTOTP выдает новый секрет; 2FA включится только после ConfirmTOTP
+func (a *AuthService) EnrollTOTP(ctx context.Context, userID string) (*model.TOTPEnrollment, error) {
+	const op = "internal/auth/service.EnrollTOTP"
+	log := a.logger.With("op: ", op)
+
+	user, err := a.repo.FindByID(ctx, userID)
+	if err != nil {
+		log.Error("Error finding user", slog.Any("error", err))
+		return nil, err
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := a.seal(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	ok, err := a.repo.SaveTOTPSecret(ctx, user.Id, sealed)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrTwoFactorEnabled
+	}
+
+	return &model.TOTPEnrollment{
+		Secret: secret,
+		URI:    totp.URI(a.twoFactor.Issuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmTOTP включает 2FA по первому коду из приложения и выдает коды восстановления
+func (a *AuthService) ConfirmTOTP(ctx context.Context, userID string, req *model.TOTPCodeRequest) (*model.RecoveryCodesResponse, error) {
+	id := uuid2.FromStringOrNil(userID)
+
+	t, err := a.repo.GetTOTP(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrTwoFactorNotEnabled
+	}
+	if err != nil {
+		return nil, err
+	}
+	if t.EnabledAt != nil {
+		return nil, ErrTwoFactorEnabled
+	}
+
+	if err := a.verifyCode(ctx, t, req.Code, false); err != nil {
+		return nil, err
+	}
+
+	codes, err := a.newRecoveryCodes(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.repo.EnableTOTP(ctx, id); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// DisableTOTP отключает 2FA; нужен действующий код или код восстановления
+func (a *AuthService) DisableTOTP(ctx context.Context, userID string, req *model.TOTPCodeRequest) error {
+	id := uuid2.FromStringOrNil(userID)
+
+	t, err := a.enabledTOTP(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := a.verifyCode(ctx, t, req.Code, true); err != nil {
+		return err
+	}
+
+	return a.repo.DeleteTOTP(ctx, id)
+}
+
+// RegenerateRecoveryCodes заменяет коды восстановления; старые перестают действовать
+func (a *AuthService) RegenerateRecoveryCodes(ctx context.Context, userID string, req *model.TOTPCodeRequest) (*model.RecoveryCodesResponse, error) {
+	id := uuid2.FromStringOrNil(userID)
+
+	t, err := a.enabledTOTP(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.verifyCode(ctx, t, req.Code, false); err != nil {
+		return nil, err
+	}
+
+	return a.newRecoveryCodes(ctx, id)
+}
+
+func (a *AuthService) twoFactorEnabled(ctx context.Context, userID uuid2.UUID) (bool, error) {
+	t, err := a.repo.GetTOTP(ctx, userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return t.EnabledAt != nil, nil
+}
+
+func (a *AuthService) enabledTOTP(ctx context.Context, userID uuid2.UUID) (*model.TOTP, error) {
+	t, err := a.repo.GetTOTP(ctx, userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrTwoFactorNotEnabled
+	}
+	if err != nil {
+		return nil, err
+	}
+	if t.EnabledAt == nil {
+		return nil, ErrTwoFactorNotEnabled
+	}
+
+	return t, nil
+}
+
+// challenge выдает короткоживущий токен второго шага входа
+func (a *AuthService) challenge(ctx context.Context, userID uuid2.UUID) (*model.AuthResponse, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, err
+	}
+	token := hex.EncodeToString(raw)
+
+	if err := a.repo.CreateChallenge(ctx, &model.LoginChallenge{
+		ID:        uuid2.Must(uuid2.NewV4()),
+		UserID:    userID,
+		TokenHash: hashToken(token),
+	}, a.twoFactor.ChallengeTTL); err != nil {
+		return nil, err
+	}
+
+	return &model.AuthResponse{
+		TwoFactorRequired: true,
+		ChallengeToken:    token,
+	}, nil
+}
+
+// verifySecondFactor принимает код TOTP (каждый шаг — один раз) или, если разрешено, код восстановления
+func (a *AuthService) verifySecondFactor(ctx context.Context, t *model.TOTP, code string, allowRecovery bool) error {
+	secret, err := a.open(t.Secret)
+	if err != nil {
+		return err
+	}
+
+	if step, ok := totp.Validate(secret, code, time.Now()); ok {
+		used, err := a.repo.UseTOTPStep(ctx, t.UserID, step)
+		if err != nil {
+			return err
+		}
+		if !used {
+			return ErrInvalidCode
+		}
+		return nil
+	}
+
+	if allowRecovery {
+		if normalized := normalizeRecoveryCode(code); len(normalized) == recoveryCodeLength {
+			ok, err := a.repo.UseRecoveryCode(ctx, t.UserID, hashToken(normalized))
+			if err != nil {
+				return err
+			}
+			if ok {
+				return nil
+			}
+		}
+	}
+
+	return ErrInvalidCode
+}
+
+// verifyCode verifySecondFactor с ограничением перебора: попытка учитывается
+// до проверки кода, поэтому параллельные запросы не обходят лимит
+func (a *AuthService) verifyCode(ctx context.Context, t *model.TOTP, code string, allowRecovery bool) error {
+	attempts, locked, err := a.repo.TOTPAttempt(ctx, t.UserID)
+	if err != nil {
+		return err
+	}
+	if locked > 0 {
+		return ErrTooManyCodes
+	}
+	if attempts > maxCodeTries {
+		if err := a.repo.LockTOTP(ctx, t.UserID, codeLockout); err != nil {
+			return err
+		}
+		return ErrTooManyCodes
+	}
+
+	if err := a.verifySecondFactor(ctx, t, code, allowRecovery); err != nil {
+		if attempts == maxCodeTries && errors.Is(err, ErrInvalidCode) {
+			if err := a.repo.LockTOTP(ctx, t.UserID, codeLockout); err != nil {
+				return err
+			}
+		}
+		return err
+	}
+
+	return a.repo.ClearTOTPAttempts(ctx, t.UserID)
+}
+
+func (a *AuthService) newRecoveryCodes(ctx context.Context, userID uuid2.UUID) (*model.RecoveryCodesResponse, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+
+	for range recoveryCodeCount {
+		raw := make([]byte, 8)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, err
+		}
+		code := strings.ToLower(recoveryEncoding.EncodeToString(raw))[:recoveryCodeLength]
+
+		codes = append(codes, code[:5]+"-"+code[5:])
+		hashes = append(hashes, hashToken(code))
+	}
+
+	if err := a.repo.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+
+	return &model.RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(code)))
+}
+
+// seal шифрует TOTP-секрет: AES-256-GCM, nonce в начале, base64
+func (a *AuthService) seal(plain string) (string, error) {
+	gcm, err := a.gcm()
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte(plain), nil)), nil
+}
+
+func (a *AuthService) open(sealed string) (string, error) {
+	gcm, err := a.gcm()
+	if err != nil {
+		return "", err
+	}
+
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil || len(data) < gcm.NonceSize() {
+		return "", errors.New("malformed totp secret")
+	}
+
+	plain, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", err
+	}
+
+	return string(plain), nil
+}
+
+func (a *AuthService) gcm() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(a.totpKey[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,255 @@
+package authservice
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/QuUteO/video-communication/internal/config"
+	"github.com/QuUteO/video-communication/internal/model"
+	"github.com/QuUteO/video-communication/pkg/totp"
+	uuid2 "github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+// twoFactorRepo TOTP одного пользователя и challenge входа в памяти
+type twoFactorRepo struct {
+	*sessionRepo
+
+	totp        *model.TOTP
+	failed      int
+	lockedUntil time.Time
+	challenges  map[string]*model.LoginChallenge // по хешу
+	consumed    map[uuid2.UUID]bool
+}
+
+func newTwoFactorRepo() *twoFactorRepo {
+	return &twoFactorRepo{
+		sessionRepo: newSessionRepo(),
+		challenges:  make(map[string]*model.LoginChallenge),
+		consumed:    make(map[uuid2.UUID]bool),
+	}
+}
+
+func (r *twoFactorRepo) GetTOTP(_ context.Context, userID uuid2.UUID) (*model.TOTP, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.totp == nil || r.totp.UserID != userID {
+		return nil, pgx.ErrNoRows
+	}
+	t := *r.totp
+	return &t, nil
+}
+
+func (r *twoFactorRepo) UseTOTPStep(_ context.Context, _ uuid2.UUID, step int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if step <= r.totp.LastStep {
+		return false, nil
+	}
+	r.totp.LastStep = step
+	return true, nil
+}
+
+func (r *twoFactorRepo) UseRecoveryCode(context.Context, uuid2.UUID, string) (bool, error) {
+	return false, nil
+}
+
+func (r *twoFactorRepo) TOTPAttempt(context.Context, uuid2.UUID) (int, time.Duration, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if locked := time.Until(r.lockedUntil); locked > 0 {
+		return r.failed, locked, nil
+	}
+	r.failed++
+	return r.failed, 0, nil
+}
+
+func (r *twoFactorRepo) LockTOTP(_ context.Context, _ uuid2.UUID, lockout time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.failed, r.lockedUntil = 0, time.Now().Add(lockout)
+	return nil
+}
+
+func (r *twoFactorRepo) ClearTOTPAttempts(context.Context, uuid2.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.failed = 0
+	return nil
+}
+
+func (r *twoFactorRepo) CreateChallenge(_ context.Context, challenge *model.LoginChallenge, _ time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c := *challenge
+	r.challenges[challenge.TokenHash] = &c
+	return nil
+}
+
+func (r *twoFactorRepo) FindChallenge(_ context.Context, tokenHash string) (*model.LoginChallenge, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c, ok := r.challenges[tokenHash]
+	if !ok || r.consumed[c.ID] {
+		return nil, pgx.ErrNoRows
+	}
+	found := *c
+	return &found, nil
+}
+
+func (r *twoFactorRepo) ChallengeAttempt(_ context.Context, id uuid2.UUID) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, c := range r.challenges {
+		if c.ID == id {
+			c.Attempts++
+			return c.Attempts, nil
+		}
+	}
+	return 0, pgx.ErrNoRows
+}
+
+func (r *twoFactorRepo) ConsumeChallenge(_ context.Context, id uuid2.UUID) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.consumed[id] {
+		return false, nil
+	}
+	r.consumed[id] = true
+	return true, nil
+}
+
+// newTwoFactorService сервис с включенной 2FA у пользователя; возвращает его id и секрет
+func newTwoFactorService(t *testing.T) (*AuthService, *twoFactorRepo, uuid2.UUID, string) {
+	t.Helper()
+
+	repo := newTwoFactorRepo()
+	svc, _ := newTestService(t, repo)
+	svc.twoFactor = &config.TwoFactor{ChallengeTTL: time.Minute}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := svc.seal(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	userID := uuid2.Must(uuid2.NewV4())
+	enabled := time.Now()
+	repo.totp = &model.TOTP{UserID: userID, Secret: sealed, EnabledAt: &enabled}
+
+	return svc, repo, userID, secret
+}
+
+func currentCode(t *testing.T, secret string) string {
+	t.Helper()
+	code, err := totp.Code(secret, totp.Step(time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
+
+func wrongCode(t *testing.T, secret string) string {
+	t.Helper()
+	code := currentCode(t, secret)
+	if code == "000000" {
+		return "111111"
+	}
+	return "000000"
+}
+
+func TestLoginTwoFactor(t *testing.T) {
+	svc, _, userID, secret := newTwoFactorService(t)
+	ctx := context.Background()
+
+	challenge, err := svc.challenge(ctx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !challenge.TwoFactorRequired || challenge.Token != "" {
+		t.Fatalf("challenge = %+v", challenge)
+	}
+
+	code := currentCode(t, secret)
+	resp, err := svc.LoginTwoFactor(ctx, &model.TwoFactorLoginRequest{ChallengeToken: challenge.ChallengeToken, Code: code}, model.DeviceInfo{})
+	if err != nil {
+		t.Fatalf("LoginTwoFactor: %v", err)
+	}
+	claims, err := svc.jwt.Parse(resp.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !claims.MFA {
+		t.Error("session is not marked as two-factor")
+	}
+
+	// challenge одноразовый, код того же шага повторно не принимается
+	_, err = svc.LoginTwoFactor(ctx, &model.TwoFactorLoginRequest{ChallengeToken: challenge.ChallengeToken, Code: code}, model.DeviceInfo{})
+	if !errors.Is(err, ErrInvalidChallenge) {
+		t.Errorf("reused challenge: error = %v, want %v", err, ErrInvalidChallenge)
+	}
+
+	again, err := svc.challenge(ctx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = svc.LoginTwoFactor(ctx, &model.TwoFactorLoginRequest{ChallengeToken: again.ChallengeToken, Code: code}, model.DeviceInfo{})
+	if !errors.Is(err, ErrInvalidCode) {
+		t.Errorf("replayed code: error = %v, want %v", err, ErrInvalidCode)
+	}
+}
+
+// новый challenge после верного пароля не дает новых попыток подобрать код
+func TestLoginTwoFactorLockoutSpansChallenges(t *testing.T) {
+	svc, _, userID, secret := newTwoFactorService(t)
+	ctx := context.Background()
+
+	for i := 0; i < maxCodeTries; i++ {
+		challenge, err := svc.challenge(ctx, userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = svc.LoginTwoFactor(ctx, &model.TwoFactorLoginRequest{ChallengeToken: challenge.ChallengeToken, Code: wrongCode(t, secret)}, model.DeviceInfo{})
+		if !errors.Is(err, ErrInvalidCode) {
+			t.Fatalf("attempt %d: error = %v, want %v", i+1, err, ErrInvalidCode)
+		}
+	}
+
+	challenge, err := svc.challenge(ctx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = svc.LoginTwoFactor(ctx, &model.TwoFactorLoginRequest{ChallengeToken: challenge.ChallengeToken, Code: currentCode(t, secret)}, model.DeviceInfo{})
+	if !errors.Is(err, ErrTooManyCodes) {
+		t.Errorf("correct code during lockout: error = %v, want %v", err, ErrTooManyCodes)
+	}
+}
+
+func TestLoginTwoFactorSuccessResetsCounter(t *testing.T) {
+	svc, repo, userID, secret := newTwoFactorService(t)
+	ctx := context.Background()
+
+	for i := 0; i < maxCodeTries-1; i++ {
+		challenge, err := svc.challenge(ctx, userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = svc.LoginTwoFactor(ctx, &model.TwoFactorLoginRequest{ChallengeToken: challenge.ChallengeToken, Code: wrongCode(t, secret)}, model.DeviceInfo{})
+	}
+
+	challenge, err := svc.challenge(ctx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.LoginTwoFactor(ctx, &model.TwoFactorLoginRequest{ChallengeToken: challenge.ChallengeToken, Code: currentCode(t, secret)}, model.DeviceInfo{}); err != nil {
+		t.Fatalf("LoginTwoFactor: %v", err)
+	}
+	if repo.failed != 0 {
+		t.Errorf("failed attempts = %d after success, want 0", repo.failed)
+	}
+}
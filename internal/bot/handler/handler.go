@@ -0,0 +1,130 @@
+package bothandler
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	authmiddleware "github.com/QuUteO/video-communication/internal/auth/middleware"
+	"github.com/QuUteO/video-communication/internal/bot/service"
+	"github.com/QuUteO/video-communication/internal/model"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+type Handler struct {
+	service botservice.Service
+	logger  *slog.Logger
+}
+
+func NewHandler(service botservice.Service, logger *slog.Logger) *Handler {
+	return &Handler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// Create POST /bots
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	const op = "internal/bot/handler/Create"
+	log := h.logger.With("op: ", op)
+
+	var req model.BotRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.error(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	userID, _ := r.Context().Value(authmiddleware.UserIDKey).(string)
+
+	bot, err := h.service.Create(r.Context(), userID, &req)
+	if err != nil {
+		h.fail(w, r, log, err)
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, model.Response{
+		StatusCode: http.StatusCreated,
+		Message:    "Bot created",
+		Data:       bot,
+		Error:      "nil",
+	})
+}
+
+// List GET /bots
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	const op = "internal/bot/handler/List"
+	log := h.logger.With("op: ", op)
+
+	bots, err := h.service.List(r.Context())
+	if err != nil {
+		h.fail(w, r, log, err)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, model.Response{
+		StatusCode: http.StatusOK,
+		Message:    "Bots retrieved successfully",
+		Data:       bots,
+		Error:      "nil",
+	})
+}
+
+// Delete DELETE /bots/{id}
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	const op = "internal/bot/handler/Delete"
+	log := h.logger.With("op: ", op)
+
+	userID, _ := r.Context().Value(authmiddleware.UserIDKey).(string)
+
+	if err := h.service.Delete(r.Context(), userID, chi.URLParam(r, "id")); err != nil {
+		h.fail(w, r, log, err)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, model.Response{
+		StatusCode: http.StatusOK,
+		Message:    "Bot deleted",
+		Error:      "nil",
+	})
+}
+
+// Commands GET /commands
+func (h *Handler) Commands(w http.ResponseWriter, r *http.Request) {
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, model.Response{
+		StatusCode: http.StatusOK,
+		Message:    "Commands retrieved successfully",
+		Data:       h.service.Commands(),
+		Error:      "nil",
+	})
+}
+
+func (h *Handler) fail(w http.ResponseWriter, r *http.Request, log *slog.Logger, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, botservice.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, botservice.ErrNameTaken), errors.Is(err, botservice.ErrCommandTaken):
+		status = http.StatusConflict
+	case errors.Is(err, botservice.ErrInvalidName), errors.Is(err, botservice.ErrInvalidURL),
+		errors.Is(err, botservice.ErrNoCommands), errors.Is(err, botservice.ErrInvalidCommand):
+		status = http.StatusBadRequest
+	default:
+		log.Error("Bot request failed", slog.Any("error", err))
+	}
+
+	h.error(w, r, status, err.Error())
+}
+
+func (h *Handler) error(w http.ResponseWriter, r *http.Request, status int, msg string) {
+	render.Status(r, status)
+	render.JSON(w, r, model.Response{
+		StatusCode: status,
+		Error:      msg,
+	})
+}
@@ -0,0 +1,113 @@
+package botrepository
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/QuUteO/video-communication/internal/model"
+	postgres "github.com/QuUteO/video-communication/pkg/db"
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+type Repository interface {
+	Create(ctx context.Context, bot *model.Bot) error
+	List(ctx context.Context) ([]model.Bot, error)
+	FindByID(ctx context.Context, id string) (*model.Bot, error)
+	Delete(ctx context.Context, id string) (bool, error)
+}
+
+type repository struct {
+	db     postgres.Client
+	logger *slog.Logger
+}
+
+func (r *repository) Create(ctx context.Context, bot *model.Bot) error {
+	const op = "./internal/bot/repository.Create"
+	log := r.logger.With("op: ", op)
+
+	q := `
+		INSERT INTO bots (id, name, url, secret, commands, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	if _, err := r.db.Exec(ctx, q, bot.ID, bot.Name, bot.URL, bot.Secret, bot.Commands, bot.CreatedBy, bot.CreatedAt); err != nil {
+		log.Error("Error to insert bot", slog.Any("err", err))
+		return err
+	}
+
+	return nil
+}
+
+// List возвращает ботов вместе с секретами: они нужны для подписи запросов
+func (r *repository) List(ctx context.Context) ([]model.Bot, error) {
+	const op = "./internal/bot/repository.List"
+	log := r.logger.With("op: ", op)
+
+	q := `
+		SELECT id, name, url, secret, commands, created_by, created_at
+		FROM bots
+		ORDER BY created_at
+	`
+
+	rows, err := r.db.Query(ctx, q)
+	if err != nil {
+		log.Error("Error to list bots", slog.Any("err", err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	bots := make([]model.Bot, 0)
+	for rows.Next() {
+		bot, err := scanBot(rows)
+		if err != nil {
+			log.Error("Error to scan bot", slog.Any("err", err))
+			return nil, err
+		}
+		bots = append(bots, *bot)
+	}
+
+	return bots, rows.Err()
+}
+
+func (r *repository) FindByID(ctx context.Context, id string) (*model.Bot, error) {
+	q := `
+		SELECT id, name, url, secret, commands, created_by, created_at
+		FROM bots
+		WHERE id = $1
+	`
+
+	return scanBot(r.db.QueryRow(ctx, q, id))
+}
+
+func (r *repository) Delete(ctx context.Context, id string) (bool, error) {
+	const op = "./internal/bot/repository.Delete"
+	log := r.logger.With("op: ", op)
+
+	tag, err := r.db.Exec(ctx, `DELETE FROM bots WHERE id = $1`, id)
+	if err != nil {
+		log.Error("Error to delete bot", slog.Any("err", err))
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+func scanBot(row pgx.Row) (*model.Bot, error) {
+	var bot model.Bot
+	var createdBy uuid.NullUUID
+
+	if err := row.Scan(&bot.ID, &bot.Name, &bot.URL, &bot.Secret, &bot.Commands, &createdBy, &bot.CreatedAt); err != nil {
+		return nil, err
+	}
+	bot.CreatedBy = createdBy.UUID
+
+	return &bot, nil
+}
+
+func New(db postgres.Client, logger *slog.Logger) Repository {
+	return &repository{
+		db:     db,
+		logger: logger,
+	}
+}
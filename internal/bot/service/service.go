@@ -0,0 +1,205 @@
+package botservice
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/QuUteO/video-communication/internal/bot/repository"
+	"github.com/QuUteO/video-communication/internal/command"
+	"github.com/QuUteO/video-communication/internal/model"
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+)
+
+// botTimeout ограничивает ожидание ответа бота: пользователь ждет ответа в чате
+const botTimeout = 5 * time.Second
+
+var nameRe = regexp.MustCompile(`^[A-Za-z0-9_.-]{2,32}$`)
+
+var (
+	ErrNotFound       = errors.New("bot not found")
+	ErrInvalidName    = errors.New("bot name must be 2-32 characters: letters, digits, '_', '.', '-'")
+	ErrNameTaken      = errors.New("bot name is already taken")
+	ErrInvalidURL     = errors.New("bot url must be an absolute http(s) url")
+	ErrNoCommands     = errors.New("bot must handle at least one command")
+	ErrCommandTaken   = errors.New("command is already registered")
+	ErrInvalidCommand = command.ErrInvalidName
+)
+
+type Service interface {
+	Create(ctx context.Context, userID string, req *model.BotRequest) (*model.Bot, error)
+	List(ctx context.Context) ([]model.Bot, error)
+	// Delete ботами управляют администраторы, удалить можно любого
+	Delete(ctx context.Context, userID, id string) error
+	Commands() []command.Info
+	// Load регистрирует команды сохраненных ботов при старте
+	Load(ctx context.Context) error
+}
+
+type BotService struct {
+	repo     botrepository.Repository
+	registry *command.Registry
+	client   *http.Client
+	logger   *slog.Logger
+}
+
+func (s *BotService) Create(ctx context.Context, userID string, req *model.BotRequest) (*model.Bot, error) {
+	const op = "internal/bot/service.Create"
+	log := s.logger.With("op: ", op)
+
+	name := strings.TrimSpace(req.Name)
+	if !nameRe.MatchString(name) || strings.EqualFold(name, command.OwnerBuiltin) {
+		return nil, ErrInvalidName
+	}
+
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, ErrInvalidURL
+	}
+
+	commands := make([]string, 0, len(req.Commands))
+	for _, c := range req.Commands {
+		commands = append(commands, strings.ToLower(strings.TrimPrefix(strings.TrimSpace(c), "/")))
+	}
+	commands = slices.Compact(slices.Sorted(slices.Values(commands)))
+	if len(commands) == 0 {
+		return nil, ErrNoCommands
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+
+	bot := &model.Bot{
+		ID:        uuid.Must(uuid.NewV4()),
+		Name:      name,
+		URL:       u.String(),
+		Secret:    hex.EncodeToString(secret),
+		Commands:  commands,
+		CreatedBy: uuid.FromStringOrNil(userID),
+		CreatedAt: time.Now(),
+	}
+
+	// команды занимаются до записи в БД, чтобы конфликт имен не оставил бота без команд
+	if err := s.register(bot); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Create(ctx, bot); err != nil {
+		s.unregister(bot)
+
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, ErrNameTaken
+		}
+		log.Error("Error creating bot", slog.Any("error", err))
+		return nil, err
+	}
+
+	return bot, nil
+}
+
+func (s *BotService) List(ctx context.Context) ([]model.Bot, error) {
+	bots, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range bots {
+		bots[i].Secret = ""
+	}
+	return bots, nil
+}
+
+func (s *BotService) Delete(ctx context.Context, userID, id string) error {
+	if _, err := uuid.FromString(id); err != nil {
+		return ErrNotFound
+	}
+
+	bot, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+		return err
+	}
+	deleted, err := s.repo.Delete(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrNotFound
+	}
+
+	s.unregister(bot)
+	return nil
+}
+
+func (s *BotService) Commands() []command.Info {
+	return s.registry.List()
+}
+
+func (s *BotService) Load(ctx context.Context) error {
+	const op = "internal/bot/service.Load"
+	log := s.logger.With("op: ", op)
+
+	bots, err := s.repo.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	for i := range bots {
+		if err := s.register(&bots[i]); err != nil {
+			log.Warn("Bot commands skipped", slog.String("bot", bots[i].Name), slog.Any("error", err))
+		}
+	}
+	return nil
+}
+
+// register добавляет все команды бота в реестр или ни одной
+func (s *BotService) register(bot *model.Bot) error {
+	handler := &command.HTTPHandler{URL: bot.URL, Secret: bot.Secret, Client: s.client, Timeout: botTimeout}
+
+	for i, name := range bot.Commands {
+		err := s.registry.Register(command.Info{Name: name, Owner: bot.Name}, handler)
+		if err == nil {
+			continue
+		}
+
+		for _, registered := range bot.Commands[:i] {
+			s.registry.Unregister(registered, bot.Name)
+		}
+		if errors.Is(err, command.ErrAlreadyExists) {
+			return ErrCommandTaken
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *BotService) unregister(bot *model.Bot) {
+	for _, name := range bot.Commands {
+		s.registry.Unregister(name, bot.Name)
+	}
+}
+
+// NewBotService client должен запрещать внутренние адреса (safehttp.NewClient)
+func NewBotService(repo botrepository.Repository, registry *command.Registry, client *http.Client, logger *slog.Logger) Service {
+	return &BotService{
+		repo:     repo,
+		registry: registry,
+		client:   client,
+		logger:   logger,
+	}
+}
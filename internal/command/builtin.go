@@ -0,0 +1,36 @@
+package command
+
+import (
+	"context"
+	"strings"
+)
+
+const OwnerBuiltin = "builtin"
+
+// RegisterBuiltins добавляет встроенные команды
+func RegisterBuiltins(r *Registry) {
+	_ = r.Register(Info{
+		Name:        "help",
+		Description: "список доступных команд",
+		Owner:       OwnerBuiltin,
+	}, HandlerFunc(func(ctx context.Context, inv Invocation) (*Response, error) {
+		var b strings.Builder
+		b.WriteString("Доступные команды:")
+		for _, info := range r.List() {
+			b.WriteString("\n/" + info.Name)
+			if info.Description != "" {
+				b.WriteString(" — " + info.Description)
+			}
+		}
+		return &Response{Text: b.String(), Ephemeral: true}, nil
+	}))
+
+	_ = r.Register(Info{
+		Name:        "shrug",
+		Description: "добавить ¯\\_(ツ)_/¯ к сообщению",
+		Owner:       OwnerBuiltin,
+	}, HandlerFunc(func(ctx context.Context, inv Invocation) (*Response, error) {
+		text := strings.TrimSpace(inv.Args + ` ¯\_(ツ)_/¯`)
+		return &Response{Text: text}, nil
+	}))
+}
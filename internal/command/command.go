@@ -0,0 +1,155 @@
+// Package command реализует slash-команды: сообщения вида "/name args"
+// перехватываются до сохранения и передаются зарегистрированному обработчику.
+package command
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var nameRe = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+
+var (
+	ErrInvalidName   = errors.New("command name must be 1-32 characters: a-z, 0-9, '_', '-'")
+	ErrAlreadyExists = errors.New("command is already registered")
+	ErrUnknown       = errors.New("unknown command")
+)
+
+// Invocation Вызов команды пользователем
+type Invocation struct {
+	Command  string `json:"command"`
+	Args     string `json:"args"`
+	Channel  string `json:"channel"`
+	UserID   string `json:"user_id"`
+	Username string `json:"username"`
+}
+
+// Response Ответ команды: публичный (сообщение в канал) или эфемерный (только вызвавшему).
+// Публичный ответ встроенной команды публикуется от имени пользователя, ответ бота — от имени бота.
+type Response struct {
+	Text      string `json:"text"`
+	Format    string `json:"format,omitempty"` // plain | markdown
+	Ephemeral bool   `json:"ephemeral"`
+}
+
+// Handler Обработчик команды: встроенный в процесс или внешний бот
+type Handler interface {
+	Handle(ctx context.Context, inv Invocation) (*Response, error)
+}
+
+// HandlerFunc Адаптер функции к Handler
+type HandlerFunc func(ctx context.Context, inv Invocation) (*Response, error)
+
+func (f HandlerFunc) Handle(ctx context.Context, inv Invocation) (*Response, error) {
+	return f(ctx, inv)
+}
+
+// Info Описание команды для /help и API
+type Info struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Owner       string `json:"owner"` // "builtin" или имя бота
+}
+
+type entry struct {
+	info    Info
+	handler Handler
+}
+
+// Registry Потокобезопасный реестр команд
+type Registry struct {
+	mu       sync.RWMutex
+	commands map[string]entry
+}
+
+func NewRegistry() *Registry {
+	return &Registry{commands: make(map[string]entry)}
+}
+
+func (r *Registry) Register(info Info, h Handler) error {
+	if !nameRe.MatchString(info.Name) {
+		return ErrInvalidName
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.commands[info.Name]; ok {
+		return ErrAlreadyExists
+	}
+	r.commands[info.Name] = entry{info: info, handler: h}
+	return nil
+}
+
+// Unregister удаляет команду, только если она принадлежит owner
+func (r *Registry) Unregister(name, owner string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if e, ok := r.commands[name]; ok && e.info.Owner == owner {
+		delete(r.commands, name)
+	}
+}
+
+func (r *Registry) Lookup(name string) (Handler, Info, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	e, ok := r.commands[name]
+	return e.handler, e.info, ok
+}
+
+func (r *Registry) List() []Info {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list := make([]Info, 0, len(r.commands))
+	for _, e := range r.commands {
+		list = append(list, e.info)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Parse выделяет команду из текста; "//" в начале — экранирование обычного сообщения
+func Parse(text string) (name, args string, ok bool) {
+	if !strings.HasPrefix(text, "/") || strings.HasPrefix(text, "//") {
+		return "", "", false
+	}
+
+	name, args, _ = strings.Cut(strings.TrimPrefix(text, "/"), " ")
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return "", "", false
+	}
+	return name, strings.TrimSpace(args), true
+}
+
+// Unescape убирает экранирующий "/" у сообщения, начинающегося с "//"
+func Unescape(text string) string {
+	if strings.HasPrefix(text, "//") {
+		return text[1:]
+	}
+	return text
+}
+
+// Dispatch находит и выполняет команду
+func (r *Registry) Dispatch(ctx context.Context, inv Invocation) (*Response, Info, error) {
+	h, info, ok := r.Lookup(inv.Command)
+	if !ok {
+		return nil, Info{}, ErrUnknown
+	}
+
+	resp, err := h.Handle(ctx, inv)
+	if err != nil {
+		return nil, info, err
+	}
+	if resp == nil {
+		resp = &Response{}
+	}
+	return resp, info, nil
+}
@@ -0,0 +1,73 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	webhookservice "github.com/QuUteO/video-communication/internal/webhook/service"
+)
+
+const maxBotResponse = 64 << 10
+
+// HTTPHandler Передает вызов внешнему боту POST-запросом, подписанным секретом бота.
+// Бот отвечает JSON в формате Response; пустое тело означает «без ответа».
+type HTTPHandler struct {
+	URL     string
+	Secret  string
+	Client  *http.Client
+	Timeout time.Duration // пользователь ждет ответа в чате
+}
+
+func (h *HTTPHandler) Handle(ctx context.Context, inv Invocation) (*Response, error) {
+	if h.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.Timeout)
+		defer cancel()
+	}
+
+	body, err := json.Marshal(inv)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhookservice.HeaderEvent, "command.invoked")
+	req.Header.Set(webhookservice.HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(webhookservice.HeaderSignature, webhookservice.Sign(h.Secret, timestamp, body))
+
+	resp, err := h.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("bot responded with %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxBotResponse))
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, nil
+	}
+
+	var out Response
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, fmt.Errorf("invalid bot response: %w", err)
+	}
+	return &out, nil
+}
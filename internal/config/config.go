@@ -11,6 +11,15 @@ type Config struct {
 	HTTPServer HTTPServer `yaml:"http_server"`
 	Postgres   Postgres   `yaml:"postgres"`
 	JWT        JWT        `yaml:"jwt"`
+	Storage    Storage    `yaml:"storage"`
+	Webhooks   Webhooks   `yaml:"webhooks"`
+	Scheduler  Scheduler  `yaml:"scheduler"`
+	Retention  Retention  `yaml:"retention"`
+	Admin      Admin      `yaml:"admin"`
+	Mail       Mail       `yaml:"mail"`
+	TwoFactor  TwoFactor  `yaml:"two_factor"`
+	Login      Login      `yaml:"login"`
+	OIDC       OIDC       `yaml:"oidc"`
 }
 
 type HTTPServer struct {
@@ -29,8 +38,111 @@ type Postgres struct {
 }
 
 type JWT struct {
-	Secret string        `yaml:"secret" env:"JWT_SECRET" env-required:"true"`
-	Ttl    time.Duration `yaml:"ttl" env:"JWT_TTL" env-default:"24h"`
+	Ttl        time.Duration `yaml:"ttl" env:"JWT_TTL" env-default:"15m"` // access-токен
+	RefreshTtl time.Duration `yaml:"refresh_ttl" env:"JWT_REFRESH_TTL" env-default:"720h"`
+	Issuer     string        `yaml:"issuer" env:"JWT_ISSUER" env-default:"video-communication"`
+	Audience   []string      `yaml:"audience" env:"JWT_AUDIENCE" env-default:"video-communication"`
+	Keys       JWTKeys       `yaml:"keys"`
+}
+
+type JWTKeys struct {
+	Dir         string        `yaml:"dir" env:"JWT_KEYS_DIR" env-default:"./data/jwt-keys"`
+	Algorithm   string        `yaml:"algorithm" env:"JWT_ALGORITHM" env-default:"EdDSA"` // RS256 | EdDSA
+	RotateEvery time.Duration `yaml:"rotate_every" env:"JWT_ROTATE_EVERY" env-default:"720h"`
+	Interval    time.Duration `yaml:"interval" env:"JWT_KEYS_INTERVAL" env-default:"1m"`
+}
+
+type Storage struct {
+	Driver        string   `yaml:"driver" env:"STORAGE_DRIVER" env-default:"local"` // local | s3
+	LocalDir      string   `yaml:"local_dir" env:"STORAGE_LOCAL_DIR" env-default:"./data/blobs"`
+	MaxUploadSize int64    `yaml:"max_upload_size" env:"STORAGE_MAX_UPLOAD_SIZE" env-default:"10485760"`
+	AllowedTypes  []string `yaml:"allowed_types" env:"STORAGE_ALLOWED_TYPES" env-default:"image/,text/plain,application/pdf,application/zip"`
+	S3            S3       `yaml:"s3"`
+}
+
+type S3 struct {
+	Endpoint  string `yaml:"endpoint" env:"S3_ENDPOINT" env-default:"localhost:9000"`
+	Region    string `yaml:"region" env:"S3_REGION" env-default:"us-east-1"`
+	Bucket    string `yaml:"bucket" env:"S3_BUCKET" env-default:"attachments"`
+	AccessKey string `yaml:"access_key" env:"S3_ACCESS_KEY"`
+	SecretKey string `yaml:"secret_key" env:"S3_SECRET_KEY"`
+	UseSSL    bool   `yaml:"use_ssl" env:"S3_USE_SSL" env-default:"false"`
+}
+
+type Webhooks struct {
+	Timeout      time.Duration `yaml:"timeout" env:"WEBHOOKS_TIMEOUT" env-default:"10s"`
+	MaxAttempts  int           `yaml:"max_attempts" env:"WEBHOOKS_MAX_ATTEMPTS" env-default:"8"`
+	BaseBackoff  time.Duration `yaml:"base_backoff" env:"WEBHOOKS_BASE_BACKOFF" env-default:"5s"`
+	MaxBackoff   time.Duration `yaml:"max_backoff" env:"WEBHOOKS_MAX_BACKOFF" env-default:"1h"`
+	PollInterval time.Duration `yaml:"poll_interval" env:"WEBHOOKS_POLL_INTERVAL" env-default:"2s"`
+	Workers      int           `yaml:"workers" env:"WEBHOOKS_WORKERS" env-default:"4"`
+	// CIDR-сети, куда можно доставлять вебхуки и вызовы ботов несмотря на запрет
+	// внутренних адресов, например 127.0.0.0/8 для локального приемника
+	AllowedNetworks []string `yaml:"allowed_networks" env:"WEBHOOKS_ALLOWED_NETWORKS"`
+}
+
+type Scheduler struct {
+	PollInterval time.Duration `yaml:"poll_interval" env:"SCHEDULER_POLL_INTERVAL" env-default:"1s"`
+	BatchSize    int           `yaml:"batch_size" env:"SCHEDULER_BATCH_SIZE" env-default:"50"`
+	Lease        time.Duration `yaml:"lease" env:"SCHEDULER_LEASE" env-default:"1m"`
+}
+
+type Retention struct {
+	Interval  time.Duration `yaml:"interval" env:"RETENTION_INTERVAL" env-default:"1m"`
+	BatchSize int           `yaml:"batch_size" env:"RETENTION_BATCH_SIZE" env-default:"500"`
+}
+
+type Mail struct {
+	Driver      string        `yaml:"driver" env:"MAIL_DRIVER" env-default:"log"` // log | file | smtp
+	From        string        `yaml:"from" env:"MAIL_FROM" env-default:"Video Communication <no-reply@localhost>"`
+	FileDir     string        `yaml:"file_dir" env:"MAIL_FILE_DIR" env-default:"./data/mail"`
+	LinkBaseURL string        `yaml:"link_base_url" env:"MAIL_LINK_BASE_URL" env-default:"http://localhost:8080"` // адрес фронтенда для ссылок в письмах
+	VerifyTTL   time.Duration `yaml:"verify_ttl" env:"MAIL_VERIFY_TTL" env-default:"48h"`
+	ResetTTL    time.Duration `yaml:"reset_ttl" env:"MAIL_RESET_TTL" env-default:"1h"`
+	SMTP        SMTP          `yaml:"smtp"`
+}
+
+type SMTP struct {
+	Host        string        `yaml:"host" env:"SMTP_HOST" env-default:"localhost"`
+	Port        int           `yaml:"port" env:"SMTP_PORT" env-default:"587"`
+	Username    string        `yaml:"username" env:"SMTP_USERNAME"`
+	Password    string        `yaml:"password" env:"SMTP_PASSWORD"`
+	ImplicitTLS bool          `yaml:"implicit_tls" env:"SMTP_IMPLICIT_TLS" env-default:"false"` // порт 465
+	Timeout     time.Duration `yaml:"timeout" env:"SMTP_TIMEOUT" env-default:"10s"`
+}
+
+// Login Защита входа от перебора паролей
+type Login struct {
+	MaxFailures         int           `yaml:"max_failures" env:"LOGIN_MAX_FAILURES" env-default:"10"` // неудач подряд до блокировки
+	Window              time.Duration `yaml:"window" env:"LOGIN_WINDOW" env-default:"15m"`            // после такой паузы счетчик начинается заново
+	Lockout             time.Duration `yaml:"lockout" env:"LOGIN_LOCKOUT" env-default:"15m"`
+	MaxDelay            time.Duration `yaml:"max_delay" env:"LOGIN_MAX_DELAY" env-default:"30s"` // предел прогрессивной задержки
+	IPFailuresPerMinute int           `yaml:"ip_failures_per_minute" env:"LOGIN_IP_FAILURES_PER_MINUTE" env-default:"30"`
+}
+
+// OIDC Вход через корпоративный SSO (authorization code + PKCE)
+type OIDC struct {
+	Enabled      bool     `yaml:"enabled" env:"OIDC_ENABLED" env-default:"false"`
+	Issuer       string   `yaml:"issuer" env:"OIDC_ISSUER"`
+	ClientID     string   `yaml:"client_id" env:"OIDC_CLIENT_ID"`
+	ClientSecret string   `yaml:"client_secret" env:"OIDC_CLIENT_SECRET"` // пусто — публичный клиент, только PKCE
+	RedirectURL  string   `yaml:"redirect_url" env:"OIDC_REDIRECT_URL" env-default:"http://localhost:8080/auth/oidc/callback"`
+	Scopes       []string `yaml:"scopes" env:"OIDC_SCOPES" env-default:"openid,email,profile"`
+	// куда вернуть браузер после входа; токены передаются во фрагменте адреса. Пусто — ответ JSON
+	PostLoginRedirect string        `yaml:"post_login_redirect" env:"OIDC_POST_LOGIN_REDIRECT"`
+	StateTTL          time.Duration `yaml:"state_ttl" env:"OIDC_STATE_TTL" env-default:"10m"`
+	Timeout           time.Duration `yaml:"timeout" env:"OIDC_TIMEOUT" env-default:"10s"`
+}
+
+// Admin Администраторы задаются ролью в users.role (подкоманда role или PUT /admin/users/{id}/role)
+type Admin struct {
+	RequireTwoFactor bool `yaml:"require_two_factor" env:"ADMIN_REQUIRE_TWO_FACTOR" env-default:"true"` // полномочия администратора только со вторым фактором
+}
+
+type TwoFactor struct {
+	Issuer        string        `yaml:"issuer" env:"TOTP_ISSUER" env-default:"Video Communication"` // подпись в приложении-аутентификаторе
+	EncryptionKey string        `yaml:"encryption_key" env:"TOTP_ENCRYPTION_KEY" env-required:"true"`
+	ChallengeTTL  time.Duration `yaml:"challenge_ttl" env:"TOTP_CHALLENGE_TTL" env-default:"5m"`
 }
 
 func New() (*Config, error) {
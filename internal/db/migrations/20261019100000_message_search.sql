@@ -0,0 +1,33 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE message
+    ADD COLUMN IF NOT EXISTS user_id UUID REFERENCES users (id) ON DELETE SET NULL;
+
+ALTER TABLE message
+    ADD COLUMN IF NOT EXISTS msg_tsv tsvector
+        GENERATED ALWAYS AS (to_tsvector('simple', coalesce(msg, ''))) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_message_msg_tsv ON message USING GIN (msg_tsv);
+CREATE INDEX IF NOT EXISTS idx_message_user_id ON message (user_id);
+
+-- Участники каналов: пользователь получает доступ к истории канала после входа в него
+CREATE TABLE IF NOT EXISTS channel_members
+(
+    channel   VARCHAR(255) NOT NULL,
+    user_id   UUID         NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    joined_at TIMESTAMP    NOT NULL DEFAULT now(),
+    PRIMARY KEY (channel, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_channel_members_user_id ON channel_members (user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_channel_members_user_id;
+DROP TABLE IF EXISTS channel_members;
+DROP INDEX IF EXISTS idx_message_user_id;
+DROP INDEX IF EXISTS idx_message_msg_tsv;
+ALTER TABLE message DROP COLUMN IF EXISTS msg_tsv;
+ALTER TABLE message DROP COLUMN IF EXISTS user_id;
+-- +goose StatementEnd
@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS attachments
+(
+    id           UUID PRIMARY KEY,
+    message_id   UUID REFERENCES message (id) ON DELETE CASCADE,
+    channel      VARCHAR(255) NOT NULL,
+    user_id      UUID         NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    file_name    VARCHAR(255) NOT NULL,
+    content_type VARCHAR(255) NOT NULL,
+    size         BIGINT       NOT NULL,
+    storage_key  VARCHAR(512) NOT NULL,
+    created_at   TIMESTAMP    NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_attachments_message_id ON attachments (message_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_attachments_message_id;
+DROP TABLE IF EXISTS attachments;
+-- +goose StatementEnd
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE attachments
+    ADD COLUMN IF NOT EXISTS width      INT,
+    ADD COLUMN IF NOT EXISTS height     INT,
+    ADD COLUMN IF NOT EXISTS thumbnails JSONB NOT NULL DEFAULT '[]';
+
+-- изображения, для которых миниатюры еще не построены
+CREATE INDEX IF NOT EXISTS idx_attachments_pending_images
+    ON attachments (created_at)
+    WHERE width IS NULL AND content_type LIKE 'image/%';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_attachments_pending_images;
+ALTER TABLE attachments
+    DROP COLUMN IF EXISTS thumbnails,
+    DROP COLUMN IF EXISTS height,
+    DROP COLUMN IF EXISTS width;
+-- +goose StatementEnd
@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+-- уникальное имя пользователя для @упоминаний
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS username VARCHAR(32);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username_lower ON users (lower(username));
+
+CREATE TABLE IF NOT EXISTS message_mentions
+(
+    message_id UUID        NOT NULL REFERENCES message (id) ON DELETE CASCADE,
+    user_id    UUID        NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    kind       VARCHAR(16) NOT NULL DEFAULT 'user', -- user | channel | here
+    created_at TIMESTAMP   NOT NULL DEFAULT now(),
+    PRIMARY KEY (message_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_message_mentions_user_created_at ON message_mentions (user_id, created_at DESC);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_message_mentions_user_created_at;
+DROP TABLE IF EXISTS message_mentions;
+DROP INDEX IF EXISTS idx_users_username_lower;
+ALTER TABLE users DROP COLUMN IF EXISTS username;
+-- +goose StatementEnd
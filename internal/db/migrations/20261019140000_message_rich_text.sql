@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+-- msg расширяется до TEXT; сгенерированную колонку поиска нужно пересоздать
+DROP INDEX IF EXISTS idx_message_msg_tsv;
+ALTER TABLE message DROP COLUMN IF EXISTS msg_tsv;
+
+ALTER TABLE message
+    ALTER COLUMN msg TYPE TEXT;
+
+ALTER TABLE message
+    ADD COLUMN msg_tsv tsvector
+        GENERATED ALWAYS AS (to_tsvector('simple', coalesce(msg, ''))) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_message_msg_tsv ON message USING GIN (msg_tsv);
+
+ALTER TABLE message
+    ADD COLUMN IF NOT EXISTS format VARCHAR(16) NOT NULL DEFAULT 'plain', -- plain | markdown
+    ADD COLUMN IF NOT EXISTS html   TEXT;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE message
+    DROP COLUMN IF EXISTS html,
+    DROP COLUMN IF EXISTS format;
+-- +goose StatementEnd
@@ -0,0 +1,56 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS webhook_subscriptions
+(
+    id         UUID PRIMARY KEY,
+    channel    VARCHAR(255)  NOT NULL,
+    url        VARCHAR(2048) NOT NULL,
+    secret     VARCHAR(128)  NOT NULL,
+    events     TEXT[]        NOT NULL,
+    created_by UUID REFERENCES users (id) ON DELETE SET NULL,
+    created_at TIMESTAMP     NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_subscriptions_channel ON webhook_subscriptions (channel);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries
+(
+    id               UUID PRIMARY KEY,
+    subscription_id  UUID         NOT NULL REFERENCES webhook_subscriptions (id) ON DELETE CASCADE,
+    event            VARCHAR(64)  NOT NULL,
+    payload          JSONB        NOT NULL,
+    status           VARCHAR(16)  NOT NULL DEFAULT 'pending', -- pending | delivered | dead
+    attempts         INT          NOT NULL DEFAULT 0,
+    last_status_code INT,
+    last_error       TEXT,
+    next_attempt_at  TIMESTAMP    NOT NULL DEFAULT now(),
+    created_at       TIMESTAMP    NOT NULL DEFAULT now(),
+    delivered_at     TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_subscription ON webhook_deliveries (subscription_id, created_at DESC);
+
+-- доставки, исчерпавшие все попытки
+CREATE TABLE IF NOT EXISTS webhook_dead_letters
+(
+    id              UUID PRIMARY KEY,
+    delivery_id     UUID        NOT NULL,
+    subscription_id UUID        NOT NULL REFERENCES webhook_subscriptions (id) ON DELETE CASCADE,
+    event           VARCHAR(64) NOT NULL,
+    payload         JSONB       NOT NULL,
+    attempts        INT         NOT NULL,
+    last_error      TEXT,
+    created_at      TIMESTAMP   NOT NULL DEFAULT now()
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS webhook_dead_letters;
+DROP INDEX IF EXISTS idx_webhook_deliveries_subscription;
+DROP INDEX IF EXISTS idx_webhook_deliveries_due;
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP INDEX IF EXISTS idx_webhook_subscriptions_channel;
+DROP TABLE IF EXISTS webhook_subscriptions;
+-- +goose StatementEnd
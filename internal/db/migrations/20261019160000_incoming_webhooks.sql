@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS incoming_webhooks
+(
+    id                    UUID PRIMARY KEY,
+    channel               VARCHAR(255) NOT NULL,
+    name                  VARCHAR(64)  NOT NULL, -- имя интеграции, от которого публикуются сообщения
+    token_hash            VARCHAR(64)  NOT NULL, -- sha256 от секретного токена
+    rate_limit_per_minute INT          NOT NULL DEFAULT 60,
+    created_by            UUID REFERENCES users (id) ON DELETE SET NULL,
+    created_at            TIMESTAMP    NOT NULL DEFAULT now(),
+    revoked_at            TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_incoming_webhooks_channel ON incoming_webhooks (channel);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_incoming_webhooks_channel;
+DROP TABLE IF EXISTS incoming_webhooks;
+-- +goose StatementEnd
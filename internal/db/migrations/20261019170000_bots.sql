@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS bots
+(
+    id         UUID PRIMARY KEY,
+    name       VARCHAR(32)  NOT NULL,
+    url        TEXT         NOT NULL,
+    secret     VARCHAR(64)  NOT NULL, -- ключ подписи запросов к боту
+    commands   TEXT[]       NOT NULL DEFAULT '{}',
+    created_by UUID REFERENCES users (id) ON DELETE SET NULL,
+    created_at TIMESTAMP    NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_bots_name ON bots (lower(name));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_bots_name;
+DROP TABLE IF EXISTS bots;
+-- +goose StatementEnd
@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS scheduled_messages
+(
+    id             UUID PRIMARY KEY, -- становится id сообщения при отправке
+    channel        VARCHAR(255) NOT NULL,
+    user_id        UUID         NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    username       VARCHAR(255) NOT NULL,
+    msg            TEXT         NOT NULL,
+    format         VARCHAR(16)  NOT NULL DEFAULT 'plain',
+    attachment_ids UUID[]       NOT NULL DEFAULT '{}', -- загруженные заранее вложения
+    send_at        TIMESTAMP    NOT NULL,
+    status         VARCHAR(16)  NOT NULL DEFAULT 'pending', -- pending | sending | sent | cancelled | failed
+    claimed_at     TIMESTAMP, -- когда планировщик взял сообщение в отправку
+    last_error     TEXT,
+    created_at     TIMESTAMP    NOT NULL DEFAULT now(),
+    sent_at        TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_scheduled_messages_due ON scheduled_messages (send_at)
+    WHERE status IN ('pending', 'sending');
+CREATE INDEX IF NOT EXISTS idx_scheduled_messages_user ON scheduled_messages (user_id, send_at)
+    WHERE status = 'pending';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_scheduled_messages_user;
+DROP INDEX IF EXISTS idx_scheduled_messages_due;
+DROP TABLE IF EXISTS scheduled_messages;
+-- +goose StatementEnd
@@ -0,0 +1,41 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE message
+    ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP; -- TTL конкретного сообщения
+
+CREATE INDEX IF NOT EXISTS idx_message_expires_at ON message (expires_at)
+    WHERE expires_at IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS channel_settings
+(
+    channel        VARCHAR(255) PRIMARY KEY,
+    retention_days INT CHECK (retention_days > 0), -- NULL: хранить всегда
+    updated_by     UUID REFERENCES users (id) ON DELETE SET NULL,
+    updated_at     TIMESTAMP NOT NULL DEFAULT now()
+);
+
+-- сообщение видно, пока не истек его TTL и срок хранения канала;
+-- используется во всех запросах чтения, чтобы не зависеть от того, успел ли отработать janitor
+CREATE OR REPLACE FUNCTION message_is_live(m_channel VARCHAR, m_created_at TIMESTAMP, m_expires_at TIMESTAMP)
+    RETURNS BOOLEAN
+    LANGUAGE sql
+    STABLE
+AS
+$$
+SELECT (m_expires_at IS NULL OR m_expires_at > now())
+           AND NOT EXISTS (SELECT 1
+                           FROM channel_settings cs
+                           WHERE cs.channel = m_channel
+                             AND cs.retention_days IS NOT NULL
+                             AND m_created_at < now() - make_interval(days => cs.retention_days))
+$$;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP FUNCTION IF EXISTS message_is_live(VARCHAR, TIMESTAMP, TIMESTAMP);
+DROP TABLE IF EXISTS channel_settings;
+DROP INDEX IF EXISTS idx_message_expires_at;
+ALTER TABLE message
+    DROP COLUMN IF EXISTS expires_at;
+-- +goose StatementEnd
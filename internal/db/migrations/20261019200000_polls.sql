@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS polls
+(
+    id         UUID PRIMARY KEY REFERENCES message (id) ON DELETE CASCADE, -- опрос — это сообщение типа poll
+    channel    VARCHAR(255) NOT NULL,
+    question   TEXT         NOT NULL,
+    options    TEXT[]       NOT NULL,
+    multiple   BOOLEAN      NOT NULL DEFAULT false,
+    anonymous  BOOLEAN      NOT NULL DEFAULT false,
+    created_by UUID REFERENCES users (id) ON DELETE SET NULL,
+    created_at TIMESTAMP    NOT NULL DEFAULT now(),
+    closed_at  TIMESTAMP,
+    results    JSONB -- итоги, зафиксированные при закрытии
+);
+
+CREATE TABLE IF NOT EXISTS poll_votes
+(
+    poll_id    UUID      NOT NULL REFERENCES polls (id) ON DELETE CASCADE,
+    option_idx INT       NOT NULL,
+    user_id    UUID      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    PRIMARY KEY (poll_id, user_id, option_idx)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS poll_votes;
+DROP TABLE IF EXISTS polls;
+-- +goose StatementEnd
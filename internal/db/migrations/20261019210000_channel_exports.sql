@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS channel_exports
+(
+    id            UUID PRIMARY KEY,
+    channel       VARCHAR(255) NOT NULL,
+    format        VARCHAR(16)  NOT NULL,                   -- jsonl | csv | html
+    status        VARCHAR(16)  NOT NULL DEFAULT 'pending', -- pending | running | done | failed
+    requested_by  UUID REFERENCES users (id) ON DELETE SET NULL,
+    storage_key   VARCHAR(512),
+    size          BIGINT       NOT NULL DEFAULT 0,
+    message_count BIGINT       NOT NULL DEFAULT 0,
+    error         TEXT,
+    created_at    TIMESTAMP    NOT NULL DEFAULT now(),
+    started_at    TIMESTAMP,
+    finished_at   TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_channel_exports_channel ON channel_exports (channel, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_channel_exports_queue ON channel_exports (created_at)
+    WHERE status IN ('pending', 'running');
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_channel_exports_queue;
+DROP INDEX IF EXISTS idx_channel_exports_channel;
+DROP TABLE IF EXISTS channel_exports;
+-- +goose StatementEnd
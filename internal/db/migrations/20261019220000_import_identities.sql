@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+-- соответствие пользователей внешней системы локальным пользователям
+CREATE TABLE IF NOT EXISTS import_identities
+(
+    source     VARCHAR(64)  NOT NULL, -- имя системы-источника (slack, mattermost, ...)
+    foreign_id VARCHAR(255) NOT NULL,
+    user_id    UUID         NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    created_at TIMESTAMP    NOT NULL DEFAULT now(),
+    PRIMARY KEY (source, foreign_id)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS import_identities;
+-- +goose StatementEnd
@@ -0,0 +1,33 @@
+-- +goose Up
+-- +goose StatementBegin
+-- сессия — семейство refresh-токенов одного входа; access-токены несут ее id в claim "sid"
+CREATE TABLE IF NOT EXISTS auth_sessions
+(
+    id             UUID PRIMARY KEY,
+    user_id        UUID        NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    created_at     TIMESTAMP   NOT NULL DEFAULT now(),
+    expires_at     TIMESTAMP   NOT NULL,
+    revoked_at     TIMESTAMP,
+    revoked_reason VARCHAR(32)
+);
+
+CREATE INDEX IF NOT EXISTS idx_auth_sessions_user ON auth_sessions (user_id);
+
+CREATE TABLE IF NOT EXISTS refresh_tokens
+(
+    id         UUID PRIMARY KEY,
+    session_id UUID      NOT NULL REFERENCES auth_sessions (id) ON DELETE CASCADE,
+    token_hash CHAR(64)  NOT NULL UNIQUE, -- sha256 hex, сам токен не хранится
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    expires_at TIMESTAMP NOT NULL,
+    used_at    TIMESTAMP -- токен одноразовый: повторное предъявление отзывает всю сессию
+);
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_session ON refresh_tokens (session_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS refresh_tokens;
+DROP TABLE IF EXISTS auth_sessions;
+-- +goose StatementEnd
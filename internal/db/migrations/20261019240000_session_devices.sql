@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE auth_sessions
+    ADD COLUMN IF NOT EXISTS user_agent   VARCHAR(512) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS device       VARCHAR(128) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS ip           VARCHAR(64)  NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS last_seen_at TIMESTAMP    NOT NULL DEFAULT now();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE auth_sessions
+    DROP COLUMN IF EXISTS user_agent,
+    DROP COLUMN IF EXISTS device,
+    DROP COLUMN IF EXISTS ip,
+    DROP COLUMN IF EXISTS last_seen_at;
+-- +goose StatementEnd
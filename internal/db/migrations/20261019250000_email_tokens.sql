@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMP;
+
+-- одноразовые токены из писем: подтверждение адреса и сброс пароля
+CREATE TABLE IF NOT EXISTS auth_tokens
+(
+    id         UUID PRIMARY KEY,
+    user_id    UUID         NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    purpose    VARCHAR(32)  NOT NULL, -- verify_email | reset_password
+    email      VARCHAR(255) NOT NULL, -- адрес, на который ушло письмо
+    token_hash CHAR(64)     NOT NULL UNIQUE,
+    created_at TIMESTAMP    NOT NULL DEFAULT now(),
+    expires_at TIMESTAMP    NOT NULL,
+    used_at    TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_auth_tokens_user ON auth_tokens (user_id, purpose, created_at DESC);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS auth_tokens;
+ALTER TABLE users
+    DROP COLUMN IF EXISTS email_verified_at;
+-- +goose StatementEnd
@@ -0,0 +1,48 @@
+-- +goose Up
+-- +goose StatementBegin
+-- TOTP-секрет хранится зашифрованным (AES-GCM, ключ из конфига)
+CREATE TABLE IF NOT EXISTS user_totp
+(
+    user_id    UUID PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    secret     TEXT      NOT NULL,
+    enabled_at TIMESTAMP,          -- NULL, пока владелец не подтвердил код
+    last_step  BIGINT    NOT NULL DEFAULT 0, -- последний принятый шаг, защита от повтора кода
+    created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS recovery_codes
+(
+    id         UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id    UUID      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    code_hash  CHAR(64)  NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    used_at    TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_recovery_codes_user ON recovery_codes (user_id);
+
+-- второй шаг входа: выдается после пароля, меняется на токены после кода
+CREATE TABLE IF NOT EXISTS login_challenges
+(
+    id         UUID PRIMARY KEY,
+    user_id    UUID      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    token_hash CHAR(64)  NOT NULL UNIQUE,
+    attempts   INT       NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    expires_at TIMESTAMP NOT NULL,
+    used_at    TIMESTAMP
+);
+
+-- сессии, открытые со вторым фактором (claim amr в access-токене)
+ALTER TABLE auth_sessions
+    ADD COLUMN IF NOT EXISTS mfa BOOLEAN NOT NULL DEFAULT false;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE auth_sessions
+    DROP COLUMN IF EXISTS mfa;
+DROP TABLE IF EXISTS login_challenges;
+DROP TABLE IF EXISTS recovery_codes;
+DROP TABLE IF EXISTS user_totp;
+-- +goose StatementEnd
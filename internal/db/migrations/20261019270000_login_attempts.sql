@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+-- неудачные входы по адресу, а не по пользователю: несуществующие адреса блокируются так же,
+-- поэтому по ответам нельзя узнать, зарегистрирован ли адрес
+CREATE TABLE IF NOT EXISTS login_attempts
+(
+    email_key      VARCHAR(255) PRIMARY KEY, -- lower(email)
+    failures       INT       NOT NULL DEFAULT 0,
+    last_failed_at TIMESTAMP NOT NULL DEFAULT now(),
+    locked_until   TIMESTAMP
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS login_attempts;
+-- +goose StatementEnd
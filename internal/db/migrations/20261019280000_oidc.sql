@@ -0,0 +1,34 @@
+-- +goose Up
+-- +goose StatementBegin
+-- незавершенные входы через SSO: state, nonce и PKCE code_verifier до возврата с провайдера
+CREATE TABLE IF NOT EXISTS oidc_states
+(
+    state_hash    CHAR(64) PRIMARY KEY,
+    nonce         VARCHAR(64) NOT NULL,
+    code_verifier VARCHAR(128) NOT NULL,
+    created_at    TIMESTAMP   NOT NULL DEFAULT now(),
+    expires_at    TIMESTAMP   NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_oidc_states_expires ON oidc_states (expires_at);
+
+-- учетные записи провайдера, привязанные к локальным пользователям
+CREATE TABLE IF NOT EXISTS oidc_identities
+(
+    issuer     VARCHAR(255) NOT NULL,
+    subject    VARCHAR(255) NOT NULL,
+    user_id    UUID         NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    email      VARCHAR(255) NOT NULL, -- адрес на момент привязки
+    created_at TIMESTAMP    NOT NULL DEFAULT now(),
+    last_login TIMESTAMP    NOT NULL DEFAULT now(),
+    PRIMARY KEY (issuer, subject)
+);
+
+CREATE INDEX IF NOT EXISTS idx_oidc_identities_user ON oidc_identities (user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS oidc_identities;
+DROP TABLE IF EXISTS oidc_states;
+-- +goose StatementEnd
@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+-- личные ключи API для скриптов; в базе только хеш ключа
+CREATE TABLE IF NOT EXISTS api_keys
+(
+    id           UUID PRIMARY KEY,
+    user_id      UUID        NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name         VARCHAR(64) NOT NULL,
+    prefix       VARCHAR(16) NOT NULL, -- начало ключа, чтобы узнать его в списке
+    key_hash     CHAR(64)    NOT NULL UNIQUE,
+    scopes       TEXT[]      NOT NULL,
+    created_at   TIMESTAMP   NOT NULL DEFAULT now(),
+    expires_at   TIMESTAMP,            -- NULL — бессрочный
+    last_used_at TIMESTAMP,
+    revoked_at   TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_user ON api_keys (user_id, created_at DESC);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS api_keys;
+-- +goose StatementEnd
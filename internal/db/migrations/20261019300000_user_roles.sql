@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+-- глобальная роль пользователя; заменяет список администраторов из конфигурации
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS role VARCHAR(16) NOT NULL DEFAULT 'user'
+        CONSTRAINT users_role_check CHECK (role IN ('user', 'admin'));
+
+CREATE INDEX IF NOT EXISTS idx_users_admins ON users (id) WHERE role = 'admin';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_users_admins;
+ALTER TABLE users
+    DROP COLUMN IF EXISTS role;
+-- +goose StatementEnd
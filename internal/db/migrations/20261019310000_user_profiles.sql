@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS display_name      VARCHAR(64),
+    ADD COLUMN IF NOT EXISTS avatar_id         UUID REFERENCES attachments (id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS bio               VARCHAR(500),
+    ADD COLUMN IF NOT EXISTS timezone          VARCHAR(64),
+    ADD COLUMN IF NOT EXISTS status_text       VARCHAR(100),
+    ADD COLUMN IF NOT EXISTS status_expires_at TIMESTAMP, -- UTC; NULL — статус без срока
+    ADD COLUMN IF NOT EXISTS profile_updated_at TIMESTAMP;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users
+    DROP COLUMN IF EXISTS display_name,
+    DROP COLUMN IF EXISTS avatar_id,
+    DROP COLUMN IF EXISTS bio,
+    DROP COLUMN IF EXISTS timezone,
+    DROP COLUMN IF EXISTS status_text,
+    DROP COLUMN IF EXISTS status_expires_at,
+    DROP COLUMN IF EXISTS profile_updated_at;
+-- +goose StatementEnd
@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+-- keyset-пагинация сравнивает (ключ, id), поэтому ключ сортировки не может быть NULL
+UPDATE users
+SET created_at = now()
+WHERE created_at IS NULL;
+
+ALTER TABLE users
+    ALTER COLUMN created_at SET NOT NULL;
+
+-- поиск по префиксу email и отображаемого имени (LIKE 'abc%' при любой collation)
+CREATE INDEX IF NOT EXISTS idx_users_email_prefix ON users (lower(email) text_pattern_ops);
+CREATE INDEX IF NOT EXISTS idx_users_display_name_prefix ON users (lower(display_name) text_pattern_ops);
+
+-- сортировка списка пользователей
+CREATE INDEX IF NOT EXISTS idx_users_email_sort ON users (lower(email), id);
+CREATE INDEX IF NOT EXISTS idx_users_name_sort ON users (lower(COALESCE(NULLIF(display_name, ''), username, email)), id);
+CREATE INDEX IF NOT EXISTS idx_users_created_at_sort ON users (created_at, id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_users_created_at_sort;
+DROP INDEX IF EXISTS idx_users_name_sort;
+DROP INDEX IF EXISTS idx_users_email_sort;
+DROP INDEX IF EXISTS idx_users_display_name_prefix;
+DROP INDEX IF EXISTS idx_users_email_prefix;
+
+ALTER TABLE users
+    ALTER COLUMN created_at DROP NOT NULL;
+-- +goose StatementEnd
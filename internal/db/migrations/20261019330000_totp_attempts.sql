@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+-- неверные коды при управлении 2FA (включение, отключение, новые коды восстановления)
+ALTER TABLE user_totp
+    ADD COLUMN IF NOT EXISTS failed_attempts INT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS locked_until    TIMESTAMP;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE user_totp
+    DROP COLUMN IF EXISTS locked_until,
+    DROP COLUMN IF EXISTS failed_attempts;
+-- +goose StatementEnd
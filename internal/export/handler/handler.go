@@ -0,0 +1,162 @@
+package exporthandler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	authmiddleware "github.com/QuUteO/video-communication/internal/auth/middleware"
+	"github.com/QuUteO/video-communication/internal/export/service"
+	"github.com/QuUteO/video-communication/internal/model"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+type Handler struct {
+	service exportservice.Service
+	logger  *slog.Logger
+}
+
+func NewHandler(service exportservice.Service, logger *slog.Logger) *Handler {
+	return &Handler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// Create POST /channels/{channel}/exports
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	const op = "internal/export/handler/Create"
+	log := h.logger.With("op: ", op)
+
+	var req model.ChannelExportRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.error(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	if format := r.URL.Query().Get("format"); format != "" {
+		req.Format = format
+	}
+
+	userID, _ := r.Context().Value(authmiddleware.UserIDKey).(string)
+
+	job, err := h.service.Create(r.Context(), userID, chi.URLParam(r, "channel"), &req)
+	if err != nil {
+		h.fail(w, r, log, err)
+		return
+	}
+
+	render.Status(r, http.StatusAccepted)
+	render.JSON(w, r, model.Response{
+		StatusCode: http.StatusAccepted,
+		Message:    "Export queued",
+		Data:       job,
+		Error:      "nil",
+	})
+}
+
+// List GET /channels/{channel}/exports
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	const op = "internal/export/handler/List"
+	log := h.logger.With("op: ", op)
+
+	userID, _ := r.Context().Value(authmiddleware.UserIDKey).(string)
+
+	jobs, err := h.service.List(r.Context(), userID, chi.URLParam(r, "channel"))
+	if err != nil {
+		h.fail(w, r, log, err)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, model.Response{
+		StatusCode: http.StatusOK,
+		Message:    "Exports retrieved successfully",
+		Data:       jobs,
+		Error:      "nil",
+	})
+}
+
+// Get GET /channels/{channel}/exports/{id}
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
+	const op = "internal/export/handler/Get"
+	log := h.logger.With("op: ", op)
+
+	userID, _ := r.Context().Value(authmiddleware.UserIDKey).(string)
+
+	job, err := h.service.Get(r.Context(), userID, chi.URLParam(r, "channel"), chi.URLParam(r, "id"))
+	if err != nil {
+		h.fail(w, r, log, err)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, model.Response{
+		StatusCode: http.StatusOK,
+		Message:    "Export retrieved successfully",
+		Data:       job,
+		Error:      "nil",
+	})
+}
+
+// Download GET /channels/{channel}/exports/{id}/download
+func (h *Handler) Download(w http.ResponseWriter, r *http.Request) {
+	const op = "internal/export/handler/Download"
+	log := h.logger.With("op: ", op)
+
+	userID, _ := r.Context().Value(authmiddleware.UserIDKey).(string)
+
+	job, body, err := h.service.Open(r.Context(), userID, chi.URLParam(r, "channel"), chi.URLParam(r, "id"))
+	if err != nil {
+		h.fail(w, r, log, err)
+		return
+	}
+	defer body.Close()
+
+	fileName := "channel-" + strings.NewReplacer("/", "_", "\\", "_").Replace(job.Channel) + "-" +
+		job.CreatedAt.Format("20060102-150405") + "." + job.Format
+
+	w.Header().Set("Content-Type", exportservice.ContentType(job.Format))
+	w.Header().Set("Content-Length", strconv.FormatInt(job.Size, 10))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": fileName}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := io.Copy(w, body); err != nil {
+		log.Error("Failed to stream export", slog.Any("error", err))
+	}
+}
+
+func (h *Handler) fail(w http.ResponseWriter, r *http.Request, log *slog.Logger, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, exportservice.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, exportservice.ErrForbidden):
+		status = http.StatusForbidden
+	case errors.Is(err, exportservice.ErrNotReady):
+		status = http.StatusConflict
+	case errors.Is(err, exportservice.ErrInvalidFormat):
+		status = http.StatusBadRequest
+	default:
+		log.Error("Export request failed", slog.Any("error", err))
+	}
+
+	h.error(w, r, status, err.Error())
+}
+
+func (h *Handler) error(w http.ResponseWriter, r *http.Request, status int, msg string) {
+	render.Status(r, status)
+	render.JSON(w, r, model.Response{
+		StatusCode: status,
+		Error:      msg,
+	})
+}
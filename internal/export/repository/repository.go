@@ -0,0 +1,182 @@
+package exportrepository
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+
+	"github.com/QuUteO/video-communication/internal/model"
+	postgres "github.com/QuUteO/video-communication/pkg/db"
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+const selectColumns = `id, channel, format, status, requested_by, COALESCE(storage_key, ''), size, message_count,
+	COALESCE(error, ''), created_at, started_at, finished_at`
+
+type Repository interface {
+	Create(ctx context.Context, job *model.ChannelExport) error
+	FindByID(ctx context.Context, channel, id string) (*model.ChannelExport, error)
+	List(ctx context.Context, channel string, limit int) ([]model.ChannelExport, error)
+
+	Claim(ctx context.Context, leaseSeconds float64) (*model.ChannelExport, error)
+	MarkDone(ctx context.Context, id uuid.UUID, storageKey string, size, count int64) error
+	MarkFailed(ctx context.Context, id uuid.UUID, lastError string) error
+
+	// StreamMessages передает сообщения канала по одному, не накапливая их в памяти
+	StreamMessages(ctx context.Context, channel string, fn func(*model.ExportRecord) error) (int64, error)
+}
+
+type repository struct {
+	db     postgres.Client
+	logger *slog.Logger
+}
+
+func (r *repository) Create(ctx context.Context, job *model.ChannelExport) error {
+	const op = "./internal/export/repository.Create"
+	log := r.logger.With("op: ", op)
+
+	q := `
+		INSERT INTO channel_exports (id, channel, format, status, requested_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+
+	if _, err := r.db.Exec(ctx, q, job.ID, job.Channel, job.Format, job.Status, job.RequestedBy, job.CreatedAt); err != nil {
+		log.Error("Error to insert export", slog.Any("err", err))
+		return err
+	}
+
+	return nil
+}
+
+func (r *repository) FindByID(ctx context.Context, channel, id string) (*model.ChannelExport, error) {
+	q := `SELECT ` + selectColumns + ` FROM channel_exports WHERE id = $1 AND channel = $2`
+
+	return scanExport(r.db.QueryRow(ctx, q, id, channel))
+}
+
+func (r *repository) List(ctx context.Context, channel string, limit int) ([]model.ChannelExport, error) {
+	const op = "./internal/export/repository.List"
+	log := r.logger.With("op: ", op)
+
+	q := `SELECT ` + selectColumns + ` FROM channel_exports WHERE channel = $1 ORDER BY created_at DESC LIMIT $2`
+
+	rows, err := r.db.Query(ctx, q, channel, limit)
+	if err != nil {
+		log.Error("Error to list exports", slog.Any("err", err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := make([]model.ChannelExport, 0)
+	for rows.Next() {
+		job, err := scanExport(rows)
+		if err != nil {
+			log.Error("Error to scan export", slog.Any("err", err))
+			return nil, err
+		}
+		jobs = append(jobs, *job)
+	}
+
+	return jobs, rows.Err()
+}
+
+// Claim берет следующее задание; задание, зависшее в 'running' дольше аренды, берется повторно
+func (r *repository) Claim(ctx context.Context, leaseSeconds float64) (*model.ChannelExport, error) {
+	q := `
+		UPDATE channel_exports
+		SET status = 'running', started_at = now()
+		WHERE id = (
+			SELECT id FROM channel_exports
+			WHERE status = 'pending'
+			   OR (status = 'running' AND started_at < now() - make_interval(secs => $1))
+			ORDER BY created_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + selectColumns
+
+	return scanExport(r.db.QueryRow(ctx, q, leaseSeconds))
+}
+
+func (r *repository) MarkDone(ctx context.Context, id uuid.UUID, storageKey string, size, count int64) error {
+	q := `
+		UPDATE channel_exports
+		SET status = 'done', storage_key = $2, size = $3, message_count = $4, error = NULL, finished_at = now()
+		WHERE id = $1
+	`
+
+	_, err := r.db.Exec(ctx, q, id, storageKey, size, count)
+	return err
+}
+
+func (r *repository) MarkFailed(ctx context.Context, id uuid.UUID, lastError string) error {
+	_, err := r.db.Exec(ctx, `UPDATE channel_exports SET status = 'failed', error = $2, finished_at = now() WHERE id = $1`, id, lastError)
+	return err
+}
+
+func (r *repository) StreamMessages(ctx context.Context, channel string, fn func(*model.ExportRecord) error) (int64, error) {
+	const op = "./internal/export/repository.StreamMessages"
+	log := r.logger.With("op: ", op)
+
+	q := `
+		SELECT m.id, m.type, m.user_id, m.username, m.msg, m.format, COALESCE(m.html, ''), m.created_at,
+		       COALESCE((SELECT json_agg(json_build_object(
+		                    'id', a.id, 'file_name', a.file_name, 'content_type', a.content_type, 'size', a.size)
+		                    ORDER BY a.created_at)
+		                 FROM attachments a
+		                 WHERE a.message_id = m.id), '[]')
+		FROM message m
+		WHERE m.channel = $1 AND message_is_live(m.channel, m.created_at, m.expires_at)
+		ORDER BY m.created_at, m.id
+	`
+
+	rows, err := r.db.Query(ctx, q, channel)
+	if err != nil {
+		log.Error("Error to query messages for export", slog.Any("err", err))
+		return 0, err
+	}
+	defer rows.Close()
+
+	var count int64
+	for rows.Next() {
+		var rec model.ExportRecord
+		var userID uuid.NullUUID
+		var attachments []byte
+
+		if err := rows.Scan(&rec.ID, &rec.Type, &userID, &rec.User, &rec.Msg, &rec.Format, &rec.HTML, &rec.Time, &attachments); err != nil {
+			return count, err
+		}
+		rec.UserID = userID.UUID
+		if err := json.Unmarshal(attachments, &rec.Attachments); err != nil {
+			return count, err
+		}
+
+		if err := fn(&rec); err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	return count, rows.Err()
+}
+
+func scanExport(row pgx.Row) (*model.ChannelExport, error) {
+	var job model.ChannelExport
+	var requestedBy uuid.NullUUID
+
+	if err := row.Scan(&job.ID, &job.Channel, &job.Format, &job.Status, &requestedBy, &job.StorageKey, &job.Size,
+		&job.MessageCount, &job.Error, &job.CreatedAt, &job.StartedAt, &job.FinishedAt); err != nil {
+		return nil, err
+	}
+	job.RequestedBy = requestedBy.UUID
+
+	return &job, nil
+}
+
+func New(db postgres.Client, logger *slog.Logger) Repository {
+	return &repository{
+		db:     db,
+		logger: logger,
+	}
+}
@@ -0,0 +1,167 @@
+package exportservice
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/QuUteO/video-communication/internal/model"
+)
+
+// Форматы выгрузки
+const (
+	FormatJSONL = "jsonl"
+	FormatCSV   = "csv"
+	FormatHTML  = "html"
+)
+
+var contentTypes = map[string]string{
+	FormatJSONL: "application/x-ndjson",
+	FormatCSV:   "text/csv; charset=utf-8",
+	FormatHTML:  "text/html; charset=utf-8",
+}
+
+// ContentType MIME-тип файла выгрузки
+func ContentType(format string) string {
+	return contentTypes[format]
+}
+
+// encoder Пишет выгрузку потоком: заголовок, записи по одной, завершение
+type encoder interface {
+	header() error
+	record(rec *model.ExportRecord) error
+	footer(count int64) error
+}
+
+func newEncoder(format, channel string, w io.Writer) encoder {
+	switch format {
+	case FormatCSV:
+		return &csvEncoder{w: csv.NewWriter(w)}
+	case FormatHTML:
+		return &htmlEncoder{w: w, channel: channel}
+	default:
+		return &jsonlEncoder{enc: json.NewEncoder(w)}
+	}
+}
+
+// jsonlEncoder одно сообщение в строке
+type jsonlEncoder struct {
+	enc *json.Encoder
+}
+
+func (e *jsonlEncoder) header() error                        { return nil }
+func (e *jsonlEncoder) record(rec *model.ExportRecord) error { return e.enc.Encode(rec) }
+func (e *jsonlEncoder) footer(int64) error                   { return nil }
+
+// csvEncoder вложения перечисляются через ";" в одной колонке
+type csvEncoder struct {
+	w *csv.Writer
+}
+
+func (e *csvEncoder) header() error {
+	return e.w.Write([]string{"id", "time", "user_id", "user", "type", "format", "msg", "attachments"})
+}
+
+func (e *csvEncoder) record(rec *model.ExportRecord) error {
+	names := make([]string, 0, len(rec.Attachments))
+	for _, a := range rec.Attachments {
+		names = append(names, a.FileName)
+	}
+
+	userID := ""
+	if !rec.UserID.IsNil() {
+		userID = rec.UserID.String()
+	}
+
+	return e.w.Write([]string{
+		rec.ID.String(),
+		rec.Time.UTC().Format(time.RFC3339),
+		userID,
+		rec.User,
+		rec.Type,
+		rec.Format,
+		rec.Msg,
+		strings.Join(names, ";"),
+	})
+}
+
+func (e *csvEncoder) footer(int64) error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// htmlEncoder самодостаточная страница без внешних ресурсов и скриптов
+type htmlEncoder struct {
+	w       io.Writer
+	channel string
+}
+
+const htmlHead = `<!DOCTYPE html>
+<html lang="ru">
+<head>
+<meta charset="utf-8">
+<meta http-equiv="Content-Security-Policy" content="default-src 'none'; style-src 'unsafe-inline'">
+<title>%s</title>
+<style>
+body{font-family:-apple-system,Segoe UI,Roboto,sans-serif;max-width:960px;margin:2em auto;padding:0 1em;color:#1d1d1f}
+.msg{padding:.5em 0;border-bottom:1px solid #eee}
+.meta{color:#6e6e73;font-size:.85em}
+.user{font-weight:600;color:#1d1d1f}
+.system .text{color:#6e6e73;font-style:italic}
+.text{margin-top:.25em;white-space:pre-wrap;word-wrap:break-word}
+.text.markdown{white-space:normal}
+.attachments{margin:.25em 0 0;padding-left:1.2em;font-size:.85em}
+footer{margin-top:2em;color:#6e6e73;font-size:.85em}
+</style>
+</head>
+<body>
+<h1>#%s</h1>
+`
+
+func (e *htmlEncoder) header() error {
+	title := html.EscapeString(e.channel)
+	_, err := fmt.Fprintf(e.w, htmlHead, title, title)
+	return err
+}
+
+func (e *htmlEncoder) record(rec *model.ExportRecord) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, `<div class="msg %s" id="m-%s">`, html.EscapeString(rec.Type), rec.ID)
+	fmt.Fprintf(&b, `<div class="meta"><span class="user">%s</span> · <time datetime="%s">%s</time></div>`,
+		html.EscapeString(rec.User),
+		rec.Time.UTC().Format(time.RFC3339),
+		rec.Time.UTC().Format("2006-01-02 15:04:05 UTC"),
+	)
+
+	// HTML markdown-сообщений уже очищен при сохранении
+	if rec.HTML != "" {
+		fmt.Fprintf(&b, `<div class="text markdown">%s</div>`, rec.HTML)
+	} else {
+		fmt.Fprintf(&b, `<div class="text">%s</div>`, html.EscapeString(rec.Msg))
+	}
+
+	if len(rec.Attachments) > 0 {
+		b.WriteString(`<ul class="attachments">`)
+		for _, a := range rec.Attachments {
+			fmt.Fprintf(&b, `<li>%s (%s, %s bytes)</li>`,
+				html.EscapeString(a.FileName), html.EscapeString(a.ContentType), strconv.FormatInt(a.Size, 10))
+		}
+		b.WriteString(`</ul>`)
+	}
+	b.WriteString("</div>\n")
+
+	_, err := io.WriteString(e.w, b.String())
+	return err
+}
+
+func (e *htmlEncoder) footer(count int64) error {
+	_, err := fmt.Fprintf(e.w, "<footer>Сообщений: %d · выгружено %s</footer>\n</body>\n</html>\n",
+		count, time.Now().UTC().Format("2006-01-02 15:04 UTC"))
+	return err
+}
@@ -0,0 +1,169 @@
+package exportservice
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/url"
+	"time"
+
+	"github.com/QuUteO/video-communication/internal/export/repository"
+	"github.com/QuUteO/video-communication/internal/model"
+	"github.com/QuUteO/video-communication/internal/user/service"
+	"github.com/QuUteO/video-communication/pkg/storage"
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+const listLimit = 50
+
+var (
+	ErrNotFound      = errors.New("export not found")
+	ErrForbidden     = errors.New("no access to channel")
+	ErrInvalidFormat = errors.New("format must be one of: jsonl, csv, html")
+	ErrNotReady      = errors.New("export is not ready yet")
+)
+
+type Service interface {
+	Create(ctx context.Context, userID, channel string, req *model.ChannelExportRequest) (*model.ChannelExport, error)
+	Get(ctx context.Context, userID, channel, id string) (*model.ChannelExport, error)
+	List(ctx context.Context, userID, channel string) ([]model.ChannelExport, error)
+	Open(ctx context.Context, userID, channel, id string) (*model.ChannelExport, io.ReadCloser, error)
+}
+
+type ExportService struct {
+	repo   exportrepository.Repository
+	store  storage.Storage
+	users  service.Service
+	worker *Worker
+	logger *slog.Logger
+}
+
+func (s *ExportService) Create(ctx context.Context, userID, channel string, req *model.ChannelExportRequest) (*model.ChannelExport, error) {
+	const op = "internal/export/service.Create"
+	log := s.logger.With("op: ", op)
+
+	if err := s.checkAccess(ctx, channel, userID); err != nil {
+		return nil, err
+	}
+
+	format := req.Format
+	if format == "" {
+		format = FormatJSONL
+	}
+	if ContentType(format) == "" {
+		return nil, ErrInvalidFormat
+	}
+
+	job := &model.ChannelExport{
+		ID:          uuid.Must(uuid.NewV4()),
+		Channel:     channel,
+		Format:      format,
+		Status:      "pending",
+		RequestedBy: uuid.FromStringOrNil(userID),
+		CreatedAt:   time.Now(),
+	}
+
+	if err := s.repo.Create(ctx, job); err != nil {
+		log.Error("Error creating export", slog.Any("error", err))
+		return nil, err
+	}
+	s.worker.Wake()
+
+	return withURL(job), nil
+}
+
+func (s *ExportService) Get(ctx context.Context, userID, channel, id string) (*model.ChannelExport, error) {
+	if err := s.checkAccess(ctx, channel, userID); err != nil {
+		return nil, err
+	}
+
+	job, err := s.find(ctx, channel, id)
+	if err != nil {
+		return nil, err
+	}
+	return withURL(job), nil
+}
+
+func (s *ExportService) List(ctx context.Context, userID, channel string) ([]model.ChannelExport, error) {
+	if err := s.checkAccess(ctx, channel, userID); err != nil {
+		return nil, err
+	}
+
+	jobs, err := s.repo.List(ctx, channel, listLimit)
+	if err != nil {
+		return nil, err
+	}
+	for i := range jobs {
+		withURL(&jobs[i])
+	}
+	return jobs, nil
+}
+
+func (s *ExportService) Open(ctx context.Context, userID, channel, id string) (*model.ChannelExport, io.ReadCloser, error) {
+	if err := s.checkAccess(ctx, channel, userID); err != nil {
+		return nil, nil, err
+	}
+
+	job, err := s.find(ctx, channel, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if job.Status != "done" {
+		return nil, nil, ErrNotReady
+	}
+
+	body, err := s.store.Get(ctx, job.StorageKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, nil, ErrNotFound
+		}
+		return nil, nil, err
+	}
+
+	return job, body, nil
+}
+
+func (s *ExportService) find(ctx context.Context, channel, id string) (*model.ChannelExport, error) {
+	if _, err := uuid.FromString(id); err != nil {
+		return nil, ErrNotFound
+	}
+
+	job, err := s.repo.FindByID(ctx, channel, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return job, nil
+}
+
+func (s *ExportService) checkAccess(ctx context.Context, channel, userID string) error {
+	member, err := s.users.IsChannelMember(ctx, channel, userID)
+	if err != nil {
+		return err
+	}
+	if !member {
+		return ErrForbidden
+	}
+	return nil
+}
+
+func withURL(job *model.ChannelExport) *model.ChannelExport {
+	if job.Status == "done" {
+		job.DownloadURL = "/channels/" + url.PathEscape(job.Channel) + "/exports/" + job.ID.String() + "/download"
+	}
+	return job
+}
+
+func NewExportService(repo exportrepository.Repository, store storage.Storage, users service.Service, worker *Worker, logger *slog.Logger) Service {
+	return &ExportService{
+		repo:   repo,
+		store:  store,
+		users:  users,
+		worker: worker,
+		logger: logger,
+	}
+}
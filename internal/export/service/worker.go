@@ -0,0 +1,150 @@
+package exportservice
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/QuUteO/video-communication/internal/export/repository"
+	"github.com/QuUteO/video-communication/internal/model"
+	"github.com/QuUteO/video-communication/pkg/storage"
+	"github.com/jackc/pgx/v4"
+)
+
+const (
+	pollInterval = 5 * time.Second
+	// lease время, после которого задание в 'running' считается брошенным
+	lease = 30 * time.Minute
+)
+
+var extensions = map[string]string{
+	FormatJSONL: ".jsonl",
+	FormatCSV:   ".csv",
+	FormatHTML:  ".html",
+}
+
+// Worker Выполняет задания на выгрузку по одному.
+// Сообщения читаются из БД потоком и пишутся во временный файл, который затем
+// загружается в хранилище: в памяти не держится вся история канала.
+type Worker struct {
+	repo   exportrepository.Repository
+	store  storage.Storage
+	wake   chan struct{}
+	logger *slog.Logger
+}
+
+func NewWorker(repo exportrepository.Repository, store storage.Storage, logger *slog.Logger) *Worker {
+	return &Worker{
+		repo:   repo,
+		store:  store,
+		wake:   make(chan struct{}, 1),
+		logger: logger,
+	}
+}
+
+// Wake запускает обработку без ожидания следующего опроса
+func (w *Worker) Wake() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run обрабатывает задания до отмены контекста
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		w.drain(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-w.wake:
+		}
+	}
+}
+
+func (w *Worker) drain(ctx context.Context) {
+	for ctx.Err() == nil {
+		job, err := w.repo.Claim(ctx, lease.Seconds())
+		if errors.Is(err, pgx.ErrNoRows) {
+			return
+		}
+		if err != nil {
+			if ctx.Err() == nil {
+				w.logger.Error("failed to claim export", slog.String("error", err.Error()))
+			}
+			return
+		}
+		w.process(ctx, job)
+	}
+}
+
+func (w *Worker) process(ctx context.Context, job *model.ChannelExport) {
+	log := w.logger.With(slog.String("export_id", job.ID.String()), slog.String("channel", job.Channel))
+
+	key, size, count, err := w.export(ctx, job)
+	if err != nil {
+		log.Error("export failed", slog.String("error", err.Error()))
+		if err := w.repo.MarkFailed(ctx, job.ID, err.Error()); err != nil {
+			log.Error("failed to mark export as failed", slog.String("error", err.Error()))
+		}
+		return
+	}
+
+	if err := w.repo.MarkDone(ctx, job.ID, key, size, count); err != nil {
+		log.Error("failed to mark export as done", slog.String("error", err.Error()))
+		return
+	}
+	log.Info("export done", slog.Int64("messages", count), slog.Int64("size", size))
+}
+
+func (w *Worker) export(ctx context.Context, job *model.ChannelExport) (string, int64, int64, error) {
+	tmp, err := os.CreateTemp("", "export-*")
+	if err != nil {
+		return "", 0, 0, err
+	}
+	defer func() {
+		tmp.Close()
+		os.Remove(tmp.Name())
+	}()
+
+	buf := bufio.NewWriterSize(tmp, 64<<10)
+	enc := newEncoder(job.Format, job.Channel, buf)
+
+	if err := enc.header(); err != nil {
+		return "", 0, 0, err
+	}
+	count, err := w.repo.StreamMessages(ctx, job.Channel, enc.record)
+	if err != nil {
+		return "", 0, 0, err
+	}
+	if err := enc.footer(count); err != nil {
+		return "", 0, 0, err
+	}
+	if err := buf.Flush(); err != nil {
+		return "", 0, 0, err
+	}
+
+	size, err := tmp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return "", 0, 0, err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return "", 0, 0, err
+	}
+
+	key := "exports/" + job.ID.String() + extensions[job.Format]
+	if err := w.store.Put(ctx, key, tmp, size, ContentType(job.Format)); err != nil {
+		return "", 0, 0, err
+	}
+
+	return key, size, count, nil
+}
@@ -0,0 +1,86 @@
+package importerhandler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/QuUteO/video-communication/internal/importer/service"
+	"github.com/QuUteO/video-communication/internal/model"
+	"github.com/go-chi/render"
+)
+
+type Handler struct {
+	service importerservice.Service
+	logger  *slog.Logger
+}
+
+func NewHandler(service importerservice.Service, logger *slog.Logger) *Handler {
+	return &Handler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// Import POST /admin/imports?source=slack&dry_run=true&batch_size=500
+// Тело — архив JSON lines, читается потоком
+func (h *Handler) Import(w http.ResponseWriter, r *http.Request) {
+	const op = "internal/importer/handler/Import"
+	log := h.logger.With("op: ", op)
+
+	q := r.URL.Query()
+	opts := model.ImportOptions{Source: q.Get("source")}
+
+	if v := q.Get("dry_run"); v != "" {
+		dryRun, err := strconv.ParseBool(v)
+		if err != nil {
+			h.error(w, r, http.StatusBadRequest, "dry_run must be a boolean")
+			return
+		}
+		opts.DryRun = dryRun
+	}
+	if v := q.Get("batch_size"); v != "" {
+		size, err := strconv.Atoi(v)
+		if err != nil || size <= 0 {
+			h.error(w, r, http.StatusBadRequest, "batch_size must be a positive integer")
+			return
+		}
+		opts.BatchSize = size
+	}
+
+	report, err := h.service.Import(r.Context(), r.Body, opts)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, importerservice.ErrInvalidSource) {
+			status = http.StatusBadRequest
+		} else {
+			log.Error("Import failed", slog.Any("error", err))
+		}
+
+		// отчет о уже загруженных пачках возвращается и при ошибке
+		render.Status(r, status)
+		render.JSON(w, r, model.Response{
+			StatusCode: status,
+			Data:       report,
+			Error:      err.Error(),
+		})
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, model.Response{
+		StatusCode: http.StatusOK,
+		Message:    "Import finished",
+		Data:       report,
+		Error:      "nil",
+	})
+}
+
+func (h *Handler) error(w http.ResponseWriter, r *http.Request, status int, msg string) {
+	render.Status(r, status)
+	render.JSON(w, r, model.Response{
+		StatusCode: status,
+		Error:      msg,
+	})
+}
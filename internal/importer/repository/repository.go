@@ -0,0 +1,140 @@
+package importerrepository
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/QuUteO/video-communication/internal/model"
+	postgres "github.com/QuUteO/video-communication/pkg/db"
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+// importedPassword не является bcrypt-хешем, поэтому вход по паролю невозможен,
+// пока пользователь не задаст пароль сам
+const importedPassword = "!imported"
+
+type Repository interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+	// WithTx репозиторий, выполняющий запросы в транзакции (или точке сохранения)
+	WithTx(tx pgx.Tx) Repository
+
+	FindIdentity(ctx context.Context, source, foreignID string) (uuid.UUID, string, error)
+	FindUserByEmail(ctx context.Context, email string) (uuid.UUID, string, error)
+	CreateUser(ctx context.Context, email, username string) (uuid.UUID, string, error)
+	LinkIdentity(ctx context.Context, source, foreignID string, userID uuid.UUID) error
+
+	AddMember(ctx context.Context, channel string, userID uuid.UUID) error
+	InsertMessage(ctx context.Context, msg *model.Message) (bool, error)
+}
+
+type repository struct {
+	db     postgres.Client
+	logger *slog.Logger
+}
+
+func (r *repository) Begin(ctx context.Context) (pgx.Tx, error) {
+	return r.db.Begin(ctx)
+}
+
+func (r *repository) WithTx(tx pgx.Tx) Repository {
+	return &repository{db: tx, logger: r.logger}
+}
+
+// FindIdentity возвращает локального пользователя и его отображаемое имя; uuid.Nil — не найден
+func (r *repository) FindIdentity(ctx context.Context, source, foreignID string) (uuid.UUID, string, error) {
+	q := `
+		SELECT u.id, COALESCE(u.username, split_part(u.email, '@', 1))
+		FROM import_identities i
+		JOIN users u ON u.id = i.user_id
+		WHERE i.source = $1 AND i.foreign_id = $2
+	`
+
+	return scanUser(r.db.QueryRow(ctx, q, source, foreignID))
+}
+
+func (r *repository) FindUserByEmail(ctx context.Context, email string) (uuid.UUID, string, error) {
+	q := `SELECT id, COALESCE(username, split_part(email, '@', 1)) FROM users WHERE lower(email) = lower($1)`
+
+	return scanUser(r.db.QueryRow(ctx, q, email))
+}
+
+// CreateUser создает пользователя без пароля; занятое имя пользователя не переносится
+func (r *repository) CreateUser(ctx context.Context, email, username string) (uuid.UUID, string, error) {
+	q := `
+		INSERT INTO users (email, password, username)
+		VALUES ($1, $2, CASE
+			WHEN $3 <> '' AND NOT EXISTS (SELECT 1 FROM users WHERE lower(username) = lower($3)) THEN $3
+		END)
+		RETURNING id, COALESCE(username, split_part(email, '@', 1))
+	`
+
+	return scanUser(r.db.QueryRow(ctx, q, email, importedPassword, username))
+}
+
+func (r *repository) LinkIdentity(ctx context.Context, source, foreignID string, userID uuid.UUID) error {
+	q := `
+		INSERT INTO import_identities (source, foreign_id, user_id)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (source, foreign_id) DO NOTHING
+	`
+
+	_, err := r.db.Exec(ctx, q, source, foreignID, userID)
+	return err
+}
+
+func (r *repository) AddMember(ctx context.Context, channel string, userID uuid.UUID) error {
+	q := `
+		INSERT INTO channel_members (channel, user_id)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING
+	`
+
+	_, err := r.db.Exec(ctx, q, channel, userID)
+	return err
+}
+
+// InsertMessage сохраняет сообщение с исходными id и временем; false — сообщение уже есть
+func (r *repository) InsertMessage(ctx context.Context, msg *model.Message) (bool, error) {
+	q := `
+		INSERT INTO message (id, type, user_id, msg, format, html, channel, username, created_at)
+		VALUES ($1, COALESCE(NULLIF($2, ''), 'message'), $3, $4, COALESCE(NULLIF($5, ''), 'plain'), NULLIF($6, ''), $7, $8, $9)
+		ON CONFLICT (id) DO NOTHING
+	`
+
+	tag, err := r.db.Exec(ctx, q,
+		msg.ID,
+		msg.Type,
+		uuid.NullUUID{UUID: msg.UserID, Valid: msg.UserID != uuid.Nil},
+		msg.Msg,
+		msg.Format,
+		msg.HTML,
+		msg.Channel,
+		msg.User,
+		msg.Time,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+func scanUser(row pgx.Row) (uuid.UUID, string, error) {
+	var id uuid.UUID
+	var display string
+
+	err := row.Scan(&id, &display)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return uuid.Nil, "", nil
+	}
+	return id, display, err
+}
+
+func New(db postgres.Client, logger *slog.Logger) Repository {
+	return &repository{
+		db:     db,
+		logger: logger,
+	}
+}
@@ -0,0 +1,416 @@
+package importerservice
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/mail"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/QuUteO/video-communication/internal/importer/repository"
+	"github.com/QuUteO/video-communication/internal/model"
+	"github.com/QuUteO/video-communication/pkg/richtext"
+	"github.com/gofrs/uuid"
+)
+
+const (
+	defaultSource    = "archive"
+	defaultBatchSize = 500
+	maxBatchSize     = 10000
+	maxLineSize      = 1 << 20
+)
+
+// совпадает с правилом регистрации; неподходящее имя не переносится
+var usernameRe = regexp.MustCompile(`^[A-Za-z0-9_.-]{2,32}$`)
+
+// namespace для детерминированных id сообщений, у которых во внешней системе id не UUID
+var messageNamespace = uuid.Must(uuid.FromString("6f1c1f43-5d5c-4b8e-9a55-3b8a3c0f2e71"))
+
+var (
+	ErrInvalidSource = errors.New("source must be 1-64 characters")
+)
+
+type Service interface {
+	// Import читает архив построчно и загружает его пачками транзакций;
+	// при DryRun все изменения откатываются, но отчет считается так же
+	Import(ctx context.Context, r io.Reader, opts model.ImportOptions) (*model.ImportReport, error)
+}
+
+type ImporterService struct {
+	repo   importerrepository.Repository
+	logger *slog.Logger
+}
+
+type importUser struct {
+	id      uuid.UUID
+	display string
+}
+
+// run состояние одного импорта
+type run struct {
+	opts   model.ImportOptions
+	report *model.ImportReport
+	users  map[string]importUser // внешний id -> локальный пользователь (только зафиксированные)
+}
+
+type line struct {
+	no  int
+	rec model.ImportRecord
+}
+
+// outcome результат применения одной записи
+type outcome struct {
+	field *int // счетчик в отчете, который нужно увеличить
+	user  *importUser
+}
+
+func (s *ImporterService) Import(ctx context.Context, r io.Reader, opts model.ImportOptions) (*model.ImportReport, error) {
+	const op = "internal/importer/service.Import"
+	log := s.logger.With("op: ", op)
+
+	if opts.Source == "" {
+		opts.Source = defaultSource
+	}
+	if utf8.RuneCountInString(opts.Source) > 64 {
+		return nil, ErrInvalidSource
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultBatchSize
+	}
+	if opts.BatchSize > maxBatchSize {
+		opts.BatchSize = maxBatchSize
+	}
+
+	st := &run{
+		opts:   opts,
+		report: &model.ImportReport{Source: opts.Source, DryRun: opts.DryRun, Rejected: []model.ImportRejection{}},
+		users:  make(map[string]importUser),
+	}
+
+	// в dry-run пачки — точки сохранения внутри одной транзакции, которая в конце откатывается,
+	// чтобы ссылки между пачками (пользователь -> сообщения) проверялись как при настоящем импорте
+	repo := s.repo
+	if opts.DryRun {
+		tx, err := s.repo.Begin(ctx)
+		if err != nil {
+			log.Error("Error starting dry-run transaction", slog.Any("error", err))
+			return nil, err
+		}
+		defer tx.Rollback(context.Background())
+		repo = s.repo.WithTx(tx)
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+
+	batch := make([]line, 0, opts.BatchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		err := s.applyBatch(ctx, repo, st, batch)
+		batch = batch[:0]
+		return err
+	}
+
+	for scanner.Scan() {
+		st.report.Lines++
+		raw := strings.TrimSpace(scanner.Text())
+		if raw == "" {
+			continue
+		}
+
+		var rec model.ImportRecord
+		if err := json.Unmarshal([]byte(raw), &rec); err != nil {
+			st.reject(st.report.Lines, rec, "invalid json: "+err.Error())
+			continue
+		}
+
+		batch = append(batch, line{no: st.report.Lines, rec: rec})
+		if len(batch) == opts.BatchSize {
+			if err := flush(); err != nil {
+				log.Error("Error importing batch", slog.Int("line", st.report.Lines), slog.Any("error", err))
+				return st.report, err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		log.Error("Error reading archive", slog.Int("line", st.report.Lines), slog.Any("error", err))
+		return st.report, fmt.Errorf("read archive after line %d: %w", st.report.Lines, err)
+	}
+	if err := flush(); err != nil {
+		log.Error("Error importing batch", slog.Int("line", st.report.Lines), slog.Any("error", err))
+		return st.report, err
+	}
+
+	log.Info("Import finished",
+		slog.String("source", opts.Source),
+		slog.Bool("dry_run", opts.DryRun),
+		slog.Int("lines", st.report.Lines),
+		slog.Int("rejected", st.report.RejectedTotal),
+	)
+
+	return st.report, nil
+}
+
+// applyBatch применяет пачку в одной транзакции; каждая запись — в своей точке сохранения,
+// поэтому ошибочная запись отклоняется, не обрывая остальные
+func (s *ImporterService) applyBatch(ctx context.Context, repo importerrepository.Repository, st *run, batch []line) error {
+	tx, err := repo.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(context.Background())
+
+	applied := make([]outcome, 0, len(batch))
+	newUsers := make(map[string]importUser)
+
+	for _, l := range batch {
+		sp, err := tx.Begin(ctx)
+		if err != nil {
+			return err
+		}
+
+		res, err := s.apply(ctx, s.repo.WithTx(sp), st, newUsers, l.rec)
+		if err != nil {
+			_ = sp.Rollback(ctx)
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			st.reject(l.no, l.rec, err.Error())
+			continue
+		}
+		if err := sp.Commit(ctx); err != nil {
+			return err
+		}
+
+		if res.user != nil {
+			newUsers[l.rec.ID] = *res.user
+		}
+		applied = append(applied, res)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	// счетчики и кеш пользователей обновляются только после фиксации пачки
+	for _, res := range applied {
+		*res.field++
+	}
+	for id, u := range newUsers {
+		st.users[id] = u
+	}
+
+	return nil
+}
+
+func (s *ImporterService) apply(ctx context.Context, repo importerrepository.Repository, st *run, pending map[string]importUser, rec model.ImportRecord) (outcome, error) {
+	switch rec.Kind {
+	case "user":
+		return s.applyUser(ctx, repo, st, rec)
+	case "channel":
+		return s.applyChannel(ctx, repo, st, pending, rec)
+	case "message":
+		return s.applyMessage(ctx, repo, st, pending, rec)
+	case "":
+		return outcome{}, errors.New("kind is required")
+	default:
+		return outcome{}, fmt.Errorf("unknown kind %q", rec.Kind)
+	}
+}
+
+func (s *ImporterService) applyUser(ctx context.Context, repo importerrepository.Repository, st *run, rec model.ImportRecord) (outcome, error) {
+	counts := &st.report.Users
+
+	if rec.ID == "" || len(rec.ID) > 255 {
+		return outcome{}, errors.New("id must be 1-255 characters")
+	}
+
+	id, display, err := repo.FindIdentity(ctx, st.opts.Source, rec.ID)
+	if err != nil {
+		return outcome{}, err
+	}
+	if id != uuid.Nil {
+		return outcome{field: &counts.Skipped, user: &importUser{id, display}}, nil
+	}
+
+	email := strings.TrimSpace(rec.Email)
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email || len(email) > 255 {
+		return outcome{}, errors.New("invalid email")
+	}
+
+	// существующий пользователь с тем же email сопоставляется, а не дублируется
+	field := &counts.Linked
+	id, display, err = repo.FindUserByEmail(ctx, email)
+	if err != nil {
+		return outcome{}, err
+	}
+	if id == uuid.Nil {
+		username := rec.Username
+		if !usernameRe.MatchString(username) {
+			username = ""
+		}
+		if id, display, err = repo.CreateUser(ctx, email, username); err != nil {
+			return outcome{}, err
+		}
+		field = &counts.Imported
+	}
+
+	if err := repo.LinkIdentity(ctx, st.opts.Source, rec.ID, id); err != nil {
+		return outcome{}, err
+	}
+
+	return outcome{field: field, user: &importUser{id, display}}, nil
+}
+
+func (s *ImporterService) applyChannel(ctx context.Context, repo importerrepository.Repository, st *run, pending map[string]importUser, rec model.ImportRecord) (outcome, error) {
+	counts := &st.report.Channels
+
+	name := rec.Name
+	if name == "" {
+		name = rec.ID
+	}
+	if err := validChannel(name); err != nil {
+		return outcome{}, err
+	}
+
+	for _, member := range rec.Members {
+		u, err := s.resolveUser(ctx, repo, st, pending, member)
+		if err != nil {
+			return outcome{}, fmt.Errorf("member %q: %w", member, err)
+		}
+		if err := repo.AddMember(ctx, name, u.id); err != nil {
+			return outcome{}, err
+		}
+	}
+
+	return outcome{field: &counts.Imported}, nil
+}
+
+func (s *ImporterService) applyMessage(ctx context.Context, repo importerrepository.Repository, st *run, pending map[string]importUser, rec model.ImportRecord) (outcome, error) {
+	counts := &st.report.Messages
+
+	if rec.ID == "" {
+		return outcome{}, errors.New("id is required")
+	}
+	if err := validChannel(rec.Channel); err != nil {
+		return outcome{}, err
+	}
+	if rec.TS.IsZero() {
+		return outcome{}, errors.New("ts is required")
+	}
+
+	msg := &model.Message{
+		ID:      messageID(st.opts.Source, rec.ID),
+		Type:    rec.Type,
+		Channel: rec.Channel,
+		Time:    rec.TS.UTC(),
+	}
+
+	switch rec.Type {
+	case "", "message":
+		msg.Type = "message"
+		u, err := s.resolveUser(ctx, repo, st, pending, rec.User)
+		if err != nil {
+			return outcome{}, fmt.Errorf("user %q: %w", rec.User, err)
+		}
+		msg.UserID = u.id
+		msg.User = u.display
+		// автор должен видеть историю канала, в который писал
+		if err := repo.AddMember(ctx, rec.Channel, u.id); err != nil {
+			return outcome{}, err
+		}
+	case "system":
+		msg.User = "System"
+	default:
+		return outcome{}, fmt.Errorf("unknown message type %q", rec.Type)
+	}
+
+	text, html, format, err := richtext.Render(rec.Format, rec.Text)
+	if err != nil {
+		return outcome{}, err
+	}
+	if strings.TrimSpace(text) == "" {
+		return outcome{}, errors.New("text is required")
+	}
+	msg.Msg, msg.HTML, msg.Format = text, html, format
+
+	inserted, err := repo.InsertMessage(ctx, msg)
+	if err != nil {
+		return outcome{}, err
+	}
+	if !inserted {
+		return outcome{field: &counts.Skipped}, nil
+	}
+
+	return outcome{field: &counts.Imported}, nil
+}
+
+// resolveUser находит локального пользователя по внешнему id: сначала в текущей пачке и кеше, затем в базе
+func (s *ImporterService) resolveUser(ctx context.Context, repo importerrepository.Repository, st *run, pending map[string]importUser, foreignID string) (importUser, error) {
+	if foreignID == "" {
+		return importUser{}, errors.New("user is required")
+	}
+	if u, ok := pending[foreignID]; ok {
+		return u, nil
+	}
+	if u, ok := st.users[foreignID]; ok {
+		return u, nil
+	}
+
+	id, display, err := repo.FindIdentity(ctx, st.opts.Source, foreignID)
+	if err != nil {
+		return importUser{}, err
+	}
+	if id == uuid.Nil {
+		return importUser{}, errors.New("unknown user, it must be imported first")
+	}
+
+	u := importUser{id: id, display: display}
+	st.users[foreignID] = u
+	return u, nil
+}
+
+func (st *run) reject(no int, rec model.ImportRecord, reason string) {
+	st.report.RejectedTotal++
+	if len(st.report.Rejected) >= model.MaxImportRejections {
+		return
+	}
+	st.report.Rejected = append(st.report.Rejected, model.ImportRejection{
+		Line:   no,
+		Kind:   rec.Kind,
+		ID:     rec.ID,
+		Reason: reason,
+	})
+}
+
+// messageID сохраняет исходный id, если это UUID; иначе выводит стабильный UUID,
+// чтобы повторный импорт того же архива не дублировал сообщения
+func messageID(source, foreignID string) uuid.UUID {
+	if id, err := uuid.FromString(foreignID); err == nil {
+		return id
+	}
+	return uuid.NewV5(messageNamespace, source+"/"+foreignID)
+}
+
+func validChannel(name string) error {
+	if name == "" || len(name) > 255 {
+		return errors.New("channel must be 1-255 characters")
+	}
+	return nil
+}
+
+func NewImporterService(repo importerrepository.Repository, logger *slog.Logger) Service {
+	return &ImporterService{
+		repo:   repo,
+		logger: logger,
+	}
+}
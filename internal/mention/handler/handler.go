@@ -0,0 +1,77 @@
+package mentionhandler
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	authmiddleware "github.com/QuUteO/video-communication/internal/auth/middleware"
+	"github.com/QuUteO/video-communication/internal/mention/service"
+	"github.com/QuUteO/video-communication/internal/model"
+	"github.com/go-chi/render"
+)
+
+type Handler struct {
+	service mentionservice.Service
+	logger  *slog.Logger
+}
+
+func NewHandler(service mentionservice.Service, logger *slog.Logger) *Handler {
+	return &Handler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// ListMine GET /me/mentions?limit=&offset=
+func (h *Handler) ListMine(w http.ResponseWriter, r *http.Request) {
+	const op = "internal/mention/handler/ListMine"
+	log := h.logger.With("op: ", op)
+
+	userID, _ := r.Context().Value(authmiddleware.UserIDKey).(string)
+
+	limit, err := parseInt(r.URL.Query().Get("limit"))
+	if err != nil {
+		badRequest(w, r, "invalid limit")
+		return
+	}
+	offset, err := parseInt(r.URL.Query().Get("offset"))
+	if err != nil {
+		badRequest(w, r, "invalid offset")
+		return
+	}
+
+	res, err := h.service.FindByUser(r.Context(), userID, limit, offset)
+	if err != nil {
+		log.Error("Failed to list mentions", slog.Any("error", err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, model.Response{
+			StatusCode: http.StatusInternalServerError,
+			Error:      err.Error(),
+		})
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, model.Response{
+		StatusCode: http.StatusOK,
+		Message:    "Mentions retrieved successfully",
+		Data:       res,
+		Error:      "nil",
+	})
+}
+
+func badRequest(w http.ResponseWriter, r *http.Request, msg string) {
+	render.Status(r, http.StatusBadRequest)
+	render.JSON(w, r, model.Response{
+		StatusCode: http.StatusBadRequest,
+		Error:      msg,
+	})
+}
+
+func parseInt(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(value)
+}
@@ -0,0 +1,144 @@
+package mentionrepository
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/QuUteO/video-communication/internal/model"
+	postgres "github.com/QuUteO/video-communication/pkg/db"
+	"github.com/gofrs/uuid"
+)
+
+type Repository interface {
+	ResolveUsernames(ctx context.Context, usernames []string) ([]string, error)
+	ChannelMemberIDs(ctx context.Context, channel string) ([]string, error)
+	Save(ctx context.Context, messageID uuid.UUID, userIDs []string, kinds []string) error
+	FindByUser(ctx context.Context, userID string, limit, offset int) ([]model.Mention, error)
+}
+
+type repository struct {
+	db     postgres.Client
+	logger *slog.Logger
+}
+
+// ResolveUsernames возвращает id пользователей по именам (без учета регистра)
+func (r *repository) ResolveUsernames(ctx context.Context, usernames []string) ([]string, error) {
+	const op = "./internal/mention/repository.ResolveUsernames"
+	log := r.logger.With("op: ", op)
+
+	q := `SELECT id::text, lower(username) FROM users WHERE lower(username) = ANY($1)`
+
+	rows, err := r.db.Query(ctx, q, usernames)
+	if err != nil {
+		log.Error("Error to resolve usernames", slog.Any("err", err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	byName := make(map[string]string)
+	for rows.Next() {
+		var id, name string
+		if err := rows.Scan(&id, &name); err != nil {
+			log.Error("Error to scan user", slog.Any("err", err))
+			return nil, err
+		}
+		byName[name] = id
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// сохраняем порядок упоминаний в тексте
+	ids := make([]string, 0, len(byName))
+	for _, name := range usernames {
+		if id, ok := byName[name]; ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func (r *repository) ChannelMemberIDs(ctx context.Context, channel string) ([]string, error) {
+	const op = "./internal/mention/repository.ChannelMemberIDs"
+	log := r.logger.With("op: ", op)
+
+	q := `SELECT user_id::text FROM channel_members WHERE channel = $1`
+
+	rows, err := r.db.Query(ctx, q, channel)
+	if err != nil {
+		log.Error("Error to find channel members", slog.Any("err", err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make([]string, 0)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			log.Error("Error to scan channel member", slog.Any("err", err))
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+func (r *repository) Save(ctx context.Context, messageID uuid.UUID, userIDs []string, kinds []string) error {
+	const op = "./internal/mention/repository.Save"
+	log := r.logger.With("op: ", op)
+
+	q := `
+		INSERT INTO message_mentions (message_id, user_id, kind)
+		SELECT $1, u.user_id, u.kind
+		FROM unnest($2::uuid[], $3::text[]) AS u(user_id, kind)
+		ON CONFLICT (message_id, user_id) DO NOTHING
+	`
+
+	if _, err := r.db.Exec(ctx, q, messageID, userIDs, kinds); err != nil {
+		log.Error("Error to save mentions", slog.Any("err", err))
+		return err
+	}
+
+	return nil
+}
+
+func (r *repository) FindByUser(ctx context.Context, userID string, limit, offset int) ([]model.Mention, error) {
+	const op = "./internal/mention/repository.FindByUser"
+	log := r.logger.With("op: ", op)
+
+	q := `
+		SELECT mm.message_id, mm.user_id, mm.kind, m.channel, m.username, m.msg, m.created_at
+		FROM message_mentions mm
+		JOIN message m ON m.id = mm.message_id
+		WHERE mm.user_id = $1 AND message_is_live(m.channel, m.created_at, m.expires_at)
+		ORDER BY mm.created_at DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := r.db.Query(ctx, q, userID, limit, offset)
+	if err != nil {
+		log.Error("Error to find mentions", slog.Any("err", err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	mentions := make([]model.Mention, 0)
+	for rows.Next() {
+		var m model.Mention
+		if err := rows.Scan(&m.MessageID, &m.UserID, &m.Kind, &m.Channel, &m.User, &m.Msg, &m.Time); err != nil {
+			log.Error("Error to scan mention", slog.Any("err", err))
+			return nil, err
+		}
+		mentions = append(mentions, m)
+	}
+
+	return mentions, rows.Err()
+}
+
+func New(db postgres.Client, logger *slog.Logger) Repository {
+	return &repository{
+		db:     db,
+		logger: logger,
+	}
+}
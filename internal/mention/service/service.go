@@ -0,0 +1,166 @@
+package mentionservice
+
+import (
+	"context"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"github.com/QuUteO/video-communication/internal/mention/repository"
+	"github.com/QuUteO/video-communication/internal/model"
+	"github.com/gofrs/uuid"
+)
+
+const (
+	defaultLimit = 20
+	maxLimit     = 100
+	// не даем одним сообщением упомянуть слишком много людей по имени
+	maxUserMentions = 50
+)
+
+// @имя в начале строки или после не-словесного символа (чтобы не ловить email)
+var mentionRe = regexp.MustCompile(`(?:^|[^\w@])@([A-Za-z0-9_.-]{2,32})`)
+
+type Service interface {
+	// Process находит упоминания в сообщении, сохраняет их и возвращает получателей уведомлений
+	Process(ctx context.Context, msg *model.Message, onlineIDs []string) ([]model.Mention, error)
+	FindByUser(ctx context.Context, userID string, limit, offset int) (*model.MentionsResponse, error)
+}
+
+type MentionService struct {
+	repo   mentionrepository.Repository
+	logger *slog.Logger
+}
+
+// Parse разбирает текст на имена пользователей и флаги @channel / @here
+func Parse(text string) (usernames []string, channel bool, here bool) {
+	seen := make(map[string]bool)
+
+	for _, match := range mentionRe.FindAllStringSubmatch(text, -1) {
+		name := strings.ToLower(strings.TrimRight(match[1], ".-"))
+
+		switch name {
+		case "channel", "all", "everyone":
+			channel = true
+		case "here":
+			here = true
+		default:
+			if len(name) >= 2 && !seen[name] && len(usernames) < maxUserMentions {
+				seen[name] = true
+				usernames = append(usernames, name)
+			}
+		}
+	}
+
+	return usernames, channel, here
+}
+
+func (s *MentionService) Process(ctx context.Context, msg *model.Message, onlineIDs []string) ([]model.Mention, error) {
+	const op = "internal/mention/service.Process"
+	log := s.logger.With("op: ", op)
+
+	usernames, channel, here := Parse(msg.Msg)
+	if len(usernames) == 0 && !channel && !here {
+		return nil, nil
+	}
+
+	// один пользователь получает одно упоминание: приоритет у прямого
+	kinds := make(map[string]string)
+	order := make([]string, 0)
+	add := func(ids []string, kind string) {
+		for _, id := range ids {
+			if id == msg.UserID.String() {
+				continue
+			}
+			if _, ok := kinds[id]; !ok {
+				order = append(order, id)
+				kinds[id] = kind
+			}
+		}
+	}
+
+	if len(usernames) > 0 {
+		ids, err := s.repo.ResolveUsernames(ctx, usernames)
+		if err != nil {
+			log.Error("Error resolving usernames", slog.Any("error", err))
+			return nil, err
+		}
+		add(ids, model.MentionUser)
+	}
+
+	if channel {
+		ids, err := s.repo.ChannelMemberIDs(ctx, msg.Channel)
+		if err != nil {
+			log.Error("Error loading channel members", slog.Any("error", err))
+			return nil, err
+		}
+		add(ids, model.MentionChannel)
+	} else if here {
+		add(onlineIDs, model.MentionHere)
+	}
+
+	if len(order) == 0 {
+		return nil, nil
+	}
+
+	kindList := make([]string, 0, len(order))
+	for _, id := range order {
+		kindList = append(kindList, kinds[id])
+	}
+
+	if err := s.repo.Save(ctx, msg.ID, order, kindList); err != nil {
+		log.Error("Error saving mentions", slog.Any("error", err))
+		return nil, err
+	}
+
+	mentions := make([]model.Mention, 0, len(order))
+	for _, id := range order {
+		userID := uuid.FromStringOrNil(id)
+		msg.Mentions = append(msg.Mentions, userID)
+		mentions = append(mentions, model.Mention{
+			MessageID: msg.ID,
+			UserID:    userID,
+			Kind:      kinds[id],
+			Channel:   msg.Channel,
+			User:      msg.User,
+			Msg:       msg.Msg,
+			Time:      msg.Time,
+		})
+	}
+
+	return mentions, nil
+}
+
+func (s *MentionService) FindByUser(ctx context.Context, userID string, limit, offset int) (*model.MentionsResponse, error) {
+	const op = "internal/mention/service.FindByUser"
+	log := s.logger.With("op: ", op)
+
+	if limit <= 0 {
+		limit = defaultLimit
+	}
+	if limit > maxLimit {
+		limit = maxLimit
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	mentions, err := s.repo.FindByUser(ctx, userID, limit, offset)
+	if err != nil {
+		log.Error("Error finding mentions", slog.Any("error", err))
+		return nil, err
+	}
+
+	return &model.MentionsResponse{
+		Mentions: mentions,
+		Limit:    limit,
+		Offset:   offset,
+	}, nil
+}
+
+func NewMentionService(repo mentionrepository.Repository, logger *slog.Logger) Service {
+	return &MentionService{
+		repo:   repo,
+		logger: logger,
+	}
+}
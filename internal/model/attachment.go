@@ -0,0 +1,34 @@
+package model
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// Attachment Метаданные файла, прикрепленного к сообщению
+type Attachment struct {
+	ID          uuid.UUID   `json:"id"`
+	MessageID   uuid.UUID   `json:"message_id"`
+	Channel     string      `json:"channel"`
+	UserID      uuid.UUID   `json:"user_id"`
+	FileName    string      `json:"file_name"`
+	ContentType string      `json:"content_type"`
+	Size        int64       `json:"size"`
+	Width       int         `json:"width,omitempty"`  // для изображений
+	Height      int         `json:"height,omitempty"` // для изображений
+	Thumbnails  []Thumbnail `json:"thumbnails,omitempty"`
+	URL         string      `json:"url"` // адрес для скачивания (требует авторизации)
+	StorageKey  string      `json:"-"`
+	CreatedAt   time.Time   `json:"created_at"`
+}
+
+// Thumbnail Уменьшенная копия изображения
+type Thumbnail struct {
+	Size        string `json:"size"` // small | medium
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+	ContentType string `json:"content_type"`
+	URL         string `json:"url"`
+	StorageKey  string `json:"-"`
+}
@@ -1,12 +1,24 @@
 package model
 
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
 type AuthRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
+	Username string `json:"username"` // необязательное имя для @упоминаний
 }
 
 type AuthResponse struct {
-	Token string `json:"token"`
+	Token        string `json:"token,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	ExpiresIn    int64  `json:"expires_in,omitempty"` // время жизни access-токена в секундах
+	// при включенной 2FA вместо токенов выдается challenge для POST /auth/login/2fa
+	TwoFactorRequired bool   `json:"two_factor_required,omitempty"`
+	ChallengeToken    string `json:"challenge_token,omitempty"`
 }
 
 type LoginRequest struct {
@@ -15,5 +27,173 @@ type LoginRequest struct {
 }
 
 type LoginResponse struct {
+	Token             string `json:"token,omitempty"`
+	RefreshToken      string `json:"refresh_token,omitempty"`
+	ExpiresIn         int64  `json:"expires_in,omitempty"`
+	TwoFactorRequired bool   `json:"two_factor_required,omitempty"`
+	ChallengeToken    string `json:"challenge_token,omitempty"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// DeviceInfo Откуда выполнен вход или обновление токена
+type DeviceInfo struct {
+	UserAgent string
+	IP        string
+}
+
+// AuthSession Сессия входа: семейство ротируемых refresh-токенов
+type AuthSession struct {
+	ID            uuid.UUID  `json:"id"`
+	UserID        uuid.UUID  `json:"user_id"`
+	Device        string     `json:"device"` // "Chrome on Windows"
+	UserAgent     string     `json:"user_agent"`
+	IP            string     `json:"ip"`
+	Current       bool       `json:"current"` // сессия токена, которым сделан запрос
+	MFA           bool       `json:"mfa"`     // вход подтвержден вторым фактором
+	CreatedAt     time.Time  `json:"created_at"`
+	LastSeenAt    time.Time  `json:"last_seen_at"`
+	ExpiresAt     time.Time  `json:"expires_at"`
+	RevokedAt     *time.Time `json:"revoked_at,omitempty"`
+	RevokedReason string     `json:"revoked_reason,omitempty"`
+}
+
+// RefreshToken Хранимый refresh-токен; в базе только хеш
+type RefreshToken struct {
+	ID        uuid.UUID
+	SessionID uuid.UUID
+	UserID    uuid.UUID
+	TokenHash string
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	// состояние сессии на момент чтения
+	SessionRevoked bool
+	SessionMFA     bool
+}
+
+// назначения одноразовых токенов из писем
+const (
+	TokenVerifyEmail   = "verify_email"
+	TokenResetPassword = "reset_password"
+)
+
+// AuthToken Одноразовый токен из письма; в базе только хеш
+type AuthToken struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	Purpose   string
+	Email     string
+	TokenHash string
+	ExpiresAt time.Time
+}
+
+type VerifyEmailRequest struct {
 	Token string `json:"token"`
 }
+
+type PasswordResetRequest struct {
+	Email string `json:"email"`
+}
+
+type PasswordResetConfirmRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// TOTP Настройка второго фактора пользователя
+type TOTP struct {
+	UserID    uuid.UUID
+	Secret    string // зашифрованный
+	EnabledAt *time.Time
+	LastStep  int64
+}
+
+// LoginChallenge Незавершенный вход, ожидающий код второго фактора
+type LoginChallenge struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	TokenHash string
+	Attempts  int
+	ExpiresAt time.Time
+}
+
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+type TOTPCodeRequest struct {
+	Code string `json:"code"` // код из приложения или код восстановления
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"` // показываются один раз
+}
+
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"` // код из приложения или код восстановления
+}
+
+// LoginAttempts Неудачные попытки входа для адреса; интервалы посчитаны по часам базы
+type LoginAttempts struct {
+	Failures         int
+	SinceLastFailure time.Duration
+	LockedFor        time.Duration // 0 — не заблокирован
+}
+
+// OIDCState Незавершенный вход через SSO; в базе только хеш state
+type OIDCState struct {
+	StateHash    string
+	Nonce        string
+	CodeVerifier string
+}
+
+// OIDCCallback Параметры возврата с провайдера
+type OIDCCallback struct {
+	Code             string
+	State            string
+	Error            string
+	ErrorDescription string
+}
+
+// APIKeyPrefix отличает ключ API от JWT в заголовке Authorization
+const APIKeyPrefix = "vck_"
+
+// права ключа API
+const (
+	APIScopeRead      = "read"  // GET-запросы REST
+	APIScopeWrite     = "write" // изменяющие запросы REST
+	APIScopeWebSocket = "ws"    // подключение к /ws
+)
+
+// APIKey Личный ключ API; сам ключ показывается один раз при создании
+type APIKey struct {
+	ID         uuid.UUID  `json:"id"`
+	UserID     uuid.UUID  `json:"-"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	KeyHash    string     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+type CreateAPIKeyRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+	// срок действия в днях; 0 — бессрочный
+	ExpiresInDays int `json:"expires_in_days"`
+}
+
+type CreateAPIKeyResponse struct {
+	APIKey
+	Key string `json:"key"` // показывается один раз
+}
+
+type SetRoleRequest struct {
+	Role string `json:"role"` // user | admin
+}
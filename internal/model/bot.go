@@ -0,0 +1,25 @@
+package model
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// Bot Внешний бот, обрабатывающий slash-команды через HTTP
+type Bot struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"secret,omitempty"` // возвращается только при создании
+	Commands  []string  `json:"commands"`
+	CreatedBy uuid.UUID `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// BotRequest Запрос на регистрацию бота
+type BotRequest struct {
+	Name     string   `json:"name"`
+	URL      string   `json:"url"`
+	Commands []string `json:"commands"`
+}
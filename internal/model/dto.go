@@ -8,8 +8,22 @@ import (
 
 type Message struct {
 	ID      uuid.UUID `json:"id"`
-	User    string    `json:"user"`    // отправитель
-	Msg     string    `json:"msg"`     // текст пользователя
-	Channel string    `json:"channel"` // канал, в котором пользователь зарегистрировался
-	Time    time.Time `json:"time"`    // время отправки сообщения отправителем
+	Type    string    `json:"type,omitempty"`   // message | system | attachment_updated | mention | ephemeral | message_deleted | poll | poll_updated
+	UserID  uuid.UUID `json:"user_id"`          // id отправителя (пустой для системных сообщений)
+	User    string    `json:"user"`             // отправитель
+	Msg     string    `json:"msg"`              // текст пользователя
+	Format  string    `json:"format,omitempty"` // plain | markdown
+	HTML    string    `json:"html,omitempty"`   // безопасный HTML для format=markdown
+	Channel string    `json:"channel"`          // канал, в котором пользователь зарегистрировался
+	Time    time.Time `json:"time"`             // время отправки сообщения отправителем
+
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // после этого момента сообщение удаляется
+
+	// имя и аватар отправителя из профиля; проставляет хаб при рассылке
+	DisplayName string `json:"display_name,omitempty"`
+	AvatarURL   string `json:"avatar_url,omitempty"`
+
+	Attachments []Attachment `json:"attachments,omitempty"` // прикрепленные файлы
+	Mentions    []uuid.UUID  `json:"mentions,omitempty"`    // id упомянутых пользователей
+	Poll        *Poll        `json:"poll,omitempty"`        // для сообщений типа poll
 }
@@ -0,0 +1,44 @@
+package model
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// Типы событий канала
+const (
+	EventMessageCreated = "message.created"
+	EventMessageDeleted = "message.deleted"
+	EventMemberJoined   = "member.joined"
+	EventMemberLeft     = "member.left"
+	EventCallStarted    = "call.started"
+	EventCallEnded      = "call.ended"
+)
+
+// EventTypes Все события, на которые можно подписаться
+var EventTypes = []string{
+	EventMessageCreated,
+	EventMessageDeleted,
+	EventMemberJoined,
+	EventMemberLeft,
+	EventCallStarted,
+	EventCallEnded,
+}
+
+// ChannelEvent Событие в канале (рассылается подписчикам вебхуков)
+type ChannelEvent struct {
+	ID      uuid.UUID   `json:"id"`
+	Type    string      `json:"type"`
+	Channel string      `json:"channel"`
+	Time    time.Time   `json:"time"`
+	Data    interface{} `json:"data"`
+}
+
+// MemberEventData Данные событий member.joined / member.left
+type MemberEventData struct {
+	UserID      string `json:"user_id"`
+	Username    string `json:"username"`
+	DisplayName string `json:"display_name,omitempty"`
+	AvatarURL   string `json:"avatar_url,omitempty"`
+}
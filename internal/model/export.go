@@ -0,0 +1,50 @@
+package model
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// ChannelExport Задание на выгрузку истории канала
+type ChannelExport struct {
+	ID           uuid.UUID  `json:"id"`
+	Channel      string     `json:"channel"`
+	Format       string     `json:"format"` // jsonl | csv | html
+	Status       string     `json:"status"` // pending | running | done | failed
+	RequestedBy  uuid.UUID  `json:"requested_by"`
+	Size         int64      `json:"size"`
+	MessageCount int64      `json:"message_count"`
+	Error        string     `json:"error,omitempty"`
+	DownloadURL  string     `json:"download_url,omitempty"`
+	StorageKey   string     `json:"-"`
+	CreatedAt    time.Time  `json:"created_at"`
+	StartedAt    *time.Time `json:"started_at,omitempty"`
+	FinishedAt   *time.Time `json:"finished_at,omitempty"`
+}
+
+// ChannelExportRequest Запрос на выгрузку
+type ChannelExportRequest struct {
+	Format string `json:"format"`
+}
+
+// ExportRecord Сообщение в выгрузке
+type ExportRecord struct {
+	ID          uuid.UUID          `json:"id"`
+	Type        string             `json:"type"`
+	UserID      uuid.UUID          `json:"user_id"`
+	User        string             `json:"user"`
+	Msg         string             `json:"msg"`
+	Format      string             `json:"format"`
+	HTML        string             `json:"html,omitempty"`
+	Time        time.Time          `json:"time"`
+	Attachments []ExportAttachment `json:"attachments,omitempty"`
+}
+
+// ExportAttachment Вложение сообщения в выгрузке (только метаданные)
+type ExportAttachment struct {
+	ID          uuid.UUID `json:"id"`
+	FileName    string    `json:"file_name"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+}
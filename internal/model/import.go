@@ -0,0 +1,62 @@
+package model
+
+import "time"
+
+// ImportRecord Строка архива импорта (JSON lines); набор полей зависит от kind
+type ImportRecord struct {
+	Kind string `json:"kind"` // user | channel | message
+	ID   string `json:"id"`   // id во внешней системе
+
+	// user
+	Email    string `json:"email,omitempty"`
+	Username string `json:"username,omitempty"`
+
+	// channel
+	Name    string   `json:"name,omitempty"`
+	Members []string `json:"members,omitempty"` // внешние id пользователей
+
+	// message
+	Channel string    `json:"channel,omitempty"`
+	User    string    `json:"user,omitempty"` // внешний id автора
+	Type    string    `json:"type,omitempty"` // message | system
+	Text    string    `json:"text,omitempty"`
+	Format  string    `json:"format,omitempty"`
+	TS      time.Time `json:"ts"`
+}
+
+// ImportOptions Параметры импорта
+type ImportOptions struct {
+	Source    string `json:"source"`
+	DryRun    bool   `json:"dry_run"`
+	BatchSize int    `json:"batch_size"`
+}
+
+// ImportReport Итог импорта
+type ImportReport struct {
+	Source        string            `json:"source"`
+	DryRun        bool              `json:"dry_run"`
+	Lines         int               `json:"lines"`
+	Users         ImportCounts      `json:"users"`
+	Channels      ImportCounts      `json:"channels"`
+	Messages      ImportCounts      `json:"messages"`
+	RejectedTotal int               `json:"rejected_total"`
+	Rejected      []ImportRejection `json:"rejected"` // первые MaxImportRejections записей
+}
+
+// ImportCounts Счетчики по виду записей
+type ImportCounts struct {
+	Imported int `json:"imported"`
+	Linked   int `json:"linked,omitempty"`  // пользователи, сопоставленные с существующими
+	Skipped  int `json:"skipped,omitempty"` // уже импортированные ранее
+}
+
+// ImportRejection Отклоненная запись
+type ImportRejection struct {
+	Line   int    `json:"line"`
+	Kind   string `json:"kind,omitempty"`
+	ID     string `json:"id,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// MaxImportRejections Сколько отклоненных записей попадает в отчет
+const MaxImportRejections = 1000
@@ -0,0 +1,32 @@
+package model
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// Виды упоминаний
+const (
+	MentionUser    = "user"    // @username
+	MentionChannel = "channel" // @channel — все участники канала
+	MentionHere    = "here"    // @here — участники канала, которые сейчас онлайн
+)
+
+// Mention Упоминание пользователя в сообщении
+type Mention struct {
+	MessageID uuid.UUID `json:"message_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	Kind      string    `json:"kind"`
+	Channel   string    `json:"channel"`
+	User      string    `json:"user"` // автор сообщения
+	Msg       string    `json:"msg"`
+	Time      time.Time `json:"time"`
+}
+
+// MentionsResponse Страница упоминаний пользователя
+type MentionsResponse struct {
+	Mentions []Mention `json:"mentions"`
+	Limit    int       `json:"limit"`
+	Offset   int       `json:"offset"`
+}
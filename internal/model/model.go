@@ -10,14 +10,53 @@ import (
 type User struct {
 	Id        uuid.UUID `db:"id"`
 	Email     string    `db:"email"`
+	Username  string    `db:"username"`
 	Password  string    `db:"password"`
 	CreatedAt time.Time `db:"created_at"`
+
+	EmailVerifiedAt *time.Time `db:"email_verified_at"`
 }
 
 // DTOResponse Структура server для ответа
 type DTOResponse struct {
-	ID    uuid.UUID `json:"id"`
-	Email string    `json:"email"`
+	ID          uuid.UUID  `json:"id"`
+	Email       string     `json:"email"`
+	Username    string     `json:"username,omitempty"`
+	DisplayName string     `json:"display_name,omitempty"`
+	AvatarID    *uuid.UUID `json:"-"`
+	AvatarURL   string     `json:"avatar_url,omitempty"`
+	Role        string     `json:"role"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// Поля сортировки списка пользователей
+const (
+	UserSortEmail     = "email"
+	UserSortName      = "name" // отображаемое имя, без него username или email
+	UserSortCreatedAt = "created_at"
+)
+
+// ListUsersRequest Параметры страницы списка пользователей
+type ListUsersRequest struct {
+	Query  string // префикс email или отображаемого имени
+	Sort   string // email | name | created_at
+	Order  string // asc | desc
+	Limit  int
+	Cursor string // next_cursor предыдущей страницы
+}
+
+// UserCursor Позиция в списке: ключ сортировки и id последней строки страницы
+type UserCursor struct {
+	Key string    `json:"k"`
+	ID  uuid.UUID `json:"id"`
+}
+
+// ListUsersResponse Страница списка пользователей
+type ListUsersResponse struct {
+	Users      []DTOResponse `json:"users"`
+	Total      int64         `json:"total"` // всего пользователей под фильтром
+	Limit      int           `json:"limit"`
+	NextCursor string        `json:"next_cursor,omitempty"` // пустой на последней странице
 }
 
 // DTORequest Структура server для запроса
@@ -0,0 +1,43 @@
+package model
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// Poll Опрос в канале; id совпадает с id сообщения типа poll
+type Poll struct {
+	ID          uuid.UUID    `json:"id"`
+	Channel     string       `json:"channel"`
+	Question    string       `json:"question"`
+	Options     []PollOption `json:"options"`
+	Multiple    bool         `json:"multiple"`
+	Anonymous   bool         `json:"anonymous"`
+	CreatedBy   uuid.UUID    `json:"created_by"`
+	CreatedAt   time.Time    `json:"created_at"`
+	ClosedAt    *time.Time   `json:"closed_at,omitempty"`
+	TotalVoters int          `json:"total_voters"`
+	MyVotes     []int        `json:"my_votes,omitempty"` // только в ответах конкретному пользователю
+}
+
+// PollOption Вариант ответа с текущим числом голосов
+type PollOption struct {
+	Index  int         `json:"index"`
+	Text   string      `json:"text"`
+	Votes  int         `json:"votes"`
+	Voters []uuid.UUID `json:"voters,omitempty"` // не заполняется для анонимных опросов
+}
+
+// PollRequest Запрос на создание опроса
+type PollRequest struct {
+	Question  string   `json:"question"`
+	Options   []string `json:"options"`
+	Multiple  bool     `json:"multiple"`
+	Anonymous bool     `json:"anonymous"`
+}
+
+// PollVoteRequest Выбор пользователя; пустой список отзывает голос
+type PollVoteRequest struct {
+	Options []int `json:"options"`
+}
@@ -0,0 +1,43 @@
+package model
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// Profile Профиль пользователя; истекший статус не возвращается
+type Profile struct {
+	UserID          uuid.UUID  `json:"user_id"`
+	Username        string     `json:"username,omitempty"`
+	DisplayName     string     `json:"display_name,omitempty"`
+	AvatarID        *uuid.UUID `json:"avatar_id,omitempty"`
+	AvatarURL       string     `json:"avatar_url,omitempty"`
+	Bio             string     `json:"bio,omitempty"`
+	Timezone        string     `json:"timezone,omitempty"` // IANA, например Europe/Moscow
+	StatusText      string     `json:"status_text,omitempty"`
+	StatusExpiresAt *time.Time `json:"status_expires_at,omitempty"`
+	UpdatedAt       *time.Time `json:"updated_at,omitempty"`
+}
+
+// ProfilePatch Изменения профиля: отсутствующее поле не меняется, пустая строка очищает
+type ProfilePatch struct {
+	DisplayName *string `json:"display_name"`
+	AvatarID    *string `json:"avatar_id"` // id загруженного пользователем изображения
+	Bio         *string `json:"bio"`
+	Timezone    *string `json:"timezone"`
+	StatusText  *string `json:"status_text"`
+	// срок статуса; без него статус бессрочный. Учитывается вместе со status_text
+	StatusExpiresAt *time.Time `json:"status_expires_at"`
+}
+
+// ProfileStamp Имя и аватар, которыми хаб подписывает сообщения и события присутствия
+type ProfileStamp struct {
+	DisplayName string
+	AvatarURL   string
+}
+
+// AvatarURL адрес меняется вместе с аватаром, чтобы клиенты не показывали старый из кеша
+func AvatarURL(userID, avatarID uuid.UUID) string {
+	return "/users/" + userID.String() + "/avatar?v=" + avatarID.String()[:8]
+}
@@ -0,0 +1,20 @@
+package model
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// RetentionPolicy Срок хранения сообщений канала; Days == nil — хранить всегда
+type RetentionPolicy struct {
+	Channel   string     `json:"channel"`
+	Days      *int       `json:"days"`
+	UpdatedBy uuid.UUID  `json:"updated_by,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// RetentionPolicyRequest Запрос на изменение срока хранения
+type RetentionPolicyRequest struct {
+	Days *int `json:"days"`
+}
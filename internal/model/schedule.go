@@ -0,0 +1,31 @@
+package model
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// ScheduledMessage Отложенное сообщение; после отправки id совпадает с id сообщения в канале
+type ScheduledMessage struct {
+	ID          uuid.UUID  `json:"id"`
+	Channel     string     `json:"channel"`
+	UserID      uuid.UUID  `json:"user_id"`
+	User        string     `json:"user"`
+	Msg         string     `json:"msg"`
+	Format      string     `json:"format"`
+	Attachments []string   `json:"attachments,omitempty"` // id вложений, привязываются при отправке
+	SendAt      time.Time  `json:"send_at"`
+	Status      string     `json:"status"` // pending | sending | sent | cancelled | failed
+	LastError   string     `json:"last_error,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	SentAt      *time.Time `json:"sent_at,omitempty"`
+}
+
+// ScheduledMessageRequest Запрос на создание отложенного сообщения
+type ScheduledMessageRequest struct {
+	Msg         string    `json:"msg"`
+	Format      string    `json:"format"`
+	Attachments []string  `json:"attachments"`
+	SendAt      time.Time `json:"send_at"`
+}
@@ -0,0 +1,39 @@
+package model
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// SearchMessagesRequest Параметры полнотекстового поиска по сообщениям
+type SearchMessagesRequest struct {
+	UserID   string    // пользователь, от имени которого выполняется поиск
+	Query    string    // текст запроса
+	Channel  string    // фильтр по каналу
+	Author   string    // фильтр по имени отправителя
+	AuthorID string    // фильтр по id отправителя
+	From     time.Time // нижняя граница даты
+	To       time.Time // верхняя граница даты
+	Limit    int
+	Offset   int
+}
+
+// SearchMessageResult Найденное сообщение с подсвеченным фрагментом
+type SearchMessageResult struct {
+	ID      uuid.UUID `json:"id"`
+	UserID  uuid.UUID `json:"user_id"`
+	User    string    `json:"user"`
+	Msg     string    `json:"msg"`
+	Channel string    `json:"channel"`
+	Time    time.Time `json:"time"`
+	Snippet string    `json:"snippet"` // фрагмент текста с <mark>...</mark>
+	Rank    float32   `json:"rank"`
+}
+
+// SearchMessagesResponse Страница результатов поиска
+type SearchMessagesResponse struct {
+	Results []SearchMessageResult `json:"results"`
+	Limit   int                   `json:"limit"`
+	Offset  int                   `json:"offset"`
+}
@@ -0,0 +1,68 @@
+package model
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// WebhookSubscription Подписка канала на исходящие вебхуки
+type WebhookSubscription struct {
+	ID        uuid.UUID `json:"id"`
+	Channel   string    `json:"channel"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"secret,omitempty"` // возвращается только при создании
+	Events    []string  `json:"events"`
+	CreatedBy uuid.UUID `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WebhookSubscriptionRequest Запрос на создание подписки
+type WebhookSubscriptionRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+}
+
+// WebhookDelivery Попытка доставки события подписчику
+type WebhookDelivery struct {
+	ID             uuid.UUID  `json:"id"`
+	SubscriptionID uuid.UUID  `json:"subscription_id"`
+	Event          string     `json:"event"`
+	Payload        []byte     `json:"-"`
+	Status         string     `json:"status"` // pending | delivered | dead
+	Attempts       int        `json:"attempts"`
+	LastStatusCode int        `json:"last_status_code,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+
+	// для отправки: адрес и секрет подписки
+	URL    string `json:"-"`
+	Secret string `json:"-"`
+}
+
+// IncomingWebhook Входящий вебхук: внешняя система публикует сообщения в канал
+type IncomingWebhook struct {
+	ID                 uuid.UUID  `json:"id"`
+	Channel            string     `json:"channel"`
+	Name               string     `json:"name"`
+	URL                string     `json:"url,omitempty"` // содержит токен, возвращается только при создании
+	TokenHash          string     `json:"-"`
+	RateLimitPerMinute int        `json:"rate_limit_per_minute"`
+	CreatedBy          uuid.UUID  `json:"created_by"`
+	CreatedAt          time.Time  `json:"created_at"`
+	RevokedAt          *time.Time `json:"revoked_at,omitempty"`
+}
+
+// IncomingWebhookRequest Запрос на создание входящего вебхука
+type IncomingWebhookRequest struct {
+	Name               string `json:"name"`
+	RateLimitPerMinute int    `json:"rate_limit_per_minute"`
+}
+
+// IncomingWebhookPayload Тело запроса от внешней системы
+type IncomingWebhookPayload struct {
+	Text   string `json:"text"`
+	Format string `json:"format"` // plain | markdown
+}
@@ -0,0 +1,154 @@
+package pollhandler
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	authmiddleware "github.com/QuUteO/video-communication/internal/auth/middleware"
+	"github.com/QuUteO/video-communication/internal/model"
+	"github.com/QuUteO/video-communication/internal/poll/service"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+type Handler struct {
+	service pollservice.Service
+	logger  *slog.Logger
+}
+
+func NewHandler(service pollservice.Service, logger *slog.Logger) *Handler {
+	return &Handler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// Create POST /channels/{channel}/polls
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	const op = "internal/poll/handler/Create"
+	log := h.logger.With("op: ", op)
+
+	var req model.PollRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.error(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	userID, _ := r.Context().Value(authmiddleware.UserIDKey).(string)
+
+	msg, err := h.service.Create(r.Context(), userID, "", chi.URLParam(r, "channel"), &req)
+	if err != nil {
+		h.fail(w, r, log, err)
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, model.Response{
+		StatusCode: http.StatusCreated,
+		Message:    "Poll created",
+		Data:       msg,
+		Error:      "nil",
+	})
+}
+
+// Get GET /polls/{id}
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
+	const op = "internal/poll/handler/Get"
+	log := h.logger.With("op: ", op)
+
+	userID, _ := r.Context().Value(authmiddleware.UserIDKey).(string)
+
+	poll, err := h.service.Get(r.Context(), userID, chi.URLParam(r, "id"))
+	if err != nil {
+		h.fail(w, r, log, err)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, model.Response{
+		StatusCode: http.StatusOK,
+		Message:    "Poll retrieved successfully",
+		Data:       poll,
+		Error:      "nil",
+	})
+}
+
+// Vote POST /polls/{id}/votes
+func (h *Handler) Vote(w http.ResponseWriter, r *http.Request) {
+	const op = "internal/poll/handler/Vote"
+	log := h.logger.With("op: ", op)
+
+	var req model.PollVoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.error(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	userID, _ := r.Context().Value(authmiddleware.UserIDKey).(string)
+
+	poll, err := h.service.Vote(r.Context(), userID, chi.URLParam(r, "id"), &req)
+	if err != nil {
+		h.fail(w, r, log, err)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, model.Response{
+		StatusCode: http.StatusOK,
+		Message:    "Vote saved",
+		Data:       poll,
+		Error:      "nil",
+	})
+}
+
+// Close POST /polls/{id}/close
+func (h *Handler) Close(w http.ResponseWriter, r *http.Request) {
+	const op = "internal/poll/handler/Close"
+	log := h.logger.With("op: ", op)
+
+	userID, _ := r.Context().Value(authmiddleware.UserIDKey).(string)
+
+	poll, err := h.service.Close(r.Context(), userID, chi.URLParam(r, "id"))
+	if err != nil {
+		h.fail(w, r, log, err)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, model.Response{
+		StatusCode: http.StatusOK,
+		Message:    "Poll closed",
+		Data:       poll,
+		Error:      "nil",
+	})
+}
+
+func (h *Handler) fail(w http.ResponseWriter, r *http.Request, log *slog.Logger, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, pollservice.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, pollservice.ErrForbidden), errors.Is(err, pollservice.ErrNotCreator):
+		status = http.StatusForbidden
+	case errors.Is(err, pollservice.ErrClosed):
+		status = http.StatusConflict
+	case errors.Is(err, pollservice.ErrInvalidPoll),
+		errors.Is(err, pollservice.ErrInvalidChoice),
+		errors.Is(err, pollservice.ErrSingleChoice):
+		status = http.StatusBadRequest
+	default:
+		log.Error("Poll request failed", slog.Any("error", err))
+	}
+
+	h.error(w, r, status, err.Error())
+}
+
+func (h *Handler) error(w http.ResponseWriter, r *http.Request, status int, msg string) {
+	render.Status(r, status)
+	render.JSON(w, r, model.Response{
+		StatusCode: status,
+		Error:      msg,
+	})
+}
@@ -0,0 +1,293 @@
+package pollrepository
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+
+	"github.com/QuUteO/video-communication/internal/model"
+	postgres "github.com/QuUteO/video-communication/pkg/db"
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+const selectColumns = `id, channel, question, options, multiple, anonymous, created_by, created_at, closed_at, results`
+
+type Repository interface {
+	Create(ctx context.Context, poll *model.Poll) error
+	FindByID(ctx context.Context, id string) (*model.Poll, error)
+	FindByIDs(ctx context.Context, ids []string) ([]model.Poll, error)
+	UserVotes(ctx context.Context, pollID, userID string) ([]int, error)
+
+	Vote(ctx context.Context, pollID, userID string, options []int) (bool, error)
+	Close(ctx context.Context, pollID, userID string) (bool, error)
+	SaveResults(ctx context.Context, poll *model.Poll) error
+}
+
+type repository struct {
+	db     postgres.Client
+	logger *slog.Logger
+}
+
+// resultRow Итоги варианта в колонке results (JSONB)
+type resultRow struct {
+	Votes  int         `json:"votes"`
+	Voters []uuid.UUID `json:"voters,omitempty"`
+}
+
+func (r *repository) Create(ctx context.Context, poll *model.Poll) error {
+	const op = "./internal/poll/repository.Create"
+	log := r.logger.With("op: ", op)
+
+	options := make([]string, 0, len(poll.Options))
+	for _, o := range poll.Options {
+		options = append(options, o.Text)
+	}
+
+	q := `
+		INSERT INTO polls (id, channel, question, options, multiple, anonymous, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+
+	if _, err := r.db.Exec(ctx, q, poll.ID, poll.Channel, poll.Question, options, poll.Multiple, poll.Anonymous,
+		poll.CreatedBy, poll.CreatedAt); err != nil {
+		log.Error("Error to insert poll", slog.Any("err", err))
+		return err
+	}
+
+	return nil
+}
+
+// FindByID опрос с подсчитанными голосами
+func (r *repository) FindByID(ctx context.Context, id string) (*model.Poll, error) {
+	polls, err := r.FindByIDs(ctx, []string{id})
+	if err != nil {
+		return nil, err
+	}
+	if len(polls) == 0 {
+		return nil, pgx.ErrNoRows
+	}
+	return &polls[0], nil
+}
+
+func (r *repository) FindByIDs(ctx context.Context, ids []string) ([]model.Poll, error) {
+	const op = "./internal/poll/repository.FindByIDs"
+	log := r.logger.With("op: ", op)
+
+	rows, err := r.db.Query(ctx, `SELECT `+selectColumns+` FROM polls WHERE id = ANY($1::uuid[])`, ids)
+	if err != nil {
+		log.Error("Error to find polls", slog.Any("err", err))
+		return nil, err
+	}
+
+	polls := make([]model.Poll, 0)
+	open := make([]string, 0)
+	for rows.Next() {
+		poll, frozen, err := scanPoll(rows)
+		if err != nil {
+			rows.Close()
+			log.Error("Error to scan poll", slog.Any("err", err))
+			return nil, err
+		}
+		if !frozen {
+			open = append(open, poll.ID.String())
+		}
+		polls = append(polls, *poll)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(open) == 0 {
+		return polls, nil
+	}
+	if err := r.tally(ctx, open, polls); err != nil {
+		log.Error("Error to count poll votes", slog.Any("err", err))
+		return nil, err
+	}
+
+	return polls, nil
+}
+
+// tally подсчитывает голоса опросов, итоги которых еще не зафиксированы
+func (r *repository) tally(ctx context.Context, ids []string, polls []model.Poll) error {
+	q := `
+		SELECT poll_id, option_idx, user_id
+		FROM poll_votes
+		WHERE poll_id = ANY($1::uuid[])
+		ORDER BY created_at
+	`
+
+	rows, err := r.db.Query(ctx, q, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	open := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		open[id] = true
+	}
+
+	byID := make(map[uuid.UUID]*model.Poll, len(ids))
+	voters := make(map[uuid.UUID]map[uuid.UUID]bool, len(ids))
+	for i := range polls {
+		if open[polls[i].ID.String()] {
+			byID[polls[i].ID] = &polls[i]
+			voters[polls[i].ID] = make(map[uuid.UUID]bool)
+		}
+	}
+
+	for rows.Next() {
+		var pollID, userID uuid.UUID
+		var idx int
+		if err := rows.Scan(&pollID, &idx, &userID); err != nil {
+			return err
+		}
+
+		poll := byID[pollID]
+		if poll == nil || idx < 0 || idx >= len(poll.Options) {
+			continue
+		}
+		poll.Options[idx].Votes++
+		poll.Options[idx].Voters = append(poll.Options[idx].Voters, userID)
+		voters[pollID][userID] = true
+	}
+
+	for id, poll := range byID {
+		poll.TotalVoters = len(voters[id])
+	}
+
+	return rows.Err()
+}
+
+func (r *repository) UserVotes(ctx context.Context, pollID, userID string) ([]int, error) {
+	rows, err := r.db.Query(ctx, `SELECT option_idx FROM poll_votes WHERE poll_id = $1 AND user_id = $2 ORDER BY option_idx`, pollID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	votes := make([]int, 0)
+	for rows.Next() {
+		var idx int
+		if err := rows.Scan(&idx); err != nil {
+			return nil, err
+		}
+		votes = append(votes, idx)
+	}
+
+	return votes, rows.Err()
+}
+
+// Vote заменяет выбор пользователя; false — опрос закрыт или не найден.
+// Снимаются только голоса вне нового выбора, поэтому удаление и вставка не пересекаются.
+func (r *repository) Vote(ctx context.Context, pollID, userID string, options []int) (bool, error) {
+	const op = "./internal/poll/repository.Vote"
+	log := r.logger.With("op: ", op)
+
+	q := `
+		WITH poll AS (
+			SELECT id FROM polls WHERE id = $1 AND closed_at IS NULL
+		), removed AS (
+			DELETE FROM poll_votes
+			WHERE poll_id IN (SELECT id FROM poll) AND user_id = $2 AND option_idx <> ALL($3::int[])
+		), added AS (
+			INSERT INTO poll_votes (poll_id, option_idx, user_id)
+			SELECT poll.id, o.idx, $2 FROM poll, unnest($3::int[]) AS o(idx)
+			ON CONFLICT DO NOTHING
+		)
+		SELECT count(*) FROM poll
+	`
+
+	var open int
+	if err := r.db.QueryRow(ctx, q, pollID, userID, options).Scan(&open); err != nil {
+		log.Error("Error to save poll vote", slog.Any("err", err))
+		return false, err
+	}
+
+	return open > 0, nil
+}
+
+// Close закрывает опрос; закрыть может только автор
+func (r *repository) Close(ctx context.Context, pollID, userID string) (bool, error) {
+	const op = "./internal/poll/repository.Close"
+	log := r.logger.With("op: ", op)
+
+	q := `UPDATE polls SET closed_at = now() WHERE id = $1 AND created_by = $2 AND closed_at IS NULL`
+
+	tag, err := r.db.Exec(ctx, q, pollID, userID)
+	if err != nil {
+		log.Error("Error to close poll", slog.Any("err", err))
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// SaveResults фиксирует итоги закрытого опроса: они не меняются, даже если голосовавшие удалят аккаунт
+func (r *repository) SaveResults(ctx context.Context, poll *model.Poll) error {
+	results := make([]resultRow, 0, len(poll.Options))
+	for _, o := range poll.Options {
+		results = append(results, resultRow{Votes: o.Votes, Voters: o.Voters})
+	}
+
+	data, err := json.Marshal(struct {
+		TotalVoters int         `json:"total_voters"`
+		Options     []resultRow `json:"options"`
+	}{poll.TotalVoters, results})
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.Exec(ctx, `UPDATE polls SET results = $2 WHERE id = $1 AND closed_at IS NOT NULL`, poll.ID, string(data))
+	return err
+}
+
+// scanPoll читает опрос; frozen — итоги взяты из зафиксированных результатов
+func scanPoll(row pgx.Row) (*model.Poll, bool, error) {
+	var poll model.Poll
+	var options []string
+	var createdBy uuid.NullUUID
+	var results []byte
+
+	if err := row.Scan(&poll.ID, &poll.Channel, &poll.Question, &options, &poll.Multiple, &poll.Anonymous,
+		&createdBy, &poll.CreatedAt, &poll.ClosedAt, &results); err != nil {
+		return nil, false, err
+	}
+	poll.CreatedBy = createdBy.UUID
+
+	poll.Options = make([]model.PollOption, len(options))
+	for i, text := range options {
+		poll.Options[i] = model.PollOption{Index: i, Text: text}
+	}
+
+	if results == nil {
+		return &poll, false, nil
+	}
+
+	var frozen struct {
+		TotalVoters int         `json:"total_voters"`
+		Options     []resultRow `json:"options"`
+	}
+	if err := json.Unmarshal(results, &frozen); err != nil {
+		return nil, false, err
+	}
+	poll.TotalVoters = frozen.TotalVoters
+	for i, res := range frozen.Options {
+		if i < len(poll.Options) {
+			poll.Options[i].Votes = res.Votes
+			poll.Options[i].Voters = res.Voters
+		}
+	}
+
+	return &poll, true, nil
+}
+
+func New(db postgres.Client, logger *slog.Logger) Repository {
+	return &repository{
+		db:     db,
+		logger: logger,
+	}
+}
@@ -0,0 +1,316 @@
+package pollservice
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/QuUteO/video-communication/internal/model"
+	"github.com/QuUteO/video-communication/internal/poll/repository"
+	"github.com/QuUteO/video-communication/internal/user/service"
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+const (
+	minOptions        = 2
+	maxOptions        = 10
+	maxQuestionLength = 300
+	maxOptionLength   = 200
+)
+
+var (
+	ErrNotFound      = errors.New("poll not found")
+	ErrForbidden     = errors.New("no access to channel")
+	ErrNotCreator    = errors.New("only the poll creator can close it")
+	ErrClosed        = errors.New("poll is closed")
+	ErrInvalidPoll   = errors.New("poll needs a question (up to 300 characters) and 2-10 distinct options (up to 200 characters)")
+	ErrInvalidChoice = errors.New("invalid poll options selected")
+	ErrSingleChoice  = errors.New("poll allows only one option")
+)
+
+// Broadcaster Рассылка сообщений подключенным клиентам (реализуется websocket.Hub)
+type Broadcaster interface {
+	Broadcast(msg model.Message)
+	PublishEvent(eventType, channel string, data interface{})
+}
+
+type Service interface {
+	Create(ctx context.Context, userID, username, channel string, req *model.PollRequest) (*model.Message, error)
+	Get(ctx context.Context, userID, id string) (*model.Poll, error)
+	Vote(ctx context.Context, userID, id string, req *model.PollVoteRequest) (*model.Poll, error)
+	Close(ctx context.Context, userID, id string) (*model.Poll, error)
+	// FillMessages прикрепляет опросы к сообщениям типа poll (история канала)
+	FillMessages(ctx context.Context, messages []model.Message) error
+}
+
+type PollService struct {
+	repo   pollrepository.Repository
+	users  service.Service
+	hub    Broadcaster
+	logger *slog.Logger
+}
+
+func (s *PollService) Create(ctx context.Context, userID, username, channel string, req *model.PollRequest) (*model.Message, error) {
+	const op = "internal/poll/service.Create"
+	log := s.logger.With("op: ", op)
+
+	if err := s.checkAccess(ctx, channel, userID); err != nil {
+		return nil, err
+	}
+
+	question := strings.TrimSpace(req.Question)
+	if question == "" || len([]rune(question)) > maxQuestionLength {
+		return nil, ErrInvalidPoll
+	}
+	if len(req.Options) < minOptions || len(req.Options) > maxOptions {
+		return nil, ErrInvalidPoll
+	}
+
+	options := make([]model.PollOption, 0, len(req.Options))
+	seen := make(map[string]bool, len(req.Options))
+	for i, text := range req.Options {
+		text = strings.TrimSpace(text)
+		key := strings.ToLower(text)
+		if text == "" || len([]rune(text)) > maxOptionLength || seen[key] {
+			return nil, ErrInvalidPoll
+		}
+		seen[key] = true
+		options = append(options, model.PollOption{Index: i, Text: text})
+	}
+
+	if username == "" {
+		user, err := s.users.FindUserById(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		username = user.Username
+		if username == "" {
+			username = "User_" + userID[len(userID)-6:]
+		}
+	}
+
+	now := time.Now()
+	poll := &model.Poll{
+		ID:        uuid.Must(uuid.NewV4()),
+		Channel:   channel,
+		Question:  question,
+		Options:   options,
+		Multiple:  req.Multiple,
+		Anonymous: req.Anonymous,
+		CreatedBy: uuid.FromStringOrNil(userID),
+		CreatedAt: now,
+	}
+
+	// опрос хранится рядом с сообщением, поэтому попадает в историю и удаляется вместе с ним
+	msg := model.Message{
+		ID:      poll.ID,
+		Type:    "poll",
+		UserID:  poll.CreatedBy,
+		User:    username,
+		Msg:     question,
+		Channel: channel,
+		Time:    now,
+	}
+
+	if err := s.users.SaveMsg(ctx, msg); err != nil {
+		log.Error("Error saving poll message", slog.Any("error", err))
+		return nil, err
+	}
+	if err := s.repo.Create(ctx, poll); err != nil {
+		log.Error("Error creating poll", slog.Any("error", err))
+		return nil, err
+	}
+
+	msg.Poll = poll
+	s.hub.Broadcast(msg)
+	s.hub.PublishEvent(model.EventMessageCreated, msg.Channel, msg)
+
+	return &msg, nil
+}
+
+func (s *PollService) Get(ctx context.Context, userID, id string) (*model.Poll, error) {
+	poll, err := s.find(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	poll.MyVotes, err = s.repo.UserVotes(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return public(poll), nil
+}
+
+func (s *PollService) Vote(ctx context.Context, userID, id string, req *model.PollVoteRequest) (*model.Poll, error) {
+	const op = "internal/poll/service.Vote"
+	log := s.logger.With("op: ", op)
+
+	poll, err := s.find(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if poll.ClosedAt != nil {
+		return nil, ErrClosed
+	}
+
+	choice := slices.Compact(slices.Sorted(slices.Values(req.Options)))
+	if !poll.Multiple && len(choice) > 1 {
+		return nil, ErrSingleChoice
+	}
+	for _, idx := range choice {
+		if idx < 0 || idx >= len(poll.Options) {
+			return nil, ErrInvalidChoice
+		}
+	}
+
+	open, err := s.repo.Vote(ctx, id, userID, choice)
+	if err != nil {
+		log.Error("Error saving vote", slog.Any("error", err))
+		return nil, err
+	}
+	if !open {
+		return nil, ErrClosed
+	}
+
+	poll, err = s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	s.broadcast(poll)
+
+	result := public(poll)
+	result.MyVotes = choice
+	return result, nil
+}
+
+func (s *PollService) Close(ctx context.Context, userID, id string) (*model.Poll, error) {
+	const op = "internal/poll/service.Close"
+	log := s.logger.With("op: ", op)
+
+	poll, err := s.find(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if poll.CreatedBy.String() != userID {
+		return nil, ErrNotCreator
+	}
+
+	closed, err := s.repo.Close(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !closed {
+		return nil, ErrClosed
+	}
+
+	// после закрытия голоса не принимаются, поэтому подсчет уже окончательный
+	poll, err = s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.SaveResults(ctx, poll); err != nil {
+		log.Error("Error saving poll results", slog.Any("error", err))
+	}
+	s.broadcast(poll)
+
+	return public(poll), nil
+}
+
+func (s *PollService) FillMessages(ctx context.Context, messages []model.Message) error {
+	ids := make([]string, 0)
+	for _, msg := range messages {
+		if msg.Type == "poll" {
+			ids = append(ids, msg.ID.String())
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	polls, err := s.repo.FindByIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	byID := make(map[uuid.UUID]*model.Poll, len(polls))
+	for i := range polls {
+		byID[polls[i].ID] = public(&polls[i])
+	}
+	for i := range messages {
+		if poll, ok := byID[messages[i].ID]; ok {
+			messages[i].Poll = poll
+		}
+	}
+
+	return nil
+}
+
+func (s *PollService) find(ctx context.Context, userID, id string) (*model.Poll, error) {
+	if _, err := uuid.FromString(id); err != nil {
+		return nil, ErrNotFound
+	}
+
+	poll, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	if err := s.checkAccess(ctx, poll.Channel, userID); err != nil {
+		return nil, err
+	}
+	return poll, nil
+}
+
+// broadcast рассылает актуальные итоги всем клиентам канала
+func (s *PollService) broadcast(poll *model.Poll) {
+	s.hub.Broadcast(model.Message{
+		ID:      poll.ID,
+		Type:    "poll_updated",
+		Channel: poll.Channel,
+		Time:    time.Now(),
+		Poll:    public(poll),
+	})
+}
+
+func (s *PollService) checkAccess(ctx context.Context, channel, userID string) error {
+	member, err := s.users.IsChannelMember(ctx, channel, userID)
+	if err != nil {
+		return err
+	}
+	if !member {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// public скрывает проголосовавших в анонимных опросах
+func public(poll *model.Poll) *model.Poll {
+	if !poll.Anonymous {
+		return poll
+	}
+
+	result := *poll
+	result.Options = make([]model.PollOption, len(poll.Options))
+	for i, o := range poll.Options {
+		o.Voters = nil
+		result.Options[i] = o
+	}
+	return &result
+}
+
+func NewPollService(repo pollrepository.Repository, users service.Service, hub Broadcaster, logger *slog.Logger) Service {
+	return &PollService{
+		repo:   repo,
+		users:  users,
+		hub:    hub,
+		logger: logger,
+	}
+}
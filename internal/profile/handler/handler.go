@@ -0,0 +1,104 @@
+package profilehandler
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	authmiddleware "github.com/QuUteO/video-communication/internal/auth/middleware"
+	"github.com/QuUteO/video-communication/internal/model"
+	"github.com/QuUteO/video-communication/internal/profile/service"
+	"github.com/go-chi/render"
+)
+
+type Handler struct {
+	service profileservice.Service
+	logger  *slog.Logger
+}
+
+func NewHandler(service profileservice.Service, logger *slog.Logger) *Handler {
+	return &Handler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// Get GET /me/profile
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
+	const op = "internal/profile/handler/Get"
+	log := h.logger.With("op: ", op)
+
+	userID, _ := r.Context().Value(authmiddleware.UserIDKey).(string)
+
+	profile, err := h.service.Get(r.Context(), userID)
+	if err != nil {
+		h.fail(w, r, log, err)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, model.Response{
+		StatusCode: http.StatusOK,
+		Message:    "Profile retrieved successfully",
+		Data:       profile,
+		Error:      "nil",
+	})
+}
+
+// Update PATCH /me/profile
+func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
+	const op = "internal/profile/handler/Update"
+	log := h.logger.With("op: ", op)
+
+	var patch model.ProfilePatch
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		h.error(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	userID, _ := r.Context().Value(authmiddleware.UserIDKey).(string)
+
+	profile, err := h.service.Update(r.Context(), userID, &patch)
+	if err != nil {
+		h.fail(w, r, log, err)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, model.Response{
+		StatusCode: http.StatusOK,
+		Message:    "Profile updated",
+		Data:       profile,
+		Error:      "nil",
+	})
+}
+
+func (h *Handler) fail(w http.ResponseWriter, r *http.Request, log *slog.Logger, err error) {
+	status := http.StatusInternalServerError
+	msg := err.Error()
+
+	switch {
+	case errors.Is(err, profileservice.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, profileservice.ErrInvalidDisplayName),
+		errors.Is(err, profileservice.ErrInvalidBio),
+		errors.Is(err, profileservice.ErrInvalidTimezone),
+		errors.Is(err, profileservice.ErrInvalidStatus),
+		errors.Is(err, profileservice.ErrInvalidAvatar):
+		status = http.StatusBadRequest
+	default:
+		log.Error("Profile request failed", slog.Any("error", err))
+		msg = "internal error"
+	}
+
+	h.error(w, r, status, msg)
+}
+
+func (h *Handler) error(w http.ResponseWriter, r *http.Request, status int, msg string) {
+	render.Status(r, status)
+	render.JSON(w, r, model.Response{
+		StatusCode: status,
+		Error:      msg,
+	})
+}
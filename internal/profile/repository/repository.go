@@ -0,0 +1,109 @@
+package profilerepository
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/QuUteO/video-communication/internal/model"
+	postgres "github.com/QuUteO/video-communication/pkg/db"
+)
+
+type Repository interface {
+	Get(ctx context.Context, userID string) (*model.Profile, error)
+	Update(ctx context.Context, userID string, patch *model.ProfilePatch) error
+	AvatarUsable(ctx context.Context, userID, attachmentID string) (bool, error)
+}
+
+type repository struct {
+	db     postgres.Client
+	logger *slog.Logger
+}
+
+// Get профиль пользователя; pgx.ErrNoRows — пользователя нет
+func (r *repository) Get(ctx context.Context, userID string) (*model.Profile, error) {
+	// истекший статус скрывается при чтении, отдельная очистка не нужна; срок хранится в UTC
+	q := `
+		SELECT id, COALESCE(username, ''), COALESCE(display_name, ''), avatar_id, COALESCE(bio, ''),
+		       COALESCE(timezone, ''),
+		       CASE WHEN status_expires_at IS NULL OR status_expires_at > (now() AT TIME ZONE 'UTC')
+		            THEN COALESCE(status_text, '') ELSE '' END,
+		       CASE WHEN status_expires_at > (now() AT TIME ZONE 'UTC') THEN status_expires_at END,
+		       profile_updated_at
+		FROM users
+		WHERE id = $1
+	`
+
+	var p model.Profile
+	if err := r.db.QueryRow(ctx, q, userID).Scan(&p.UserID, &p.Username, &p.DisplayName, &p.AvatarID, &p.Bio,
+		&p.Timezone, &p.StatusText, &p.StatusExpiresAt, &p.UpdatedAt); err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+// Update меняет только переданные поля; пустая строка записывается как NULL
+func (r *repository) Update(ctx context.Context, userID string, patch *model.ProfilePatch) error {
+	const op = "./internal/profile/repository.Update"
+	log := r.logger.With("op: ", op)
+
+	sets := []string{"profile_updated_at = now()"}
+	args := []any{userID}
+	set := func(column string, value any) {
+		args = append(args, value)
+		sets = append(sets, fmt.Sprintf("%s = NULLIF($%d, '')", column, len(args)))
+	}
+
+	if patch.DisplayName != nil {
+		set("display_name", *patch.DisplayName)
+	}
+	if patch.AvatarID != nil {
+		args = append(args, *patch.AvatarID)
+		sets = append(sets, fmt.Sprintf("avatar_id = NULLIF($%d, '')::uuid", len(args)))
+	}
+	if patch.Bio != nil {
+		set("bio", *patch.Bio)
+	}
+	if patch.Timezone != nil {
+		set("timezone", *patch.Timezone)
+	}
+	if patch.StatusText != nil {
+		set("status_text", *patch.StatusText)
+		// новый статус заменяет и срок прежнего
+		args = append(args, patch.StatusExpiresAt)
+		sets = append(sets, fmt.Sprintf("status_expires_at = $%d", len(args)))
+	}
+
+	q := `UPDATE users SET ` + strings.Join(sets, ", ") + ` WHERE id = $1`
+
+	if _, err := r.db.Exec(ctx, q, args...); err != nil {
+		log.Error("Error to update profile", slog.Any("err", err))
+		return err
+	}
+
+	return nil
+}
+
+// AvatarUsable вложение загружено самим пользователем и является растровым изображением
+func (r *repository) AvatarUsable(ctx context.Context, userID, attachmentID string) (bool, error) {
+	q := `
+		SELECT EXISTS (
+			SELECT 1 FROM attachments
+			WHERE id = $2 AND user_id = $1
+			  AND content_type LIKE 'image/%' AND content_type NOT LIKE 'image/svg%'
+		)
+	`
+
+	var ok bool
+	err := r.db.QueryRow(ctx, q, userID, attachmentID).Scan(&ok)
+	return ok, err
+}
+
+func New(db postgres.Client, logger *slog.Logger) Repository {
+	return &repository{
+		db:     db,
+		logger: logger,
+	}
+}
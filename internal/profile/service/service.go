@@ -0,0 +1,189 @@
+package profileservice
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+	_ "time/tzdata" // проверка часовых поясов не зависит от системной базы
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/QuUteO/video-communication/internal/model"
+	"github.com/QuUteO/video-communication/internal/profile/repository"
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+const (
+	maxDisplayName = 64
+	maxBio         = 500
+	maxStatusText  = 100
+)
+
+var (
+	ErrNotFound           = errors.New("user not found")
+	ErrInvalidDisplayName = errors.New("display name must be up to 64 characters without control characters")
+	ErrInvalidBio         = errors.New("bio must be up to 500 characters")
+	ErrInvalidTimezone    = errors.New("timezone must be an IANA name, e.g. Europe/Moscow")
+	ErrInvalidStatus      = errors.New("status text must be up to 100 characters; status_expires_at must be in the future and requires status_text")
+	ErrInvalidAvatar      = errors.New("avatar must be an image you uploaded")
+)
+
+type Service interface {
+	Get(ctx context.Context, userID string) (*model.Profile, error)
+	Update(ctx context.Context, userID string, patch *model.ProfilePatch) (*model.Profile, error)
+	// Stamp имя и аватар для подписи сообщений в хабе
+	Stamp(ctx context.Context, userID string) (model.ProfileStamp, error)
+}
+
+// ProfileNotifier получает новые имя и аватар, чтобы подключенные клиенты подписывались ими сразу (websocket.Hub)
+type ProfileNotifier interface {
+	UpdateProfile(userID string, stamp model.ProfileStamp)
+}
+
+type ProfileService struct {
+	repo     profilerepository.Repository
+	notifier ProfileNotifier
+	logger   *slog.Logger
+}
+
+func (s *ProfileService) Get(ctx context.Context, userID string) (*model.Profile, error) {
+	if _, err := uuid.FromString(userID); err != nil {
+		return nil, ErrNotFound
+	}
+
+	p, err := s.repo.Get(ctx, userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	withAvatarURL(p)
+	return p, nil
+}
+
+func (s *ProfileService) Update(ctx context.Context, userID string, patch *model.ProfilePatch) (*model.Profile, error) {
+	const op = "internal/profile/service.Update"
+	log := s.logger.With("op: ", op)
+
+	if err := s.validate(ctx, userID, patch); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Update(ctx, userID, patch); err != nil {
+		log.Error("Error updating profile", slog.Any("error", err))
+		return nil, err
+	}
+
+	p, err := s.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.notifier != nil {
+		s.notifier.UpdateProfile(userID, stampOf(p))
+	}
+
+	return p, nil
+}
+
+func (s *ProfileService) Stamp(ctx context.Context, userID string) (model.ProfileStamp, error) {
+	p, err := s.Get(ctx, userID)
+	if err != nil {
+		return model.ProfileStamp{}, err
+	}
+
+	return stampOf(p), nil
+}
+
+// validate нормализует поля патча на месте
+func (s *ProfileService) validate(ctx context.Context, userID string, patch *model.ProfilePatch) error {
+	if patch.DisplayName != nil {
+		name := strings.TrimSpace(*patch.DisplayName)
+		if utf8.RuneCountInString(name) > maxDisplayName || strings.IndexFunc(name, unicode.IsControl) >= 0 {
+			return ErrInvalidDisplayName
+		}
+		patch.DisplayName = &name
+	}
+
+	if patch.Bio != nil {
+		bio := strings.TrimSpace(*patch.Bio)
+		if utf8.RuneCountInString(bio) > maxBio {
+			return ErrInvalidBio
+		}
+		patch.Bio = &bio
+	}
+
+	if patch.Timezone != nil && *patch.Timezone != "" {
+		if *patch.Timezone == "Local" {
+			return ErrInvalidTimezone
+		}
+		if _, err := time.LoadLocation(*patch.Timezone); err != nil {
+			return ErrInvalidTimezone
+		}
+	}
+
+	if patch.StatusExpiresAt != nil && (patch.StatusText == nil || !patch.StatusExpiresAt.After(time.Now())) {
+		return ErrInvalidStatus
+	}
+	if patch.StatusText != nil {
+		text := strings.TrimSpace(*patch.StatusText)
+		if utf8.RuneCountInString(text) > maxStatusText || strings.IndexFunc(text, unicode.IsControl) >= 0 {
+			return ErrInvalidStatus
+		}
+		patch.StatusText = &text
+		if text == "" {
+			patch.StatusExpiresAt = nil
+		}
+		if patch.StatusExpiresAt != nil {
+			expires := patch.StatusExpiresAt.UTC()
+			patch.StatusExpiresAt = &expires
+		}
+	}
+
+	if patch.AvatarID != nil && *patch.AvatarID != "" {
+		if _, err := uuid.FromString(*patch.AvatarID); err != nil {
+			return ErrInvalidAvatar
+		}
+		ok, err := s.repo.AvatarUsable(ctx, userID, *patch.AvatarID)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrInvalidAvatar
+		}
+	}
+
+	return nil
+}
+
+func withAvatarURL(p *model.Profile) {
+	if p.AvatarID != nil {
+		p.AvatarURL = model.AvatarURL(p.UserID, *p.AvatarID)
+	}
+}
+
+// stampOf без отображаемого имени сообщения подписываются именем пользователя
+func stampOf(p *model.Profile) model.ProfileStamp {
+	name := p.DisplayName
+	if name == "" {
+		name = p.Username
+	}
+
+	return model.ProfileStamp{
+		DisplayName: name,
+		AvatarURL:   p.AvatarURL,
+	}
+}
+
+func NewProfileService(repo profilerepository.Repository, notifier ProfileNotifier, logger *slog.Logger) Service {
+	return &ProfileService{
+		repo:     repo,
+		notifier: notifier,
+		logger:   logger,
+	}
+}
@@ -0,0 +1,98 @@
+package retentionhandler
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	authmiddleware "github.com/QuUteO/video-communication/internal/auth/middleware"
+	"github.com/QuUteO/video-communication/internal/model"
+	"github.com/QuUteO/video-communication/internal/retention/service"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+type Handler struct {
+	service retentionservice.Service
+	logger  *slog.Logger
+}
+
+func NewHandler(service retentionservice.Service, logger *slog.Logger) *Handler {
+	return &Handler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// Get GET /channels/{channel}/retention
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
+	const op = "internal/retention/handler/Get"
+	log := h.logger.With("op: ", op)
+
+	userID, _ := r.Context().Value(authmiddleware.UserIDKey).(string)
+
+	policy, err := h.service.Get(r.Context(), userID, chi.URLParam(r, "channel"))
+	if err != nil {
+		h.fail(w, r, log, err)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, model.Response{
+		StatusCode: http.StatusOK,
+		Message:    "Retention policy retrieved successfully",
+		Data:       policy,
+		Error:      "nil",
+	})
+}
+
+// Set PUT /channels/{channel}/retention
+func (h *Handler) Set(w http.ResponseWriter, r *http.Request) {
+	const op = "internal/retention/handler/Set"
+	log := h.logger.With("op: ", op)
+
+	var req model.RetentionPolicyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.error(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	userID, _ := r.Context().Value(authmiddleware.UserIDKey).(string)
+
+	policy, err := h.service.Set(r.Context(), userID, chi.URLParam(r, "channel"), &req)
+	if err != nil {
+		h.fail(w, r, log, err)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, model.Response{
+		StatusCode: http.StatusOK,
+		Message:    "Retention policy updated",
+		Data:       policy,
+		Error:      "nil",
+	})
+}
+
+func (h *Handler) fail(w http.ResponseWriter, r *http.Request, log *slog.Logger, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, retentionservice.ErrForbidden):
+		status = http.StatusForbidden
+	case errors.Is(err, retentionservice.ErrInvalidDays):
+		status = http.StatusBadRequest
+	default:
+		log.Error("Retention request failed", slog.Any("error", err))
+	}
+
+	h.error(w, r, status, err.Error())
+}
+
+func (h *Handler) error(w http.ResponseWriter, r *http.Request, status int, msg string) {
+	render.Status(r, status)
+	render.JSON(w, r, model.Response{
+		StatusCode: status,
+		Error:      msg,
+	})
+}
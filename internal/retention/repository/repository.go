@@ -0,0 +1,125 @@
+package retentionrepository
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/QuUteO/video-communication/internal/model"
+	postgres "github.com/QuUteO/video-communication/pkg/db"
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+type Repository interface {
+	GetPolicy(ctx context.Context, channel string) (*model.RetentionPolicy, error)
+	SetPolicy(ctx context.Context, policy *model.RetentionPolicy) error
+
+	FindExpired(ctx context.Context, limit int) ([]model.Message, error)
+	DeleteMessages(ctx context.Context, ids []string) (int64, error)
+}
+
+type repository struct {
+	db     postgres.Client
+	logger *slog.Logger
+}
+
+func (r *repository) GetPolicy(ctx context.Context, channel string) (*model.RetentionPolicy, error) {
+	q := `SELECT retention_days, updated_by, updated_at FROM channel_settings WHERE channel = $1`
+
+	policy := &model.RetentionPolicy{Channel: channel}
+	var updatedBy uuid.NullUUID
+
+	err := r.db.QueryRow(ctx, q, channel).Scan(&policy.Days, &updatedBy, &policy.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return policy, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	policy.UpdatedBy = updatedBy.UUID
+
+	return policy, nil
+}
+
+func (r *repository) SetPolicy(ctx context.Context, policy *model.RetentionPolicy) error {
+	const op = "./internal/retention/repository.SetPolicy"
+	log := r.logger.With("op: ", op)
+
+	q := `
+		INSERT INTO channel_settings (channel, retention_days, updated_by, updated_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (channel) DO UPDATE
+		SET retention_days = EXCLUDED.retention_days,
+		    updated_by = EXCLUDED.updated_by,
+		    updated_at = EXCLUDED.updated_at
+	`
+
+	if _, err := r.db.Exec(ctx, q, policy.Channel, policy.Days, policy.UpdatedBy, policy.UpdatedAt); err != nil {
+		log.Error("Error to save retention policy", slog.Any("err", err))
+		return err
+	}
+
+	return nil
+}
+
+// FindExpired сообщения с истекшим TTL или сроком хранения канала (заполнены только id и канал)
+func (r *repository) FindExpired(ctx context.Context, limit int) ([]model.Message, error) {
+	const op = "./internal/retention/repository.FindExpired"
+	log := r.logger.With("op: ", op)
+
+	q := `
+		(SELECT id, channel FROM message
+		 WHERE expires_at <= now()
+		 ORDER BY expires_at
+		 LIMIT $1)
+		UNION
+		(SELECT m.id, m.channel
+		 FROM channel_settings cs
+		 JOIN message m ON m.channel = cs.channel
+		 WHERE cs.retention_days IS NOT NULL
+		   AND m.created_at < now() - make_interval(days => cs.retention_days)
+		 LIMIT $1)
+		LIMIT $1
+	`
+
+	rows, err := r.db.Query(ctx, q, limit)
+	if err != nil {
+		log.Error("Error to find expired messages", slog.Any("err", err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := make([]model.Message, 0)
+	for rows.Next() {
+		var msg model.Message
+		if err := rows.Scan(&msg.ID, &msg.Channel); err != nil {
+			log.Error("Error to scan expired message", slog.Any("err", err))
+			return nil, err
+		}
+		messages = append(messages, msg)
+	}
+
+	return messages, rows.Err()
+}
+
+// DeleteMessages удаляет сообщения; упоминания удаляются каскадом
+func (r *repository) DeleteMessages(ctx context.Context, ids []string) (int64, error) {
+	const op = "./internal/retention/repository.DeleteMessages"
+	log := r.logger.With("op: ", op)
+
+	tag, err := r.db.Exec(ctx, `DELETE FROM message WHERE id = ANY($1::uuid[])`, ids)
+	if err != nil {
+		log.Error("Error to delete messages", slog.Any("err", err))
+		return 0, err
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+func New(db postgres.Client, logger *slog.Logger) Repository {
+	return &repository{
+		db:     db,
+		logger: logger,
+	}
+}
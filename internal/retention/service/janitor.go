@@ -0,0 +1,123 @@
+package retentionservice
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/QuUteO/video-communication/internal/config"
+	"github.com/QuUteO/video-communication/internal/model"
+	"github.com/QuUteO/video-communication/internal/retention/repository"
+)
+
+// Purger Удаление вложений сообщений вместе с файлами (реализуется сервисом вложений)
+type Purger interface {
+	Purge(ctx context.Context, messageIDs []string) error
+}
+
+// Broadcaster Рассылка подключенным клиентам (реализуется websocket.Hub)
+type Broadcaster interface {
+	Broadcast(msg model.Message)
+	PublishEvent(eventType, channel string, data interface{})
+	GetUserIDsInChannel(channelName string) []string
+}
+
+// Janitor Периодически удаляет истекшие сообщения пачками и сообщает об этом клиентам.
+// Чтение истекших сообщений закрыто в запросах, поэтому задержка janitor не видна пользователям.
+type Janitor struct {
+	repo        retentionrepository.Repository
+	attachments Purger
+	hub         Broadcaster
+	cfg         *config.Retention
+	logger      *slog.Logger
+}
+
+func NewJanitor(repo retentionrepository.Repository, attachments Purger, hub Broadcaster, cfg *config.Retention, logger *slog.Logger) *Janitor {
+	return &Janitor{
+		repo:        repo,
+		attachments: attachments,
+		hub:         hub,
+		cfg:         cfg,
+		logger:      logger,
+	}
+}
+
+// Run удаляет истекшие сообщения до отмены контекста
+func (j *Janitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			j.sweep(ctx)
+		}
+	}
+}
+
+// sweep удаляет пачки, пока находятся истекшие сообщения
+func (j *Janitor) sweep(ctx context.Context) {
+	for ctx.Err() == nil {
+		expired, err := j.repo.FindExpired(ctx, j.cfg.BatchSize)
+		if err != nil {
+			if ctx.Err() == nil {
+				j.logger.Error("failed to find expired messages", slog.String("error", err.Error()))
+			}
+			return
+		}
+		if len(expired) == 0 {
+			return
+		}
+
+		ids := make([]string, 0, len(expired))
+		for _, msg := range expired {
+			ids = append(ids, msg.ID.String())
+		}
+
+		// сначала вложения: после удаления сообщения ключи файлов уже не найти
+		if err := j.attachments.Purge(ctx, ids); err != nil {
+			j.logger.Error("failed to purge attachments", slog.String("error", err.Error()))
+			return
+		}
+
+		deleted, err := j.repo.DeleteMessages(ctx, ids)
+		if err != nil {
+			j.logger.Error("failed to delete expired messages", slog.String("error", err.Error()))
+			return
+		}
+		j.logger.Info("expired messages deleted", slog.Int64("count", deleted))
+
+		j.notify(expired)
+
+		if len(expired) < j.cfg.BatchSize {
+			return
+		}
+	}
+}
+
+func (j *Janitor) notify(expired []model.Message) {
+	now := time.Now()
+	online := make(map[string]bool)
+
+	for _, msg := range expired {
+		j.hub.PublishEvent(model.EventMessageDeleted, msg.Channel, map[string]string{"id": msg.ID.String()})
+
+		isOnline, ok := online[msg.Channel]
+		if !ok {
+			isOnline = len(j.hub.GetUserIDsInChannel(msg.Channel)) > 0
+			online[msg.Channel] = isOnline
+		}
+		if !isOnline {
+			continue
+		}
+
+		j.hub.Broadcast(model.Message{
+			ID:      msg.ID,
+			Type:    "message_deleted",
+			Channel: msg.Channel,
+			Time:    now,
+		})
+	}
+}
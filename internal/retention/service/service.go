@@ -0,0 +1,85 @@
+package retentionservice
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/QuUteO/video-communication/internal/model"
+	"github.com/QuUteO/video-communication/internal/retention/repository"
+	"github.com/QuUteO/video-communication/internal/user/service"
+	"github.com/gofrs/uuid"
+)
+
+const maxRetentionDays = 3650
+
+var (
+	ErrForbidden   = errors.New("no access to channel")
+	ErrInvalidDays = errors.New("days must be between 1 and 3650, or null to keep messages forever")
+)
+
+type Service interface {
+	Get(ctx context.Context, userID, channel string) (*model.RetentionPolicy, error)
+	Set(ctx context.Context, userID, channel string, req *model.RetentionPolicyRequest) (*model.RetentionPolicy, error)
+}
+
+type RetentionService struct {
+	repo   retentionrepository.Repository
+	users  service.Service
+	logger *slog.Logger
+}
+
+func (s *RetentionService) Get(ctx context.Context, userID, channel string) (*model.RetentionPolicy, error) {
+	if err := s.checkAccess(ctx, channel, userID); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetPolicy(ctx, channel)
+}
+
+func (s *RetentionService) Set(ctx context.Context, userID, channel string, req *model.RetentionPolicyRequest) (*model.RetentionPolicy, error) {
+	const op = "internal/retention/service.Set"
+	log := s.logger.With("op: ", op)
+
+	if err := s.checkAccess(ctx, channel, userID); err != nil {
+		return nil, err
+	}
+	if req.Days != nil && (*req.Days < 1 || *req.Days > maxRetentionDays) {
+		return nil, ErrInvalidDays
+	}
+
+	now := time.Now()
+	policy := &model.RetentionPolicy{
+		Channel:   channel,
+		Days:      req.Days,
+		UpdatedBy: uuid.FromStringOrNil(userID),
+		UpdatedAt: &now,
+	}
+
+	if err := s.repo.SetPolicy(ctx, policy); err != nil {
+		log.Error("Error saving retention policy", slog.Any("error", err))
+		return nil, err
+	}
+
+	return policy, nil
+}
+
+func (s *RetentionService) checkAccess(ctx context.Context, channel, userID string) error {
+	member, err := s.users.IsChannelMember(ctx, channel, userID)
+	if err != nil {
+		return err
+	}
+	if !member {
+		return ErrForbidden
+	}
+	return nil
+}
+
+func NewRetentionService(repo retentionrepository.Repository, users service.Service, logger *slog.Logger) Service {
+	return &RetentionService{
+		repo:   repo,
+		users:  users,
+		logger: logger,
+	}
+}
@@ -1,60 +1,236 @@
 package routes
 
 import (
+	attachmenthandler "github.com/QuUteO/video-communication/internal/attachment/handler"
 	authhandler "github.com/QuUteO/video-communication/internal/auth/handler"
 	authjwt "github.com/QuUteO/video-communication/internal/auth/jwt"
 	authmiddleware "github.com/QuUteO/video-communication/internal/auth/middleware"
+	authpolicy "github.com/QuUteO/video-communication/internal/auth/policy"
+	bothandler "github.com/QuUteO/video-communication/internal/bot/handler"
+	exporthandler "github.com/QuUteO/video-communication/internal/export/handler"
+	importerhandler "github.com/QuUteO/video-communication/internal/importer/handler"
+	mentionhandler "github.com/QuUteO/video-communication/internal/mention/handler"
+	"github.com/QuUteO/video-communication/internal/model"
+	pollhandler "github.com/QuUteO/video-communication/internal/poll/handler"
+	profilehandler "github.com/QuUteO/video-communication/internal/profile/handler"
+	retentionhandler "github.com/QuUteO/video-communication/internal/retention/handler"
+	schedulehandler "github.com/QuUteO/video-communication/internal/schedule/handler"
+	searchhandler "github.com/QuUteO/video-communication/internal/search/handler"
 	"github.com/QuUteO/video-communication/internal/static"
 	"github.com/QuUteO/video-communication/internal/user/handler"
+	webhookhandler "github.com/QuUteO/video-communication/internal/webhook/handler"
 	"github.com/QuUteO/video-communication/internal/websocket"
 	"github.com/go-chi/chi/v5"
 )
 
 type Route struct {
-	UserHandler      *handler.UserHandler
-	WebSocketHandler *websocket.HandlerWS
-	AuthHandler      *authhandler.Handler
-	jwt              *authjwt.Manager
+	UserHandler       *handler.UserHandler
+	WebSocketHandler  *websocket.HandlerWS
+	AuthHandler       *authhandler.Handler
+	SearchHandler     *searchhandler.Handler
+	AttachmentHandler *attachmenthandler.Handler
+	MentionHandler    *mentionhandler.Handler
+	WebhookHandler    *webhookhandler.Handler
+	IncomingHandler   *webhookhandler.IncomingHandler
+	BotHandler        *bothandler.Handler
+	ScheduleHandler   *schedulehandler.Handler
+	RetentionHandler  *retentionhandler.Handler
+	PollHandler       *pollhandler.Handler
+	ExportHandler     *exporthandler.Handler
+	ImportHandler     *importerhandler.Handler
+	ProfileHandler    *profilehandler.Handler
+	jwt               *authjwt.Manager
+	sessions          authmiddleware.Sessions
+	apiKeys           authmiddleware.APIKeys
+	authz             *authmiddleware.Authorizer
 }
 
 func NewRoute(
 	userHandler *handler.UserHandler,
 	WebSocketHandler *websocket.HandlerWS,
 	AuthHandler *authhandler.Handler,
-	jwt *authjwt.Manager) *Route {
+	SearchHandler *searchhandler.Handler,
+	AttachmentHandler *attachmenthandler.Handler,
+	MentionHandler *mentionhandler.Handler,
+	WebhookHandler *webhookhandler.Handler,
+	IncomingHandler *webhookhandler.IncomingHandler,
+	BotHandler *bothandler.Handler,
+	ScheduleHandler *schedulehandler.Handler,
+	RetentionHandler *retentionhandler.Handler,
+	PollHandler *pollhandler.Handler,
+	ExportHandler *exporthandler.Handler,
+	ImportHandler *importerhandler.Handler,
+	ProfileHandler *profilehandler.Handler,
+	jwt *authjwt.Manager,
+	sessions authmiddleware.Sessions,
+	apiKeys authmiddleware.APIKeys,
+	authz *authmiddleware.Authorizer) *Route {
 	return &Route{
-		UserHandler:      userHandler,
-		WebSocketHandler: WebSocketHandler,
-		AuthHandler:      AuthHandler,
-		jwt:              jwt,
+		UserHandler:       userHandler,
+		WebSocketHandler:  WebSocketHandler,
+		AuthHandler:       AuthHandler,
+		SearchHandler:     SearchHandler,
+		AttachmentHandler: AttachmentHandler,
+		MentionHandler:    MentionHandler,
+		WebhookHandler:    WebhookHandler,
+		IncomingHandler:   IncomingHandler,
+		BotHandler:        BotHandler,
+		ScheduleHandler:   ScheduleHandler,
+		RetentionHandler:  RetentionHandler,
+		PollHandler:       PollHandler,
+		ExportHandler:     ExportHandler,
+		ImportHandler:     ImportHandler,
+		ProfileHandler:    ProfileHandler,
+		jwt:               jwt,
+		sessions:          sessions,
+		apiKeys:           apiKeys,
+		authz:             authz,
 	}
 }
 
 func (h *Route) RegisterRoutes(router chi.Router) {
 	router.Get("/", static.ServeHtml("index.html"))
 
+	router.Get("/.well-known/jwks.json", h.AuthHandler.JWKS)
+
 	router.Route("/auth", func(r chi.Router) {
 		r.Post("/register", h.AuthHandler.Register)
 		r.Post("/login", h.AuthHandler.Login)
+		r.Post("/login/2fa", h.AuthHandler.LoginTwoFactor)
+		r.Post("/refresh", h.AuthHandler.Refresh)
+		// ключи API здесь не принимаются: у них нет сессии и письма подтверждения им не нужны
+		r.With(authmiddleware.JWT(h.jwt, h.sessions, nil)).Post("/logout", h.AuthHandler.Logout)
+		r.Post("/verify-email", h.AuthHandler.VerifyEmail)
+		r.With(authmiddleware.JWT(h.jwt, h.sessions, nil)).Post("/verify-email/request", h.AuthHandler.RequestEmailVerification)
+		r.Post("/password-reset", h.AuthHandler.RequestPasswordReset)
+		r.Post("/password-reset/confirm", h.AuthHandler.ResetPassword)
+		r.Get("/oidc/login", h.AuthHandler.OIDCLogin)
+		r.Get("/oidc/callback", h.AuthHandler.OIDCCallback)
 	})
 
-	router.Group(func(r chi.Router) {
-		r.Use(authmiddleware.JWT(h.jwt))
+	// входящие вебхуки авторизуются токеном в адресе
+	router.Post("/hooks/{id}/{token}", h.IncomingHandler.Post)
 
-		// websocket
-		r.Route("/ws", func(r chi.Router) {
-			r.Get("/", h.WebSocketHandler.WebSocketHTTP)
-		})
+	// websocket: ключу API нужно право ws, а не read
+	router.Route("/ws", func(r chi.Router) {
+		r.Use(authmiddleware.Scope(model.APIScopeWebSocket))
+		r.Use(authmiddleware.JWT(h.jwt, h.sessions, h.apiKeys))
+		r.Get("/", h.WebSocketHandler.WebSocketHTTP)
+	})
+
+	router.Group(func(r chi.Router) {
+		r.Use(authmiddleware.JWT(h.jwt, h.sessions, h.apiKeys))
 
 		// users
 		r.Route("/users", func(r chi.Router) {
-			r.Get("/", h.UserHandler.GetAllUsers)
+			r.With(h.authz.Require(authpolicy.UsersRead)).Get("/", h.UserHandler.GetAllUsers)
 
 			r.Route("/{id}", func(r chi.Router) {
-				r.Get("/", h.UserHandler.GetUserByID)
-				r.Put("/", h.UserHandler.UpdateUser)
-				r.Delete("/", h.UserHandler.DeleteUser)
+				r.With(h.authz.Require(authpolicy.UsersRead)).Get("/", h.UserHandler.GetUserByID)
+				// адрес, пароль и удаление аккаунта — только из интерактивной сессии, не по ключу API
+				r.With(authmiddleware.Interactive, h.authz.Require(authpolicy.UsersUpdate)).Put("/", h.UserHandler.UpdateUser)
+				r.With(authmiddleware.Interactive, h.authz.Require(authpolicy.UsersDelete)).Delete("/", h.UserHandler.DeleteUser)
+				r.With(h.authz.Require(authpolicy.UsersRead)).Get("/avatar", h.AttachmentHandler.Avatar)
 			})
 		})
+
+		// search
+		r.Route("/search", func(r chi.Router) {
+			r.Get("/messages", h.SearchHandler.SearchMessages)
+		})
+
+		// current user
+		r.Route("/me", func(r chi.Router) {
+			r.Get("/profile", h.ProfileHandler.Get)
+			r.Patch("/profile", h.ProfileHandler.Update)
+			r.Get("/mentions", h.MentionHandler.ListMine)
+			r.Get("/scheduled-messages", h.ScheduleHandler.ListMine)
+			r.Delete("/scheduled-messages/{id}", h.ScheduleHandler.Cancel)
+			r.Group(func(r chi.Router) {
+				r.Use(authmiddleware.Interactive)
+				r.Get("/sessions", h.AuthHandler.ListSessions)
+				r.Delete("/sessions/{id}", h.AuthHandler.RevokeSession)
+				r.Route("/2fa", func(r chi.Router) {
+					r.Post("/totp", h.AuthHandler.EnrollTOTP)
+					r.Post("/totp/confirm", h.AuthHandler.ConfirmTOTP)
+					r.Delete("/totp", h.AuthHandler.DisableTOTP)
+					r.Post("/recovery-codes", h.AuthHandler.RegenerateRecoveryCodes)
+				})
+				r.Route("/api-keys", func(r chi.Router) {
+					r.Get("/", h.AuthHandler.ListAPIKeys)
+					r.Post("/", h.AuthHandler.CreateAPIKey)
+					r.Delete("/{id}", h.AuthHandler.RevokeAPIKey)
+				})
+			})
+		})
+
+		// channels
+		r.Route("/channels/{channel}", func(r chi.Router) {
+			r.Route("/webhooks", func(r chi.Router) {
+				r.Get("/", h.WebhookHandler.List)
+				r.Get("/{id}/deliveries", h.WebhookHandler.Deliveries)
+				// подписка получает все сообщения канала
+				r.Group(func(r chi.Router) {
+					r.Use(authmiddleware.Interactive, h.authz.Require(authpolicy.ChannelsManage))
+					r.Post("/", h.WebhookHandler.Create)
+					r.Delete("/{id}", h.WebhookHandler.Delete)
+				})
+			})
+			r.Route("/incoming-webhooks", func(r chi.Router) {
+				r.Get("/", h.IncomingHandler.List)
+				// токен входящего вебхука пишет в канал без входа
+				r.Group(func(r chi.Router) {
+					r.Use(authmiddleware.Interactive, h.authz.Require(authpolicy.ChannelsManage))
+					r.Post("/", h.IncomingHandler.Create)
+					r.Delete("/{id}", h.IncomingHandler.Revoke)
+				})
+			})
+			r.Post("/scheduled-messages", h.ScheduleHandler.Create)
+			r.Get("/retention", h.RetentionHandler.Get)
+			// janitor безвозвратно удаляет историю старше срока
+			r.With(authmiddleware.Interactive, h.authz.Require(authpolicy.ChannelsManage)).Put("/retention", h.RetentionHandler.Set)
+			r.Post("/polls", h.PollHandler.Create)
+			// выгрузка содержит всю историю канала
+			r.Route("/exports", func(r chi.Router) {
+				r.Use(authmiddleware.Interactive, h.authz.Require(authpolicy.ChannelsManage))
+				r.Get("/", h.ExportHandler.List)
+				r.Post("/", h.ExportHandler.Create)
+				r.Get("/{id}", h.ExportHandler.Get)
+				r.Get("/{id}/download", h.ExportHandler.Download)
+			})
+		})
+
+		// polls
+		r.Route("/polls/{id}", func(r chi.Router) {
+			r.Get("/", h.PollHandler.Get)
+			r.Post("/votes", h.PollHandler.Vote)
+			r.Post("/close", h.PollHandler.Close)
+		})
+
+		// bots and slash commands
+		r.Route("/bots", func(r chi.Router) {
+			r.Get("/", h.BotHandler.List)
+			r.Group(func(r chi.Router) {
+				r.Use(authmiddleware.Interactive, h.authz.Require(authpolicy.BotsManage))
+				r.Post("/", h.BotHandler.Create)
+				r.Delete("/{id}", h.BotHandler.Delete)
+			})
+		})
+		r.Get("/commands", h.BotHandler.Commands)
+
+		// attachments
+		r.Route("/attachments", func(r chi.Router) {
+			r.Post("/", h.AttachmentHandler.Upload)
+			r.Get("/{id}", h.AttachmentHandler.Download)
+			r.Get("/{id}/thumbnails/{size}", h.AttachmentHandler.Thumbnail)
+		})
+
+		// admin
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(authmiddleware.Interactive)
+			r.With(h.authz.Require(authpolicy.AdminImport)).Post("/imports", h.ImportHandler.Import)
+			r.With(h.authz.Require(authpolicy.AdminUnlock)).Post("/users/{id}/unlock", h.AuthHandler.Unlock)
+			r.With(h.authz.Require(authpolicy.AdminRoles)).Put("/users/{id}/role", h.AuthHandler.SetRole)
+		})
 	})
 }
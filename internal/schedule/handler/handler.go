@@ -0,0 +1,131 @@
+package schedulehandler
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/QuUteO/video-communication/internal/attachment/service"
+	authmiddleware "github.com/QuUteO/video-communication/internal/auth/middleware"
+	"github.com/QuUteO/video-communication/internal/model"
+	"github.com/QuUteO/video-communication/internal/schedule/service"
+	"github.com/QuUteO/video-communication/pkg/richtext"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+type Handler struct {
+	service scheduleservice.Service
+	logger  *slog.Logger
+}
+
+func NewHandler(service scheduleservice.Service, logger *slog.Logger) *Handler {
+	return &Handler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// Create POST /channels/{channel}/scheduled-messages
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	const op = "internal/schedule/handler/Create"
+	log := h.logger.With("op: ", op)
+
+	var req model.ScheduledMessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.error(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	userID, _ := r.Context().Value(authmiddleware.UserIDKey).(string)
+
+	sm, err := h.service.Create(r.Context(), userID, "", chi.URLParam(r, "channel"), &req)
+	if err != nil {
+		h.fail(w, r, log, err)
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, model.Response{
+		StatusCode: http.StatusCreated,
+		Message:    "Message scheduled",
+		Data:       sm,
+		Error:      "nil",
+	})
+}
+
+// ListMine GET /me/scheduled-messages?channel=
+func (h *Handler) ListMine(w http.ResponseWriter, r *http.Request) {
+	const op = "internal/schedule/handler/ListMine"
+	log := h.logger.With("op: ", op)
+
+	userID, _ := r.Context().Value(authmiddleware.UserIDKey).(string)
+
+	list, err := h.service.List(r.Context(), userID, r.URL.Query().Get("channel"))
+	if err != nil {
+		h.fail(w, r, log, err)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, model.Response{
+		StatusCode: http.StatusOK,
+		Message:    "Scheduled messages retrieved successfully",
+		Data:       list,
+		Error:      "nil",
+	})
+}
+
+// Cancel DELETE /me/scheduled-messages/{id}
+func (h *Handler) Cancel(w http.ResponseWriter, r *http.Request) {
+	const op = "internal/schedule/handler/Cancel"
+	log := h.logger.With("op: ", op)
+
+	userID, _ := r.Context().Value(authmiddleware.UserIDKey).(string)
+
+	if err := h.service.Cancel(r.Context(), userID, chi.URLParam(r, "id")); err != nil {
+		h.fail(w, r, log, err)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, model.Response{
+		StatusCode: http.StatusOK,
+		Message:    "Scheduled message cancelled",
+		Error:      "nil",
+	})
+}
+
+func (h *Handler) fail(w http.ResponseWriter, r *http.Request, log *slog.Logger, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, scheduleservice.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, scheduleservice.ErrForbidden):
+		status = http.StatusForbidden
+	case errors.Is(err, scheduleservice.ErrTooManyItems):
+		status = http.StatusConflict
+	case errors.Is(err, scheduleservice.ErrInvalidTime),
+		errors.Is(err, scheduleservice.ErrEmptyText),
+		errors.Is(err, attachmentservice.ErrInvalidAttachment),
+		errors.Is(err, richtext.ErrUnknownFormat),
+		errors.Is(err, richtext.ErrTooLong),
+		errors.Is(err, richtext.ErrScriptInjection),
+		errors.Is(err, richtext.ErrUnsafeLink),
+		errors.Is(err, richtext.ErrInvalidURL):
+		status = http.StatusBadRequest
+	default:
+		log.Error("Scheduled message request failed", slog.Any("error", err))
+	}
+
+	h.error(w, r, status, err.Error())
+}
+
+func (h *Handler) error(w http.ResponseWriter, r *http.Request, status int, msg string) {
+	render.Status(r, status)
+	render.JSON(w, r, model.Response{
+		StatusCode: status,
+		Error:      msg,
+	})
+}
@@ -0,0 +1,159 @@
+package schedulerepository
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/QuUteO/video-communication/internal/model"
+	postgres "github.com/QuUteO/video-communication/pkg/db"
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+const columns = `id, channel, user_id, username, msg, format, attachment_ids::text[], send_at, status, COALESCE(last_error, ''), created_at, sent_at`
+
+type Repository interface {
+	Create(ctx context.Context, sm *model.ScheduledMessage) error
+	CountPending(ctx context.Context, userID string) (int, error)
+	ListPending(ctx context.Context, userID, channel string) ([]model.ScheduledMessage, error)
+	Cancel(ctx context.Context, userID, id string) (bool, error)
+
+	ClaimDue(ctx context.Context, limit int, leaseSeconds float64) ([]model.ScheduledMessage, error)
+	MarkSent(ctx context.Context, id uuid.UUID) error
+	MarkFailed(ctx context.Context, id uuid.UUID, lastError string) error
+}
+
+type repository struct {
+	db     postgres.Client
+	logger *slog.Logger
+}
+
+func (r *repository) Create(ctx context.Context, sm *model.ScheduledMessage) error {
+	const op = "./internal/schedule/repository.Create"
+	log := r.logger.With("op: ", op)
+
+	q := `
+		INSERT INTO scheduled_messages (id, channel, user_id, username, msg, format, attachment_ids, send_at, status, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7::uuid[], $8, $9, $10)
+	`
+
+	attachments := sm.Attachments
+	if attachments == nil {
+		attachments = []string{}
+	}
+
+	if _, err := r.db.Exec(ctx, q, sm.ID, sm.Channel, sm.UserID, sm.User, sm.Msg, sm.Format, attachments, sm.SendAt, sm.Status, sm.CreatedAt); err != nil {
+		log.Error("Error to insert scheduled message", slog.Any("err", err))
+		return err
+	}
+
+	return nil
+}
+
+func (r *repository) CountPending(ctx context.Context, userID string) (int, error) {
+	var count int
+	err := r.db.QueryRow(ctx, `SELECT count(*) FROM scheduled_messages WHERE user_id = $1 AND status = 'pending'`, userID).Scan(&count)
+	return count, err
+}
+
+// ListPending сообщения пользователя, ожидающие отправки; пустой channel — по всем каналам
+func (r *repository) ListPending(ctx context.Context, userID, channel string) ([]model.ScheduledMessage, error) {
+	const op = "./internal/schedule/repository.ListPending"
+	log := r.logger.With("op: ", op)
+
+	q := `
+		SELECT ` + columns + `
+		FROM scheduled_messages
+		WHERE user_id = $1 AND status = 'pending' AND ($2 = '' OR channel = $2)
+		ORDER BY send_at
+	`
+
+	rows, err := r.db.Query(ctx, q, userID, channel)
+	if err != nil {
+		log.Error("Error to list scheduled messages", slog.Any("err", err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAll(rows)
+}
+
+// Cancel отменяет сообщение, только пока планировщик его не забрал
+func (r *repository) Cancel(ctx context.Context, userID, id string) (bool, error) {
+	const op = "./internal/schedule/repository.Cancel"
+	log := r.logger.With("op: ", op)
+
+	q := `
+		UPDATE scheduled_messages
+		SET status = 'cancelled'
+		WHERE id = $1 AND user_id = $2 AND status = 'pending'
+	`
+
+	tag, err := r.db.Exec(ctx, q, id, userID)
+	if err != nil {
+		log.Error("Error to cancel scheduled message", slog.Any("err", err))
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// ClaimDue переводит наступившие сообщения в 'sending'. Сообщения, застрявшие в 'sending'
+// дольше аренды (процесс упал во время отправки), забираются повторно.
+func (r *repository) ClaimDue(ctx context.Context, limit int, leaseSeconds float64) ([]model.ScheduledMessage, error) {
+	const op = "./internal/schedule/repository.ClaimDue"
+	log := r.logger.With("op: ", op)
+
+	q := `
+		UPDATE scheduled_messages
+		SET status = 'sending', claimed_at = now()
+		WHERE id IN (
+			SELECT id FROM scheduled_messages
+			WHERE (status = 'pending' AND send_at <= (now() AT TIME ZONE 'UTC')) -- send_at хранится в UTC
+			   OR (status = 'sending' AND claimed_at < now() - make_interval(secs => $2))
+			ORDER BY send_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + columns
+
+	rows, err := r.db.Query(ctx, q, limit, leaseSeconds)
+	if err != nil {
+		log.Error("Error to claim scheduled messages", slog.Any("err", err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAll(rows)
+}
+
+func (r *repository) MarkSent(ctx context.Context, id uuid.UUID) error {
+	_, err := r.db.Exec(ctx, `UPDATE scheduled_messages SET status = 'sent', sent_at = now() WHERE id = $1`, id)
+	return err
+}
+
+func (r *repository) MarkFailed(ctx context.Context, id uuid.UUID, lastError string) error {
+	_, err := r.db.Exec(ctx, `UPDATE scheduled_messages SET status = 'failed', last_error = $2 WHERE id = $1`, id, lastError)
+	return err
+}
+
+func scanAll(rows pgx.Rows) ([]model.ScheduledMessage, error) {
+	result := make([]model.ScheduledMessage, 0)
+	for rows.Next() {
+		var sm model.ScheduledMessage
+		if err := rows.Scan(&sm.ID, &sm.Channel, &sm.UserID, &sm.User, &sm.Msg, &sm.Format, &sm.Attachments, &sm.SendAt,
+			&sm.Status, &sm.LastError, &sm.CreatedAt, &sm.SentAt); err != nil {
+			return nil, err
+		}
+		result = append(result, sm)
+	}
+
+	return result, rows.Err()
+}
+
+func New(db postgres.Client, logger *slog.Logger) Repository {
+	return &repository{
+		db:     db,
+		logger: logger,
+	}
+}
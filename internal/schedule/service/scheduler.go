@@ -0,0 +1,144 @@
+package scheduleservice
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/QuUteO/video-communication/internal/attachment/service"
+	"github.com/QuUteO/video-communication/internal/config"
+	"github.com/QuUteO/video-communication/internal/model"
+	"github.com/QuUteO/video-communication/internal/schedule/repository"
+	"github.com/QuUteO/video-communication/internal/user/service"
+	"github.com/QuUteO/video-communication/pkg/richtext"
+	"github.com/jackc/pgconn"
+)
+
+// Publisher Шаги после сохранения сообщения, общие с сообщениями из сокета:
+// привязка вложений, упоминания, рассылка и событие (реализуется websocket.Publisher)
+type Publisher interface {
+	Publish(ctx context.Context, msg *model.Message)
+}
+
+// Scheduler Публикует наступившие отложенные сообщения.
+// Сообщение сохраняется с id отложенного сообщения, поэтому повторная попытка
+// после падения процесса упирается в первичный ключ и не создает дубль.
+type Scheduler struct {
+	repo        schedulerepository.Repository
+	users       service.Service
+	attachments attachmentservice.Service
+	publisher   Publisher
+	cfg         *config.Scheduler
+	logger      *slog.Logger
+}
+
+func NewScheduler(repo schedulerepository.Repository, users service.Service, attachments attachmentservice.Service, publisher Publisher, cfg *config.Scheduler, logger *slog.Logger) *Scheduler {
+	return &Scheduler{
+		repo:        repo,
+		users:       users,
+		attachments: attachments,
+		publisher:   publisher,
+		cfg:         cfg,
+		logger:      logger,
+	}
+}
+
+// Run опрашивает очередь до отмены контекста
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.tick(ctx)
+		}
+	}
+}
+
+func (s *Scheduler) tick(ctx context.Context) {
+	due, err := s.repo.ClaimDue(ctx, s.cfg.BatchSize, s.cfg.Lease.Seconds())
+	if err != nil {
+		if ctx.Err() == nil {
+			s.logger.Error("failed to claim scheduled messages", slog.String("error", err.Error()))
+		}
+		return
+	}
+
+	for _, sm := range due {
+		s.send(ctx, sm)
+	}
+}
+
+func (s *Scheduler) send(ctx context.Context, sm model.ScheduledMessage) {
+	log := s.logger.With(slog.String("scheduled_id", sm.ID.String()))
+
+	// автор мог потерять доступ к каналу, пока сообщение ждало отправки
+	member, err := s.users.IsChannelMember(ctx, sm.Channel, sm.UserID.String())
+	if err != nil {
+		log.Error("failed to check channel membership", slog.String("error", err.Error()))
+		return
+	}
+	if !member {
+		s.fail(ctx, log, sm, ErrForbidden)
+		return
+	}
+
+	msgText, msgHTML, format, err := richtext.Render(sm.Format, sm.Msg)
+	if err != nil {
+		s.fail(ctx, log, sm, err)
+		return
+	}
+
+	attachments, err := s.attachments.Resolve(ctx, sm.UserID.String(), sm.Channel, sm.Attachments)
+	if errors.Is(err, attachmentservice.ErrInvalidAttachment) {
+		s.fail(ctx, log, sm, err)
+		return
+	}
+	if err != nil {
+		log.Error("failed to resolve attachments", slog.String("error", err.Error()))
+		return
+	}
+
+	msg := model.Message{
+		ID:      sm.ID,
+		Type:    "message",
+		UserID:  sm.UserID,
+		User:    sm.User,
+		Msg:     msgText,
+		Format:  format,
+		HTML:    msgHTML,
+		Channel: sm.Channel,
+		Time:    time.Now(),
+
+		Attachments: attachments,
+	}
+
+	if err := s.users.SaveMsg(ctx, msg); err != nil {
+		var pgErr *pgconn.PgError
+		if !errors.As(err, &pgErr) || pgErr.Code != "23505" {
+			// остается в 'sending' и будет повторено после истечения аренды
+			log.Error("failed to save scheduled message", slog.String("error", err.Error()))
+			return
+		}
+		// сохранено предыдущей попыткой: повторно не рассылаем
+		log.Warn("scheduled message was already saved")
+	} else {
+		s.publisher.Publish(ctx, &msg)
+	}
+
+	if err := s.repo.MarkSent(ctx, sm.ID); err != nil {
+		log.Error("failed to mark scheduled message as sent", slog.String("error", err.Error()))
+	}
+}
+
+// fail отправка невозможна и не будет повторена
+func (s *Scheduler) fail(ctx context.Context, log *slog.Logger, sm model.ScheduledMessage, reason error) {
+	log.Warn("scheduled message is not sent", slog.String("error", reason.Error()))
+	if err := s.repo.MarkFailed(ctx, sm.ID, reason.Error()); err != nil {
+		log.Error("failed to mark scheduled message", slog.String("error", err.Error()))
+	}
+}
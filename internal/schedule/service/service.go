@@ -0,0 +1,141 @@
+package scheduleservice
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/QuUteO/video-communication/internal/attachment/service"
+	"github.com/QuUteO/video-communication/internal/model"
+	"github.com/QuUteO/video-communication/internal/schedule/repository"
+	"github.com/QuUteO/video-communication/internal/user/service"
+	"github.com/QuUteO/video-communication/pkg/richtext"
+	"github.com/gofrs/uuid"
+)
+
+const (
+	maxPendingPerUser = 100
+	maxScheduleAhead  = 365 * 24 * time.Hour
+)
+
+var (
+	ErrNotFound     = errors.New("scheduled message not found")
+	ErrForbidden    = errors.New("no access to channel")
+	ErrInvalidTime  = errors.New("send_at must be in the future and within a year")
+	ErrEmptyText    = errors.New("msg or attachments are required")
+	ErrTooManyItems = errors.New("too many scheduled messages")
+)
+
+type Service interface {
+	Create(ctx context.Context, userID, username, channel string, req *model.ScheduledMessageRequest) (*model.ScheduledMessage, error)
+	List(ctx context.Context, userID, channel string) ([]model.ScheduledMessage, error)
+	Cancel(ctx context.Context, userID, id string) error
+}
+
+type ScheduleService struct {
+	repo        schedulerepository.Repository
+	users       service.Service
+	attachments attachmentservice.Service
+	logger      *slog.Logger
+}
+
+// Create username берется из соединения; для REST-запросов он подставляется из профиля
+func (s *ScheduleService) Create(ctx context.Context, userID, username, channel string, req *model.ScheduledMessageRequest) (*model.ScheduledMessage, error) {
+	const op = "internal/schedule/service.Create"
+	log := s.logger.With("op: ", op)
+
+	member, err := s.users.IsChannelMember(ctx, channel, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !member {
+		return nil, ErrForbidden
+	}
+
+	if req.Msg == "" && len(req.Attachments) == 0 {
+		return nil, ErrEmptyText
+	}
+	// текст проверяется сразу, чтобы ошибка не всплыла только в момент отправки
+	msgText, _, format, err := richtext.Render(req.Format, req.Msg)
+	if err != nil {
+		return nil, err
+	}
+
+	// вложения проверяются и при отправке: к тому времени их могут отправить обычным сообщением
+	if _, err := s.attachments.Resolve(ctx, userID, channel, req.Attachments); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if !req.SendAt.After(now) || req.SendAt.Sub(now) > maxScheduleAhead {
+		return nil, ErrInvalidTime
+	}
+
+	pending, err := s.repo.CountPending(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if pending >= maxPendingPerUser {
+		return nil, ErrTooManyItems
+	}
+
+	if username == "" {
+		user, err := s.users.FindUserById(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		username = user.Username
+		if username == "" {
+			username = "User_" + userID[len(userID)-6:]
+		}
+	}
+
+	sm := &model.ScheduledMessage{
+		ID:          uuid.Must(uuid.NewV4()),
+		Channel:     channel,
+		UserID:      uuid.FromStringOrNil(userID),
+		User:        username,
+		Msg:         msgText,
+		Format:      format,
+		Attachments: req.Attachments,
+		SendAt:      req.SendAt.UTC(),
+		Status:      "pending",
+		CreatedAt:   now,
+	}
+
+	if err := s.repo.Create(ctx, sm); err != nil {
+		log.Error("Error creating scheduled message", slog.Any("error", err))
+		return nil, err
+	}
+
+	return sm, nil
+}
+
+func (s *ScheduleService) List(ctx context.Context, userID, channel string) ([]model.ScheduledMessage, error) {
+	return s.repo.ListPending(ctx, userID, channel)
+}
+
+func (s *ScheduleService) Cancel(ctx context.Context, userID, id string) error {
+	if _, err := uuid.FromString(id); err != nil {
+		return ErrNotFound
+	}
+
+	cancelled, err := s.repo.Cancel(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if !cancelled {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func NewScheduleService(repo schedulerepository.Repository, users service.Service, attachments attachmentservice.Service, logger *slog.Logger) Service {
+	return &ScheduleService{
+		repo:        repo,
+		users:       users,
+		attachments: attachments,
+		logger:      logger,
+	}
+}
@@ -0,0 +1,111 @@
+package searchhandler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	authmiddleware "github.com/QuUteO/video-communication/internal/auth/middleware"
+	"github.com/QuUteO/video-communication/internal/model"
+	"github.com/QuUteO/video-communication/internal/search/service"
+	"github.com/go-chi/render"
+)
+
+type Handler struct {
+	service searchservice.Service
+	logger  *slog.Logger
+}
+
+func NewHandler(service searchservice.Service, logger *slog.Logger) *Handler {
+	return &Handler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// SearchMessages GET /search/messages?q=&channel=&author=&author_id=&from=&to=&limit=&offset=
+func (h *Handler) SearchMessages(w http.ResponseWriter, r *http.Request) {
+	const op = "internal/search/handler/SearchMessages"
+	log := h.logger.With("op: ", op)
+
+	query := r.URL.Query()
+	userID, _ := r.Context().Value(authmiddleware.UserIDKey).(string)
+
+	req := &model.SearchMessagesRequest{
+		UserID:   userID,
+		Query:    query.Get("q"),
+		Channel:  query.Get("channel"),
+		Author:   query.Get("author"),
+		AuthorID: query.Get("author_id"),
+	}
+
+	var err error
+	if req.From, err = parseTime(query.Get("from")); err != nil {
+		badRequest(w, r, "invalid from: "+err.Error())
+		return
+	}
+	if req.To, err = parseTime(query.Get("to")); err != nil {
+		badRequest(w, r, "invalid to: "+err.Error())
+		return
+	}
+	if req.Limit, err = parseInt(query.Get("limit")); err != nil {
+		badRequest(w, r, "invalid limit: "+err.Error())
+		return
+	}
+	if req.Offset, err = parseInt(query.Get("offset")); err != nil {
+		badRequest(w, r, "invalid offset: "+err.Error())
+		return
+	}
+
+	res, err := h.service.SearchMessages(r.Context(), req)
+	if err != nil {
+		if errors.Is(err, searchservice.ErrEmptyQuery) || errors.Is(err, searchservice.ErrInvalidRange) {
+			badRequest(w, r, err.Error())
+			return
+		}
+
+		log.Error("Failed to search messages", slog.Any("error", err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, model.Response{
+			StatusCode: http.StatusInternalServerError,
+			Error:      err.Error(),
+		})
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, model.Response{
+		StatusCode: http.StatusOK,
+		Message:    "Messages found",
+		Data:       res,
+		Error:      "nil",
+	})
+}
+
+func badRequest(w http.ResponseWriter, r *http.Request, msg string) {
+	render.Status(r, http.StatusBadRequest)
+	render.JSON(w, r, model.Response{
+		StatusCode: http.StatusBadRequest,
+		Error:      msg,
+	})
+}
+
+// parseTime принимает RFC3339 или дату в формате 2006-01-02
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, value)
+}
+
+func parseInt(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(value)
+}
@@ -0,0 +1,130 @@
+package searchrepository
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"log/slog"
+	"strings"
+
+	"github.com/QuUteO/video-communication/internal/model"
+	postgres "github.com/QuUteO/video-communication/pkg/db"
+	"github.com/gofrs/uuid"
+)
+
+// Служебные маркеры подсветки: ts_headline работает с сырым текстом,
+// поэтому сначала экранируем фрагмент, а потом подставляем <mark>.
+const (
+	markStart = "\x02"
+	markStop  = "\x03"
+)
+
+const headlineOptions = "StartSel=" + markStart + ", StopSel=" + markStop +
+	", MaxWords=25, MinWords=8, MaxFragments=2, FragmentDelimiter=\" … \""
+
+type Repository interface {
+	SearchMessages(ctx context.Context, req *model.SearchMessagesRequest) ([]model.SearchMessageResult, error)
+}
+
+type repository struct {
+	db     postgres.Client
+	logger *slog.Logger
+}
+
+func (r *repository) SearchMessages(ctx context.Context, req *model.SearchMessagesRequest) ([]model.SearchMessageResult, error) {
+	const op = "./internal/search/repository.SearchMessages"
+	log := r.logger.With("op: ", op)
+
+	args := []interface{}{req.Query, req.UserID, headlineOptions}
+	where := []string{
+		"m.msg_tsv @@ q",
+		"message_is_live(m.channel, m.created_at, m.expires_at)",
+		// доступ только к каналам, участником которых является пользователь
+		"EXISTS (SELECT 1 FROM channel_members cm WHERE cm.channel = m.channel AND cm.user_id = $2)",
+	}
+
+	addFilter := func(cond string, value interface{}) {
+		args = append(args, value)
+		where = append(where, fmt.Sprintf(cond, len(args)))
+	}
+
+	if req.Channel != "" {
+		addFilter("m.channel = $%d", req.Channel)
+	}
+	if req.Author != "" {
+		addFilter("m.username = $%d", req.Author)
+	}
+	if req.AuthorID != "" {
+		addFilter("m.user_id = $%d", req.AuthorID)
+	}
+	if !req.From.IsZero() {
+		addFilter("m.created_at >= $%d", req.From)
+	}
+	if !req.To.IsZero() {
+		addFilter("m.created_at < $%d", req.To)
+	}
+
+	args = append(args, req.Limit, req.Offset)
+
+	q := fmt.Sprintf(`
+		SELECT m.id, m.user_id, m.msg, m.channel, m.username, m.created_at,
+		       ts_headline('simple', m.msg, q, $3) AS snippet,
+		       ts_rank(m.msg_tsv, q) AS rank
+		FROM message m, websearch_to_tsquery('simple', $1) q
+		WHERE %s
+		ORDER BY rank DESC, m.created_at DESC
+		LIMIT $%d OFFSET $%d
+	`, strings.Join(where, " AND "), len(args)-1, len(args))
+
+	rows, err := r.db.Query(ctx, q, args...)
+	if err != nil {
+		log.Error("Error searching messages", slog.Any("err", err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := make([]model.SearchMessageResult, 0)
+	for rows.Next() {
+		var res model.SearchMessageResult
+		var userID uuid.NullUUID
+
+		if err := rows.Scan(
+			&res.ID,
+			&userID,
+			&res.Msg,
+			&res.Channel,
+			&res.User,
+			&res.Time,
+			&res.Snippet,
+			&res.Rank,
+		); err != nil {
+			log.Error("Error scanning search result", slog.Any("err", err))
+			return nil, err
+		}
+
+		res.UserID = userID.UUID
+		res.Snippet = highlight(res.Snippet)
+		results = append(results, res)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Error("Error iterating search results", slog.Any("err", err))
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// highlight экранирует фрагмент и заменяет служебные маркеры на <mark>
+func highlight(snippet string) string {
+	snippet = html.EscapeString(snippet)
+	snippet = strings.ReplaceAll(snippet, markStart, "<mark>")
+	return strings.ReplaceAll(snippet, markStop, "</mark>")
+}
+
+func New(db postgres.Client, logger *slog.Logger) Repository {
+	return &repository{
+		db:     db,
+		logger: logger,
+	}
+}
@@ -0,0 +1,73 @@
+package searchservice
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/QuUteO/video-communication/internal/model"
+	"github.com/QuUteO/video-communication/internal/search/repository"
+)
+
+const (
+	defaultLimit = 20
+	maxLimit     = 100
+)
+
+var (
+	ErrEmptyQuery   = errors.New("search query is required")
+	ErrInvalidRange = errors.New("invalid date range")
+)
+
+type Service interface {
+	SearchMessages(ctx context.Context, req *model.SearchMessagesRequest) (*model.SearchMessagesResponse, error)
+}
+
+type SearchService struct {
+	repo   searchrepository.Repository
+	logger *slog.Logger
+}
+
+func (s *SearchService) SearchMessages(ctx context.Context, req *model.SearchMessagesRequest) (*model.SearchMessagesResponse, error) {
+	const op = "internal/search/service.SearchMessages"
+	log := s.logger.With("op: ", op)
+
+	req.Query = strings.TrimSpace(req.Query)
+	if req.Query == "" {
+		return nil, ErrEmptyQuery
+	}
+
+	if !req.From.IsZero() && !req.To.IsZero() && !req.From.Before(req.To) {
+		return nil, ErrInvalidRange
+	}
+
+	if req.Limit <= 0 {
+		req.Limit = defaultLimit
+	}
+	if req.Limit > maxLimit {
+		req.Limit = maxLimit
+	}
+	if req.Offset < 0 {
+		req.Offset = 0
+	}
+
+	results, err := s.repo.SearchMessages(ctx, req)
+	if err != nil {
+		log.Error("Error searching messages", slog.Any("error", err))
+		return nil, err
+	}
+
+	return &model.SearchMessagesResponse{
+		Results: results,
+		Limit:   req.Limit,
+		Offset:  req.Offset,
+	}, nil
+}
+
+func NewSearchService(repo searchrepository.Repository, logger *slog.Logger) Service {
+	return &SearchService{
+		repo:   repo,
+		logger: logger,
+	}
+}
@@ -2,9 +2,12 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
+	"strconv"
 
+	authmiddleware "github.com/QuUteO/video-communication/internal/auth/middleware"
 	"github.com/QuUteO/video-communication/internal/model"
 	"github.com/QuUteO/video-communication/internal/user/service"
 	"github.com/go-chi/chi/v5"
@@ -58,13 +61,36 @@ func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// GetAllUsers GET /users?q=&sort=email|name|created_at&order=asc|desc&limit=&cursor=
 func (h *UserHandler) GetAllUsers(w http.ResponseWriter, r *http.Request) {
-	const op = "./internal/server/handler/FindUserById"
+	const op = "./internal/server/handler/GetAllUsers"
 	log := h.logger.With("op", op)
 
+	query := r.URL.Query()
+	req := &model.ListUsersRequest{
+		Query:  query.Get("q"),
+		Sort:   query.Get("sort"),
+		Order:  query.Get("order"),
+		Cursor: query.Get("cursor"),
+	}
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil {
+			badRequest(w, r, "invalid limit: "+err.Error())
+			return
+		}
+		req.Limit = n
+	}
+
 	ctx := r.Context()
-	users, err := h.service.FindAllUser(ctx)
+	users, err := h.service.ListUsers(ctx, req)
 	if err != nil {
+		if errors.Is(err, service.ErrInvalidSort) || errors.Is(err, service.ErrInvalidOrder) ||
+			errors.Is(err, service.ErrInvalidCursor) || errors.Is(err, service.ErrQueryTooLong) {
+			badRequest(w, r, err.Error())
+			return
+		}
+
 		log.Error("Failed to find users", slog.Any("error", err))
 		render.Status(r, http.StatusInternalServerError)
 		render.JSON(w, r, model.Response{
@@ -127,8 +153,19 @@ func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// своя текущая сессия остается, остальные завершаются
 	ctx := r.Context()
-	if err := h.service.UpdateUser(ctx, id, req.Email, req.Password); err != nil {
+	sessionID := ""
+	if userID, _ := ctx.Value(authmiddleware.UserIDKey).(string); userID == id {
+		sessionID, _ = ctx.Value(authmiddleware.SessionIDKey).(string)
+	}
+
+	if err := h.service.UpdateUser(ctx, id, req.Email, req.Password, sessionID); err != nil {
+		if errors.Is(err, service.ErrWeakPassword) {
+			badRequest(w, r, err.Error())
+			return
+		}
+
 		log.Error("Failed to update server", slog.Any("error", err))
 		render.Status(r, http.StatusInternalServerError)
 		render.JSON(w, r, model.Response{
@@ -170,3 +207,11 @@ func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 		Error:      "nil",
 	})
 }
+
+func badRequest(w http.ResponseWriter, r *http.Request, msg string) {
+	render.Status(r, http.StatusBadRequest)
+	render.JSON(w, r, model.Response{
+		StatusCode: http.StatusBadRequest,
+		Error:      msg,
+	})
+}
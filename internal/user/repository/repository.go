@@ -5,6 +5,8 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"strings"
+	"time"
 
 	"github.com/QuUteO/video-communication/internal/model"
 	"github.com/QuUteO/video-communication/pkg/db"
@@ -14,14 +16,20 @@ import (
 
 type Repository interface {
 	Create(ctx context.Context, user *model.User) (uuid.UUID, error)
-	FindAll(ctx context.Context) ([]model.DTOResponse, error)
+	// ListUsers страница пользователей после курсора и курсор следующей страницы
+	ListUsers(ctx context.Context, req *model.ListUsersRequest, after *model.UserCursor) ([]model.DTOResponse, *model.UserCursor, error)
+	CountUsers(ctx context.Context, query string) (int64, error)
 	FindByID(ctx context.Context, id string) (*model.User, error)
 	FindByEmail(ctx context.Context, email string) (*model.User, error)
 	Update(ctx context.Context, user *model.User) error
 	Delete(ctx context.Context, id string) error
 
 	SaveMsg(ctx context.Context, msg model.Message) error
+	SaveMsgTTL(ctx context.Context, msg *model.Message, ttl time.Duration) error
 	GetMessagesByChannel(ctx context.Context, channel string) ([]model.Message, error)
+
+	JoinChannel(ctx context.Context, channel string, userID string) error
+	IsChannelMember(ctx context.Context, channel string, userID string) (bool, error)
 }
 
 type repository struct {
@@ -50,31 +58,108 @@ func (r *repository) Create(ctx context.Context, user *model.User) (uuid.UUID, e
 	return user.Id, nil
 }
 
-func (r *repository) FindAll(ctx context.Context) ([]model.DTOResponse, error) {
-	const op = "./internal/server/repository/FindAll"
+// userSortKeys выражения сортировки; совпадают с индексами idx_users_*_sort
+var userSortKeys = map[string]struct {
+	expr string // выражение в ORDER BY
+	cast string // приведение ключа из курсора
+}{
+	model.UserSortEmail:     {expr: "lower(email)"},
+	model.UserSortName:      {expr: "lower(COALESCE(NULLIF(display_name, ''), username, email))"},
+	model.UserSortCreatedAt: {expr: "created_at", cast: "::timestamp"},
+}
+
+func (r *repository) ListUsers(ctx context.Context, req *model.ListUsersRequest, after *model.UserCursor) ([]model.DTOResponse, *model.UserCursor, error) {
+	const op = "./internal/server/repository/ListUsers"
 	log := r.logger.With("op:", op)
 
+	key, ok := userSortKeys[req.Sort]
+	if !ok {
+		return nil, nil, fmt.Errorf("unknown sort %q", req.Sort)
+	}
+
+	where, args := userFilter(req.Query)
+	cmp, order := ">", "ASC"
+	if req.Order == "desc" {
+		cmp, order = "<", "DESC"
+	}
+	if after != nil {
+		args = append(args, after.Key, after.ID)
+		where = append(where, fmt.Sprintf("(%s, id) %s ($%d%s, $%d)", key.expr, cmp, len(args)-1, key.cast, len(args)))
+	}
+	// лишняя строка показывает, что есть следующая страница
+	args = append(args, req.Limit+1)
+
 	q := `
-	SELECT id, email FROM users
-	`
+		SELECT id, email, COALESCE(username, ''), COALESCE(display_name, ''), avatar_id, role, created_at, ` + key.expr + `::text
+		FROM users` + whereClause(where) + `
+		ORDER BY ` + key.expr + ` ` + order + `, id ` + order + `
+		LIMIT $` + fmt.Sprint(len(args))
 
-	var users []model.DTOResponse
-	rows, err := r.client.Query(ctx, q)
+	rows, err := r.client.Query(ctx, q, args...)
 	if err != nil {
 		log.Error("Error querying users: ", slog.String("error", err.Error()))
-		return nil, err
+		return nil, nil, err
 	}
+	defer rows.Close()
 
+	users := make([]model.DTOResponse, 0, req.Limit)
+	var next *model.UserCursor
+	var lastKey string
 	for rows.Next() {
 		var user model.DTOResponse
-		if err := rows.Scan(&user.ID, &user.Email); err != nil {
+		var sortKey string
+		if err := rows.Scan(&user.ID, &user.Email, &user.Username, &user.DisplayName, &user.AvatarID, &user.Role, &user.CreatedAt, &sortKey); err != nil {
 			log.Error(err.Error())
-			return nil, err
+			return nil, nil, err
+		}
+		if len(users) == req.Limit {
+			last := users[len(users)-1]
+			next = &model.UserCursor{Key: lastKey, ID: last.ID}
+			break
 		}
 		users = append(users, user)
+		lastKey = sortKey
+	}
+	if err := rows.Err(); err != nil {
+		log.Error("Error reading users", slog.String("error", err.Error()))
+		return nil, nil, err
 	}
 
-	return users, nil
+	return users, next, nil
+}
+
+func (r *repository) CountUsers(ctx context.Context, query string) (int64, error) {
+	const op = "./internal/server/repository/CountUsers"
+	log := r.logger.With("op:", op)
+
+	where, args := userFilter(query)
+	q := `SELECT count(*) FROM users` + whereClause(where)
+
+	var total int64
+	if err := r.client.QueryRow(ctx, q, args...).Scan(&total); err != nil {
+		log.Error("Error counting users", slog.String("error", err.Error()))
+		return 0, err
+	}
+
+	return total, nil
+}
+
+// userFilter поиск по префиксу; шаблон в нижнем регистре, чтобы подходили индексы *_prefix
+func userFilter(query string) ([]string, []any) {
+	if query == "" {
+		return nil, nil
+	}
+	pattern := likeEscaper.Replace(strings.ToLower(query)) + "%"
+	return []string{"(lower(email) LIKE $1 OR lower(display_name) LIKE $1)"}, []any{pattern}
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func whereClause(where []string) string {
+	if len(where) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(where, " AND ")
 }
 
 func (r *repository) FindByID(ctx context.Context, id string) (*model.User, error) {
@@ -82,11 +167,11 @@ func (r *repository) FindByID(ctx context.Context, id string) (*model.User, erro
 	log := r.logger.With("op:", op)
 
 	q := `
-	SELECT id, email FROM users WHERE id = $1
+	SELECT id, email, COALESCE(username, '') FROM users WHERE id = $1
 	`
 
 	var user model.User
-	if err := r.client.QueryRow(ctx, q, id).Scan(&user.Id, &user.Email); err != nil {
+	if err := r.client.QueryRow(ctx, q, id).Scan(&user.Id, &user.Email, &user.Username); err != nil {
 		log.Info("Error querying user: ", slog.String("error", err.Error()))
 		return nil, err
 	}
@@ -115,9 +200,11 @@ func (r *repository) Update(ctx context.Context, user *model.User) error {
 	const op = "./internal/server/repository/Update"
 	log := r.logger.With("op:", op)
 
+	// новый адрес не подтвержден, даже если старый был
 	q := `
 	UPDATE users 
-	SET email = $1, password = $2 
+	SET email = $1, password = COALESCE(NULLIF($2, ''), password),
+	    email_verified_at = CASE WHEN lower(email) = lower($1) THEN email_verified_at END
 	WHERE id = $3
 	`
 
@@ -148,9 +235,9 @@ func (r *repository) GetMessagesByChannel(ctx context.Context, channel string) (
 	const op = "./internal/server/repository/GetMessagesByChannel"
 	log := r.logger.With("op: ", op)
 
-	q := `SELECT id, msg, channel, username, created_at 
+	q := `SELECT id, type, user_id, msg, format, COALESCE(html, ''), channel, username, created_at, expires_at
 		FROM message 
-		WHERE channel = $1
+		WHERE channel = $1 AND message_is_live(channel, created_at, expires_at)
 		ORDER BY created_at DESC
 		LIMIT 100
 		`
@@ -166,17 +253,24 @@ func (r *repository) GetMessagesByChannel(ctx context.Context, channel string) (
 
 	for rows.Next() {
 		var msg model.Message
+		var userID uuid.NullUUID
 
 		if err := rows.Scan(
 			&msg.ID,
+			&msg.Type,
+			&userID,
 			&msg.Msg,
+			&msg.Format,
+			&msg.HTML,
 			&msg.Channel,
 			&msg.User,
 			&msg.Time,
+			&msg.ExpiresAt,
 		); err != nil {
 			log.Error("error scanning message", slog.String("error", err.Error()))
 			return nil, err
 		}
+		msg.UserID = userID.UUID
 
 		messages = append(messages, msg)
 	}
@@ -189,24 +283,96 @@ func (r *repository) SaveMsg(ctx context.Context, msg model.Message) error {
 	log := r.logger.With("op:", op)
 
 	q := `
-		INSERT INTO message (id, msg, channel, username, created_at)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO message (id, type, user_id, msg, format, html, channel, username, created_at, expires_at)
+		VALUES ($1, COALESCE(NULLIF($2, ''), 'message'), $3, $4, COALESCE(NULLIF($5, ''), 'plain'), NULLIF($6, ''), $7, $8, $9, $10)
 	`
 
 	if _, err := r.client.Exec(ctx, q,
 		msg.ID,
+		msg.Type,
+		uuid.NullUUID{UUID: msg.UserID, Valid: msg.UserID != uuid.Nil},
 		msg.Msg,
+		msg.Format,
+		msg.HTML,
 		msg.Channel,
 		msg.User,
 		msg.Time,
+		msg.ExpiresAt,
 	); err != nil {
 		log.Info("Error saving message", slog.String("error", err.Error()))
-		return fmt.Errorf("%w: %s", err, msg)
+		return fmt.Errorf("%w: message %s", err, msg.ID)
 	}
 
 	return nil
 }
 
+// SaveMsgTTL сохраняет самоуничтожающееся сообщение: срок считается по часам БД,
+// с которыми его сравнивает message_is_live
+func (r *repository) SaveMsgTTL(ctx context.Context, msg *model.Message, ttl time.Duration) error {
+	const op = "./internal/server/repository/SaveMsgTTL"
+	log := r.logger.With("op:", op)
+
+	q := `
+		INSERT INTO message (id, type, user_id, msg, format, html, channel, username, created_at, expires_at)
+		VALUES ($1, COALESCE(NULLIF($2, ''), 'message'), $3, $4, COALESCE(NULLIF($5, ''), 'plain'), NULLIF($6, ''), $7, $8, $9,
+		        now() + make_interval(secs => $10::float8))
+		RETURNING expires_at
+	`
+
+	if err := r.client.QueryRow(ctx, q,
+		msg.ID,
+		msg.Type,
+		uuid.NullUUID{UUID: msg.UserID, Valid: msg.UserID != uuid.Nil},
+		msg.Msg,
+		msg.Format,
+		msg.HTML,
+		msg.Channel,
+		msg.User,
+		msg.Time,
+		ttl.Seconds(),
+	).Scan(&msg.ExpiresAt); err != nil {
+		log.Info("Error saving message", slog.String("error", err.Error()))
+		return fmt.Errorf("%w: message %s", err, msg.ID)
+	}
+
+	return nil
+}
+
+func (r *repository) JoinChannel(ctx context.Context, channel string, userID string) error {
+	const op = "./internal/server/repository/JoinChannel"
+	log := r.logger.With("op:", op)
+
+	q := `
+		INSERT INTO channel_members (channel, user_id)
+		VALUES ($1, $2)
+		ON CONFLICT (channel, user_id) DO NOTHING
+	`
+
+	if _, err := r.client.Exec(ctx, q, channel, userID); err != nil {
+		log.Error("Error joining channel", slog.String("error", err.Error()))
+		return err
+	}
+
+	return nil
+}
+
+func (r *repository) IsChannelMember(ctx context.Context, channel string, userID string) (bool, error) {
+	const op = "./internal/server/repository/IsChannelMember"
+	log := r.logger.With("op:", op)
+
+	q := `
+		SELECT EXISTS (SELECT 1 FROM channel_members WHERE channel = $1 AND user_id = $2)
+	`
+
+	var member bool
+	if err := r.client.QueryRow(ctx, q, channel, userID).Scan(&member); err != nil {
+		log.Error("Error checking channel membership", slog.String("error", err.Error()))
+		return false, err
+	}
+
+	return member, nil
+}
+
 func NewRepository(client postgres.Client, logger *slog.Logger) Repository {
 	return &repository{
 		client: client,
@@ -2,8 +2,13 @@ package service
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"log/slog"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/QuUteO/video-communication/internal/model"
 	"github.com/QuUteO/video-communication/internal/user/repository"
@@ -14,17 +19,52 @@ import (
 type Service interface {
 	CreateUser(ctx context.Context, email string, password string) (uuid.UUID, error)
 	DeleteUser(ctx context.Context, id string) error
-	UpdateUser(ctx context.Context, id string, email string, password string) error
-	FindAllUser(ctx context.Context) ([]model.DTOResponse, error)
+	// UpdateUser пустые email и password не меняются; после смены сессии пользователя,
+	// кроме sessionID, завершаются
+	UpdateUser(ctx context.Context, id string, email string, password string, sessionID string) error
+	ListUsers(ctx context.Context, req *model.ListUsersRequest) (*model.ListUsersResponse, error)
 	FindUserById(ctx context.Context, id string) (*model.User, error)
 
 	SaveMsg(ctx context.Context, msg model.Message) error
+	SaveMsgTTL(ctx context.Context, msg *model.Message, ttl time.Duration) error
 	GetMessageByChannel(ctx context.Context, channel string) ([]model.Message, error)
+
+	JoinChannel(ctx context.Context, channel string, userID string) error
+	IsChannelMember(ctx context.Context, channel string, userID string) (bool, error)
+}
+
+const (
+	minPassword = 8
+	maxPassword = 72 // предел bcrypt
+
+	defaultLimit   = 50
+	maxLimit       = 200
+	maxQueryLength = 255
+
+	// created_at::text при DateStyle ISO
+	cursorTimeLayout = "2006-01-02 15:04:05.999999"
+)
+
+var (
+	ErrInvalidSort   = errors.New("sort must be one of email, name, created_at")
+	ErrInvalidOrder  = errors.New("order must be asc or desc")
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrQueryTooLong  = errors.New("search query is too long")
+	ErrWeakPassword  = errors.New("password must be 8-72 characters")
+)
+
+// Credentials Последствия смены адреса и пароля (authservice.AuthService)
+type Credentials interface {
+	// RequestEmailVerification отправляет письмо для подтверждения нового адреса
+	RequestEmailVerification(ctx context.Context, userID string) error
+	// RevokeOtherSessions завершает сессии пользователя, кроме keepSessionID
+	RevokeOtherSessions(ctx context.Context, userID, keepSessionID string) error
 }
 
 type service struct {
-	repository repository.Repository
-	logger     *slog.Logger
+	repository  repository.Repository
+	credentials Credentials
+	logger      *slog.Logger
 }
 
 func (s *service) CreateUser(ctx context.Context, email string, password string) (uuid.UUID, error) {
@@ -65,19 +105,38 @@ func (s *service) DeleteUser(ctx context.Context, id string) error {
 	return nil
 }
 
-func (s *service) UpdateUser(ctx context.Context, id string, email string, password string) error {
+func (s *service) UpdateUser(ctx context.Context, id string, email string, password string, sessionID string) error {
 	const op = "./internal/server/service.UpdateUser"
 	log := s.logger.With("op:", op)
 
+	email = strings.TrimSpace(email)
+	if password != "" {
+		if n := utf8.RuneCountInString(password); n < minPassword || len(password) > maxPassword {
+			return ErrWeakPassword
+		}
+	}
+
 	user, err := s.repository.FindByID(ctx, id)
 	if err != nil {
 		log.Error("Failed to find server", "error:", err, "id", id)
 		return err
 	}
 
-	s.logger.Info("Found server and updating server email:", email, "password:", password)
-	user.Email = email
-	user.Password = password
+	s.logger.Info("Found server and updating server", "email", email)
+	emailChanged := email != "" && !strings.EqualFold(user.Email, email)
+	if email != "" {
+		user.Email = email
+	}
+
+	// пароль хранится только как bcrypt-хеш, иначе вход по нему невозможен
+	user.Password = ""
+	if password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			return err
+		}
+		user.Password = string(hash)
+	}
 
 	err = s.repository.Update(ctx, user)
 	if err != nil {
@@ -85,22 +144,133 @@ func (s *service) UpdateUser(ctx context.Context, id string, email string, passw
 		return err
 	}
 
+	// адрес сброшен в неподтвержденный — письмо уходит на новый
+	if emailChanged {
+		if err := s.credentials.RequestEmailVerification(ctx, id); err != nil {
+			log.Error("Failed to request email verification", "error:", err, "id", id)
+		}
+	}
+
+	if emailChanged || password != "" {
+		if err := s.credentials.RevokeOtherSessions(ctx, id, sessionID); err != nil {
+			log.Error("Failed to revoke sessions", "error:", err, "id", id)
+			return err
+		}
+	}
+
 	log.Info("Updated server")
 	return nil
 }
 
-func (s *service) FindAllUser(ctx context.Context) ([]model.DTOResponse, error) {
-	const op = "./internal/server/service.FindAllUser"
+func (s *service) ListUsers(ctx context.Context, req *model.ListUsersRequest) (*model.ListUsersResponse, error) {
+	const op = "./internal/server/service.ListUsers"
 	log := s.logger.With("op:", op)
 
-	users, err := s.repository.FindAll(ctx)
+	req.Query = strings.TrimSpace(req.Query)
+	if len(req.Query) > maxQueryLength {
+		return nil, ErrQueryTooLong
+	}
+	if req.Sort == "" {
+		req.Sort = model.UserSortCreatedAt
+	}
+	switch req.Sort {
+	case model.UserSortEmail, model.UserSortName, model.UserSortCreatedAt:
+	default:
+		return nil, ErrInvalidSort
+	}
+	// новые пользователи по умолчанию первыми, остальные поля по алфавиту
+	if req.Order == "" {
+		req.Order = "asc"
+		if req.Sort == model.UserSortCreatedAt {
+			req.Order = "desc"
+		}
+	}
+	if req.Order != "asc" && req.Order != "desc" {
+		return nil, ErrInvalidOrder
+	}
+	if req.Limit <= 0 {
+		req.Limit = defaultLimit
+	}
+	if req.Limit > maxLimit {
+		req.Limit = maxLimit
+	}
+
+	var after *model.UserCursor
+	if req.Cursor != "" {
+		c, err := decodeCursor(req)
+		if err != nil {
+			return nil, err
+		}
+		after = c
+	}
+
+	users, next, err := s.repository.ListUsers(ctx, req, after)
 	if err != nil {
-		log.Error("Failed to find all users", "error:", err, "users", users)
+		log.Error("Failed to list users", "error:", err)
 		return nil, err
 	}
+	total, err := s.repository.CountUsers(ctx, req.Query)
+	if err != nil {
+		log.Error("Failed to count users", "error:", err)
+		return nil, err
+	}
+
+	for i := range users {
+		if users[i].AvatarID != nil {
+			users[i].AvatarURL = model.AvatarURL(users[i].ID, *users[i].AvatarID)
+		}
+	}
 
-	log.Info("Found all users")
-	return users, nil
+	res := &model.ListUsersResponse{
+		Users: users,
+		Total: total,
+		Limit: req.Limit,
+	}
+	if next != nil {
+		if res.NextCursor, err = encodeCursor(req, next); err != nil {
+			return nil, err
+		}
+	}
+
+	log.Info("Listed users", "count", len(users), "total", total)
+	return res, nil
+}
+
+// cursorToken курсор действителен только для той же сортировки и того же поиска
+type cursorToken struct {
+	model.UserCursor
+	Sort  string `json:"s"`
+	Order string `json:"o"`
+	Query string `json:"q,omitempty"`
+}
+
+func encodeCursor(req *model.ListUsersRequest, c *model.UserCursor) (string, error) {
+	raw, err := json.Marshal(cursorToken{UserCursor: *c, Sort: req.Sort, Order: req.Order, Query: req.Query})
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+func decodeCursor(req *model.ListUsersRequest) (*model.UserCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(req.Cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var token cursorToken
+	if err := json.Unmarshal(raw, &token); err != nil || token.ID == uuid.Nil {
+		return nil, ErrInvalidCursor
+	}
+	if token.Sort != req.Sort || token.Order != req.Order || token.Query != req.Query {
+		return nil, ErrInvalidCursor
+	}
+	// ключ created_at приводится к timestamp в запросе, битое значение вернуло бы 500
+	if req.Sort == model.UserSortCreatedAt {
+		if _, err := time.Parse(cursorTimeLayout, token.Key); err != nil {
+			return nil, ErrInvalidCursor
+		}
+	}
+	return &token.UserCursor, nil
 }
 
 func (s *service) FindUserById(ctx context.Context, id string) (*model.User, error) {
@@ -145,9 +315,52 @@ func (s *service) SaveMsg(ctx context.Context, msg model.Message) error {
 	return nil
 }
 
-func NewService(repository repository.Repository, logger *slog.Logger) Service {
+// SaveMsgTTL сохраняет сообщение со сроком жизни ttl и проставляет msg.ExpiresAt
+func (s *service) SaveMsgTTL(ctx context.Context, msg *model.Message, ttl time.Duration) error {
+	const op = "./internal/server/repository/SaveMsgTTL"
+	log := s.logger.With("op: ", op)
+
+	if msg.Time.IsZero() {
+		msg.Time = time.Now()
+	}
+
+	if err := s.repository.SaveMsgTTL(ctx, msg, ttl); err != nil {
+		log.Error("Error saving message: ", slog.Any("err", err))
+		return err
+	}
+
+	return nil
+}
+
+func (s *service) JoinChannel(ctx context.Context, channel string, userID string) error {
+	const op = "./internal/user/service.JoinChannel"
+	log := s.logger.With("op: ", op)
+
+	if err := s.repository.JoinChannel(ctx, channel, userID); err != nil {
+		log.Error("Error joining channel: ", slog.Any("err", err))
+		return err
+	}
+
+	return nil
+}
+
+func (s *service) IsChannelMember(ctx context.Context, channel string, userID string) (bool, error) {
+	const op = "./internal/user/service.IsChannelMember"
+	log := s.logger.With("op: ", op)
+
+	member, err := s.repository.IsChannelMember(ctx, channel, userID)
+	if err != nil {
+		log.Error("Error checking channel membership: ", slog.Any("err", err))
+		return false, err
+	}
+
+	return member, nil
+}
+
+func NewService(repository repository.Repository, credentials Credentials, logger *slog.Logger) Service {
 	return &service{
-		repository: repository,
-		logger:     logger,
+		repository:  repository,
+		credentials: credentials,
+		logger:      logger,
 	}
 }
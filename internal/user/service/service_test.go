@@ -0,0 +1,95 @@
+package service
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"github.com/QuUteO/video-communication/internal/model"
+	"github.com/gofrs/uuid"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		req  model.ListUsersRequest
+		key  string
+	}{
+		{"created_at", model.ListUsersRequest{Sort: model.UserSortCreatedAt, Order: "desc"}, "2026-10-19 18:00:00.123456"},
+		{"email with query", model.ListUsersRequest{Sort: model.UserSortEmail, Order: "asc", Query: "al"}, "alice@example.com"},
+		{"name", model.ListUsersRequest{Sort: model.UserSortName, Order: "desc"}, "Алиса"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := model.UserCursor{Key: tt.key, ID: uuid.Must(uuid.NewV4())}
+
+			req := tt.req
+			cursor, err := encodeCursor(&req, &want)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Cursor = cursor
+
+			got, err := decodeCursor(&req)
+			if err != nil {
+				t.Fatalf("decodeCursor: %v", err)
+			}
+			if *got != want {
+				t.Errorf("cursor = %+v, want %+v", *got, want)
+			}
+		})
+	}
+}
+
+func TestCursorBoundToRequest(t *testing.T) {
+	base := model.ListUsersRequest{Sort: model.UserSortEmail, Order: "asc", Query: "al"}
+	cursor, err := encodeCursor(&base, &model.UserCursor{Key: "alice@example.com", ID: uuid.Must(uuid.NewV4())})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// курсор другой сортировки или другого поиска пропустил бы или повторил строки
+	tests := []struct {
+		name string
+		req  model.ListUsersRequest
+	}{
+		{"other sort", model.ListUsersRequest{Sort: model.UserSortName, Order: "asc", Query: "al"}},
+		{"other order", model.ListUsersRequest{Sort: model.UserSortEmail, Order: "desc", Query: "al"}},
+		{"other query", model.ListUsersRequest{Sort: model.UserSortEmail, Order: "asc", Query: "bo"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req
+			req.Cursor = cursor
+			if _, err := decodeCursor(&req); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("error = %v, want %v", err, ErrInvalidCursor)
+			}
+		})
+	}
+}
+
+func TestCursorRejectsMalformed(t *testing.T) {
+	encode := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+	id := uuid.Must(uuid.NewV4()).String()
+
+	tests := []struct {
+		name   string
+		sort   string
+		cursor string
+	}{
+		{"not base64", model.UserSortEmail, "!!!"},
+		{"not json", model.UserSortEmail, encode("cursor")},
+		{"no id", model.UserSortEmail, encode(`{"k":"a","s":"email","o":"asc"}`)},
+		{"bad id", model.UserSortEmail, encode(`{"k":"a","id":"1","s":"email","o":"asc"}`)},
+		{"bad time key", model.UserSortCreatedAt, encode(`{"k":"yesterday","id":"` + id + `","s":"created_at","o":"asc"}`)},
+		{"sql in time key", model.UserSortCreatedAt, encode(`{"k":"2026-01-01'; --","id":"` + id + `","s":"created_at","o":"asc"}`)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := model.ListUsersRequest{Sort: tt.sort, Order: "asc", Cursor: tt.cursor}
+			if _, err := decodeCursor(&req); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("error = %v, want %v", err, ErrInvalidCursor)
+			}
+		})
+	}
+}
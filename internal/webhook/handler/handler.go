@@ -0,0 +1,145 @@
+package webhookhandler
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	authmiddleware "github.com/QuUteO/video-communication/internal/auth/middleware"
+	"github.com/QuUteO/video-communication/internal/model"
+	"github.com/QuUteO/video-communication/internal/webhook/service"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+type Handler struct {
+	service webhookservice.Service
+	logger  *slog.Logger
+}
+
+func NewHandler(service webhookservice.Service, logger *slog.Logger) *Handler {
+	return &Handler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// Create POST /channels/{channel}/webhooks
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	const op = "internal/webhook/handler/Create"
+	log := h.logger.With("op: ", op)
+
+	var req model.WebhookSubscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.error(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	userID, _ := r.Context().Value(authmiddleware.UserIDKey).(string)
+
+	sub, err := h.service.Create(r.Context(), userID, chi.URLParam(r, "channel"), &req)
+	if err != nil {
+		h.fail(w, r, log, err)
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, model.Response{
+		StatusCode: http.StatusCreated,
+		Message:    "Webhook created",
+		Data:       sub,
+		Error:      "nil",
+	})
+}
+
+// List GET /channels/{channel}/webhooks
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	const op = "internal/webhook/handler/List"
+	log := h.logger.With("op: ", op)
+
+	userID, _ := r.Context().Value(authmiddleware.UserIDKey).(string)
+
+	subs, err := h.service.List(r.Context(), userID, chi.URLParam(r, "channel"))
+	if err != nil {
+		h.fail(w, r, log, err)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, model.Response{
+		StatusCode: http.StatusOK,
+		Message:    "Webhooks retrieved successfully",
+		Data:       subs,
+		Error:      "nil",
+	})
+}
+
+// Delete DELETE /channels/{channel}/webhooks/{id}
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	const op = "internal/webhook/handler/Delete"
+	log := h.logger.With("op: ", op)
+
+	userID, _ := r.Context().Value(authmiddleware.UserIDKey).(string)
+
+	if err := h.service.Delete(r.Context(), userID, chi.URLParam(r, "channel"), chi.URLParam(r, "id")); err != nil {
+		h.fail(w, r, log, err)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, model.Response{
+		StatusCode: http.StatusOK,
+		Message:    "Webhook deleted",
+		Error:      "nil",
+	})
+}
+
+// Deliveries GET /channels/{channel}/webhooks/{id}/deliveries?limit=&offset=
+func (h *Handler) Deliveries(w http.ResponseWriter, r *http.Request) {
+	const op = "internal/webhook/handler/Deliveries"
+	log := h.logger.With("op: ", op)
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	userID, _ := r.Context().Value(authmiddleware.UserIDKey).(string)
+
+	deliveries, err := h.service.Deliveries(r.Context(), userID, chi.URLParam(r, "channel"), chi.URLParam(r, "id"), limit, offset)
+	if err != nil {
+		h.fail(w, r, log, err)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, model.Response{
+		StatusCode: http.StatusOK,
+		Message:    "Deliveries retrieved successfully",
+		Data:       deliveries,
+		Error:      "nil",
+	})
+}
+
+func (h *Handler) fail(w http.ResponseWriter, r *http.Request, log *slog.Logger, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, webhookservice.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, webhookservice.ErrForbidden):
+		status = http.StatusForbidden
+	case errors.Is(err, webhookservice.ErrInvalidURL), errors.Is(err, webhookservice.ErrInvalidEvent):
+		status = http.StatusBadRequest
+	default:
+		log.Error("Webhook request failed", slog.Any("error", err))
+	}
+
+	h.error(w, r, status, err.Error())
+}
+
+func (h *Handler) error(w http.ResponseWriter, r *http.Request, status int, msg string) {
+	render.Status(r, status)
+	render.JSON(w, r, model.Response{
+		StatusCode: status,
+		Error:      msg,
+	})
+}
@@ -0,0 +1,159 @@
+package webhookhandler
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	authmiddleware "github.com/QuUteO/video-communication/internal/auth/middleware"
+	"github.com/QuUteO/video-communication/internal/model"
+	"github.com/QuUteO/video-communication/internal/webhook/service"
+	"github.com/QuUteO/video-communication/pkg/richtext"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+const maxIncomingBody = 64 << 10
+
+type IncomingHandler struct {
+	service webhookservice.IncomingService
+	logger  *slog.Logger
+}
+
+func NewIncomingHandler(service webhookservice.IncomingService, logger *slog.Logger) *IncomingHandler {
+	return &IncomingHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// Create POST /channels/{channel}/incoming-webhooks
+func (h *IncomingHandler) Create(w http.ResponseWriter, r *http.Request) {
+	const op = "internal/webhook/handler/CreateIncoming"
+	log := h.logger.With("op: ", op)
+
+	var req model.IncomingWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.error(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	userID, _ := r.Context().Value(authmiddleware.UserIDKey).(string)
+
+	hook, err := h.service.Create(r.Context(), userID, chi.URLParam(r, "channel"), &req)
+	if err != nil {
+		h.fail(w, r, log, err)
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, model.Response{
+		StatusCode: http.StatusCreated,
+		Message:    "Incoming webhook created",
+		Data:       hook,
+		Error:      "nil",
+	})
+}
+
+// List GET /channels/{channel}/incoming-webhooks
+func (h *IncomingHandler) List(w http.ResponseWriter, r *http.Request) {
+	const op = "internal/webhook/handler/ListIncoming"
+	log := h.logger.With("op: ", op)
+
+	userID, _ := r.Context().Value(authmiddleware.UserIDKey).(string)
+
+	hooks, err := h.service.List(r.Context(), userID, chi.URLParam(r, "channel"))
+	if err != nil {
+		h.fail(w, r, log, err)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, model.Response{
+		StatusCode: http.StatusOK,
+		Message:    "Incoming webhooks retrieved successfully",
+		Data:       hooks,
+		Error:      "nil",
+	})
+}
+
+// Revoke DELETE /channels/{channel}/incoming-webhooks/{id}
+func (h *IncomingHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	const op = "internal/webhook/handler/RevokeIncoming"
+	log := h.logger.With("op: ", op)
+
+	userID, _ := r.Context().Value(authmiddleware.UserIDKey).(string)
+
+	if err := h.service.Revoke(r.Context(), userID, chi.URLParam(r, "channel"), chi.URLParam(r, "id")); err != nil {
+		h.fail(w, r, log, err)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, model.Response{
+		StatusCode: http.StatusOK,
+		Message:    "Incoming webhook revoked",
+		Error:      "nil",
+	})
+}
+
+// Post POST /hooks/{id}/{token} — публичный адрес для внешних систем
+func (h *IncomingHandler) Post(w http.ResponseWriter, r *http.Request) {
+	const op = "internal/webhook/handler/PostIncoming"
+	log := h.logger.With("op: ", op)
+
+	var payload model.IncomingWebhookPayload
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxIncomingBody)).Decode(&payload); err != nil {
+		h.error(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	msg, err := h.service.Post(r.Context(), chi.URLParam(r, "id"), chi.URLParam(r, "token"), &payload)
+	if err != nil {
+		h.fail(w, r, log, err)
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, model.Response{
+		StatusCode: http.StatusCreated,
+		Message:    "Message posted",
+		Data:       msg,
+		Error:      "nil",
+	})
+}
+
+func (h *IncomingHandler) fail(w http.ResponseWriter, r *http.Request, log *slog.Logger, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, webhookservice.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, webhookservice.ErrForbidden):
+		status = http.StatusForbidden
+	case errors.Is(err, webhookservice.ErrInvalidToken):
+		status = http.StatusUnauthorized
+	case errors.Is(err, webhookservice.ErrRateLimited):
+		status = http.StatusTooManyRequests
+	case errors.Is(err, webhookservice.ErrInvalidName),
+		errors.Is(err, webhookservice.ErrEmptyText),
+		errors.Is(err, richtext.ErrUnknownFormat),
+		errors.Is(err, richtext.ErrTooLong),
+		errors.Is(err, richtext.ErrScriptInjection),
+		errors.Is(err, richtext.ErrUnsafeLink),
+		errors.Is(err, richtext.ErrInvalidURL):
+		status = http.StatusBadRequest
+	default:
+		log.Error("Incoming webhook request failed", slog.Any("error", err))
+	}
+
+	h.error(w, r, status, err.Error())
+}
+
+func (h *IncomingHandler) error(w http.ResponseWriter, r *http.Request, status int, msg string) {
+	render.Status(r, status)
+	render.JSON(w, r, model.Response{
+		StatusCode: status,
+		Error:      msg,
+	})
+}
@@ -0,0 +1,394 @@
+package webhookrepository
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/QuUteO/video-communication/internal/model"
+	postgres "github.com/QuUteO/video-communication/pkg/db"
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+type Repository interface {
+	CreateSubscription(ctx context.Context, sub *model.WebhookSubscription) error
+	ListSubscriptions(ctx context.Context, channel string) ([]model.WebhookSubscription, error)
+	FindSubscription(ctx context.Context, channel, id string) (*model.WebhookSubscription, error)
+	DeleteSubscription(ctx context.Context, channel, id string) (bool, error)
+
+	EnqueueEvent(ctx context.Context, channel, event string, payload []byte) (int64, error)
+	ClaimDue(ctx context.Context, limit int, leaseSeconds float64) ([]model.WebhookDelivery, error)
+	MarkDelivered(ctx context.Context, id uuid.UUID, attempts, statusCode int) error
+	MarkFailed(ctx context.Context, id uuid.UUID, attempts, statusCode int, lastError string, retryInSeconds float64) error
+	MarkDead(ctx context.Context, id uuid.UUID, attempts, statusCode int, lastError string) error
+	ListDeliveries(ctx context.Context, subscriptionID string, limit, offset int) ([]model.WebhookDelivery, error)
+
+	CreateIncoming(ctx context.Context, hook *model.IncomingWebhook) error
+	ListIncoming(ctx context.Context, channel string) ([]model.IncomingWebhook, error)
+	FindIncoming(ctx context.Context, id string) (*model.IncomingWebhook, error)
+	RevokeIncoming(ctx context.Context, channel, id string) (bool, error)
+}
+
+type repository struct {
+	db     postgres.Client
+	logger *slog.Logger
+}
+
+func (r *repository) CreateSubscription(ctx context.Context, sub *model.WebhookSubscription) error {
+	const op = "./internal/webhook/repository.CreateSubscription"
+	log := r.logger.With("op: ", op)
+
+	q := `
+		INSERT INTO webhook_subscriptions (id, channel, url, secret, events, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	if _, err := r.db.Exec(ctx, q, sub.ID, sub.Channel, sub.URL, sub.Secret, sub.Events, sub.CreatedBy, sub.CreatedAt); err != nil {
+		log.Error("Error to insert webhook subscription", slog.Any("err", err))
+		return err
+	}
+
+	return nil
+}
+
+func (r *repository) ListSubscriptions(ctx context.Context, channel string) ([]model.WebhookSubscription, error) {
+	const op = "./internal/webhook/repository.ListSubscriptions"
+	log := r.logger.With("op: ", op)
+
+	q := `
+		SELECT id, channel, url, events, created_by, created_at
+		FROM webhook_subscriptions
+		WHERE channel = $1
+		ORDER BY created_at
+	`
+
+	rows, err := r.db.Query(ctx, q, channel)
+	if err != nil {
+		log.Error("Error to list webhook subscriptions", slog.Any("err", err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	subs := make([]model.WebhookSubscription, 0)
+	for rows.Next() {
+		sub, err := scanSubscription(rows)
+		if err != nil {
+			log.Error("Error to scan webhook subscription", slog.Any("err", err))
+			return nil, err
+		}
+		subs = append(subs, *sub)
+	}
+
+	return subs, rows.Err()
+}
+
+func (r *repository) FindSubscription(ctx context.Context, channel, id string) (*model.WebhookSubscription, error) {
+	const op = "./internal/webhook/repository.FindSubscription"
+	log := r.logger.With("op: ", op)
+
+	q := `
+		SELECT id, channel, url, events, created_by, created_at
+		FROM webhook_subscriptions
+		WHERE channel = $1 AND id = $2
+	`
+
+	sub, err := scanSubscription(r.db.QueryRow(ctx, q, channel, id))
+	if err != nil {
+		log.Info("Error to find webhook subscription", slog.Any("err", err))
+		return nil, err
+	}
+
+	return sub, nil
+}
+
+func (r *repository) DeleteSubscription(ctx context.Context, channel, id string) (bool, error) {
+	const op = "./internal/webhook/repository.DeleteSubscription"
+	log := r.logger.With("op: ", op)
+
+	q := `DELETE FROM webhook_subscriptions WHERE channel = $1 AND id = $2`
+
+	tag, err := r.db.Exec(ctx, q, channel, id)
+	if err != nil {
+		log.Error("Error to delete webhook subscription", slog.Any("err", err))
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// EnqueueEvent создает доставку для каждой подписки канала на это событие
+func (r *repository) EnqueueEvent(ctx context.Context, channel, event string, payload []byte) (int64, error) {
+	const op = "./internal/webhook/repository.EnqueueEvent"
+	log := r.logger.With("op: ", op)
+
+	q := `
+		INSERT INTO webhook_deliveries (id, subscription_id, event, payload)
+		SELECT gen_random_uuid(), s.id, $2, $3
+		FROM webhook_subscriptions s
+		WHERE s.channel = $1 AND $2 = ANY(s.events)
+	`
+
+	tag, err := r.db.Exec(ctx, q, channel, event, string(payload))
+	if err != nil {
+		log.Error("Error to enqueue webhook event", slog.Any("err", err))
+		return 0, err
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// ClaimDue забирает готовые к отправке доставки и продлевает их «аренду»,
+// чтобы параллельные диспетчеры не отправили одно событие дважды
+func (r *repository) ClaimDue(ctx context.Context, limit int, leaseSeconds float64) ([]model.WebhookDelivery, error) {
+	const op = "./internal/webhook/repository.ClaimDue"
+	log := r.logger.With("op: ", op)
+
+	q := `
+		UPDATE webhook_deliveries d
+		SET next_attempt_at = now() + make_interval(secs => $2)
+		FROM webhook_subscriptions s
+		WHERE s.id = d.subscription_id
+		  AND d.id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= now()
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		  )
+		RETURNING d.id, d.subscription_id, d.event, d.payload, d.attempts, d.created_at, s.url, s.secret
+	`
+
+	rows, err := r.db.Query(ctx, q, limit, leaseSeconds)
+	if err != nil {
+		log.Error("Error to claim webhook deliveries", slog.Any("err", err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := make([]model.WebhookDelivery, 0)
+	for rows.Next() {
+		var d model.WebhookDelivery
+		if err := rows.Scan(&d.ID, &d.SubscriptionID, &d.Event, &d.Payload, &d.Attempts, &d.CreatedAt, &d.URL, &d.Secret); err != nil {
+			log.Error("Error to scan webhook delivery", slog.Any("err", err))
+			return nil, err
+		}
+		d.Status = "pending"
+		deliveries = append(deliveries, d)
+	}
+
+	return deliveries, rows.Err()
+}
+
+func (r *repository) MarkDelivered(ctx context.Context, id uuid.UUID, attempts, statusCode int) error {
+	const op = "./internal/webhook/repository.MarkDelivered"
+	log := r.logger.With("op: ", op)
+
+	q := `
+		UPDATE webhook_deliveries
+		SET status = 'delivered', attempts = $2, last_status_code = $3, last_error = NULL, delivered_at = now()
+		WHERE id = $1
+	`
+
+	if _, err := r.db.Exec(ctx, q, id, attempts, statusCode); err != nil {
+		log.Error("Error to mark webhook delivered", slog.Any("err", err))
+		return err
+	}
+
+	return nil
+}
+
+func (r *repository) MarkFailed(ctx context.Context, id uuid.UUID, attempts, statusCode int, lastError string, retryInSeconds float64) error {
+	const op = "./internal/webhook/repository.MarkFailed"
+	log := r.logger.With("op: ", op)
+
+	q := `
+		UPDATE webhook_deliveries
+		SET attempts = $2, last_status_code = NULLIF($3, 0), last_error = $4,
+		    next_attempt_at = now() + make_interval(secs => $5)
+		WHERE id = $1
+	`
+
+	if _, err := r.db.Exec(ctx, q, id, attempts, statusCode, lastError, retryInSeconds); err != nil {
+		log.Error("Error to mark webhook failed", slog.Any("err", err))
+		return err
+	}
+
+	return nil
+}
+
+// MarkDead переносит доставку в таблицу «мертвых писем»
+func (r *repository) MarkDead(ctx context.Context, id uuid.UUID, attempts, statusCode int, lastError string) error {
+	const op = "./internal/webhook/repository.MarkDead"
+	log := r.logger.With("op: ", op)
+
+	q := `
+		WITH dead AS (
+			UPDATE webhook_deliveries
+			SET status = 'dead', attempts = $2, last_status_code = NULLIF($3, 0), last_error = $4
+			WHERE id = $1
+			RETURNING id, subscription_id, event, payload, attempts, last_error
+		)
+		INSERT INTO webhook_dead_letters (id, delivery_id, subscription_id, event, payload, attempts, last_error)
+		SELECT gen_random_uuid(), id, subscription_id, event, payload, attempts, last_error FROM dead
+	`
+
+	if _, err := r.db.Exec(ctx, q, id, attempts, statusCode, lastError); err != nil {
+		log.Error("Error to move webhook to dead letters", slog.Any("err", err))
+		return err
+	}
+
+	return nil
+}
+
+func (r *repository) ListDeliveries(ctx context.Context, subscriptionID string, limit, offset int) ([]model.WebhookDelivery, error) {
+	const op = "./internal/webhook/repository.ListDeliveries"
+	log := r.logger.With("op: ", op)
+
+	q := `
+		SELECT id, subscription_id, event, status, attempts, last_status_code, COALESCE(last_error, ''),
+		       next_attempt_at, created_at, delivered_at
+		FROM webhook_deliveries
+		WHERE subscription_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := r.db.Query(ctx, q, subscriptionID, limit, offset)
+	if err != nil {
+		log.Error("Error to list webhook deliveries", slog.Any("err", err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := make([]model.WebhookDelivery, 0)
+	for rows.Next() {
+		var d model.WebhookDelivery
+		var statusCode *int
+		if err := rows.Scan(&d.ID, &d.SubscriptionID, &d.Event, &d.Status, &d.Attempts, &statusCode,
+			&d.LastError, &d.NextAttemptAt, &d.CreatedAt, &d.DeliveredAt); err != nil {
+			log.Error("Error to scan webhook delivery", slog.Any("err", err))
+			return nil, err
+		}
+		if statusCode != nil {
+			d.LastStatusCode = *statusCode
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	return deliveries, rows.Err()
+}
+
+func (r *repository) CreateIncoming(ctx context.Context, hook *model.IncomingWebhook) error {
+	const op = "./internal/webhook/repository.CreateIncoming"
+	log := r.logger.With("op: ", op)
+
+	q := `
+		INSERT INTO incoming_webhooks (id, channel, name, token_hash, rate_limit_per_minute, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	if _, err := r.db.Exec(ctx, q, hook.ID, hook.Channel, hook.Name, hook.TokenHash,
+		hook.RateLimitPerMinute, hook.CreatedBy, hook.CreatedAt); err != nil {
+		log.Error("Error to insert incoming webhook", slog.Any("err", err))
+		return err
+	}
+
+	return nil
+}
+
+func (r *repository) ListIncoming(ctx context.Context, channel string) ([]model.IncomingWebhook, error) {
+	const op = "./internal/webhook/repository.ListIncoming"
+	log := r.logger.With("op: ", op)
+
+	q := `
+		SELECT id, channel, name, token_hash, rate_limit_per_minute, created_by, created_at, revoked_at
+		FROM incoming_webhooks
+		WHERE channel = $1
+		ORDER BY created_at
+	`
+
+	rows, err := r.db.Query(ctx, q, channel)
+	if err != nil {
+		log.Error("Error to list incoming webhooks", slog.Any("err", err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	hooks := make([]model.IncomingWebhook, 0)
+	for rows.Next() {
+		hook, err := scanIncoming(rows)
+		if err != nil {
+			log.Error("Error to scan incoming webhook", slog.Any("err", err))
+			return nil, err
+		}
+		hooks = append(hooks, *hook)
+	}
+
+	return hooks, rows.Err()
+}
+
+func (r *repository) FindIncoming(ctx context.Context, id string) (*model.IncomingWebhook, error) {
+	const op = "./internal/webhook/repository.FindIncoming"
+	log := r.logger.With("op: ", op)
+
+	q := `
+		SELECT id, channel, name, token_hash, rate_limit_per_minute, created_by, created_at, revoked_at
+		FROM incoming_webhooks
+		WHERE id = $1
+	`
+
+	hook, err := scanIncoming(r.db.QueryRow(ctx, q, id))
+	if err != nil {
+		log.Info("Error to find incoming webhook", slog.Any("err", err))
+		return nil, err
+	}
+
+	return hook, nil
+}
+
+func (r *repository) RevokeIncoming(ctx context.Context, channel, id string) (bool, error) {
+	const op = "./internal/webhook/repository.RevokeIncoming"
+	log := r.logger.With("op: ", op)
+
+	q := `UPDATE incoming_webhooks SET revoked_at = now() WHERE channel = $1 AND id = $2 AND revoked_at IS NULL`
+
+	tag, err := r.db.Exec(ctx, q, channel, id)
+	if err != nil {
+		log.Error("Error to revoke incoming webhook", slog.Any("err", err))
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+func scanIncoming(row pgx.Row) (*model.IncomingWebhook, error) {
+	var hook model.IncomingWebhook
+	var createdBy uuid.NullUUID
+
+	if err := row.Scan(&hook.ID, &hook.Channel, &hook.Name, &hook.TokenHash, &hook.RateLimitPerMinute,
+		&createdBy, &hook.CreatedAt, &hook.RevokedAt); err != nil {
+		return nil, err
+	}
+	hook.CreatedBy = createdBy.UUID
+
+	return &hook, nil
+}
+
+func scanSubscription(row pgx.Row) (*model.WebhookSubscription, error) {
+	var sub model.WebhookSubscription
+	var createdBy uuid.NullUUID
+
+	if err := row.Scan(&sub.ID, &sub.Channel, &sub.URL, &sub.Events, &createdBy, &sub.CreatedAt); err != nil {
+		return nil, err
+	}
+	sub.CreatedBy = createdBy.UUID
+
+	return &sub, nil
+}
+
+func New(db postgres.Client, logger *slog.Logger) Repository {
+	return &repository{
+		db:     db,
+		logger: logger,
+	}
+}
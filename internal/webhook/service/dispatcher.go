@@ -0,0 +1,237 @@
+package webhookservice
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/QuUteO/video-communication/internal/config"
+	"github.com/QuUteO/video-communication/internal/model"
+	"github.com/QuUteO/video-communication/internal/webhook/repository"
+	"github.com/QuUteO/video-communication/pkg/safehttp"
+)
+
+// Заголовки исходящего вебхука
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+const (
+	eventQueueSize = 1024
+	claimBatchSize = 50
+)
+
+// Sign подписывает тело запроса: sha256=hex(HMAC-SHA256(secret, "<timestamp>.<body>"))
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Dispatcher Фоновая доставка событий каналов подписчикам.
+// Publish не блокирует вызывающего: события сохраняются в БД отдельной горутиной,
+// поэтому их можно публиковать прямо из цикла Hub.
+type Dispatcher struct {
+	repo   webhookrepository.Repository
+	client *http.Client
+	cfg    *config.Webhooks
+	events chan model.ChannelEvent
+	wake   chan struct{}
+	logger *slog.Logger
+}
+
+// NewDispatcher client должен запрещать внутренние адреса (safehttp.NewClient)
+func NewDispatcher(repo webhookrepository.Repository, client *http.Client, cfg *config.Webhooks, logger *slog.Logger) *Dispatcher {
+	if client == nil {
+		// без списка исключений клиент создается без ошибок
+		client, _ = safehttp.NewClient(cfg.Timeout, nil)
+	}
+
+	return &Dispatcher{
+		repo:   repo,
+		client: client,
+		cfg:    cfg,
+		events: make(chan model.ChannelEvent, eventQueueSize),
+		wake:   make(chan struct{}, 1),
+		logger: logger,
+	}
+}
+
+func (d *Dispatcher) Publish(event model.ChannelEvent) {
+	select {
+	case d.events <- event:
+	default:
+		d.logger.Warn("webhook event queue is full, dropping event",
+			slog.String("event", event.Type), slog.String("channel", event.Channel))
+	}
+}
+
+// Run сохраняет события и доставляет их до отмены контекста
+func (d *Dispatcher) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+		d.enqueueLoop(ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		d.deliverLoop(ctx)
+	}()
+
+	wg.Wait()
+}
+
+func (d *Dispatcher) enqueueLoop(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-d.events:
+			payload, err := json.Marshal(event)
+			if err != nil {
+				d.logger.Error("failed to marshal webhook event", slog.String("error", err.Error()))
+				continue
+			}
+
+			n, err := d.repo.EnqueueEvent(ctx, event.Channel, event.Type, payload)
+			if err != nil {
+				d.logger.Error("failed to enqueue webhook event", slog.String("error", err.Error()))
+				continue
+			}
+
+			if n > 0 {
+				select {
+				case d.wake <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}
+}
+
+func (d *Dispatcher) deliverLoop(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+
+		d.deliverDue(ctx)
+	}
+}
+
+func (d *Dispatcher) deliverDue(ctx context.Context) {
+	// аренда с запасом на таймаут запроса: если процесс упадет, доставка вернется в очередь
+	lease := (2*d.cfg.Timeout + 30*time.Second).Seconds()
+
+	deliveries, err := d.repo.ClaimDue(ctx, claimBatchSize, lease)
+	if err != nil {
+		d.logger.Error("failed to claim webhook deliveries", slog.String("error", err.Error()))
+		return
+	}
+
+	sem := make(chan struct{}, max(1, d.cfg.Workers))
+	var wg sync.WaitGroup
+	for _, delivery := range deliveries {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(delivery model.WebhookDelivery) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			d.deliver(ctx, delivery)
+		}(delivery)
+	}
+	wg.Wait()
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, delivery model.WebhookDelivery) {
+	attempts := delivery.Attempts + 1
+	statusCode, err := d.send(ctx, delivery)
+
+	if err == nil {
+		if err := d.repo.MarkDelivered(ctx, delivery.ID, attempts, statusCode); err != nil {
+			d.logger.Error("failed to mark webhook delivered", slog.String("error", err.Error()))
+		}
+		return
+	}
+
+	d.logger.Warn("webhook delivery failed",
+		slog.String("delivery_id", delivery.ID.String()),
+		slog.Int("attempt", attempts),
+		slog.String("error", err.Error()))
+
+	if attempts >= d.cfg.MaxAttempts {
+		if err := d.repo.MarkDead(ctx, delivery.ID, attempts, statusCode, err.Error()); err != nil {
+			d.logger.Error("failed to move webhook to dead letters", slog.String("error", err.Error()))
+		}
+		return
+	}
+
+	if err := d.repo.MarkFailed(ctx, delivery.ID, attempts, statusCode, err.Error(), d.backoff(attempts).Seconds()); err != nil {
+		d.logger.Error("failed to mark webhook failed", slog.String("error", err.Error()))
+	}
+}
+
+func (d *Dispatcher) send(ctx context.Context, delivery model.WebhookDelivery) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, d.cfg.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "video-communication-webhooks/1.0")
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderDelivery, delivery.ID.String())
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(delivery.Secret, timestamp, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff экспоненциальная задержка с джиттером до 10%
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.cfg.BaseBackoff << (attempts - 1)
+	if delay <= 0 || delay > d.cfg.MaxBackoff {
+		delay = d.cfg.MaxBackoff
+	}
+	return delay + time.Duration(rand.Int64N(int64(delay)/10+1))
+}
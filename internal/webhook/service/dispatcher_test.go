@@ -0,0 +1,214 @@
+package webhookservice
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/QuUteO/video-communication/internal/config"
+	"github.com/QuUteO/video-communication/internal/model"
+	"github.com/QuUteO/video-communication/internal/webhook/repository"
+	"github.com/QuUteO/video-communication/pkg/safehttp"
+	"github.com/gofrs/uuid"
+)
+
+// fakeRepo запоминает итог доставки, остальные методы не вызываются
+type fakeRepo struct {
+	webhookrepository.Repository
+
+	mu         sync.Mutex
+	delivered  []int
+	failed     []float64
+	dead       []int
+	lastStatus int
+}
+
+func (r *fakeRepo) MarkDelivered(_ context.Context, _ uuid.UUID, attempts, statusCode int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.delivered = append(r.delivered, attempts)
+	r.lastStatus = statusCode
+	return nil
+}
+
+func (r *fakeRepo) MarkFailed(_ context.Context, _ uuid.UUID, _, statusCode int, _ string, retryInSeconds float64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.failed = append(r.failed, retryInSeconds)
+	r.lastStatus = statusCode
+	return nil
+}
+
+func (r *fakeRepo) MarkDead(_ context.Context, _ uuid.UUID, attempts, statusCode int, _ string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.dead = append(r.dead, attempts)
+	r.lastStatus = statusCode
+	return nil
+}
+
+func testConfig() *config.Webhooks {
+	return &config.Webhooks{
+		Timeout:     time.Second,
+		MaxAttempts: 2,
+		BaseBackoff: time.Second,
+		MaxBackoff:  time.Minute,
+		Workers:     1,
+	}
+}
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// localDispatcher разрешает loopback, где слушает httptest.Server
+func localDispatcher(t *testing.T, repo *fakeRepo) *Dispatcher {
+	t.Helper()
+
+	client, err := safehttp.NewClient(time.Second, []string{"127.0.0.0/8", "::1/128"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewDispatcher(repo, client, testConfig(), testLogger())
+}
+
+func testDelivery(url string) model.WebhookDelivery {
+	return model.WebhookDelivery{
+		ID:      uuid.Must(uuid.NewV4()),
+		Event:   "message.created",
+		Payload: []byte(`{"type":"message.created","channel":"general"}`),
+		URL:     url,
+		Secret:  "top-secret",
+	}
+}
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"hello":"world"}`)
+
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte("1700000000." + string(body)))
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	if got := Sign("secret", 1700000000, body); got != want {
+		t.Errorf("Sign = %s, want %s", got, want)
+	}
+	if Sign("other", 1700000000, body) == want {
+		t.Error("Sign does not depend on secret")
+	}
+	if Sign("secret", 1700000001, body) == want {
+		t.Error("Sign does not depend on timestamp")
+	}
+}
+
+func TestDeliverRoundTrip(t *testing.T) {
+	delivery := testDelivery("")
+
+	var checked bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		timestamp, err := strconv.ParseInt(r.Header.Get(HeaderTimestamp), 10, 64)
+		if err != nil {
+			t.Errorf("bad %s: %v", HeaderTimestamp, err)
+		}
+
+		switch {
+		case r.Method != http.MethodPost:
+			t.Errorf("method = %s", r.Method)
+		case string(body) != string(delivery.Payload):
+			t.Errorf("body = %s", body)
+		case r.Header.Get(HeaderEvent) != delivery.Event:
+			t.Errorf("%s = %s", HeaderEvent, r.Header.Get(HeaderEvent))
+		case r.Header.Get(HeaderDelivery) != delivery.ID.String():
+			t.Errorf("%s = %s", HeaderDelivery, r.Header.Get(HeaderDelivery))
+		case r.Header.Get(HeaderSignature) != Sign(delivery.Secret, timestamp, body):
+			t.Errorf("%s = %s", HeaderSignature, r.Header.Get(HeaderSignature))
+		default:
+			checked = true
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	repo := &fakeRepo{}
+	delivery.URL = server.URL
+	localDispatcher(t, repo).deliver(context.Background(), delivery)
+
+	if !checked {
+		t.Fatal("receiver did not get a valid request")
+	}
+	if len(repo.delivered) != 1 || repo.delivered[0] != 1 || repo.lastStatus != http.StatusOK {
+		t.Errorf("delivered = %v, status = %d", repo.delivered, repo.lastStatus)
+	}
+}
+
+func TestDeliverRetriesThenDead(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	repo := &fakeRepo{}
+	d := localDispatcher(t, repo)
+	delivery := testDelivery(server.URL)
+
+	d.deliver(context.Background(), delivery)
+	if len(repo.failed) != 1 || len(repo.dead) != 0 || repo.lastStatus != http.StatusInternalServerError {
+		t.Fatalf("after first attempt: failed = %v, dead = %v", repo.failed, repo.dead)
+	}
+	if repo.failed[0] < 1 {
+		t.Errorf("retry in %vs, want at least base backoff", repo.failed[0])
+	}
+
+	delivery.Attempts = 1
+	d.deliver(context.Background(), delivery)
+	if len(repo.dead) != 1 || repo.dead[0] != 2 {
+		t.Errorf("after last attempt: dead = %v", repo.dead)
+	}
+}
+
+// клиент по умолчанию не ходит во внутреннюю сеть
+func TestDeliverRefusesLoopback(t *testing.T) {
+	var called bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	repo := &fakeRepo{}
+	cfg := testConfig()
+	cfg.MaxAttempts = 1
+	NewDispatcher(repo, nil, cfg, testLogger()).deliver(context.Background(), testDelivery(server.URL))
+
+	if called {
+		t.Error("request reached loopback receiver")
+	}
+	if len(repo.dead) != 1 {
+		t.Errorf("dead = %v", repo.dead)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	d := NewDispatcher(&fakeRepo{}, nil, testConfig(), testLogger())
+
+	for attempts := 1; attempts <= 64; attempts++ {
+		base := time.Second << (attempts - 1)
+		if base <= 0 || base > time.Minute {
+			base = time.Minute
+		}
+
+		got := d.backoff(attempts)
+		if got < base || got > base+base/10 {
+			t.Errorf("backoff(%d) = %v, want [%v, %v]", attempts, got, base, base+base/10)
+		}
+	}
+}
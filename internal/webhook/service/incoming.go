@@ -0,0 +1,208 @@
+package webhookservice
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/QuUteO/video-communication/internal/model"
+	"github.com/QuUteO/video-communication/internal/user/service"
+	"github.com/QuUteO/video-communication/internal/webhook/repository"
+	"github.com/QuUteO/video-communication/pkg/ratelimit"
+	"github.com/QuUteO/video-communication/pkg/richtext"
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+const (
+	defaultIncomingRate = 60
+	maxIncomingRate     = 600
+	maxIntegrationName  = 64
+)
+
+var (
+	ErrInvalidName  = errors.New("integration name is required (up to 64 characters)")
+	ErrInvalidToken = errors.New("invalid webhook token")
+	ErrRateLimited  = errors.New("webhook rate limit exceeded")
+	ErrEmptyText    = errors.New("text is required")
+)
+
+// Broadcaster Рассылка сообщений подключенным клиентам (реализуется websocket.Hub)
+type Broadcaster interface {
+	Broadcast(msg model.Message)
+	PublishEvent(eventType, channel string, data interface{})
+}
+
+type IncomingService interface {
+	Create(ctx context.Context, userID, channel string, req *model.IncomingWebhookRequest) (*model.IncomingWebhook, error)
+	List(ctx context.Context, userID, channel string) ([]model.IncomingWebhook, error)
+	Revoke(ctx context.Context, userID, channel, id string) error
+	Post(ctx context.Context, id, token string, payload *model.IncomingWebhookPayload) (*model.Message, error)
+}
+
+type IncomingWebhookService struct {
+	repo    webhookrepository.Repository
+	users   service.Service
+	hub     Broadcaster
+	limiter *ratelimit.Limiter
+	logger  *slog.Logger
+}
+
+func (s *IncomingWebhookService) Create(ctx context.Context, userID, channel string, req *model.IncomingWebhookRequest) (*model.IncomingWebhook, error) {
+	const op = "internal/webhook/service.CreateIncoming"
+	log := s.logger.With("op: ", op)
+
+	if err := s.checkAccess(ctx, channel, userID); err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len([]rune(name)) > maxIntegrationName {
+		return nil, ErrInvalidName
+	}
+
+	rate := req.RateLimitPerMinute
+	if rate <= 0 {
+		rate = defaultIncomingRate
+	}
+	rate = min(rate, maxIncomingRate)
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, err
+	}
+	token := hex.EncodeToString(raw)
+
+	hook := &model.IncomingWebhook{
+		ID:                 uuid.Must(uuid.NewV4()),
+		Channel:            channel,
+		Name:               name,
+		TokenHash:          hashToken(token),
+		RateLimitPerMinute: rate,
+		CreatedBy:          uuid.FromStringOrNil(userID),
+		CreatedAt:          time.Now(),
+	}
+
+	if err := s.repo.CreateIncoming(ctx, hook); err != nil {
+		log.Error("Error creating incoming webhook", slog.Any("error", err))
+		return nil, err
+	}
+
+	// токен хранится только в виде хеша, поэтому URL показывается один раз
+	hook.URL = "/hooks/" + hook.ID.String() + "/" + token
+	return hook, nil
+}
+
+func (s *IncomingWebhookService) List(ctx context.Context, userID, channel string) ([]model.IncomingWebhook, error) {
+	if err := s.checkAccess(ctx, channel, userID); err != nil {
+		return nil, err
+	}
+
+	return s.repo.ListIncoming(ctx, channel)
+}
+
+func (s *IncomingWebhookService) Revoke(ctx context.Context, userID, channel, id string) error {
+	if err := s.checkAccess(ctx, channel, userID); err != nil {
+		return err
+	}
+	if _, err := uuid.FromString(id); err != nil {
+		return ErrNotFound
+	}
+
+	revoked, err := s.repo.RevokeIncoming(ctx, channel, id)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Post публикует сообщение от имени интеграции тем же путем, что и сообщения пользователей
+func (s *IncomingWebhookService) Post(ctx context.Context, id, token string, payload *model.IncomingWebhookPayload) (*model.Message, error) {
+	const op = "internal/webhook/service.PostIncoming"
+	log := s.logger.With("op: ", op)
+
+	if _, err := uuid.FromString(id); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	hook, err := s.repo.FindIncoming(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	if hook.RevokedAt != nil || subtle.ConstantTimeCompare([]byte(hashToken(token)), []byte(hook.TokenHash)) != 1 {
+		return nil, ErrInvalidToken
+	}
+
+	if !s.limiter.Allow(hook.ID.String(), hook.RateLimitPerMinute) {
+		return nil, ErrRateLimited
+	}
+
+	if strings.TrimSpace(payload.Text) == "" {
+		return nil, ErrEmptyText
+	}
+
+	text, html, format, err := richtext.Render(payload.Format, payload.Text)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := model.Message{
+		ID:      uuid.Must(uuid.NewV4()),
+		Type:    "message",
+		User:    hook.Name,
+		Msg:     text,
+		Format:  format,
+		HTML:    html,
+		Channel: hook.Channel,
+		Time:    time.Now(),
+	}
+
+	if err := s.users.SaveMsg(ctx, msg); err != nil {
+		log.Error("Error saving webhook message", slog.Any("error", err))
+		return nil, err
+	}
+
+	s.hub.Broadcast(msg)
+	s.hub.PublishEvent(model.EventMessageCreated, msg.Channel, msg)
+
+	return &msg, nil
+}
+
+func (s *IncomingWebhookService) checkAccess(ctx context.Context, channel, userID string) error {
+	member, err := s.users.IsChannelMember(ctx, channel, userID)
+	if err != nil {
+		return err
+	}
+	if !member {
+		return ErrForbidden
+	}
+	return nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func NewIncomingWebhookService(repo webhookrepository.Repository, users service.Service, hub Broadcaster, logger *slog.Logger) IncomingService {
+	return &IncomingWebhookService{
+		repo:    repo,
+		users:   users,
+		hub:     hub,
+		limiter: ratelimit.New(),
+		logger:  logger,
+	}
+}
@@ -0,0 +1,162 @@
+package webhookservice
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/QuUteO/video-communication/internal/model"
+	"github.com/QuUteO/video-communication/internal/user/service"
+	"github.com/QuUteO/video-communication/internal/webhook/repository"
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+const (
+	defaultLimit = 50
+	maxLimit     = 200
+)
+
+var (
+	ErrNotFound     = errors.New("webhook not found")
+	ErrForbidden    = errors.New("no access to channel")
+	ErrInvalidURL   = errors.New("webhook url must be an absolute http(s) url")
+	ErrInvalidEvent = errors.New("unknown webhook event")
+)
+
+type Service interface {
+	Create(ctx context.Context, userID, channel string, req *model.WebhookSubscriptionRequest) (*model.WebhookSubscription, error)
+	List(ctx context.Context, userID, channel string) ([]model.WebhookSubscription, error)
+	Delete(ctx context.Context, userID, channel, id string) error
+	Deliveries(ctx context.Context, userID, channel, id string, limit, offset int) ([]model.WebhookDelivery, error)
+}
+
+type WebhookService struct {
+	repo   webhookrepository.Repository
+	users  service.Service
+	logger *slog.Logger
+}
+
+func (s *WebhookService) Create(ctx context.Context, userID, channel string, req *model.WebhookSubscriptionRequest) (*model.WebhookSubscription, error) {
+	const op = "internal/webhook/service.Create"
+	log := s.logger.With("op: ", op)
+
+	if err := s.checkAccess(ctx, channel, userID); err != nil {
+		return nil, err
+	}
+
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, ErrInvalidURL
+	}
+
+	events := req.Events
+	if len(events) == 0 {
+		events = model.EventTypes
+	}
+	for _, event := range events {
+		if !slices.Contains(model.EventTypes, event) {
+			return nil, ErrInvalidEvent
+		}
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+
+	sub := &model.WebhookSubscription{
+		ID:        uuid.Must(uuid.NewV4()),
+		Channel:   channel,
+		URL:       u.String(),
+		Secret:    hex.EncodeToString(secret),
+		Events:    slices.Compact(slices.Sorted(slices.Values(events))),
+		CreatedBy: uuid.FromStringOrNil(userID),
+		CreatedAt: time.Now(),
+	}
+
+	if err := s.repo.CreateSubscription(ctx, sub); err != nil {
+		log.Error("Error creating webhook subscription", slog.Any("error", err))
+		return nil, err
+	}
+
+	return sub, nil
+}
+
+func (s *WebhookService) List(ctx context.Context, userID, channel string) ([]model.WebhookSubscription, error) {
+	if err := s.checkAccess(ctx, channel, userID); err != nil {
+		return nil, err
+	}
+
+	return s.repo.ListSubscriptions(ctx, channel)
+}
+
+func (s *WebhookService) Delete(ctx context.Context, userID, channel, id string) error {
+	if err := s.checkAccess(ctx, channel, userID); err != nil {
+		return err
+	}
+	if _, err := uuid.FromString(id); err != nil {
+		return ErrNotFound
+	}
+
+	deleted, err := s.repo.DeleteSubscription(ctx, channel, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *WebhookService) Deliveries(ctx context.Context, userID, channel, id string, limit, offset int) ([]model.WebhookDelivery, error) {
+	if err := s.checkAccess(ctx, channel, userID); err != nil {
+		return nil, err
+	}
+	if _, err := uuid.FromString(id); err != nil {
+		return nil, ErrNotFound
+	}
+
+	if _, err := s.repo.FindSubscription(ctx, channel, id); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	if limit <= 0 {
+		limit = defaultLimit
+	}
+	if limit > maxLimit {
+		limit = maxLimit
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	return s.repo.ListDeliveries(ctx, id, limit, offset)
+}
+
+func (s *WebhookService) checkAccess(ctx context.Context, channel, userID string) error {
+	member, err := s.users.IsChannelMember(ctx, channel, userID)
+	if err != nil {
+		return err
+	}
+	if !member {
+		return ErrForbidden
+	}
+	return nil
+}
+
+func NewWebhookService(repo webhookrepository.Repository, users service.Service, logger *slog.Logger) Service {
+	return &WebhookService{
+		repo:   repo,
+		users:  users,
+		logger: logger,
+	}
+}
@@ -3,35 +3,56 @@ package websocket
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"time"
 
+	"github.com/QuUteO/video-communication/internal/attachment/service"
+	"github.com/QuUteO/video-communication/internal/command"
 	"github.com/QuUteO/video-communication/internal/model"
+	"github.com/QuUteO/video-communication/internal/poll/service"
+	"github.com/QuUteO/video-communication/internal/schedule/service"
 	"github.com/QuUteO/video-communication/internal/user/service"
+	"github.com/QuUteO/video-communication/pkg/richtext"
 	"github.com/gofrs/uuid"
 	"github.com/gorilla/websocket"
 )
 
+// maxMessageTTL Верхняя граница TTL самоуничтожающегося сообщения
+const maxMessageTTL = 30 * 24 * time.Hour
+
 type Client struct {
-	ID             string             // Уникальный ID клиента
-	Conn           *websocket.Conn    // WebSocket соединение
-	Send           chan model.Message // Канал для отправки сообщений
-	Hub            *Hub               // Хаб
-	Srv            service.Service    // Слой сервиса для работы с БД
-	CurrentChannel string             // Текущий канал
-	Username       string             // Имя пользователя
+	ID             string                    // Уникальный ID клиента
+	SessionID      string                    // сессия входа, по ее отзыву соединение закрывается
+	Conn           *websocket.Conn           // WebSocket соединение
+	Send           chan model.Message        // Канал для отправки сообщений
+	Hub            *Hub                      // Хаб
+	Srv            service.Service           // Слой сервиса для работы с БД
+	Attachments    attachmentservice.Service // Вложения к сообщениям
+	Publisher      *Publisher                // привязка вложений, упоминания и рассылка
+	Commands       *command.Registry         // slash-команды
+	Schedules      scheduleservice.Service   // отложенные сообщения
+	Polls          pollservice.Service       // опросы
+	CurrentChannel string                    // Текущий канал
+	Username       string                    // Имя пользователя
+	Profile        model.ProfileStamp        // имя и аватар на момент подключения; актуальные хранит хаб
 	Logger         *slog.Logger
 }
 
-func NewClient(clientID, username string, conn *websocket.Conn, srv service.Service, hub *Hub, logger *slog.Logger) *Client {
+func NewClient(clientID, username string, conn *websocket.Conn, srv service.Service, attachments attachmentservice.Service, publisher *Publisher, commands *command.Registry, schedules scheduleservice.Service, polls pollservice.Service, hub *Hub, logger *slog.Logger) *Client {
 	return &Client{
-		ID:       clientID,
-		Conn:     conn,
-		Send:     make(chan model.Message, 256),
-		Hub:      hub,
-		Srv:      srv,
-		Username: username,
-		Logger:   logger,
+		ID:          clientID,
+		Conn:        conn,
+		Send:        make(chan model.Message, 256),
+		Hub:         hub,
+		Srv:         srv,
+		Attachments: attachments,
+		Publisher:   publisher,
+		Commands:    commands,
+		Schedules:   schedules,
+		Polls:       polls,
+		Username:    username,
+		Logger:      logger,
 	}
 }
 
@@ -46,6 +67,8 @@ func (c *Client) ReadPump() {
 		}
 		c.Hub.mu.Unlock()
 
+		c.Hub.Disconnect(c)
+
 		// Закрываем соединение
 		if c.Conn != nil {
 			c.Conn.Close()
@@ -93,6 +116,18 @@ func (c *Client) handleReadMessage(data []byte) {
 		c.handleMessage(rawMsg)
 	case "leave":
 		c.handleLeave()
+	case "schedule":
+		c.handleSchedule(rawMsg)
+	case "schedule_list":
+		c.handleScheduleList(rawMsg)
+	case "schedule_cancel":
+		c.handleScheduleCancel(rawMsg)
+	case "poll_create":
+		c.handlePollCreate(rawMsg)
+	case "poll_vote":
+		c.handlePollVote(rawMsg)
+	case "poll_close":
+		c.handlePollClose(rawMsg)
 	}
 }
 
@@ -113,6 +148,13 @@ func (c *Client) handleJoinMessage(rawMsg map[string]interface{}) {
 
 	c.CurrentChannel = channel
 
+	// сохранение участника канала (дает доступ к истории и поиску)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := c.Srv.JoinChannel(ctx, channel, c.ID); err != nil {
+		c.Logger.Error("Error saving channel member:", slog.String("error", err.Error()))
+	}
+
 	c.Hub.register <- &ClientRegistration{
 		Client:  c,
 		Channel: c.CurrentChannel,
@@ -139,6 +181,13 @@ func (c *Client) loadChannelHistory(channel string) {
 		return
 	}
 
+	if err := c.Attachments.FillMessages(ctx, messages); err != nil {
+		c.Logger.Error("Error loading attachments:", slog.String("error", err.Error()))
+	}
+	if err := c.Polls.FillMessages(ctx, messages); err != nil {
+		c.Logger.Error("Error loading polls:", slog.String("error", err.Error()))
+	}
+
 	for _, message := range messages {
 		select {
 		case c.Send <- message:
@@ -155,21 +204,127 @@ func (c *Client) handleMessage(rawMsg map[string]interface{}) {
 
 	if c.CurrentChannel == "" {
 		c.Logger.Warn("client not in any channel", slog.String("client_id", c.ID))
+		c.Hub.NotifyClient(c, "", "Сначала присоединитесь к каналу")
 		return
 	}
 
-	msgText, ok := rawMsg["msg"].(string)
-	if !ok || msgText == "" {
+	attachmentIDs := stringList(rawMsg["attachments"])
+
+	msgText, _ := rawMsg["msg"].(string)
+	if msgText == "" && len(attachmentIDs) == 0 {
 		c.Logger.Error("message type is required")
 		return
 	}
 
+	// "/name args" не сохраняется, а передается обработчику команды;
+	// ответ бота может занять время, поэтому чтение сокета не блокируется
+	if name, args, ok := command.Parse(msgText); ok && len(attachmentIDs) == 0 {
+		go c.handleCommand(c.CurrentChannel, name, args)
+		return
+	}
+	msgText = command.Unescape(msgText)
+
+	// markdown нормализуется и рендерится на сервере, чтобы все клиенты показывали одно и то же
+	format, _ := rawMsg["format"].(string)
+	msgText, msgHTML, format, err := richtext.Render(format, msgText)
+	if err != nil {
+		c.Logger.Warn("invalid message text", slog.String("client_id", c.ID), slog.String("error", err.Error()))
+		c.Hub.NotifyClient(c, c.CurrentChannel, "Сообщение не отправлено: "+err.Error())
+		return
+	}
+
+	attachments, err := c.Attachments.Resolve(ctx, c.ID, c.CurrentChannel, attachmentIDs)
+	if err != nil {
+		c.Logger.Error("Error resolving attachments:", slog.String("error", err.Error()))
+		if errors.Is(err, attachmentservice.ErrInvalidAttachment) {
+			c.Hub.NotifyClient(c, c.CurrentChannel, "Сообщение не отправлено: "+err.Error())
+		} else {
+			c.Hub.NotifyClient(c, c.CurrentChannel, "Сообщение не отправлено, попробуйте позже")
+		}
+		return
+	}
+
 	msg := model.Message{
 		ID:      uuid.Must(uuid.NewV4()),
+		Type:    "message",
+		UserID:  uuid.FromStringOrNil(c.ID),
 		User:    c.Username,
 		Msg:     msgText,
+		Format:  format,
+		HTML:    msgHTML,
 		Channel: c.CurrentChannel,
 		Time:    time.Now(),
+
+		Attachments: attachments,
+	}
+
+	// самоуничтожающееся сообщение: "ttl" в секундах; ограничиваем до перевода в
+	// time.Duration, иначе большое значение переполнится в отрицательное
+	if ttl, ok := rawMsg["ttl"].(float64); ok && ttl > 0 {
+		ttl = min(ttl, maxMessageTTL.Seconds())
+		err = c.Srv.SaveMsgTTL(ctx, &msg, time.Duration(ttl*float64(time.Second)))
+	} else {
+		err = c.Srv.SaveMsg(ctx, msg)
+	}
+	if err != nil {
+		c.Logger.Error("Error saving message:", slog.String("error", err.Error()))
+		c.Hub.NotifyClient(c, msg.Channel, "Сообщение не отправлено, попробуйте позже")
+		return
+	}
+
+	c.Publisher.Publish(ctx, &msg)
+}
+
+func (c *Client) handleCommand(channel, name, args string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	resp, info, err := c.Commands.Dispatch(ctx, command.Invocation{
+		Command:  name,
+		Args:     args,
+		Channel:  channel,
+		UserID:   c.ID,
+		Username: c.Username,
+	})
+	if errors.Is(err, command.ErrUnknown) {
+		c.Hub.NotifyClient(c, channel, "Неизвестная команда /"+name+". Список команд: /help")
+		return
+	}
+	if err != nil {
+		c.Logger.Error("Error running command:", slog.String("command", name), slog.String("error", err.Error()))
+		c.Hub.NotifyClient(c, channel, "Команда /"+name+" завершилась с ошибкой")
+		return
+	}
+
+	if resp.Text == "" {
+		return
+	}
+	if resp.Ephemeral {
+		c.Hub.NotifyClient(c, channel, resp.Text)
+		return
+	}
+
+	msgText, msgHTML, format, err := richtext.Render(resp.Format, resp.Text)
+	if err != nil {
+		c.Logger.Warn("invalid command response", slog.String("command", name), slog.String("error", err.Error()))
+		c.Hub.NotifyClient(c, channel, "Команда /"+name+" вернула некорректный ответ")
+		return
+	}
+
+	msg := model.Message{
+		ID:      uuid.Must(uuid.NewV4()),
+		Type:    "message",
+		User:    info.Owner,
+		Msg:     msgText,
+		Format:  format,
+		HTML:    msgHTML,
+		Channel: channel,
+		Time:    time.Now(),
+	}
+	// встроенные команды отвечают от имени пользователя, боты — от своего имени
+	if info.Owner == command.OwnerBuiltin {
+		msg.UserID = uuid.FromStringOrNil(c.ID)
+		msg.User = c.Username
 	}
 
 	if err := c.Srv.SaveMsg(ctx, msg); err != nil {
@@ -177,7 +332,156 @@ func (c *Client) handleMessage(rawMsg map[string]interface{}) {
 		return
 	}
 
-	c.Hub.broadcast <- msg
+	c.Hub.Broadcast(msg)
+	c.Hub.PublishEvent(model.EventMessageCreated, msg.Channel, msg)
+}
+
+// отложенное сообщение в текущий канал: {"type":"schedule","msg":"...","format":"...","send_at":"RFC3339"}
+func (c *Client) handleSchedule(rawMsg map[string]interface{}) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if c.CurrentChannel == "" {
+		c.Hub.NotifyClient(c, "", "Сначала присоединитесь к каналу")
+		return
+	}
+
+	sendAtRaw, _ := rawMsg["send_at"].(string)
+	sendAt, err := time.Parse(time.RFC3339, sendAtRaw)
+	if err != nil {
+		c.Hub.NotifyClient(c, c.CurrentChannel, "send_at должен быть в формате RFC3339")
+		return
+	}
+
+	req := &model.ScheduledMessageRequest{SendAt: sendAt}
+	req.Msg, _ = rawMsg["msg"].(string)
+	req.Format, _ = rawMsg["format"].(string)
+	req.Attachments = stringList(rawMsg["attachments"])
+
+	sm, err := c.Schedules.Create(ctx, c.ID, c.Username, c.CurrentChannel, req)
+	if err != nil {
+		c.Logger.Warn("Error scheduling message:", slog.String("error", err.Error()))
+		c.Hub.NotifyClient(c, c.CurrentChannel, "Сообщение не запланировано: "+err.Error())
+		return
+	}
+
+	c.Conn.WriteJSON(map[string]interface{}{
+		"type": "scheduled",
+		"data": sm,
+	})
+}
+
+func (c *Client) handleScheduleList(rawMsg map[string]interface{}) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	channel, _ := rawMsg["channel"].(string)
+
+	list, err := c.Schedules.List(ctx, c.ID, channel)
+	if err != nil {
+		c.Logger.Error("Error listing scheduled messages:", slog.String("error", err.Error()))
+		c.Hub.NotifyClient(c, channel, "Не удалось получить отложенные сообщения")
+		return
+	}
+
+	c.Conn.WriteJSON(map[string]interface{}{
+		"type": "scheduled_list",
+		"data": list,
+	})
+}
+
+func (c *Client) handleScheduleCancel(rawMsg map[string]interface{}) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	id, _ := rawMsg["id"].(string)
+
+	if err := c.Schedules.Cancel(ctx, c.ID, id); err != nil {
+		c.Hub.NotifyClient(c, c.CurrentChannel, "Не удалось отменить сообщение: "+err.Error())
+		return
+	}
+
+	c.Conn.WriteJSON(map[string]interface{}{
+		"type": "schedule_cancelled",
+		"id":   id,
+	})
+}
+
+// опрос в текущем канале; сообщение с опросом приходит всем через рассылку канала
+func (c *Client) handlePollCreate(rawMsg map[string]interface{}) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if c.CurrentChannel == "" {
+		c.Hub.NotifyClient(c, "", "Сначала присоединитесь к каналу")
+		return
+	}
+
+	req := &model.PollRequest{Options: stringList(rawMsg["options"])}
+	req.Question, _ = rawMsg["question"].(string)
+	req.Multiple, _ = rawMsg["multiple"].(bool)
+	req.Anonymous, _ = rawMsg["anonymous"].(bool)
+
+	if _, err := c.Polls.Create(ctx, c.ID, c.Username, c.CurrentChannel, req); err != nil {
+		c.Logger.Warn("Error creating poll:", slog.String("error", err.Error()))
+		c.Hub.NotifyClient(c, c.CurrentChannel, "Опрос не создан: "+err.Error())
+	}
+}
+
+// голос в опросе: {"type":"poll_vote","poll_id":"...","options":[0,2]}; итоги приходят как poll_updated
+func (c *Client) handlePollVote(rawMsg map[string]interface{}) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	pollID, _ := rawMsg["poll_id"].(string)
+	req := &model.PollVoteRequest{Options: intList(rawMsg["options"])}
+
+	if _, err := c.Polls.Vote(ctx, c.ID, pollID, req); err != nil {
+		c.Hub.NotifyClient(c, c.CurrentChannel, "Голос не учтен: "+err.Error())
+	}
+}
+
+func (c *Client) handlePollClose(rawMsg map[string]interface{}) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	pollID, _ := rawMsg["poll_id"].(string)
+
+	if _, err := c.Polls.Close(ctx, c.ID, pollID); err != nil {
+		c.Hub.NotifyClient(c, c.CurrentChannel, "Опрос не закрыт: "+err.Error())
+	}
+}
+
+// intList приводит JSON-массив чисел к []int, остальные элементы пропускает
+func intList(value interface{}) []int {
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil
+	}
+
+	result := make([]int, 0, len(items))
+	for _, item := range items {
+		if n, ok := item.(float64); ok {
+			result = append(result, int(n))
+		}
+	}
+	return result
+}
+
+// stringList приводит JSON-массив строк к []string, остальные элементы пропускает
+func stringList(value interface{}) []string {
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil
+	}
+
+	result := make([]string, 0, len(items))
+	for _, item := range items {
+		if s, ok := item.(string); ok && s != "" {
+			result = append(result, s)
+		}
+	}
+	return result
 }
 
 func (c *Client) handleLeave() {
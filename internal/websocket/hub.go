@@ -1,41 +1,80 @@
 package websocket
 
 import (
+	"context"
 	"log/slog"
 	"sync"
 	"time"
 
 	"github.com/QuUteO/video-communication/internal/model"
 	"github.com/gofrs/uuid"
+	"github.com/gorilla/websocket"
 )
 
 type Hub struct {
-	channels map[string]map[*Client]bool // мапа для хранения пользователей в канале
+	channels map[string]map[*Client]bool   // мапа для хранения пользователей в канале
+	users    map[string]map[*Client]bool   // все соединения пользователя, независимо от канала
+	profiles map[string]model.ProfileStamp // имя и аватар подключенных пользователей
+	offline  map[string]cachedStamp        // имя и аватар недавних отправителей без соединений
+
+	stamper ProfileStamper // nil — сообщения без соединения автора не подписываются
 
 	register   chan *ClientRegistration // канал для регистрации в канал
 	unregister chan *ClientRegistration // канал для ухода из канала
 	broadcast  chan model.Message       // канал для трансляции всем пользователем в канале
 
+	events EventPublisher // внешние подписчики на события каналов (вебхуки)
+
 	mu     *sync.RWMutex
 	logger *slog.Logger
 }
 
+// ProfileStamper Имя и аватар из профиля пользователя (profileservice.ProfileService)
+type ProfileStamper interface {
+	Stamp(ctx context.Context, userID string) (model.ProfileStamp, error)
+}
+
+type cachedStamp struct {
+	stamp   model.ProfileStamp
+	expires time.Time
+}
+
+const (
+	offlineStampTTL   = time.Minute
+	offlineStampLimit = 4096
+	stampTimeout      = 2 * time.Second
+)
+
+// EventPublisher Получатель событий каналов; Publish не должен блокировать
+type EventPublisher interface {
+	Publish(event model.ChannelEvent)
+}
+
 type ClientRegistration struct {
 	Client  *Client
 	Channel string
 }
 
-func NewHub(logger *slog.Logger) *Hub {
+func NewHub(events EventPublisher, logger *slog.Logger) *Hub {
 	return &Hub{
 		channels:   make(map[string]map[*Client]bool),
+		users:      make(map[string]map[*Client]bool),
+		profiles:   make(map[string]model.ProfileStamp),
+		offline:    make(map[string]cachedStamp),
 		register:   make(chan *ClientRegistration),
 		unregister: make(chan *ClientRegistration),
 		broadcast:  make(chan model.Message),
+		events:     events,
 		mu:         &sync.RWMutex{},
 		logger:     logger,
 	}
 }
 
+// UseProfiles подключает профили для подписи сообщений авторов без соединений; до Run
+func (h *Hub) UseProfiles(stamper ProfileStamper) {
+	h.stamper = stamper
+}
+
 func (h *Hub) Run() {
 	for {
 
@@ -72,11 +111,14 @@ func (h *Hub) registerClientToChannel(client *Client, channel string) {
 	// Добавление название канала в информацию о клиенте
 	client.CurrentChannel = channel
 
+	h.PublishEvent(model.EventMemberJoined, channel, h.memberEvent(client))
+
 	// Создание системного сообщения
 	systemMsg := model.Message{
 		ID:      uuid.Must(uuid.NewV4()),
+		Type:    "system",
 		User:    "System",
-		Msg:     client.Username + " присоединился к каналу",
+		Msg:     h.nameOf(client) + " присоединился к каналу",
 		Channel: channel,
 		Time:    time.Now(),
 	}
@@ -105,10 +147,13 @@ func (h *Hub) unregisterClientToChannel(client *Client, channel string) {
 
 		client.CurrentChannel = ""
 
+		h.PublishEvent(model.EventMemberLeft, channel, h.memberEvent(client))
+
 		systemMsg := model.Message{
 			ID:      uuid.Must(uuid.NewV4()),
+			Type:    "system",
 			User:    "System",
-			Msg:     client.Username + " покинул канал",
+			Msg:     h.nameOf(client) + " покинул канал",
 			Channel: channel,
 			Time:    time.Now(),
 		}
@@ -140,6 +185,7 @@ func (h *Hub) broadcastToChannel(msg model.Message) {
 	defer h.mu.Unlock()
 
 	channelName := msg.Channel
+	h.stamp(&msg)
 
 	if channel, ok := h.channels[channelName]; ok {
 		for c := range channel {
@@ -158,6 +204,247 @@ func (h *Hub) broadcastToChannel(msg model.Message) {
 	}
 }
 
+// PublishEvent передает событие канала внешним подписчикам
+func (h *Hub) PublishEvent(eventType, channel string, data interface{}) {
+	if h.events == nil {
+		return
+	}
+
+	h.events.Publish(model.ChannelEvent{
+		ID:      uuid.Must(uuid.NewV4()),
+		Type:    eventType,
+		Channel: channel,
+		Time:    time.Now(),
+		Data:    data,
+	})
+}
+
+// Broadcast рассылает сообщение в канал msg.Channel (для вызова из других пакетов)
+func (h *Hub) Broadcast(msg model.Message) {
+	h.loadStamp(msg.UserID)
+	h.broadcast <- msg
+}
+
+// Connect добавляет соединение в индекс пользователей
+func (h *Hub) Connect(client *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.users[client.ID]; !ok {
+		h.users[client.ID] = make(map[*Client]bool)
+	}
+	h.users[client.ID][client] = true
+	h.profiles[client.ID] = client.Profile
+}
+
+// UpdateProfile новые имя и аватар действуют для следующих сообщений без переподключения
+func (h *Hub) UpdateProfile(userID string, stamp model.ProfileStamp) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if entry, ok := h.offline[userID]; ok {
+		entry.stamp = stamp
+		h.offline[userID] = entry
+	}
+	if _, ok := h.users[userID]; !ok {
+		return
+	}
+	h.profiles[userID] = stamp
+}
+
+// stamp подписывает сообщение текущими именем и аватаром отправителя; вызывается под h.mu
+func (h *Hub) stamp(msg *model.Message) {
+	if msg.UserID == uuid.Nil {
+		return
+	}
+	p, ok := h.profiles[msg.UserID.String()]
+	if !ok {
+		var entry cachedStamp
+		entry, ok = h.offline[msg.UserID.String()]
+		p = entry.stamp
+	}
+	if ok {
+		msg.DisplayName = p.DisplayName
+		msg.AvatarURL = p.AvatarURL
+	}
+}
+
+// loadStamp загружает профиль отправителя без соединений до рассылки, вне h.mu и цикла Run:
+// так подписываются сообщения планировщика, опросов и входящих вебхуков
+func (h *Hub) loadStamp(userID uuid.UUID) {
+	if h.stamper == nil || userID == uuid.Nil {
+		return
+	}
+	id := userID.String()
+
+	h.mu.RLock()
+	_, online := h.profiles[id]
+	entry, cached := h.offline[id]
+	h.mu.RUnlock()
+	if online || (cached && time.Now().Before(entry.expires)) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), stampTimeout)
+	defer cancel()
+
+	stamp, err := h.stamper.Stamp(ctx, id)
+	if err != nil {
+		h.logger.Warn("failed to load profile", slog.String("user_id", id), slog.String("error", err.Error()))
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.cacheOffline(id, stamp)
+}
+
+// cacheOffline вызывается под h.mu
+func (h *Hub) cacheOffline(userID string, stamp model.ProfileStamp) {
+	if len(h.offline) >= offlineStampLimit {
+		now := time.Now()
+		for id, entry := range h.offline {
+			if now.After(entry.expires) {
+				delete(h.offline, id)
+			}
+		}
+		if len(h.offline) >= offlineStampLimit {
+			clear(h.offline)
+		}
+	}
+	h.offline[userID] = cachedStamp{stamp: stamp, expires: time.Now().Add(offlineStampTTL)}
+}
+
+// nameOf имя для системных сообщений: из профиля, иначе введенное клиентом; вызывается под h.mu
+func (h *Hub) nameOf(client *Client) string {
+	if p, ok := h.profiles[client.ID]; ok && p.DisplayName != "" {
+		return p.DisplayName
+	}
+	return client.Username
+}
+
+func (h *Hub) memberEvent(client *Client) model.MemberEventData {
+	p := h.profiles[client.ID]
+	return model.MemberEventData{
+		UserID:      client.ID,
+		Username:    client.Username,
+		DisplayName: p.DisplayName,
+		AvatarURL:   p.AvatarURL,
+	}
+}
+
+// Disconnect убирает соединение из индекса пользователей
+func (h *Hub) Disconnect(client *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if conns, ok := h.users[client.ID]; ok {
+		delete(conns, client)
+		if len(conns) == 0 {
+			delete(h.users, client.ID)
+			// запланированные сообщения вскоре после выхода подписываются без запроса к БД
+			h.cacheOffline(client.ID, h.profiles[client.ID])
+			delete(h.profiles, client.ID)
+		}
+	}
+}
+
+// CloseSession закрывает соединения отозванной сессии; ReadPump затем убирает их из хаба
+func (h *Hub) CloseSession(sessionID string) {
+	h.mu.RLock()
+	var clients []*Client
+	for _, conns := range h.users {
+		for c := range conns {
+			if c.SessionID == sessionID {
+				clients = append(clients, c)
+			}
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, c := range clients {
+		// WriteControl и Close безопасны при конкурентной записи из WritePump
+		_ = c.Conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "session revoked"),
+			time.Now().Add(time.Second))
+		_ = c.Conn.Close()
+	}
+}
+
+// SendToUser отправляет сообщение во все соединения пользователя, даже если он не в канале
+func (h *Hub) SendToUser(userID string, msg model.Message) {
+	h.loadStamp(msg.UserID)
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	h.stamp(&msg)
+
+	for c := range h.users[userID] {
+		select {
+		case c.Send <- msg:
+		default:
+			h.logger.Warn("client channel overflow, dropping message",
+				slog.String("client_id", c.ID))
+		}
+	}
+}
+
+// SendToClient отправляет сообщение одному соединению, если оно еще подключено
+func (h *Hub) SendToClient(client *Client, msg model.Message) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if !h.users[client.ID][client] {
+		return
+	}
+
+	select {
+	case client.Send <- msg:
+	default:
+		h.logger.Warn("client channel overflow, dropping message",
+			slog.String("client_id", client.ID))
+	}
+}
+
+// NotifyClient эфемерное уведомление одному соединению: не сохраняется и не рассылается в канал
+func (h *Hub) NotifyClient(client *Client, channel, text string) {
+	h.SendToClient(client, EphemeralMessage(channel, text))
+}
+
+// NotifyUser эфемерное уведомление во все соединения пользователя
+func (h *Hub) NotifyUser(userID, channel, text string) {
+	h.SendToUser(userID, EphemeralMessage(channel, text))
+}
+
+// EphemeralMessage системное сообщение, которое видит только адресат
+func EphemeralMessage(channel, text string) model.Message {
+	return model.Message{
+		ID:      uuid.Must(uuid.NewV4()),
+		Type:    "ephemeral",
+		User:    "System",
+		Msg:     text,
+		Channel: channel,
+		Time:    time.Now(),
+	}
+}
+
+// GetUserIDsInChannel id пользователей, которые сейчас находятся в канале
+func (h *Hub) GetUserIDsInChannel(channelName string) []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	seen := make(map[string]bool)
+	ids := make([]string, 0)
+	for client := range h.channels[channelName] {
+		if !seen[client.ID] {
+			seen[client.ID] = true
+			ids = append(ids, client.ID)
+		}
+	}
+	return ids
+}
+
 func (h *Hub) GetChannels() []string {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
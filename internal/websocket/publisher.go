@@ -0,0 +1,56 @@
+package websocket
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/QuUteO/video-communication/internal/attachment/service"
+	"github.com/QuUteO/video-communication/internal/mention/service"
+	"github.com/QuUteO/video-communication/internal/model"
+)
+
+// Publisher Шаги после сохранения сообщения пользователя: привязка вложений, упоминания,
+// рассылка в канал и событие для вебхуков. Общий для сокета и отложенных сообщений
+type Publisher struct {
+	hub         *Hub
+	attachments attachmentservice.Service
+	mentions    mentionservice.Service
+	logger      *slog.Logger
+}
+
+func NewPublisher(hub *Hub, attachments attachmentservice.Service, mentions mentionservice.Service, logger *slog.Logger) *Publisher {
+	return &Publisher{
+		hub:         hub,
+		attachments: attachments,
+		mentions:    mentions,
+		logger:      logger,
+	}
+}
+
+// Publish msg уже сохранено, вложения проверены через Resolve
+func (p *Publisher) Publish(ctx context.Context, msg *model.Message) {
+	if err := p.attachments.Bind(ctx, msg); err != nil {
+		p.logger.Error("Error binding attachments:", slog.String("error", err.Error()))
+	}
+
+	mentions, err := p.mentions.Process(ctx, msg, p.hub.GetUserIDsInChannel(msg.Channel))
+	if err != nil {
+		p.logger.Error("Error processing mentions:", slog.String("error", err.Error()))
+	}
+
+	p.hub.Broadcast(*msg)
+	p.hub.PublishEvent(model.EventMessageCreated, msg.Channel, *msg)
+
+	// уведомления приходят во все соединения упомянутого пользователя
+	for _, mention := range mentions {
+		p.hub.SendToUser(mention.UserID.String(), model.Message{
+			ID:      msg.ID,
+			Type:    "mention",
+			UserID:  msg.UserID,
+			User:    msg.User,
+			Msg:     msg.Msg,
+			Channel: msg.Channel,
+			Time:    msg.Time,
+		})
+	}
+}
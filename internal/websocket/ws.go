@@ -1,22 +1,35 @@
 package websocket
 
 import (
+	"errors"
 	"log/slog"
 	"net/http"
 	"time"
 
+	"github.com/QuUteO/video-communication/internal/attachment/service"
+	authmiddleware "github.com/QuUteO/video-communication/internal/auth/middleware"
+	"github.com/QuUteO/video-communication/internal/command"
+	"github.com/QuUteO/video-communication/internal/poll/service"
+	"github.com/QuUteO/video-communication/internal/profile/service"
+	"github.com/QuUteO/video-communication/internal/schedule/service"
 	"github.com/QuUteO/video-communication/internal/user/service"
 	"github.com/gorilla/websocket"
 )
 
 type HandlerWS struct {
-	upgrader websocket.Upgrader
-	logger   *slog.Logger
-	hub      *Hub
-	service  service.Service
+	upgrader    websocket.Upgrader
+	logger      *slog.Logger
+	hub         *Hub
+	service     service.Service
+	attachments attachmentservice.Service
+	publisher   *Publisher
+	commands    *command.Registry
+	schedules   scheduleservice.Service
+	polls       pollservice.Service
+	profiles    profileservice.Service
 }
 
-func NewHandlerWS(hub *Hub, service service.Service, logger *slog.Logger) *HandlerWS {
+func NewHandlerWS(hub *Hub, service service.Service, attachments attachmentservice.Service, publisher *Publisher, commands *command.Registry, schedules scheduleservice.Service, polls pollservice.Service, profiles profileservice.Service, logger *slog.Logger) *HandlerWS {
 	return &HandlerWS{
 		upgrader: websocket.Upgrader{
 			ReadBufferSize:  1024,
@@ -26,9 +39,15 @@ func NewHandlerWS(hub *Hub, service service.Service, logger *slog.Logger) *Handl
 				return true
 			},
 		},
-		hub:     hub,
-		service: service,
-		logger:  logger,
+		hub:         hub,
+		service:     service,
+		attachments: attachments,
+		publisher:   publisher,
+		commands:    commands,
+		schedules:   schedules,
+		polls:       polls,
+		profiles:    profiles,
+		logger:      logger,
 	}
 }
 
@@ -36,7 +55,8 @@ func (h *HandlerWS) WebSocketHTTP(w http.ResponseWriter, r *http.Request) {
 	const op = "WebSocketHTTP"
 	h.logger.With("op: ", op)
 
-	userID := r.URL.Query().Get("user_id")
+	// id пользователя берется из JWT, а не из параметров запроса
+	userID, _ := r.Context().Value(authmiddleware.UserIDKey).(string)
 	username := r.URL.Query().Get("username")
 
 	if userID == "" {
@@ -53,7 +73,18 @@ func (h *HandlerWS) WebSocketHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	client := NewClient(userID, username, conn, h.service, h.hub, h.logger)
+	client := NewClient(userID, username, conn, h.service, h.attachments, h.publisher, h.commands, h.schedules, h.polls, h.hub, h.logger)
+	if stamp, err := h.profiles.Stamp(r.Context(), userID); err == nil {
+		client.Profile = stamp
+	} else if !errors.Is(err, profileservice.ErrNotFound) {
+		h.logger.Warn("Error loading profile", slog.String("user_id", userID), slog.String("error", err.Error()))
+	}
+	client.SessionID, _ = r.Context().Value(authmiddleware.SessionIDKey).(string)
+	if keyID, _ := r.Context().Value(authmiddleware.APIKeyIDKey).(string); keyID != "" {
+		// соединение по ключу API закрывается при отзыве ключа, как по отзыву сессии
+		client.SessionID = keyID
+	}
+	h.hub.Connect(client)
 
 	// запуск обработчиков
 	go client.ReadPump()
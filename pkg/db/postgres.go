@@ -15,6 +15,8 @@ type Client interface {
 	Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error)
 	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
 	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+	// Begin открывает транзакцию; внутри транзакции — точку сохранения (pgx.Tx тоже реализует Client)
+	Begin(ctx context.Context) (pgx.Tx, error)
 }
 
 func NewClient(ctx context.Context, ps *config.Postgres) (pool *pgxpool.Pool, err error) {
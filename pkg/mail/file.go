@@ -0,0 +1,52 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// File Заглушка для локальной разработки и тестов: пишет письма в лог и, если задан каталог, в .eml-файлы
+type File struct {
+	dir    string
+	logger *slog.Logger
+}
+
+func NewFile(dir string, logger *slog.Logger) (*File, error) {
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0o750); err != nil {
+			return nil, fmt.Errorf("failed to create mail dir: %w", err)
+		}
+	}
+	return &File{dir: dir, logger: logger}, nil
+}
+
+func (f *File) Send(_ context.Context, msg Message) error {
+	f.logger.Info("Mail sent",
+		slog.String("to", msg.To),
+		slog.String("subject", msg.Subject),
+		slog.String("body", msg.Body),
+	)
+
+	if f.dir == "" {
+		return nil
+	}
+
+	name := time.Now().UTC().Format("20060102T150405.000000000Z") + "-" + sanitize(msg.To) + ".eml"
+	return os.WriteFile(filepath.Join(f.dir, name), compose("", msg), 0o640)
+}
+
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '@':
+			return r
+		default:
+			return '_'
+		}
+	}, s)
+}
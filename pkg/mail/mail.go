@@ -0,0 +1,36 @@
+// Package mail отправляет служебные письма (подтверждение адреса, сброс пароля).
+package mail
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/QuUteO/video-communication/internal/config"
+)
+
+// Message Письмо в виде обычного текста
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender Способ доставки писем
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// New создает отправителя по настройкам из конфига
+func New(cfg *config.Mail, logger *slog.Logger) (Sender, error) {
+	switch cfg.Driver {
+	case "", "log":
+		return NewFile("", logger)
+	case "file":
+		return NewFile(cfg.FileDir, logger)
+	case "smtp":
+		return NewSMTP(&cfg.SMTP, cfg.From), nil
+	default:
+		return nil, fmt.Errorf("unknown mail driver: %s", cfg.Driver)
+	}
+}
@@ -0,0 +1,98 @@
+// Package ratelimit реализует ограничитель запросов «token bucket» с отдельным ведром на каждый ключ.
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// после этого времени без запросов ведро считается полным и удаляется
+const idleTTL = 10 * time.Minute
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Limiter Ограничитель с ведрами по ключу (id вебхука, IP и т.п.)
+type Limiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func New() *Limiter {
+	return &Limiter{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// Allow списывает один токен; perMinute — емкость ведра и скорость пополнения
+func (l *Limiter) Allow(key string, perMinute int) bool {
+	if perMinute <= 0 {
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	capacity := float64(perMinute)
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, last: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = min(capacity, b.tokens+now.Sub(b.last).Minutes()*capacity)
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// Exhausted сообщает, что ведро пусто, не списывая токен (проверка перед дорогой операцией)
+func (l *Limiter) Exhausted(key string, perMinute int) bool {
+	if perMinute <= 0 {
+		return false
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[key]
+	if !ok {
+		return false
+	}
+
+	capacity := float64(perMinute)
+	return min(capacity, b.tokens+l.now().Sub(b.last).Minutes()*capacity) < 1
+}
+
+// Reset забывает ведро ключа (например, после успешного входа)
+func (l *Limiter) Reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.buckets, key)
+}
+
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < idleTTL {
+		return
+	}
+	l.lastSweep = now
+
+	for key, b := range l.buckets {
+		if now.Sub(b.last) > idleTTL {
+			delete(l.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package richtext
+
+import (
+	"html"
+	"strings"
+)
+
+// HTML рендерит дерево в безопасный HTML
+func HTML(doc *Node) string {
+	var b strings.Builder
+	renderHTML(&b, doc)
+	return b.String()
+}
+
+func renderHTML(b *strings.Builder, n *Node) {
+	switch n.Type {
+	case Document:
+		renderChildrenHTML(b, n)
+	case Paragraph:
+		b.WriteString("<p>")
+		renderChildrenHTML(b, n)
+		b.WriteString("</p>")
+	case Quote:
+		b.WriteString("<blockquote>")
+		renderChildrenHTML(b, n)
+		b.WriteString("</blockquote>")
+	case CodeBlock:
+		if n.Lang != "" {
+			b.WriteString(`<pre><code class="language-` + html.EscapeString(n.Lang) + `">`)
+		} else {
+			b.WriteString("<pre><code>")
+		}
+		b.WriteString(html.EscapeString(n.Text))
+		b.WriteString("</code></pre>")
+	case Text:
+		b.WriteString(html.EscapeString(n.Text))
+	case Bold:
+		b.WriteString("<strong>")
+		renderChildrenHTML(b, n)
+		b.WriteString("</strong>")
+	case Italic:
+		b.WriteString("<em>")
+		renderChildrenHTML(b, n)
+		b.WriteString("</em>")
+	case Code:
+		b.WriteString("<code>")
+		b.WriteString(html.EscapeString(n.Text))
+		b.WriteString("</code>")
+	case Link:
+		b.WriteString(`<a href="` + html.EscapeString(n.URL) + `" rel="nofollow noopener noreferrer" target="_blank">`)
+		renderChildrenHTML(b, n)
+		b.WriteString("</a>")
+	case LineBreak:
+		b.WriteString("<br>")
+	}
+}
+
+func renderChildrenHTML(b *strings.Builder, n *Node) {
+	for _, child := range n.Children {
+		renderHTML(b, child)
+	}
+}
+
+// Markdown рендерит дерево обратно в нормализованный markdown
+func Markdown(doc *Node) string {
+	var b strings.Builder
+	renderBlocksMarkdown(&b, doc.Children, "")
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func renderBlocksMarkdown(b *strings.Builder, blocks []*Node, prefix string) {
+	for i, n := range blocks {
+		if i > 0 {
+			b.WriteString(strings.TrimRight(prefix, " ") + "\n")
+		}
+
+		switch n.Type {
+		case Paragraph:
+			var line strings.Builder
+			for _, child := range n.Children {
+				if child.Type == LineBreak {
+					b.WriteString(prefix + escapeLineStart(line.String()) + "\n")
+					line.Reset()
+					continue
+				}
+				renderInlineMarkdown(&line, child)
+			}
+			b.WriteString(prefix + escapeLineStart(line.String()) + "\n")
+		case Quote:
+			renderBlocksMarkdown(b, n.Children, prefix+"> ")
+		case CodeBlock:
+			b.WriteString(prefix + "```" + n.Lang + "\n")
+			for _, line := range strings.Split(n.Text, "\n") {
+				b.WriteString(prefix + line + "\n")
+			}
+			b.WriteString(prefix + "```\n")
+		}
+	}
+}
+
+func renderInlineMarkdown(b *strings.Builder, n *Node) {
+	switch n.Type {
+	case Text:
+		b.WriteString(escapeMarkdown(n.Text))
+	case Bold:
+		b.WriteString("**")
+		renderChildrenMarkdown(b, n)
+		b.WriteString("**")
+	case Italic:
+		b.WriteString("*")
+		renderChildrenMarkdown(b, n)
+		b.WriteString("*")
+	case Code:
+		b.WriteString("`" + n.Text + "`")
+	case Link:
+		b.WriteString("[")
+		renderChildrenMarkdown(b, n)
+		b.WriteString("](" + n.URL + ")")
+	}
+}
+
+func renderChildrenMarkdown(b *strings.Builder, n *Node) {
+	for _, child := range n.Children {
+		renderInlineMarkdown(b, child)
+	}
+}
+
+// escapeMarkdown экранирует служебные символы; «_» внутри слова (snake_case, @user_name) оставляем как есть
+func escapeMarkdown(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch c {
+		case '\\', '`', '*', '[', ']':
+			b.WriteByte('\\')
+		case '_':
+			if i == 0 || i+1 == len(s) || !isWordByte(s[i-1]) || !isWordByte(s[i+1]) {
+				b.WriteByte('\\')
+			}
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// escapeLineStart экранирует маркер цитаты или блока кода в начале строки абзаца,
+// иначе повторный разбор нормализованного текста превратит ее в блок
+func escapeLineStart(line string) string {
+	rest := strings.TrimLeft(line, " \t")
+	if strings.HasPrefix(rest, ">") || strings.HasPrefix(rest, "```") {
+		indent := line[:len(line)-len(rest)]
+		return indent + "\\" + rest
+	}
+	return line
+}
@@ -0,0 +1,375 @@
+// Package richtext разбирает подмножество markdown (жирный, курсив, код,
+// блоки кода, ссылки, цитаты) в безопасное дерево и рендерит его в HTML.
+// Любой HTML во входном тексте экранируется, ссылки допускаются только http(s) и mailto.
+package richtext
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	MaxLength     = 4000 // максимальная длина исходного текста в символах
+	maxQuoteDepth = 3
+)
+
+// Форматы исходного текста
+const (
+	FormatPlain    = "plain"
+	FormatMarkdown = "markdown"
+)
+
+var (
+	ErrUnknownFormat   = errors.New("unknown message format")
+	ErrTooLong         = errors.New("message is too long")
+	ErrScriptInjection = errors.New("html tags are not allowed")
+	ErrUnsafeLink      = errors.New("link scheme is not allowed")
+	ErrInvalidURL      = errors.New("invalid link url")
+)
+
+type NodeType string
+
+const (
+	Document  NodeType = "document"
+	Paragraph NodeType = "paragraph"
+	Quote     NodeType = "quote"
+	CodeBlock NodeType = "code_block"
+	Text      NodeType = "text"
+	Bold      NodeType = "bold"
+	Italic    NodeType = "italic"
+	Code      NodeType = "code"
+	Link      NodeType = "link"
+	LineBreak NodeType = "line_break"
+)
+
+// Node Узел дерева разметки
+type Node struct {
+	Type     NodeType `json:"type"`
+	Text     string   `json:"text,omitempty"` // text, code, code_block
+	URL      string   `json:"url,omitempty"`  // link
+	Lang     string   `json:"lang,omitempty"` // code_block
+	Children []*Node  `json:"children,omitempty"`
+}
+
+// запрещенные теги вне блоков кода: сообщение с ними отклоняется целиком
+var forbiddenTags = []string{"<script", "</script", "<iframe", "<object", "<embed"}
+
+// Render проверяет текст в заданном формате: для plain только длина,
+// для markdown — нормализованный текст и HTML. Пустой формат означает plain.
+func Render(format, src string) (text string, html string, normalizedFormat string, err error) {
+	switch format {
+	case "", FormatPlain:
+		if utf8.RuneCountInString(src) > MaxLength {
+			return "", "", "", ErrTooLong
+		}
+		return src, "", FormatPlain, nil
+	case FormatMarkdown:
+		text, html, err := Normalize(src)
+		if err != nil {
+			return "", "", "", err
+		}
+		return text, html, FormatMarkdown, nil
+	default:
+		return "", "", "", ErrUnknownFormat
+	}
+}
+
+// Normalize разбирает markdown и возвращает нормализованный текст и безопасный HTML
+func Normalize(src string) (markdown string, html string, err error) {
+	doc, err := Parse(src)
+	if err != nil {
+		return "", "", err
+	}
+	return Markdown(doc), HTML(doc), nil
+}
+
+// Parse разбирает текст в дерево; возвращает ошибку для небезопасных ссылок
+func Parse(src string) (*Node, error) {
+	if utf8.RuneCountInString(src) > MaxLength {
+		return nil, ErrTooLong
+	}
+
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	src = strings.ReplaceAll(src, "\r", "\n")
+
+	children, err := parseBlocks(strings.Split(src, "\n"), 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Node{Type: Document, Children: children}, nil
+}
+
+func parseBlocks(lines []string, depth int) ([]*Node, error) {
+	var blocks []*Node
+	var paragraph []string
+
+	flush := func() error {
+		if len(paragraph) == 0 {
+			return nil
+		}
+		p, err := parseParagraph(paragraph)
+		if err != nil {
+			return err
+		}
+		blocks = append(blocks, p)
+		paragraph = nil
+		return nil
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case strings.HasPrefix(trimmed, "```"):
+			if err := flush(); err != nil {
+				return nil, err
+			}
+
+			lang := sanitizeLang(strings.TrimSpace(strings.TrimPrefix(trimmed, "```")))
+			var code []string
+			// незакрытый блок кода продолжается до конца сообщения
+			for i++; i < len(lines) && strings.TrimSpace(lines[i]) != "```"; i++ {
+				code = append(code, lines[i])
+			}
+			blocks = append(blocks, &Node{Type: CodeBlock, Lang: lang, Text: strings.Join(code, "\n")})
+
+		case strings.HasPrefix(trimmed, ">") && depth < maxQuoteDepth:
+			if err := flush(); err != nil {
+				return nil, err
+			}
+
+			var quoted []string
+			for ; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), ">"); i++ {
+				q := strings.TrimPrefix(strings.TrimSpace(lines[i]), ">")
+				quoted = append(quoted, strings.TrimPrefix(q, " "))
+			}
+			i--
+
+			children, err := parseBlocks(quoted, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			blocks = append(blocks, &Node{Type: Quote, Children: children})
+
+		case trimmed == "":
+			if err := flush(); err != nil {
+				return nil, err
+			}
+
+		default:
+			paragraph = append(paragraph, strings.TrimRight(line, " \t"))
+		}
+	}
+
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return blocks, nil
+}
+
+func parseParagraph(lines []string) (*Node, error) {
+	p := &Node{Type: Paragraph}
+	for i, line := range lines {
+		if i > 0 {
+			p.Children = append(p.Children, &Node{Type: LineBreak})
+		}
+		inline, err := parseInline(line, true)
+		if err != nil {
+			return nil, err
+		}
+		p.Children = append(p.Children, inline...)
+	}
+	return p, nil
+}
+
+// parseInline разбирает строку; allowNested=false запрещает вложенное форматирование (внутри ссылок и выделений)
+func parseInline(s string, allowNested bool) ([]*Node, error) {
+	var nodes []*Node
+	var text strings.Builder
+
+	flush := func() error {
+		if text.Len() == 0 {
+			return nil
+		}
+		if err := checkText(text.String()); err != nil {
+			return err
+		}
+		nodes = append(nodes, &Node{Type: Text, Text: text.String()})
+		text.Reset()
+		return nil
+	}
+	emit := func(n *Node) error {
+		if err := flush(); err != nil {
+			return err
+		}
+		nodes = append(nodes, n)
+		return nil
+	}
+
+	for i := 0; i < len(s); {
+		c := s[i]
+
+		switch {
+		case c == '\\' && i+1 < len(s) && strings.IndexByte("\\`*_[]()>", s[i+1]) >= 0:
+			text.WriteByte(s[i+1])
+			i += 2
+			continue
+
+		case c == '`':
+			if end := strings.IndexByte(s[i+1:], '`'); end > 0 {
+				if err := emit(&Node{Type: Code, Text: s[i+1 : i+1+end]}); err != nil {
+					return nil, err
+				}
+				i += end + 2
+				continue
+			}
+
+		case c == '*' && strings.HasPrefix(s[i:], "**") && allowNested:
+			if end := strings.Index(s[i+2:], "**"); end > 0 {
+				children, err := parseInline(s[i+2:i+2+end], false)
+				if err != nil {
+					return nil, err
+				}
+				if err := emit(&Node{Type: Bold, Children: children}); err != nil {
+					return nil, err
+				}
+				i += end + 4
+				continue
+			}
+
+		case (c == '*' || c == '_') && allowNested && opensEmphasis(s, i):
+			if end := closingEmphasis(s, i); end > 0 {
+				children, err := parseInline(s[i+1:end], false)
+				if err != nil {
+					return nil, err
+				}
+				if err := emit(&Node{Type: Italic, Children: children}); err != nil {
+					return nil, err
+				}
+				i = end + 1
+				continue
+			}
+
+		case c == '[':
+			if label, href, n, ok := scanLink(s[i:]); ok {
+				safe, err := validateURL(href)
+				if err != nil {
+					return nil, err
+				}
+				children, err := parseInline(label, false)
+				if err != nil {
+					return nil, err
+				}
+				if err := emit(&Node{Type: Link, URL: safe, Children: children}); err != nil {
+					return nil, err
+				}
+				i += n
+				continue
+			}
+		}
+
+		text.WriteByte(c)
+		i++
+	}
+
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return nodes, nil
+}
+
+func checkText(s string) error {
+	lower := strings.ToLower(s)
+	for _, tag := range forbiddenTags {
+		if strings.Contains(lower, tag) {
+			return ErrScriptInjection
+		}
+	}
+	return nil
+}
+
+// opensEmphasis: «_» внутри слова (snake_case) не считается разметкой
+func opensEmphasis(s string, i int) bool {
+	if i+1 >= len(s) || s[i+1] == ' ' || s[i+1] == s[i] {
+		return false
+	}
+	return s[i] == '*' || i == 0 || !isWordByte(s[i-1])
+}
+
+func closingEmphasis(s string, i int) int {
+	delim := s[i]
+	for j := i + 2; j < len(s); j++ {
+		if s[j] != delim || s[j-1] == ' ' {
+			continue
+		}
+		if delim == '_' && j+1 < len(s) && isWordByte(s[j+1]) {
+			continue
+		}
+		return j
+	}
+	return -1
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || b >= 0x80 || ('0' <= b && b <= '9') || ('a' <= b && b <= 'z') || ('A' <= b && b <= 'Z')
+}
+
+// scanLink разбирает [label](url); возвращает длину разобранного фрагмента
+func scanLink(s string) (label, href string, n int, ok bool) {
+	closeLabel := strings.Index(s, "](")
+	if closeLabel <= 1 || strings.ContainsAny(s[1:closeLabel], "[]") {
+		return "", "", 0, false
+	}
+
+	closeURL := strings.IndexByte(s[closeLabel+2:], ')')
+	if closeURL <= 0 {
+		return "", "", 0, false
+	}
+
+	href = strings.TrimSpace(s[closeLabel+2 : closeLabel+2+closeURL])
+	if href == "" || strings.ContainsAny(href, " \t") {
+		return "", "", 0, false
+	}
+
+	return s[1:closeLabel], href, closeLabel + 3 + closeURL, true
+}
+
+func validateURL(raw string) (string, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", ErrInvalidURL
+	}
+
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+		if u.Host == "" {
+			return "", ErrInvalidURL
+		}
+	case "mailto":
+		if u.Opaque == "" {
+			return "", ErrInvalidURL
+		}
+	default:
+		// javascript:, data:, vbscript: и относительные ссылки
+		return "", ErrUnsafeLink
+	}
+
+	return u.String(), nil
+}
+
+func sanitizeLang(lang string) string {
+	lang = strings.ToLower(lang)
+	if len(lang) > 20 {
+		return ""
+	}
+	for _, r := range lang {
+		if !(r >= 'a' && r <= 'z') && !(r >= '0' && r <= '9') && r != '+' && r != '-' && r != '#' {
+			return ""
+		}
+	}
+	return lang
+}
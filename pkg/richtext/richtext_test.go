@@ -0,0 +1,129 @@
+package richtext
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeRejectsUnsafeLinks(t *testing.T) {
+	for _, src := range []string{
+		"[x](javascript:alert(1))",
+		"[x](JaVaScRiPt:alert(1))",
+		"[x](  javascript:alert(1))",
+		"[x](vbscript:msgbox(1))",
+		"[x](data:text/html;base64,PHNjcmlwdD5hbGVydCgxKTwvc2NyaXB0Pg==)",
+	} {
+		if _, _, err := Normalize(src); !errors.Is(err, ErrUnsafeLink) {
+			t.Errorf("Normalize(%q) error = %v, want %v", src, err, ErrUnsafeLink)
+		}
+	}
+}
+
+func TestNormalizeRejectsScriptTags(t *testing.T) {
+	for _, src := range []string{
+		"<script>alert(1)</script>",
+		"hi <SCRIPT src=//evil.example></SCRIPT>",
+		"<iframe src=https://evil.example></iframe>",
+		"**<object data=x>**",
+	} {
+		if _, _, err := Normalize(src); !errors.Is(err, ErrScriptInjection) {
+			t.Errorf("Normalize(%q) error = %v, want %v", src, err, ErrScriptInjection)
+		}
+	}
+}
+
+func TestNormalizeEscapesHTML(t *testing.T) {
+	tests := []struct {
+		src  string
+		html string
+	}{
+		{
+			src:  "<img src=x onerror=alert(1)>",
+			html: "<p>&lt;img src=x onerror=alert(1)&gt;</p>",
+		},
+		{
+			src:  "**<b>hi</b>**",
+			html: "<p><strong>&lt;b&gt;hi&lt;/b&gt;</strong></p>",
+		},
+		{
+			src:  "`<iframe>`",
+			html: "<p><code>&lt;iframe&gt;</code></p>",
+		},
+		{
+			// в блоке кода теги допустимы как текст
+			src:  "```\n<script>alert(1)</script>\n```",
+			html: "<pre><code>&lt;script&gt;alert(1)&lt;/script&gt;</code></pre>",
+		},
+		{
+			// кавычка в адресе не закрывает атрибут href
+			src:  `[ok](https://example.com/?a="><b>)`,
+			html: `<p><a href="https://example.com/?a=&#34;&gt;&lt;b&gt;" rel="nofollow noopener noreferrer" target="_blank">ok</a></p>`,
+		},
+	}
+
+	for _, tt := range tests {
+		_, html, err := Normalize(tt.src)
+		if err != nil {
+			t.Errorf("Normalize(%q): %v", tt.src, err)
+			continue
+		}
+		if html != tt.html {
+			t.Errorf("Normalize(%q) html = %q, want %q", tt.src, html, tt.html)
+		}
+	}
+}
+
+func TestNormalizeKeepsSafeLinks(t *testing.T) {
+	_, html, err := Normalize("[site](https://example.com) and [mail](mailto:a@example.com)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`href="https://example.com"`, `href="mailto:a@example.com"`} {
+		if !strings.Contains(html, want) {
+			t.Errorf("html %q does not contain %s", html, want)
+		}
+	}
+}
+
+func TestRenderPlainIsNotParsed(t *testing.T) {
+	text, html, format, err := Render("", "<b>[x](javascript:alert(1))</b>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format != FormatPlain || html != "" || text != "<b>[x](javascript:alert(1))</b>" {
+		t.Errorf("Render plain = %q, %q, %q", text, html, format)
+	}
+}
+
+// нормализованный текст при повторной нормализации не меняется ни сам, ни по смыслу
+func TestNormalizeIdempotent(t *testing.T) {
+	for _, src := range []string{
+		`\> not a quote`,
+		` \> indented`,
+		"line\n\\> second line",
+		"> \\> quoted marker",
+		"\\`\\`\\` not a fence",
+		"x\n \\```go",
+		"> ```\n> code\n> ```",
+		"**bold** *it* `code` [link](https://example.com)",
+		"> a\n>\n> b",
+		"> > > > deep",
+		"\\_a\\_ snake_case",
+	} {
+		once, html, err := Normalize(src)
+		if err != nil {
+			t.Errorf("Normalize(%q): %v", src, err)
+			continue
+		}
+
+		twice, html2, err := Normalize(once)
+		if err != nil {
+			t.Errorf("Normalize(%q): %v", once, err)
+			continue
+		}
+		if twice != once || html2 != html {
+			t.Errorf("Normalize(%q) = %q (%s), again = %q (%s)", src, once, html, twice, html2)
+		}
+	}
+}
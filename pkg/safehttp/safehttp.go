@@ -0,0 +1,96 @@
+package safehttp
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenAddress адрес назначения во внутренней сети
+var ErrForbiddenAddress = errors.New("destination address is not allowed")
+
+// blocked Сети, недоступные для исходящих запросов по пользовательским URL,
+// помимо loopback, private и link-local, которые проверяет netip
+var blocked = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"), // CGNAT
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"), // NAT64 поверх IPv4
+}
+
+// NewClient HTTP-клиент для URL, которые задают пользователи (вебхуки, боты).
+// Адрес проверяется при каждом соединении уже после DNS, поэтому не помогают ни
+// редиректы, ни DNS rebinding. allowed — сети-исключения в формате CIDR,
+// например 127.0.0.0/8 для локального приемника в тестах.
+func NewClient(timeout time.Duration, allowed []string) (*http.Client, error) {
+	allow := make([]netip.Prefix, 0, len(allowed))
+	for _, cidr := range allowed {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid allowed network %q: %w", cidr, err)
+		}
+		allow = append(allow, prefix.Masked())
+	}
+
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			return checkAddress(address, allow)
+		},
+	}
+
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			// через прокси проверялся бы адрес прокси, а не получателя
+			Proxy:                 nil,
+			DialContext:           dialer.DialContext,
+			ForceAttemptHTTP2:     true,
+			MaxIdleConns:          100,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: time.Second,
+		},
+	}, nil
+}
+
+func checkAddress(address string, allow []netip.Prefix) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, address)
+	}
+
+	ip := addrPort.Addr().Unmap()
+	for _, prefix := range allow {
+		if prefix.Contains(ip) {
+			return nil
+		}
+	}
+
+	if !Public(ip) {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, ip)
+	}
+	return nil
+}
+
+// Public адрес из публичного интернета
+func Public(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if !ip.IsValid() || ip.IsUnspecified() || ip.IsLoopback() || ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() {
+		return false
+	}
+	for _, prefix := range blocked {
+		if prefix.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
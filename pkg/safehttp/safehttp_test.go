@@ -0,0 +1,55 @@
+package safehttp
+
+import (
+	"errors"
+	"net/netip"
+	"testing"
+)
+
+func TestPublic(t *testing.T) {
+	tests := map[string]bool{
+		"8.8.8.8":            true,
+		"2606:4700::1111":    true,
+		"127.0.0.1":          false,
+		"10.1.2.3":           false,
+		"172.16.0.1":         false,
+		"192.168.1.1":        false,
+		"169.254.169.254":    false,
+		"100.64.0.1":         false,
+		"0.0.0.0":            false,
+		"224.0.0.1":          false,
+		"::1":                false,
+		"fd00::1":            false,
+		"fe80::1":            false,
+		"::ffff:127.0.0.1":   false,
+		"64:ff9b::a9fe:a9fe": false,
+	}
+
+	for addr, want := range tests {
+		if got := Public(netip.MustParseAddr(addr)); got != want {
+			t.Errorf("Public(%s) = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+func TestCheckAddress(t *testing.T) {
+	allow := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+
+	if err := checkAddress("10.1.2.3:443", allow); err != nil {
+		t.Errorf("allowed network: %v", err)
+	}
+	if err := checkAddress("93.184.216.34:443", allow); err != nil {
+		t.Errorf("public address: %v", err)
+	}
+	for _, address := range []string{"192.168.0.1:80", "[::1]:80", "localhost:80"} {
+		if err := checkAddress(address, allow); !errors.Is(err, ErrForbiddenAddress) {
+			t.Errorf("checkAddress(%s) = %v, want %v", address, err, ErrForbiddenAddress)
+		}
+	}
+}
+
+func TestNewClientInvalidNetwork(t *testing.T) {
+	if _, err := NewClient(0, []string{"not-a-cidr"}); err == nil {
+		t.Error("NewClient accepted invalid CIDR")
+	}
+}
@@ -0,0 +1,67 @@
+package totp
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+)
+
+// rfcSecret ключ из приложения B RFC 6238 для HMAC-SHA1
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+// значения RFC даны для 8 цифр; 6-значный код — их последние 6 цифр
+var rfcVectors = []struct {
+	unix int64
+	code string
+}{
+	{unix: 59, code: "287082"},
+	{unix: 1111111109, code: "081804"},
+	{unix: 1111111111, code: "050471"},
+	{unix: 1234567890, code: "005924"},
+	{unix: 2000000000, code: "279037"},
+	{unix: 20000000000, code: "353130"},
+}
+
+func TestCodeRFC6238(t *testing.T) {
+	for _, v := range rfcVectors {
+		got, err := Code(rfcSecret, Step(time.Unix(v.unix, 0)))
+		if err != nil {
+			t.Fatalf("Code(%d): %v", v.unix, err)
+		}
+		if got != v.code {
+			t.Errorf("Code(%d) = %s, want %s", v.unix, got, v.code)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, v := range rfcVectors {
+		now := time.Unix(v.unix, 0)
+
+		step, ok := Validate(rfcSecret, v.code, now)
+		if !ok || step != Step(now) {
+			t.Errorf("Validate(%d) = %d, %v; want %d, true", v.unix, step, ok, Step(now))
+		}
+
+		// соседний шаг допускается из-за расхождения часов, более далекий — нет
+		if _, ok := Validate(rfcSecret, v.code, now.Add(Period)); !ok {
+			t.Errorf("Validate(%d) rejected code from the previous step", v.unix)
+		}
+		if _, ok := Validate(rfcSecret, v.code, now.Add(3*Period)); ok {
+			t.Errorf("Validate(%d) accepted code three steps old", v.unix)
+		}
+	}
+}
+
+func TestValidateRejectsMalformedCodes(t *testing.T) {
+	now := time.Unix(59, 0)
+
+	for _, code := range []string{"", "28708", "2870820", "abcdef"} {
+		if _, ok := Validate(rfcSecret, code, now); ok {
+			t.Errorf("Validate accepted %q", code)
+		}
+	}
+	if _, ok := Validate(rfcSecret, " 287 082 ", now); !ok {
+		t.Error("Validate rejected code with spaces")
+	}
+}